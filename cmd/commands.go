@@ -0,0 +1,211 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"finance_project/internal/config"
+	"finance_project/internal/models"
+	"finance_project/internal/money"
+	"finance_project/internal/ratefeeds"
+	"finance_project/internal/redis_client"
+	"finance_project/internal/services"
+	"finance_project/internal/statements"
+)
+
+// runCommand выполняет подкоманду CLI вместо запуска HTTP-сервера.
+func runCommand(db *sql.DB, cfg *config.Config, name string, args []string) error {
+	switch name {
+	case "import-rates":
+		return importRates(db, cfg, args)
+	case "set-rate":
+		return setRate(db, cfg, args)
+	case "delete-rate":
+		return deleteRate(db, cfg, args)
+	case "import-statement":
+		return importStatement(db, cfg, args)
+	default:
+		return fmt.Errorf("unknown command %q (available: import-rates, set-rate, delete-rate, import-statement)", name)
+	}
+}
+
+// importRates загружает курсы валют из файла или по URL:
+//
+//	finance import-rates -format ecb -source ./eurofxref-hist.xml
+func importRates(db *sql.DB, cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("import-rates", flag.ContinueOnError)
+	source := flags.String("source", "", "path or http(s) URL of the rate feed")
+	format := flags.String("format", ratefeeds.FormatAuto, "feed format: ecb, nbk, csv or auto")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *source == "" && flags.NArg() > 0 {
+		*source = flags.Arg(0)
+	}
+	if *source == "" {
+		return fmt.Errorf("import-rates: -source is required")
+	}
+
+	rates := services.NewCurrencyRateService(db, cfg.Currency.BaseCurrency)
+	result, err := rates.ImportRates(*source, *format)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Imported rates from %s for %d date(s): %d new, %d updated, %d unchanged\n",
+		result.Source, len(result.Dates), result.Inserted, result.Updated, result.Unchanged)
+	if len(result.Missing) > 0 {
+		fmt.Printf("Missing pairs: %s\n", strings.Join(result.Missing, ", "))
+	}
+	return nil
+}
+
+// setRate сохраняет курс валюты вручную. Курсы общие для всех пользователей, поэтому через API
+// они не меняются:
+//
+//	finance set-rate -base USD -target KZT -rate 470.25 -date 2026-10-01
+func setRate(db *sql.DB, cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("set-rate", flag.ContinueOnError)
+	var rate models.CurrencyRate
+	flags.StringVar(&rate.BaseCurrency, "base", "", "base currency")
+	flags.StringVar(&rate.TargetCurrency, "target", "", "target currency")
+	raw := flags.String("rate", "", "units of the target currency per unit of the base currency")
+	date := flags.String("date", "", "rate date, YYYY-MM-DD (default today)")
+	flags.StringVar(&rate.Source, "source", "", "source label (default manual)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	var err error
+	if rate.Rate, err = money.ParseRate(*raw); err != nil {
+		return fmt.Errorf("set-rate: invalid -rate: %v", err)
+	}
+	if *date != "" {
+		if rate.Date, err = time.Parse("2006-01-02", *date); err != nil {
+			return fmt.Errorf("set-rate: invalid -date: %v", err)
+		}
+	}
+
+	rates := services.NewCurrencyRateService(db, cfg.Currency.BaseCurrency)
+	if _, err := rates.SetRate(&rate); err != nil {
+		return err
+	}
+	fmt.Printf("Saved rate %d: 1 %s = %s %s on %s\n", rate.ID, rate.BaseCurrency, rate.Rate, rate.TargetCurrency, rate.Date.Format("2006-01-02"))
+	return nil
+}
+
+// deleteRate удаляет сохранённый курс по ID:
+//
+//	finance delete-rate -id 42
+func deleteRate(db *sql.DB, cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("delete-rate", flag.ContinueOnError)
+	id := flags.Int("id", 0, "currency rate ID")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *id == 0 {
+		return fmt.Errorf("delete-rate: -id is required")
+	}
+
+	rates := services.NewCurrencyRateService(db, cfg.Currency.BaseCurrency)
+	if err := rates.DeleteRate(*id); err != nil {
+		return err
+	}
+	fmt.Printf("Deleted rate %d\n", *id)
+	return nil
+}
+
+// importStatement импортирует выписку (CSV по профилю, OFX/QFX, QIF, camt.053 или MT940) от имени владельца счёта:
+//
+//	finance import-statement -account 2 -profile 1 -file ./statement.csv -dry-run
+//	finance import-statement -account 2 -file ./statement.ofx
+//	finance import-statement -account 3 -file ./camt053.xml -statement-account DE89370400440532013000
+func importStatement(db *sql.DB, cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("import-statement", flag.ContinueOnError)
+	var request models.StatementImportRequest
+	flags.IntVar(&request.AccountID, "account", 0, "account to import into")
+	flags.IntVar(&request.ProfileID, "profile", 0, "statement profile ID (CSV)")
+	flags.IntVar(&request.CategoryID, "category", 0, "category assigned to imported transactions")
+	flags.StringVar(&request.Format, "format", statements.FormatAuto, "statement format: csv, ofx, qfx, qif, camt053, mt940 or auto")
+	flags.StringVar(&request.DateFormat, "date-format", "", "date order for QIF, e.g. DD/MM/YYYY (default MM/DD/YYYY)")
+	flags.StringVar(&request.DecimalSeparator, "decimal-separator", "", "decimal separator for QIF: . or ,")
+	flags.StringVar(&request.StatementAccount, "statement-account", "", "bank account (IBAN or number) to import from a camt.053/MT940 file")
+	flags.BoolVar(&request.DryRun, "dry-run", false, "preview without creating transactions")
+	path := flags.String("file", "", "path of the statement file")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *path == "" && flags.NArg() > 0 {
+		*path = flags.Arg(0)
+	}
+	if request.AccountID == 0 || *path == "" {
+		return fmt.Errorf("import-statement: -account and -file are required")
+	}
+	request.FileName = *path
+
+	file, err := os.Open(*path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	redisClient := redis_client.NewRedisClient(cfg.Redis.Addr, cfg.Redis.Password, cfg.Redis.DB)
+	rates := services.NewCurrencyRateService(db, cfg.Currency.BaseCurrency)
+	transactions := services.NewTransactionService(db, redisClient, rates)
+	transactions.Duplicates = services.NewDuplicateService(db, transactions, cfg.Duplicates)
+	transactions.Rules = services.NewCategoryRuleService(db, transactions)
+	transactions.Suggestions = services.NewCategorySuggestionService(db, transactions)
+	service := services.NewStatementService(db, transactions)
+	userID, err := service.AccountOwner(request.AccountID)
+	if err != nil {
+		return err
+	}
+	result, err := service.Import(userID, request, file)
+	if err != nil {
+		return err
+	}
+	printStatementResult(result)
+	return nil
+}
+
+// printStatementResult печатает отчёт об импорте выписки построчно.
+func printStatementResult(result *models.StatementImportResult) {
+	for _, row := range result.Rows {
+		if row.Error != "" {
+			fmt.Printf("line %d: error: %s\n", row.Line, row.Error)
+			continue
+		}
+		t := row.Transaction
+		status := ""
+		if row.Duplicate {
+			status = " (already imported)"
+		} else if len(t.PossibleDuplicates) > 0 {
+			status = fmt.Sprintf(" (possible duplicate of transaction %d)", t.PossibleDuplicates[0].DuplicateOf)
+		}
+		if c := t.SuggestedCategory; c != nil {
+			status += fmt.Sprintf(" (suggested category: %s, %.0f%%)", c.Name, c.Confidence*100)
+		}
+		fmt.Printf("line %d: %s %s %s %s %s%s\n", row.Line, t.CreatedAt.Format("2006-01-02"), t.Type, t.Amount, t.Currency, t.Description, status)
+	}
+	if result.DryRun {
+		fmt.Printf("Dry run (%s): %d row(s), %d new, %d already imported, %d with errors\n",
+			result.Format, result.Total, result.Total-result.Skipped-result.Failed, result.Skipped, result.Failed)
+	} else {
+		fmt.Printf("Imported %d of %d row(s) (%s), %d already imported, %d with errors\n",
+			result.Imported, result.Total, result.Format, result.Skipped, result.Failed)
+	}
+	if result.Flagged > 0 {
+		fmt.Printf("%d row(s) look like existing transactions, review them at /duplicates\n", result.Flagged)
+	}
+	if b := result.Balance; b != nil {
+		if b.Reconciled {
+			fmt.Printf("Statement balance %s matches the account balance\n", b.StatementBalance)
+		} else {
+			fmt.Printf("Statement balance %s, account balance %s: difference %s\n", b.StatementBalance, b.AccountBalance, b.Difference)
+		}
+	}
+}
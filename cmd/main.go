@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+	"os"
 
 	_ "finance_project/docs"
 	"finance_project/internal/app"
@@ -14,6 +15,9 @@ import (
 	_ "github.com/lib/pq" // PostgreSQL driver
 )
 
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
 func main() {
 	// Загружаем .env файл
 	err := godotenv.Load("/home/abylay/finance_project/.env")
@@ -50,6 +54,14 @@ func main() {
 		fmt.Println("No new migrations were applied.")
 	}
 
+	// Подкоманды CLI, например: import-rates -source ./eurofxref-daily.xml
+	if len(os.Args) > 1 {
+		if err := runCommand(db, cfg, os.Args[1], os.Args[2:]); err != nil {
+			log.Fatalf("Command %s failed: %v", os.Args[1], err)
+		}
+		return
+	}
+
 	// Запуск приложения
 	app.Run(cfg)
 }
@@ -1,6 +1,7 @@
 package app
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
@@ -8,6 +9,8 @@ import (
 	"finance_project/internal/config"
 	"finance_project/internal/database"
 	"finance_project/internal/handlers"
+	"finance_project/internal/jobs"
+	"finance_project/internal/middleware"
 	"finance_project/internal/redis_client"
 	"finance_project/internal/services"
 
@@ -26,26 +29,88 @@ func Run(cfg *config.Config) {
 	// Подключение к Redis
 	redisClient := redis_client.NewRedisClient(cfg.Redis.Addr, cfg.Redis.Password, cfg.Redis.DB)
 	// Initialize services
-	userService := services.NewUserService(db)
-	accountService := services.NewAccountService(db)
-	transactionService := services.NewTransactionService(db, redisClient)
-	categoryService := services.NewCategoryService(db)
+	authService := services.NewAuthService(redisClient, cfg.Auth)
+	userService := services.NewUserService(db, cfg.Auth.BcryptCost)
+	currencyRateService := services.NewCurrencyRateService(db, cfg.Currency.BaseCurrency)
+	transactionService := services.NewTransactionService(db, redisClient, currencyRateService)
+	accountService := services.NewAccountService(db, transactionService)
+	categoryService := services.NewCategoryService(db, transactionService)
 	financialGoalsService := services.NewFinancialGoalsService(db)
-	reportsService := services.NewReportsService(db)
+	reportsService := services.NewReportsService(db, currencyRateService)
+	transferService := services.NewTransferService(db, transactionService)
+	scheduledTransactionService := services.NewScheduledTransactionService(db, transactionService)
+	debtService := services.NewDebtService(db, transactionService)
+	depositService := services.NewDepositService(db, transactionService)
+	budgetService := services.NewBudgetService(db, reportsService, cfg.Budgets.AlertThresholds)
+	envelopeService := services.NewEnvelopeService(db, currencyRateService)
+	statementService := services.NewStatementService(db, transactionService)
+	duplicateService := services.NewDuplicateService(db, transactionService, cfg.Duplicates)
+	transactionService.Duplicates = duplicateService
+	categoryRuleService := services.NewCategoryRuleService(db, transactionService)
+	transactionService.Rules = categoryRuleService
+	categorySuggestionService := services.NewCategorySuggestionService(db, transactionService)
+	transactionService.Suggestions = categorySuggestionService
+	tagService := services.NewTagService(db, transactionService)
+	auditService := services.NewAuditService(db)
+	trashService := services.NewTrashService(db, transactionService, cfg.Trash.Retention)
+
+	// Фоновые задачи
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	scheduler := jobs.NewScheduler()
+	scheduler.Add(jobs.ScheduledTransactions(scheduledTransactionService, cfg.Jobs.ScheduledTransactionsInterval))
+	scheduler.Add(jobs.DepositAccruals(depositService, cfg.Jobs.DepositAccrualsInterval))
+	scheduler.Add(jobs.BudgetAlerts(budgetService, cfg.Jobs.BudgetAlertsInterval))
+	scheduler.Add(jobs.TrashPurge(trashService, cfg.Jobs.TrashPurgeInterval))
+	scheduler.Add(jobs.NetWorthSnapshots(reportsService, cfg.Jobs.NetWorthSnapshotsInterval))
+	for _, feed := range cfg.RateFeeds {
+		scheduler.Add(jobs.RateImport(currencyRateService, feed))
+	}
+	scheduler.Start(ctx)
 
 	// Initialize handlers
-	userHandler := handlers.NewUserHandler(userService)
+	authHandler := handlers.NewAuthHandler(userService, authService)
+	userHandler := handlers.NewUserHandler(userService, authService)
 	accountHandler := handlers.NewAccountHandler(accountService)
 	transactionHandler := handlers.NewTransactionHandler(transactionService)
 	categoryHandler := handlers.NewCategoryHandler(categoryService)
 	financialGoalsHandler := handlers.NewFinancialGoalsHandler(financialGoalsService)
 	reportsHandler := handlers.NewReportsHandler(reportsService)
+	transferHandler := handlers.NewTransferHandler(transferService)
+	currencyRateHandler := handlers.NewCurrencyRateHandler(currencyRateService)
+	scheduledTransactionHandler := handlers.NewScheduledTransactionHandler(scheduledTransactionService)
+	debtHandler := handlers.NewDebtHandler(debtService)
+	depositHandler := handlers.NewDepositHandler(depositService)
+	budgetHandler := handlers.NewBudgetHandler(budgetService)
+	envelopeHandler := handlers.NewEnvelopeHandler(envelopeService)
+	statementHandler := handlers.NewStatementHandler(statementService)
+	duplicateHandler := handlers.NewDuplicateHandler(duplicateService)
+	categoryRuleHandler := handlers.NewCategoryRuleHandler(categoryRuleService)
+	categorySuggestionHandler := handlers.NewCategorySuggestionHandler(categorySuggestionService)
+	tagHandler := handlers.NewTagHandler(tagService)
+	auditHandler := handlers.NewAuditHandler(auditService)
+	trashHandler := handlers.NewTrashHandler(trashService)
 
 	// Create router
-	r := mux.NewRouter()
+	router := mux.NewRouter()
+
+	// Public routes
+	router.HandleFunc("/auth/register", authHandler.RegisterHandler).Methods(http.MethodPost)
+	router.HandleFunc("/auth/login", authHandler.LoginHandler).Methods(http.MethodPost)
+	router.HandleFunc("/auth/refresh", authHandler.RefreshHandler).Methods(http.MethodPost)
+	router.HandleFunc("/auth/restore", authHandler.RestoreHandler).Methods(http.MethodPost)
+
+	// Swagger UI
+	router.PathPrefix("/swagger/").Handler(httpSwagger.WrapHandler)
+
+	// Все остальные маршруты требуют access-токен
+	r := router.PathPrefix("/").Subrouter()
+	r.Use(middleware.Auth(authService))
+
+	r.HandleFunc("/auth/logout", authHandler.LogoutHandler).Methods(http.MethodPost)
 
 	// User routes
-	r.HandleFunc("/users", userHandler.GetAllUsersHandler).Methods("GET")
+	r.HandleFunc("/users/me", userHandler.GetCurrentUserHandler).Methods("GET")
 	r.HandleFunc("/users/{id}", userHandler.GetUserByIDHandler).Methods("GET")
 	r.HandleFunc("/users/update", userHandler.UpdateUserHandler).Methods("PUT")
 	r.HandleFunc("/users/delete", userHandler.DeleteUserHandler).Methods("DELETE")
@@ -53,6 +118,7 @@ func Run(cfg *config.Config) {
 	// Account routes
 	r.HandleFunc("/accounts", accountHandler.GetAccountsHandler).Methods("GET")
 	r.HandleFunc("/accounts/create", accountHandler.CreateAccountHandler).Methods("POST")
+	r.HandleFunc("/accounts/reconcile", accountHandler.ReconcileAccountsHandler).Methods(http.MethodGet, http.MethodPost)
 	r.HandleFunc("/accounts/{id}", accountHandler.GetAccountByIDHandler).Methods("GET")
 	r.HandleFunc("/accounts/update", accountHandler.UpdateAccountHandler).Methods("PUT")
 	r.HandleFunc("/accounts/delete", accountHandler.DeleteAccountHandler).Methods("DELETE")
@@ -60,17 +126,114 @@ func Run(cfg *config.Config) {
 	// Transaction routes
 	r.HandleFunc("/transactions", transactionHandler.GetAllTransactionsHandler).Methods("GET")
 	r.HandleFunc("/transactions/create", transactionHandler.CreateTransactionHandler).Methods("POST")
+	r.HandleFunc("/transactions/update", transactionHandler.UpdateTransactionHandler).Methods(http.MethodPut)
 	r.HandleFunc("/transactions/{id}", transactionHandler.GetTransactionByIDHandler).Methods("GET")
+	r.HandleFunc("/transactions/{id}", transactionHandler.PatchTransactionHandler).Methods(http.MethodPut)
 	r.HandleFunc("/transactions/delete", transactionHandler.DeleteTransactionHandler).Methods("DELETE")
 	r.HandleFunc("/transactions/{userID}/cache", transactionHandler.GetAllTransactionsWithCacheHandler).Methods("GET")
 	r.HandleFunc("/users/{id}/transactions/compare", transactionHandler.CompareIncomeAndExpensesHandler).Methods("GET")
 
+	// Transfer routes
+	r.HandleFunc("/transfers", transferHandler.GetTransfersHandler).Methods(http.MethodGet)
+	r.HandleFunc("/transfers/create", transferHandler.CreateTransferHandler).Methods(http.MethodPost)
+	r.HandleFunc("/transfers/delete", transferHandler.DeleteTransferHandler).Methods(http.MethodDelete)
+	r.HandleFunc("/transfers/{id}", transferHandler.GetTransferByIDHandler).Methods(http.MethodGet)
+
+	// Scheduled transaction routes
+	r.HandleFunc("/scheduled-transactions", scheduledTransactionHandler.GetSchedulesHandler).Methods(http.MethodGet)
+	r.HandleFunc("/scheduled-transactions/create", scheduledTransactionHandler.CreateScheduleHandler).Methods(http.MethodPost)
+	r.HandleFunc("/scheduled-transactions/update", scheduledTransactionHandler.UpdateScheduleHandler).Methods(http.MethodPut)
+	r.HandleFunc("/scheduled-transactions/delete", scheduledTransactionHandler.DeleteScheduleHandler).Methods(http.MethodDelete)
+	r.HandleFunc("/scheduled-transactions/{id}", scheduledTransactionHandler.GetScheduleByIDHandler).Methods(http.MethodGet)
+	r.HandleFunc("/scheduled-transactions/{id}/pause", scheduledTransactionHandler.PauseScheduleHandler).Methods(http.MethodPost)
+	r.HandleFunc("/scheduled-transactions/{id}/resume", scheduledTransactionHandler.ResumeScheduleHandler).Methods(http.MethodPost)
+	r.HandleFunc("/scheduled-transactions/{id}/occurrences", scheduledTransactionHandler.GetOccurrencesHandler).Methods(http.MethodGet)
+	r.HandleFunc("/scheduled-transactions/{id}/skip", scheduledTransactionHandler.SkipOccurrenceHandler).Methods(http.MethodPost)
+	r.HandleFunc("/scheduled-transactions/{id}/hold", scheduledTransactionHandler.HoldOccurrenceHandler).Methods(http.MethodPost)
+	r.HandleFunc("/scheduled-transactions/{id}/release", scheduledTransactionHandler.ReleaseOccurrenceHandler).Methods(http.MethodPost)
+
+	// Debt routes
+	r.HandleFunc("/debts", debtHandler.GetDebtsHandler).Methods(http.MethodGet)
+	r.HandleFunc("/debts/create", debtHandler.CreateDebtHandler).Methods(http.MethodPost)
+	r.HandleFunc("/debts/update", debtHandler.UpdateDebtHandler).Methods(http.MethodPut)
+	r.HandleFunc("/debts/delete", debtHandler.DeleteDebtHandler).Methods(http.MethodDelete)
+	r.HandleFunc("/debts/contacts", debtHandler.GetContactBalancesHandler).Methods(http.MethodGet)
+	r.HandleFunc("/debts/overdue", debtHandler.GetOverdueDebtsHandler).Methods(http.MethodGet)
+	r.HandleFunc("/debts/{id}", debtHandler.GetDebtByIDHandler).Methods(http.MethodGet)
+	r.HandleFunc("/debts/{id}/repayments", debtHandler.AddRepaymentHandler).Methods(http.MethodPost)
+
+	// Deposit routes
+	r.HandleFunc("/deposits", depositHandler.GetDepositsHandler).Methods(http.MethodGet)
+	r.HandleFunc("/deposits/create", depositHandler.CreateDepositHandler).Methods(http.MethodPost)
+	r.HandleFunc("/deposits/update", depositHandler.UpdateDepositHandler).Methods(http.MethodPut)
+	r.HandleFunc("/deposits/delete", depositHandler.DeleteDepositHandler).Methods(http.MethodDelete)
+	r.HandleFunc("/deposits/{id}", depositHandler.GetDepositByIDHandler).Methods(http.MethodGet)
+	r.HandleFunc("/deposits/{id}/projection", depositHandler.GetProjectionHandler).Methods(http.MethodGet)
+
+	// Budget routes
+	r.HandleFunc("/budgets", budgetHandler.GetBudgetsHandler).Methods(http.MethodGet)
+	r.HandleFunc("/budgets/create", budgetHandler.CreateBudgetHandler).Methods(http.MethodPost)
+	r.HandleFunc("/budgets/update", budgetHandler.UpdateBudgetHandler).Methods(http.MethodPut)
+	r.HandleFunc("/budgets/delete", budgetHandler.DeleteBudgetHandler).Methods(http.MethodDelete)
+	r.HandleFunc("/budgets/status", budgetHandler.GetStatusHandler).Methods(http.MethodGet)
+	r.HandleFunc("/budgets/alerts", budgetHandler.GetAlertsHandler).Methods(http.MethodGet)
+	r.HandleFunc("/budgets/alerts/{id}/read", budgetHandler.MarkAlertReadHandler).Methods(http.MethodPost)
+	r.HandleFunc("/budgets/{id}", budgetHandler.GetBudgetByIDHandler).Methods(http.MethodGet)
+
+	// Envelope routes
+	r.HandleFunc("/envelopes", envelopeHandler.GetEnvelopesHandler).Methods(http.MethodGet)
+	r.HandleFunc("/envelopes/create", envelopeHandler.CreateEnvelopeHandler).Methods(http.MethodPost)
+	r.HandleFunc("/envelopes/update", envelopeHandler.UpdateEnvelopeHandler).Methods(http.MethodPut)
+	r.HandleFunc("/envelopes/delete", envelopeHandler.DeleteEnvelopeHandler).Methods(http.MethodDelete)
+	r.HandleFunc("/envelopes/month", envelopeHandler.GetMonthHandler).Methods(http.MethodGet)
+	r.HandleFunc("/envelopes/assign", envelopeHandler.AssignHandler).Methods(http.MethodPost)
+	r.HandleFunc("/envelopes/move", envelopeHandler.MoveHandler).Methods(http.MethodPost)
+
+	// Statement import routes
+	r.HandleFunc("/statement-profiles", statementHandler.GetProfilesHandler).Methods(http.MethodGet)
+	r.HandleFunc("/statement-profiles/create", statementHandler.CreateProfileHandler).Methods(http.MethodPost)
+	r.HandleFunc("/statement-profiles/update", statementHandler.UpdateProfileHandler).Methods(http.MethodPut)
+	r.HandleFunc("/statement-profiles/delete", statementHandler.DeleteProfileHandler).Methods(http.MethodDelete)
+	r.HandleFunc("/statements/import", statementHandler.ImportHandler).Methods(http.MethodPost)
+
+	// Duplicate routes
+	r.HandleFunc("/duplicates", duplicateHandler.GetDuplicatesHandler).Methods(http.MethodGet)
+	r.HandleFunc("/duplicates/{id}/merge", duplicateHandler.MergeDuplicateHandler).Methods(http.MethodPost)
+	r.HandleFunc("/duplicates/{id}/dismiss", duplicateHandler.DismissDuplicateHandler).Methods(http.MethodPost)
+
+	// Category rule routes
+	r.HandleFunc("/category-rules", categoryRuleHandler.GetRulesHandler).Methods(http.MethodGet)
+	r.HandleFunc("/category-rules/create", categoryRuleHandler.CreateRuleHandler).Methods(http.MethodPost)
+	r.HandleFunc("/category-rules/update", categoryRuleHandler.UpdateRuleHandler).Methods(http.MethodPut)
+	r.HandleFunc("/category-rules/delete", categoryRuleHandler.DeleteRuleHandler).Methods(http.MethodDelete)
+	r.HandleFunc("/category-rules/apply", categoryRuleHandler.ApplyRulesHandler).Methods(http.MethodPost)
+	r.HandleFunc("/category-rules/{id}", categoryRuleHandler.GetRuleByIDHandler).Methods(http.MethodGet)
+
+	// Category suggestion routes
+	r.HandleFunc("/category-suggestions", categorySuggestionHandler.SuggestHandler).Methods(http.MethodGet)
+	r.HandleFunc("/category-suggestions/feedback", categorySuggestionHandler.FeedbackHandler).Methods(http.MethodPost)
+	r.HandleFunc("/transactions/{id}/suggestions", categorySuggestionHandler.SuggestForTransactionHandler).Methods(http.MethodGet)
+
+	// Tag routes
+	r.HandleFunc("/tags", tagHandler.GetTagsHandler).Methods(http.MethodGet)
+	r.HandleFunc("/tags/create", tagHandler.CreateTagHandler).Methods(http.MethodPost)
+	r.HandleFunc("/tags/update", tagHandler.UpdateTagHandler).Methods(http.MethodPut)
+	r.HandleFunc("/tags/delete", tagHandler.DeleteTagHandler).Methods(http.MethodDelete)
+	r.HandleFunc("/tags/apply", tagHandler.TagTransactionsHandler).Methods(http.MethodPost)
+	r.HandleFunc("/tags/{id}", tagHandler.GetTagByIDHandler).Methods(http.MethodGet)
+
+	// Currency rate routes
+	r.HandleFunc("/currency-rates", currencyRateHandler.GetRatesHandler).Methods(http.MethodGet)
+	r.HandleFunc("/currency-rates/convert", currencyRateHandler.ConvertHandler).Methods(http.MethodGet)
+
 	// Category routes
 	r.HandleFunc("/categories", categoryHandler.GetAllCategoriesHandler).Methods("GET")
 	r.HandleFunc("/categories/create", categoryHandler.CreateCategoryHandler).Methods("POST")
 	r.HandleFunc("/categories/{id}", categoryHandler.GetCategoryByIDHandler).Methods("GET")
 	r.HandleFunc("/categories/update", categoryHandler.UpdateCategoryHandler).Methods("PUT")
 	r.HandleFunc("/categories/delete", categoryHandler.DeleteCategoryHandler).Methods("DELETE")
+	r.HandleFunc("/categories/{id}/move", categoryHandler.MoveCategoryHandler).Methods("POST")
+	r.HandleFunc("/categories/{id}/merge", categoryHandler.MergeCategoryHandler).Methods("POST")
 	r.HandleFunc("/categories/{id}/transactions", transactionHandler.GetTransactionsByCategoryHandler).Methods("GET")
 	r.HandleFunc("/accounts/{id}/transactions", transactionHandler.GetTransactionsByAccountHandler).Methods("GET")
 
@@ -81,18 +244,25 @@ func Run(cfg *config.Config) {
 	r.HandleFunc("/financial-goals/delete", financialGoalsHandler.DeleteFinancialGoalHandler).Methods(http.MethodDelete)
 	r.HandleFunc("/users/{id}/goals/progress", financialGoalsHandler.GetGoalProgressHandler).Methods("GET")
 
+	// Audit routes
+	r.HandleFunc("/audit/{entity}/{id}", auditHandler.GetHistoryHandler).Methods(http.MethodGet)
+
+	// Trash routes
+	r.HandleFunc("/trash", trashHandler.GetTrashHandler).Methods(http.MethodGet)
+	r.HandleFunc("/trash/{id}/restore", trashHandler.RestoreTrashHandler).Methods(http.MethodPost)
+
 	// Reports routes
 	r.HandleFunc("/reports/summary", reportsHandler.GetSummaryHandler).Methods(http.MethodGet)
 	r.HandleFunc("/reports/by-category", reportsHandler.GetExpensesByCategoryHandler).Methods(http.MethodGet)
-
-	// Swagger UI
-	r.PathPrefix("/swagger/").Handler(httpSwagger.WrapHandler)
+	r.HandleFunc("/reports/by-tag", reportsHandler.GetTotalsByTagHandler).Methods(http.MethodGet)
+	r.HandleFunc("/reports/net-worth", reportsHandler.GetNetWorthHandler).Methods(http.MethodGet)
+	r.HandleFunc("/reports/net-worth/backfill", reportsHandler.BackfillNetWorthHandler).Methods(http.MethodPost)
 
 	// Start server
 	port := ":8080"
 	fmt.Printf("Server is running on http://localhost%s\n", port)
 	fmt.Println("Swagger docs available at http://localhost:8080/swagger/")
-	if err := http.ListenAndServe(port, r); err != nil {
+	if err := http.ListenAndServe(port, router); err != nil {
 		log.Fatalf("Failed to start server: %v", err)
 	}
 }
@@ -0,0 +1,127 @@
+// Package classifier implements the multinomial naive Bayes classifier behind category suggestions.
+//
+// A transaction is described by a bag of features: the words of its description and a bucket of
+// its amount (see Features). A Model is trained with labelled feature bags and predicts the
+// posterior probability of every label for a new bag, using add-one (Laplace) smoothing.
+package classifier
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"finance_project/internal/money"
+)
+
+// Prediction is the posterior probability of one label.
+type Prediction struct {
+	Label       int
+	Probability float64
+}
+
+// Model is a naive Bayes model. The zero value is not usable; create models with New.
+type Model struct {
+	labels     map[int]*labelCounts
+	vocabulary map[string]bool
+	documents  float64
+}
+
+type labelCounts struct {
+	documents float64
+	features  float64
+	counts    map[string]float64
+}
+
+// New returns an empty model.
+func New() *Model {
+	return &Model{labels: make(map[int]*labelCounts), vocabulary: make(map[string]bool)}
+}
+
+// Add trains the model with one labelled document. A weight above 1 counts the document several
+// times, e.g. for labels confirmed by the user.
+func (m *Model) Add(label int, features []string, weight float64) {
+	counts := m.labels[label]
+	if counts == nil {
+		counts = &labelCounts{counts: make(map[string]float64)}
+		m.labels[label] = counts
+	}
+	counts.documents += weight
+	m.documents += weight
+	for _, f := range features {
+		counts.counts[f] += weight
+		counts.features += weight
+		m.vocabulary[f] = true
+	}
+}
+
+// Documents returns the weighted number of training documents.
+func (m *Model) Documents() float64 {
+	return m.documents
+}
+
+// Predict returns the probability of every label for features, most probable first. Features
+// never seen in training carry no information and are ignored; if none is known, Predict
+// returns nil rather than a guess based on label frequency alone.
+func (m *Model) Predict(features []string) []Prediction {
+	var known []string
+	for _, f := range features {
+		if m.vocabulary[f] {
+			known = append(known, f)
+		}
+	}
+	if len(known) == 0 {
+		return nil
+	}
+
+	vocabulary := float64(len(m.vocabulary))
+	predictions := make([]Prediction, 0, len(m.labels))
+	scores := make([]float64, 0, len(m.labels))
+	maxScore := math.Inf(-1)
+	for label, counts := range m.labels {
+		score := math.Log(counts.documents / m.documents)
+		for _, f := range known {
+			score += math.Log((counts.counts[f] + 1) / (counts.features + vocabulary))
+		}
+		predictions = append(predictions, Prediction{Label: label})
+		scores = append(scores, score)
+		maxScore = math.Max(maxScore, score)
+	}
+
+	// log-sum-exp keeps the normalisation stable for long descriptions
+	var total float64
+	for i, score := range scores {
+		predictions[i].Probability = math.Exp(score - maxScore)
+		total += predictions[i].Probability
+	}
+	for i := range predictions {
+		predictions[i].Probability /= total
+	}
+	sort.Slice(predictions, func(i, j int) bool {
+		if predictions[i].Probability != predictions[j].Probability {
+			return predictions[i].Probability > predictions[j].Probability
+		}
+		return predictions[i].Label < predictions[j].Label
+	})
+	return predictions
+}
+
+// Features returns the feature bag of a transaction: the distinct lowercase words of its
+// description (letters only, at least two of them, so card numbers and dates are dropped) and
+// an amount bucket. Buckets are half an order of magnitude wide (1-3, 3-10, 10-31, ...), so
+// a coffee and a rent payment fall apart while small price changes do not matter.
+func Features(description string, amount money.Amount) []string {
+	var features []string
+	seen := make(map[string]bool)
+	for _, word := range strings.FieldsFunc(strings.ToLower(description), func(r rune) bool { return !unicode.IsLetter(r) }) {
+		if len([]rune(word)) > 1 && !seen[word] {
+			seen[word] = true
+			features = append(features, word)
+		}
+	}
+	if value := amount.Abs().Float64(); value > 0 {
+		features = append(features, "amount:"+strconv.Itoa(int(math.Floor(2*math.Log10(value)))))
+	}
+	return features
+}
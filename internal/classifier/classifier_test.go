@@ -0,0 +1,166 @@
+package classifier
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"finance_project/internal/money"
+)
+
+func TestPredict(t *testing.T) {
+	type document struct {
+		label    int
+		features []string
+		weight   float64
+	}
+	tests := []struct {
+		name      string
+		documents []document
+		features  []string
+		want      []int // labels, most probable first
+	}{
+		{
+			name: "word evidence wins",
+			documents: []document{
+				{1, []string{"coffee", "shop"}, 1},
+				{2, []string{"rent", "landlord"}, 1},
+				{2, []string{"rent"}, 1},
+			},
+			features: []string{"coffee"},
+			want:     []int{1, 2},
+		},
+		{
+			name: "ties are broken by label",
+			documents: []document{
+				{3, []string{"market"}, 1},
+				{1, []string{"market"}, 1},
+				{2, []string{"market"}, 1},
+			},
+			features: []string{"market"},
+			want:     []int{1, 2, 3},
+		},
+		{
+			name: "unknown features are ignored",
+			documents: []document{
+				{1, []string{"coffee"}, 1},
+				{2, []string{"rent"}, 1},
+			},
+			features: []string{"rent", "unseen", "words"},
+			want:     []int{2, 1},
+		},
+		{
+			name: "only unknown features",
+			documents: []document{
+				{1, []string{"coffee"}, 1},
+			},
+			features: []string{"unseen"},
+			want:     nil,
+		},
+		{
+			name:     "empty model",
+			features: []string{"coffee"},
+			want:     nil,
+		},
+		{
+			name: "unweighted majority",
+			documents: []document{
+				{1, []string{"store"}, 1},
+				{1, []string{"store"}, 1},
+				{2, []string{"store"}, 1},
+			},
+			features: []string{"store"},
+			want:     []int{1, 2},
+		},
+		{
+			name: "confirmed label weight changes the ranking",
+			documents: []document{
+				{1, []string{"store"}, 1},
+				{1, []string{"store"}, 1},
+				{2, []string{"store"}, 5},
+			},
+			features: []string{"store"},
+			want:     []int{2, 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			model := New()
+			for _, d := range tt.documents {
+				model.Add(d.label, d.features, d.weight)
+			}
+			predictions := model.Predict(tt.features)
+			if tt.want == nil {
+				if predictions != nil {
+					t.Fatalf("Predict = %v, want nil", predictions)
+				}
+				return
+			}
+
+			var labels []int
+			var total float64
+			for i, p := range predictions {
+				labels = append(labels, p.Label)
+				total += p.Probability
+				if p.Probability <= 0 || p.Probability > 1 {
+					t.Errorf("probability of label %d = %v, want (0, 1]", p.Label, p.Probability)
+				}
+				if i > 0 && p.Probability > predictions[i-1].Probability {
+					t.Errorf("predictions are not sorted: %v", predictions)
+				}
+			}
+			if !reflect.DeepEqual(labels, tt.want) {
+				t.Errorf("labels = %v, want %v (%v)", labels, tt.want, predictions)
+			}
+			if math.Abs(total-1) > 1e-9 {
+				t.Errorf("probabilities add up to %v, want 1", total)
+			}
+		})
+	}
+}
+
+func TestPredictTiesHaveEqualProbability(t *testing.T) {
+	model := New()
+	model.Add(2, []string{"market"}, 1)
+	model.Add(1, []string{"market"}, 1)
+	predictions := model.Predict([]string{"market"})
+	if len(predictions) != 2 || predictions[0].Probability != 0.5 || predictions[1].Probability != 0.5 {
+		t.Errorf("Predict = %v, want two labels at 0.5", predictions)
+	}
+}
+
+func TestDocuments(t *testing.T) {
+	model := New()
+	model.Add(1, []string{"coffee"}, 1)
+	model.Add(2, nil, 2.5)
+	if got := model.Documents(); got != 3.5 {
+		t.Errorf("Documents = %v, want 3.5", got)
+	}
+}
+
+func TestFeatures(t *testing.T) {
+	tests := []struct {
+		description string
+		amount      string
+		want        []string
+	}{
+		{"Coffee SHOP coffee", "4.50", []string{"coffee", "shop", "amount:1"}},
+		{"Card *1234 31.01 Café Ümlaut", "12", []string{"card", "café", "ümlaut", "amount:2"}},
+		{"a b cd", "0", []string{"cd"}},
+		{"", "0", nil},
+		{"refund", "-250", []string{"refund", "amount:4"}},
+		{"", "0.99", []string{"amount:-1"}},
+		{"", "1", []string{"amount:0"}},
+		{"", "3", []string{"amount:0"}},
+		{"", "3.17", []string{"amount:1"}},
+		{"", "9.99", []string{"amount:1"}},
+		{"", "10", []string{"amount:2"}},
+		{"", "31.63", []string{"amount:3"}},
+	}
+	for _, tt := range tests {
+		got := Features(tt.description, money.MustParse(tt.amount))
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Features(%q, %s) = %v, want %v", tt.description, tt.amount, got, tt.want)
+		}
+	}
+}
@@ -3,6 +3,8 @@ package config
 import (
 	"fmt"
 	"io/ioutil"
+	"os"
+	"time"
 
 	"gopkg.in/yaml.v2"
 )
@@ -29,9 +31,69 @@ type RedisConfig struct {
 	DB       int    `yaml:"db"`
 }
 
+// AuthConfig содержит параметры выдачи JWT-токенов и хеширования паролей.
+type AuthConfig struct {
+	JWTSecret       string        `yaml:"jwt_secret"`
+	AccessTokenTTL  time.Duration `yaml:"access_token_ttl"`
+	RefreshTokenTTL time.Duration `yaml:"refresh_token_ttl"`
+	BcryptCost      int           `yaml:"bcrypt_cost"`
+}
+
+// CurrencyConfig содержит параметры пересчёта валют.
+type CurrencyConfig struct {
+	// BaseCurrency — валюта кросс-курсов и отчётов пользователей без предпочитаемой валюты.
+	BaseCurrency string `yaml:"base_currency"`
+}
+
+// BudgetsConfig содержит параметры бюджетов.
+type BudgetsConfig struct {
+	// AlertThresholds — пороги уведомлений в процентах для бюджетов, в которых пороги не заданы.
+	AlertThresholds []int `yaml:"alert_thresholds"`
+}
+
+// DuplicatesConfig содержит параметры поиска дублей транзакций.
+type DuplicatesConfig struct {
+	// WindowDays — на сколько дней в каждую сторону может отличаться дата дубля.
+	WindowDays int `yaml:"window_days"`
+	// Threshold — минимальная уверенность (от 0 до 1), с которой пара отмечается как вероятный дубль.
+	Threshold float64 `yaml:"threshold"`
+}
+
+// TrashConfig содержит параметры корзины.
+type TrashConfig struct {
+	// Retention — сколько удалённые записи и профили пользователей хранятся до окончательного удаления.
+	Retention time.Duration `yaml:"retention"`
+}
+
+// RateFeedConfig описывает источник курсов валют, загружаемый по расписанию.
+type RateFeedConfig struct {
+	Name string `yaml:"name"`
+	// Source — путь к файлу или http(s) URL.
+	Source string `yaml:"source"`
+	// Format — ecb, nbk, csv или auto.
+	Format   string        `yaml:"format"`
+	Interval time.Duration `yaml:"interval"`
+}
+
+// JobsConfig содержит интервалы фоновых задач.
+type JobsConfig struct {
+	ScheduledTransactionsInterval time.Duration `yaml:"scheduled_transactions_interval"`
+	DepositAccrualsInterval       time.Duration `yaml:"deposit_accruals_interval"`
+	BudgetAlertsInterval          time.Duration `yaml:"budget_alerts_interval"`
+	TrashPurgeInterval            time.Duration `yaml:"trash_purge_interval"`
+	NetWorthSnapshotsInterval     time.Duration `yaml:"net_worth_snapshots_interval"`
+}
+
 type Config struct {
-	Database DatabaseConfig `yaml:"database"`
-	Redis    RedisConfig    `yaml:"redis"`
+	Database   DatabaseConfig   `yaml:"database"`
+	Redis      RedisConfig      `yaml:"redis"`
+	Auth       AuthConfig       `yaml:"auth"`
+	Currency   CurrencyConfig   `yaml:"currency"`
+	Budgets    BudgetsConfig    `yaml:"budgets"`
+	Duplicates DuplicatesConfig `yaml:"duplicates"`
+	Trash      TrashConfig      `yaml:"trash"`
+	RateFeeds  []RateFeedConfig `yaml:"rate_feeds"`
+	Jobs       JobsConfig       `yaml:"jobs"`
 }
 
 func LoadConfig(filePath string) (*Config, error) {
@@ -46,5 +108,61 @@ func LoadConfig(filePath string) (*Config, error) {
 		return nil, err
 	}
 
+	// Секрет из окружения имеет приоритет над файлом конфигурации
+	if secret := os.Getenv("JWT_SECRET"); secret != "" {
+		cfg.Auth.JWTSecret = secret
+	}
+	if cfg.Auth.JWTSecret == "" {
+		return nil, fmt.Errorf("auth.jwt_secret is not configured")
+	}
+	if cfg.Auth.AccessTokenTTL == 0 {
+		cfg.Auth.AccessTokenTTL = 15 * time.Minute
+	}
+	if cfg.Auth.RefreshTokenTTL == 0 {
+		cfg.Auth.RefreshTokenTTL = 30 * 24 * time.Hour
+	}
+	if cfg.Currency.BaseCurrency == "" {
+		cfg.Currency.BaseCurrency = "KZT"
+	}
+	if cfg.Jobs.ScheduledTransactionsInterval == 0 {
+		cfg.Jobs.ScheduledTransactionsInterval = time.Minute
+	}
+	if cfg.Jobs.DepositAccrualsInterval == 0 {
+		cfg.Jobs.DepositAccrualsInterval = time.Hour
+	}
+	if cfg.Jobs.BudgetAlertsInterval == 0 {
+		cfg.Jobs.BudgetAlertsInterval = 15 * time.Minute
+	}
+	if cfg.Jobs.TrashPurgeInterval == 0 {
+		cfg.Jobs.TrashPurgeInterval = time.Hour
+	}
+	if cfg.Jobs.NetWorthSnapshotsInterval == 0 {
+		cfg.Jobs.NetWorthSnapshotsInterval = time.Hour
+	}
+	if cfg.Trash.Retention == 0 {
+		cfg.Trash.Retention = 30 * 24 * time.Hour
+	}
+	if cfg.Budgets.AlertThresholds == nil {
+		cfg.Budgets.AlertThresholds = []int{80, 100}
+	}
+	if cfg.Duplicates.WindowDays == 0 {
+		cfg.Duplicates.WindowDays = 3
+	}
+	if cfg.Duplicates.Threshold == 0 {
+		cfg.Duplicates.Threshold = 0.7
+	}
+	for i := range cfg.RateFeeds {
+		feed := &cfg.RateFeeds[i]
+		if feed.Source == "" {
+			return nil, fmt.Errorf("rate_feeds[%d].source is not configured", i)
+		}
+		if feed.Name == "" {
+			feed.Name = feed.Source
+		}
+		if feed.Interval == 0 {
+			feed.Interval = 24 * time.Hour
+		}
+	}
+
 	return &cfg, nil
 }
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"finance_project/internal/middleware"
+	"finance_project/internal/services"
+)
+
+// currentUserID возвращает ID пользователя, аутентифицированного middleware.Auth.
+func currentUserID(r *http.Request) int {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	return userID
+}
+
+// resolveUserID проверяет ID пользователя, переданный клиентом в запросе.
+// Пустое значение означает текущего пользователя, чужой ID приводит к ответу 403.
+func resolveUserID(w http.ResponseWriter, r *http.Request, raw string) (int, bool) {
+	userID := currentUserID(r)
+	if raw == "" {
+		return userID, true
+	}
+
+	requested, err := strconv.Atoi(raw)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return 0, false
+	}
+	if requested != userID {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return 0, false
+	}
+	return userID, true
+}
+
+// writeServiceError преобразует ошибку сервиса в HTTP-ответ.
+func writeServiceError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidInput):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, services.ErrNotFound):
+		http.Error(w, "Not found", http.StatusNotFound)
+	case errors.Is(err, services.ErrForbidden):
+		http.Error(w, "Forbidden", http.StatusForbidden)
+	case errors.Is(err, services.ErrRateNotFound):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	default:
+		http.Error(w, message, http.StatusInternalServerError)
+	}
+}
+
+// parseIDs разбирает повторяющийся параметр запроса со списком ID.
+func parseIDs(values []string) ([]int, error) {
+	ids := make([]int, 0, len(values))
+	for _, raw := range values {
+		id, err := strconv.Atoi(raw)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
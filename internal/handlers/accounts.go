@@ -25,9 +25,10 @@ func NewAccountHandler(service *services.AccountService) *AccountHandler {
 // @Tags Accounts
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param account body models.Account true "Account body"
 // @Success 201 {string} string "Created"
-// @Failure 400 {string} string "Invalid request body"
+// @Failure 400 {string} string "Invalid request body, currency or amount precision"
 // @Failure 500 {string} string "Failed to create account"
 // @Router /accounts/create [post]
 func (h *AccountHandler) CreateAccountHandler(w http.ResponseWriter, r *http.Request) {
@@ -36,9 +37,10 @@ func (h *AccountHandler) CreateAccountHandler(w http.ResponseWriter, r *http.Req
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
+	account.UserID = currentUserID(r)
 
 	if err := h.Service.CreateAccount(account); err != nil {
-		http.Error(w, "Failed to create account", http.StatusInternalServerError)
+		writeServiceError(w, err, "Failed to create account")
 		return
 	}
 
@@ -52,9 +54,11 @@ func (h *AccountHandler) CreateAccountHandler(w http.ResponseWriter, r *http.Req
 // @Tags Accounts
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param id query int true "Account ID"
 // @Success 200 {object} models.Account
 // @Failure 400 {string} string "Invalid account ID"
+// @Failure 403 {string} string "Forbidden"
 // @Failure 404 {string} string "Account not found"
 // @Router /accounts/get [get]
 func (h *AccountHandler) GetAccountByIDHandler(w http.ResponseWriter, r *http.Request) {
@@ -69,6 +73,10 @@ func (h *AccountHandler) GetAccountByIDHandler(w http.ResponseWriter, r *http.Re
 		http.Error(w, "Account not found", http.StatusNotFound)
 		return
 	}
+	if account.UserID != currentUserID(r) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(account)
@@ -80,15 +88,16 @@ func (h *AccountHandler) GetAccountByIDHandler(w http.ResponseWriter, r *http.Re
 // @Tags Accounts
 // @Accept json
 // @Produce json
-// @Param user_id query int true "User ID"
+// @Security BearerAuth
+// @Param user_id query int false "User ID (defaults to the current user)"
 // @Success 200 {array} models.Account
 // @Failure 400 {string} string "Invalid user ID"
+// @Failure 403 {string} string "Forbidden"
 // @Failure 500 {string} string "Failed to retrieve accounts"
 // @Router /accounts [get]
 func (h *AccountHandler) GetAccountsHandler(w http.ResponseWriter, r *http.Request) {
-	userID, err := strconv.Atoi(r.URL.Query().Get("user_id"))
-	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+	userID, ok := resolveUserID(w, r, r.URL.Query().Get("user_id"))
+	if !ok {
 		return
 	}
 
@@ -104,24 +113,31 @@ func (h *AccountHandler) GetAccountsHandler(w http.ResponseWriter, r *http.Reque
 
 // UpdateAccountHandler обновляет данные счёта
 // @Summary Обновление счёта
-// @Description Обновляет данные существующего счёта
+// @Description Обновляет переданные поля счёта; не переданные поля не меняются. Изменение баланса переносится в начальный баланс. Валюту можно сменить только у счёта без транзакций
 // @Tags Accounts
 // @Accept json
 // @Produce json
-// @Param account body models.Account true "Account body"
+// @Security BearerAuth
+// @Param account body models.AccountUpdate true "Account fields to change"
 // @Success 200 {string} string "Updated"
-// @Failure 400 {string} string "Invalid request body"
+// @Failure 400 {string} string "Invalid request body, currency or amount precision"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Account not found"
 // @Failure 500 {string} string "Failed to update account"
 // @Router /accounts/update [put]
 func (h *AccountHandler) UpdateAccountHandler(w http.ResponseWriter, r *http.Request) {
-	var account models.Account
-	if err := json.NewDecoder(r.Body).Decode(&account); err != nil {
+	var update models.AccountUpdate
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	if err := h.Service.UpdateAccount(account); err != nil {
-		http.Error(w, "Failed to update account", http.StatusInternalServerError)
+	if !h.authorize(w, r, update.ID) {
+		return
+	}
+
+	if err := h.Service.UpdateAccount(update, currentUserID(r)); err != nil {
+		writeServiceError(w, err, "Failed to update account")
 		return
 	}
 
@@ -131,13 +147,16 @@ func (h *AccountHandler) UpdateAccountHandler(w http.ResponseWriter, r *http.Req
 
 // DeleteAccountHandler удаляет счёт
 // @Summary Удаление счёта
-// @Description Удаляет существующий счёт по его ID
+// @Description Переносит счёт в корзину вместе со всеми его транзакциями и переводами. Восстанавливается через /trash/{id}/restore
 // @Tags Accounts
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param id query int true "Account ID"
 // @Success 200 {string} string "Deleted"
 // @Failure 400 {string} string "Invalid account ID"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Account not found"
 // @Failure 500 {string} string "Failed to delete account"
 // @Router /accounts/delete [delete]
 func (h *AccountHandler) DeleteAccountHandler(w http.ResponseWriter, r *http.Request) {
@@ -147,11 +166,53 @@ func (h *AccountHandler) DeleteAccountHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	if err := h.Service.DeleteAccount(id); err != nil {
-		http.Error(w, "Failed to delete account", http.StatusInternalServerError)
+	if !h.authorize(w, r, id) {
+		return
+	}
+
+	if err := h.Service.DeleteAccount(id, currentUserID(r)); err != nil {
+		writeServiceError(w, err, "Failed to delete account")
 		return
 	}
 
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("Account deleted successfully"))
 }
+
+// ReconcileAccountsHandler сверяет балансы счетов с транзакциями
+// @Summary Сверка балансов
+// @Description Пересчитывает баланс каждого счёта по транзакциям и возвращает расхождения. POST исправляет найденные расхождения.
+// @Tags Accounts
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.BalanceDiscrepancy
+// @Failure 500 {string} string "Failed to reconcile accounts"
+// @Router /accounts/reconcile [get]
+// @Router /accounts/reconcile [post]
+func (h *AccountHandler) ReconcileAccountsHandler(w http.ResponseWriter, r *http.Request) {
+	apply := r.Method == http.MethodPost
+
+	discrepancies, err := h.Service.ReconcileBalances(currentUserID(r), apply)
+	if err != nil {
+		http.Error(w, "Failed to reconcile accounts", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(discrepancies)
+}
+
+// authorize проверяет, что счёт существует и принадлежит текущему пользователю.
+func (h *AccountHandler) authorize(w http.ResponseWriter, r *http.Request, id int) bool {
+	account, err := h.Service.GetAccountByID(id)
+	if err != nil {
+		writeServiceError(w, err, "Failed to retrieve account")
+		return false
+	}
+	if account.UserID != currentUserID(r) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"finance_project/internal/models"
+	"finance_project/internal/services"
+
+	"github.com/gorilla/mux"
+)
+
+// auditEntities сопоставляет сегмент пути /audit/{entity} с типом записи журнала.
+var auditEntities = map[string]string{
+	"transactions":    models.AuditEntityTransaction,
+	"accounts":        models.AuditEntityAccount,
+	"financial-goals": models.AuditEntityFinancialGoal,
+}
+
+// AuditHandler представляет обработчики журнала изменений.
+type AuditHandler struct {
+	Service *services.AuditService
+}
+
+// NewAuditHandler создает новый обработчик журнала изменений.
+func NewAuditHandler(service *services.AuditService) *AuditHandler {
+	return &AuditHandler{Service: service}
+}
+
+// GetHistoryHandler возвращает историю изменений записи.
+// @Summary История изменений
+// @Description Возвращает все вставки, изменения и удаления транзакции, счёта или финансовой цели в порядке их выполнения: кто изменил (actor — user:<id> или job:<задача>), когда, и состояние записи до и после. История удалённой записи остаётся доступной
+// @Tags Audit
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param entity path string true "transactions, accounts or financial-goals"
+// @Param id path int true "Entity ID"
+// @Success 200 {array} models.AuditEntry
+// @Failure 400 {string} string "Invalid parameters"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not found"
+// @Failure 500 {string} string "Failed to retrieve history"
+// @Router /audit/{entity}/{id} [get]
+func (h *AuditHandler) GetHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	entityType, ok := auditEntities[vars["entity"]]
+	if !ok {
+		http.Error(w, "Unknown entity", http.StatusBadRequest)
+		return
+	}
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	history, err := h.Service.GetHistory(entityType, id, currentUserID(r))
+	if err != nil {
+		writeServiceError(w, err, "Failed to retrieve history")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(history)
+}
@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"finance_project/internal/middleware"
+	"finance_project/internal/models"
+	"finance_project/internal/services"
+)
+
+// AuthHandler представляет обработчики входа и выхода.
+type AuthHandler struct {
+	Users *services.UserService
+	Auth  *services.AuthService
+}
+
+// NewAuthHandler создает новый обработчик аутентификации.
+func NewAuthHandler(users *services.UserService, auth *services.AuthService) *AuthHandler {
+	return &AuthHandler{Users: users, Auth: auth}
+}
+
+// RegisterHandler регистрирует нового пользователя.
+// @Summary Регистрация
+// @Description Создает пользователя; пароль сохраняется только в виде bcrypt-хеша
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param user body models.RegisterRequest true "Registration data"
+// @Success 201 {object} models.User
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 409 {string} string "Email already registered"
+// @Failure 500 {string} string "Failed to register user"
+// @Router /auth/register [post]
+func (h *AuthHandler) RegisterHandler(w http.ResponseWriter, r *http.Request) {
+	var request models.RegisterRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	user := models.User{
+		Name:              request.Name,
+		Email:             request.Email,
+		PreferredCurrency: request.PreferredCurrency,
+	}
+	userID, err := h.Users.RegisterUser(user, request.Password)
+	switch {
+	case errors.Is(err, services.ErrInvalidEmail), errors.Is(err, services.ErrWeakPassword):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, services.ErrEmailTaken):
+		http.Error(w, "Email already registered", http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, "Failed to register user", http.StatusInternalServerError)
+		return
+	}
+
+	created, err := h.Users.GetUserByID(userID)
+	if err != nil {
+		http.Error(w, "Failed to register user", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+// LoginHandler выдает пару токенов по email и паролю.
+// @Summary Вход
+// @Description Проверяет email и пароль и выдает access- и refresh-токены
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param credentials body models.Credentials true "Credentials"
+// @Success 200 {object} models.TokenPair
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 401 {string} string "Invalid credentials"
+// @Failure 500 {string} string "Failed to log in"
+// @Router /auth/login [post]
+func (h *AuthHandler) LoginHandler(w http.ResponseWriter, r *http.Request) {
+	var credentials models.Credentials
+	if err := json.NewDecoder(r.Body).Decode(&credentials); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	userID, err := h.Users.Authenticate(credentials.Email, credentials.Password)
+	if errors.Is(err, services.ErrInvalidCredentials) {
+		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to log in", http.StatusInternalServerError)
+		return
+	}
+
+	tokens, err := h.Auth.IssueTokens(userID)
+	if err != nil {
+		http.Error(w, "Failed to log in", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tokens)
+}
+
+// RestoreHandler восстанавливает удалённый профиль и выдает пару токенов.
+// @Summary Восстановление профиля
+// @Description Отменяет удаление профиля по email и паролю, пока не истёк срок хранения корзины, и выдает access- и refresh-токены
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param credentials body models.Credentials true "Credentials"
+// @Success 200 {object} models.TokenPair
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 401 {string} string "Invalid credentials"
+// @Failure 500 {string} string "Failed to restore user"
+// @Router /auth/restore [post]
+func (h *AuthHandler) RestoreHandler(w http.ResponseWriter, r *http.Request) {
+	var credentials models.Credentials
+	if err := json.NewDecoder(r.Body).Decode(&credentials); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	userID, err := h.Users.RestoreUser(credentials.Email, credentials.Password)
+	if errors.Is(err, services.ErrInvalidCredentials) {
+		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to restore user", http.StatusInternalServerError)
+		return
+	}
+
+	tokens, err := h.Auth.IssueTokens(userID)
+	if err != nil {
+		http.Error(w, "Failed to restore user", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tokens)
+}
+
+// RefreshHandler обменивает refresh-токен на новую пару токенов.
+// @Summary Обновление токенов
+// @Description Выдает новую пару токенов, отзывая переданный refresh-токен
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body models.RefreshRequest true "Refresh token"
+// @Success 200 {object} models.TokenPair
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 401 {string} string "Invalid or expired token"
+// @Router /auth/refresh [post]
+func (h *AuthHandler) RefreshHandler(w http.ResponseWriter, r *http.Request) {
+	var request models.RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.RefreshToken == "" {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	tokens, err := h.Auth.Refresh(request.RefreshToken)
+	if errors.Is(err, services.ErrInvalidToken) {
+		http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to refresh token", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tokens)
+}
+
+// LogoutHandler отзывает текущий access-токен и переданный refresh-токен.
+// @Summary Выход
+// @Description Отзывает access-токен из заголовка Authorization и refresh-токен из тела запроса
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.RefreshRequest false "Refresh token"
+// @Success 204 {string} string "Logged out"
+// @Failure 401 {string} string "Invalid or expired token"
+// @Router /auth/logout [post]
+func (h *AuthHandler) LogoutHandler(w http.ResponseWriter, r *http.Request) {
+	var request models.RefreshRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	accessToken, _ := middleware.BearerToken(r)
+	err := h.Auth.Logout(accessToken, request.RefreshToken)
+	if errors.Is(err, services.ErrInvalidToken) {
+		http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to log out", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
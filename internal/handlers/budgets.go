@@ -0,0 +1,258 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"finance_project/internal/models"
+	"finance_project/internal/services"
+
+	"github.com/gorilla/mux"
+)
+
+// BudgetHandler представляет обработчики бюджетов.
+type BudgetHandler struct {
+	Service *services.BudgetService
+}
+
+// NewBudgetHandler создает новый обработчик бюджетов.
+func NewBudgetHandler(service *services.BudgetService) *BudgetHandler {
+	return &BudgetHandler{Service: service}
+}
+
+// CreateBudgetHandler создает бюджет.
+// @Summary Создание бюджета
+// @Description Создает лимит расходов по категории на период weekly, monthly, quarterly или yearly. Если rollover включен, неизрасходованный остаток переносится на следующий период. Бюджет родительской категории учитывает расходы её подкатегорий.
+// @Tags Budgets
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param budget body models.Budget true "Budget body"
+// @Success 201 {object} models.Budget
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not found"
+// @Failure 500 {string} string "Failed to create budget"
+// @Router /budgets/create [post]
+func (h *BudgetHandler) CreateBudgetHandler(w http.ResponseWriter, r *http.Request) {
+	var budget models.Budget
+	if err := json.NewDecoder(r.Body).Decode(&budget); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	budget.UserID = currentUserID(r)
+
+	id, err := h.Service.CreateBudget(&budget)
+	if err != nil {
+		writeServiceError(w, err, "Failed to create budget")
+		return
+	}
+
+	created, err := h.Service.GetBudgetByID(id, budget.UserID)
+	if err != nil {
+		writeServiceError(w, err, "Failed to create budget")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+// GetBudgetsHandler возвращает бюджеты пользователя.
+// @Summary Список бюджетов
+// @Description Возвращает все бюджеты текущего пользователя
+// @Tags Budgets
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.Budget
+// @Failure 500 {string} string "Failed to retrieve budgets"
+// @Router /budgets [get]
+func (h *BudgetHandler) GetBudgetsHandler(w http.ResponseWriter, r *http.Request) {
+	budgets, err := h.Service.GetBudgets(currentUserID(r))
+	if err != nil {
+		http.Error(w, "Failed to retrieve budgets", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(budgets)
+}
+
+// GetBudgetByIDHandler возвращает бюджет по ID.
+// @Summary Получение бюджета
+// @Description Возвращает бюджет по ID
+// @Tags Budgets
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Budget ID"
+// @Success 200 {object} models.Budget
+// @Failure 400 {string} string "Invalid budget ID"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not found"
+// @Router /budgets/{id} [get]
+func (h *BudgetHandler) GetBudgetByIDHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid budget ID", http.StatusBadRequest)
+		return
+	}
+
+	budget, err := h.Service.GetBudgetByID(id, currentUserID(r))
+	if err != nil {
+		writeServiceError(w, err, "Failed to retrieve budget")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(budget)
+}
+
+// UpdateBudgetHandler изменяет бюджет.
+// @Summary Обновление бюджета
+// @Description Изменяет лимит, период, перенос остатков и пороги уведомлений
+// @Tags Budgets
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param budget body models.Budget true "Budget body"
+// @Success 200 {string} string "Budget updated successfully"
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not found"
+// @Failure 500 {string} string "Failed to update budget"
+// @Router /budgets/update [put]
+func (h *BudgetHandler) UpdateBudgetHandler(w http.ResponseWriter, r *http.Request) {
+	var budget models.Budget
+	if err := json.NewDecoder(r.Body).Decode(&budget); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	budget.UserID = currentUserID(r)
+
+	if err := h.Service.UpdateBudget(&budget); err != nil {
+		writeServiceError(w, err, "Failed to update budget")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Budget updated successfully"))
+}
+
+// DeleteBudgetHandler удаляет бюджет.
+// @Summary Удаление бюджета
+// @Description Удаляет бюджет вместе с его уведомлениями
+// @Tags Budgets
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id query int true "Budget ID"
+// @Success 204 {string} string "Deleted"
+// @Failure 400 {string} string "Invalid budget ID"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not found"
+// @Failure 500 {string} string "Failed to delete budget"
+// @Router /budgets/delete [delete]
+func (h *BudgetHandler) DeleteBudgetHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		http.Error(w, "Invalid budget ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Service.DeleteBudget(id, currentUserID(r)); err != nil {
+		writeServiceError(w, err, "Failed to delete budget")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetStatusHandler возвращает исполнение бюджетов.
+// @Summary Исполнение бюджетов
+// @Description Возвращает потраченную и оставшуюся сумму и процент использования каждого бюджета за период, содержащий date (по умолчанию сегодня). Расходы пересчитываются в валюту бюджета по курсу на дату транзакции.
+// @Tags Budgets
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param date query string false "Date (YYYY-MM-DD)"
+// @Success 200 {array} models.BudgetStatus
+// @Failure 400 {string} string "Invalid date"
+// @Failure 422 {string} string "Exchange rate not found"
+// @Failure 500 {string} string "Failed to calculate budget status"
+// @Router /budgets/status [get]
+func (h *BudgetHandler) GetStatusHandler(w http.ResponseWriter, r *http.Request) {
+	date := time.Now()
+	if raw := r.URL.Query().Get("date"); raw != "" {
+		var err error
+		if date, err = time.Parse("2006-01-02", raw); err != nil {
+			http.Error(w, "Invalid date", http.StatusBadRequest)
+			return
+		}
+	}
+
+	statuses, err := h.Service.GetStatus(currentUserID(r), date)
+	if err != nil {
+		writeServiceError(w, err, "Failed to calculate budget status")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(statuses)
+}
+
+// GetAlertsHandler возвращает уведомления по бюджетам.
+// @Summary Уведомления по бюджетам
+// @Description Возвращает уведомления о достижении порогов бюджетов, новые первыми
+// @Tags Budgets
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param unread query bool false "Only unread alerts"
+// @Success 200 {array} models.BudgetAlert
+// @Failure 500 {string} string "Failed to retrieve budget alerts"
+// @Router /budgets/alerts [get]
+func (h *BudgetHandler) GetAlertsHandler(w http.ResponseWriter, r *http.Request) {
+	unreadOnly, _ := strconv.ParseBool(r.URL.Query().Get("unread"))
+
+	alerts, err := h.Service.GetAlerts(currentUserID(r), unreadOnly)
+	if err != nil {
+		http.Error(w, "Failed to retrieve budget alerts", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(alerts)
+}
+
+// MarkAlertReadHandler отмечает уведомление прочитанным.
+// @Summary Прочтение уведомления
+// @Description Отмечает уведомление по бюджету прочитанным
+// @Tags Budgets
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Alert ID"
+// @Success 204 {string} string "Marked as read"
+// @Failure 400 {string} string "Invalid alert ID"
+// @Failure 404 {string} string "Not found"
+// @Failure 500 {string} string "Failed to update budget alert"
+// @Router /budgets/alerts/{id}/read [post]
+func (h *BudgetHandler) MarkAlertReadHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid alert ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Service.MarkAlertRead(id, currentUserID(r)); err != nil {
+		writeServiceError(w, err, "Failed to update budget alert")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
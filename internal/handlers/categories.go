@@ -26,11 +26,12 @@ func NewCategoryHandler(service *services.CategoryService) *CategoryHandler {
 // @Tags Categories
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Success 200 {array} models.Category
 // @Failure 500 {string} string "Failed to retrieve categories"
 // @Router /categories [get]
 func (h *CategoryHandler) GetAllCategoriesHandler(w http.ResponseWriter, r *http.Request) {
-	categories, err := h.Service.GetAllCategories()
+	categories, err := h.Service.GetAllCategories(currentUserID(r))
 	if err != nil {
 		http.Error(w, "Failed to retrieve categories", http.StatusInternalServerError)
 		return
@@ -42,13 +43,16 @@ func (h *CategoryHandler) GetAllCategoriesHandler(w http.ResponseWriter, r *http
 
 // CreateCategoryHandler создает новую категорию.
 // @Summary Создание категории
-// @Description Создает новую категорию
+// @Description Создает новую категорию. parent_id делает её подкатегорией существующей категории того же типа
 // @Tags Categories
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param category body models.Category true "Category body"
 // @Success 201 {string} string "Created"
 // @Failure 400 {string} string "Invalid request body"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Parent category not found"
 // @Failure 500 {string} string "Failed to create category"
 // @Router /categories/create [post]
 func (h *CategoryHandler) CreateCategoryHandler(w http.ResponseWriter, r *http.Request) {
@@ -57,9 +61,10 @@ func (h *CategoryHandler) CreateCategoryHandler(w http.ResponseWriter, r *http.R
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
+	category.UserID = currentUserID(r)
 
 	if err := h.Service.CreateCategory(category); err != nil {
-		http.Error(w, "Failed to create category", http.StatusInternalServerError)
+		writeServiceError(w, err, "Failed to create category")
 		return
 	}
 
@@ -72,9 +77,11 @@ func (h *CategoryHandler) CreateCategoryHandler(w http.ResponseWriter, r *http.R
 // @Tags Categories
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param id query int true "Category ID"
 // @Success 200 {object} models.Category
 // @Failure 400 {string} string "Invalid category ID"
+// @Failure 403 {string} string "Forbidden"
 // @Failure 404 {string} string "Category not found"
 // @Failure 500 {string} string "Failed to retrieve category"
 // @Router /categories/{id} [get]
@@ -94,6 +101,10 @@ func (h *CategoryHandler) GetCategoryByIDHandler(w http.ResponseWriter, r *http.
 		http.Error(w, "Category not found", http.StatusNotFound)
 		return
 	}
+	if category.UserID != currentUserID(r) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(category)
@@ -101,13 +112,16 @@ func (h *CategoryHandler) GetCategoryByIDHandler(w http.ResponseWriter, r *http.
 
 // UpdateCategoryHandler обновляет категорию.
 // @Summary Обновление категории
-// @Description Обновляет данные категории
+// @Description Обновляет данные категории, включая parent_id. Категорию нельзя поместить в саму себя или в её подкатегорию
 // @Tags Categories
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param category body models.Category true "Category body"
 // @Success 200 {string} string "Updated"
 // @Failure 400 {string} string "Invalid request body"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Category not found"
 // @Failure 500 {string} string "Failed to update category"
 // @Router /categories/{id} [put]
 func (h *CategoryHandler) UpdateCategoryHandler(w http.ResponseWriter, r *http.Request) {
@@ -117,8 +131,13 @@ func (h *CategoryHandler) UpdateCategoryHandler(w http.ResponseWriter, r *http.R
 		return
 	}
 
+	if !h.authorize(w, r, category.ID) {
+		return
+	}
+	category.UserID = currentUserID(r)
+
 	if err := h.Service.UpdateCategory(category); err != nil {
-		http.Error(w, "Failed to update category", http.StatusInternalServerError)
+		writeServiceError(w, err, "Failed to update category")
 		return
 	}
 
@@ -127,13 +146,16 @@ func (h *CategoryHandler) UpdateCategoryHandler(w http.ResponseWriter, r *http.R
 
 // DeleteCategoryHandler удаляет категорию.
 // @Summary Удаление категории
-// @Description Удаляет категорию по её ID
+// @Description Переносит категорию в корзину вместе с подкатегориями и транзакциями, у которых есть категория или часть разбивки из них. Восстанавливается через /trash/{id}/restore
 // @Tags Categories
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param id query int true "Category ID"
 // @Success 200 {string} string "Deleted"
 // @Failure 400 {string} string "Invalid category ID"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Category not found"
 // @Failure 500 {string} string "Failed to delete category"
 // @Router /categories/{id} [delete]
 func (h *CategoryHandler) DeleteCategoryHandler(w http.ResponseWriter, r *http.Request) {
@@ -143,21 +165,102 @@ func (h *CategoryHandler) DeleteCategoryHandler(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	if err := h.Service.DeleteCategory(id); err != nil {
-		http.Error(w, "Failed to delete category", http.StatusInternalServerError)
+	if !h.authorize(w, r, id) {
+		return
+	}
+
+	if err := h.Service.DeleteCategory(id, currentUserID(r)); err != nil {
+		writeServiceError(w, err, "Failed to delete category")
 		return
 	}
 
 	w.WriteHeader(http.StatusOK)
 }
 
+// MoveCategoryHandler переносит категорию под другого родителя.
+// @Summary Перенос категории
+// @Description Переносит категорию вместе с подкатегориями под родителя parent_id того же типа; без parent_id или с 0 категория становится верхнеуровневой. Категорию нельзя перенести в саму себя или в её подкатегорию
+// @Tags Categories
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Category ID"
+// @Param parent_id query int false "Parent category ID"
+// @Success 204 {string} string "No Content"
+// @Failure 400 {string} string "Invalid parameters"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not found"
+// @Failure 500 {string} string "Failed to move category"
+// @Router /categories/{id}/move [post]
+func (h *CategoryHandler) MoveCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid category ID", http.StatusBadRequest)
+		return
+	}
+	parentID := 0
+	if raw := r.URL.Query().Get("parent_id"); raw != "" {
+		parentID, err = strconv.Atoi(raw)
+		if err != nil {
+			http.Error(w, "Invalid parent_id", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if err := h.Service.MoveCategory(id, currentUserID(r), parentID); err != nil {
+		writeServiceError(w, err, "Failed to move category")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// MergeCategoryHandler сливает категорию в другую.
+// @Summary Слияние категорий
+// @Description Переносит транзакции, части разбитых транзакций, плановые транзакции, правила и подкатегории в категорию into того же типа и удаляет исходную категорию. Бюджет и конверт переходят, только если у категории into их нет
+// @Tags Categories
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Category ID"
+// @Param into query int true "Target category ID"
+// @Success 204 {string} string "No Content"
+// @Failure 400 {string} string "Invalid parameters"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not found"
+// @Failure 500 {string} string "Failed to merge categories"
+// @Router /categories/{id}/merge [post]
+func (h *CategoryHandler) MergeCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid category ID", http.StatusBadRequest)
+		return
+	}
+	targetID, err := strconv.Atoi(r.URL.Query().Get("into"))
+	if err != nil {
+		http.Error(w, "Invalid into", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Service.MergeCategory(id, currentUserID(r), targetID); err != nil {
+		writeServiceError(w, err, "Failed to merge categories")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
 // GetTransactionsByCategoryHandler godoc
 // @Summary Get transactions by category
-// @Description Retrieves all transactions for a specific category
+// @Description Retrieves all transactions for a specific category, including split transactions with a line in it. Repeated tag parameters keep only transactions carrying all of the tags
 // @Tags Transactions
+// @Security BearerAuth
 // @Param id path int true "Category ID"
+// @Param tag query []string false "Tag names" collectionFormat(multi)
 // @Success 200 {array} models.Transaction
 // @Failure 400 {string} string "Invalid category ID"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not found"
 // @Failure 500 {string} string "Internal server error"
 // @Router /categories/{id}/transactions [get]
 func (h *TransactionHandler) GetTransactionsByCategoryHandler(w http.ResponseWriter, r *http.Request) {
@@ -168,9 +271,9 @@ func (h *TransactionHandler) GetTransactionsByCategoryHandler(w http.ResponseWri
 		return
 	}
 
-	transactions, err := h.Service.GetTransactionsByCategory(categoryID)
+	transactions, err := h.Service.GetTransactionsByCategory(categoryID, currentUserID(r), r.URL.Query()["tag"])
 	if err != nil {
-		http.Error(w, "Failed to retrieve transactions", http.StatusInternalServerError)
+		writeServiceError(w, err, "Failed to retrieve transactions")
 		return
 	}
 
@@ -180,11 +283,15 @@ func (h *TransactionHandler) GetTransactionsByCategoryHandler(w http.ResponseWri
 
 // GetTransactionsByAccountHandler godoc
 // @Summary Get transactions by account
-// @Description Retrieves all transactions for a specific account
+// @Description Retrieves all transactions for a specific account. Repeated tag parameters keep only transactions carrying all of the tags
 // @Tags Transactions
+// @Security BearerAuth
 // @Param id path int true "Account ID"
+// @Param tag query []string false "Tag names" collectionFormat(multi)
 // @Success 200 {array} models.Transaction
 // @Failure 400 {string} string "Invalid account ID"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not found"
 // @Failure 500 {string} string "Internal server error"
 // @Router /accounts/{id}/transactions [get]
 func (h *TransactionHandler) GetTransactionsByAccountHandler(w http.ResponseWriter, r *http.Request) {
@@ -195,12 +302,30 @@ func (h *TransactionHandler) GetTransactionsByAccountHandler(w http.ResponseWrit
 		return
 	}
 
-	transactions, err := h.Service.GetTransactionsByAccount(accountID)
+	transactions, err := h.Service.GetTransactionsByAccount(accountID, currentUserID(r), r.URL.Query()["tag"])
 	if err != nil {
-		http.Error(w, "Failed to retrieve transactions", http.StatusInternalServerError)
+		writeServiceError(w, err, "Failed to retrieve transactions")
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(transactions)
 }
+
+// authorize проверяет, что категория существует и принадлежит текущему пользователю.
+func (h *CategoryHandler) authorize(w http.ResponseWriter, r *http.Request, id int) bool {
+	category, err := h.Service.GetCategoryByID(id)
+	if err != nil {
+		http.Error(w, "Failed to retrieve category", http.StatusInternalServerError)
+		return false
+	}
+	if category == nil {
+		http.Error(w, "Category not found", http.StatusNotFound)
+		return false
+	}
+	if category.UserID != currentUserID(r) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"finance_project/internal/models"
+	"finance_project/internal/services"
+
+	"github.com/gorilla/mux"
+)
+
+// CategoryRuleHandler представляет обработчики правил категоризации.
+type CategoryRuleHandler struct {
+	Service *services.CategoryRuleService
+}
+
+// NewCategoryRuleHandler создает новый обработчик правил категоризации.
+func NewCategoryRuleHandler(service *services.CategoryRuleService) *CategoryRuleHandler {
+	return &CategoryRuleHandler{Service: service}
+}
+
+// CreateRuleHandler создает правило категоризации.
+// @Summary Создание правила категоризации
+// @Description Создает правило, назначающее категорию новым транзакциям без категории и строкам импортируемых выписок. Условия (description_contains, description_pattern — регулярное выражение, type, account_id, min_amount, max_amount) объединяются по И; правила проверяются по возрастанию priority, срабатывает первое подошедшее.
+// @Tags CategoryRules
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param rule body models.CategoryRule true "Rule body"
+// @Success 201 {object} models.CategoryRule
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not found"
+// @Failure 500 {string} string "Failed to create category rule"
+// @Router /category-rules/create [post]
+func (h *CategoryRuleHandler) CreateRuleHandler(w http.ResponseWriter, r *http.Request) {
+	var rule models.CategoryRule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	rule.UserID = currentUserID(r)
+
+	id, err := h.Service.CreateRule(&rule)
+	if err != nil {
+		writeServiceError(w, err, "Failed to create category rule")
+		return
+	}
+
+	created, err := h.Service.GetRuleByID(id, rule.UserID)
+	if err != nil {
+		writeServiceError(w, err, "Failed to create category rule")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+// GetRulesHandler возвращает правила категоризации пользователя.
+// @Summary Список правил категоризации
+// @Description Возвращает правила текущего пользователя в порядке применения
+// @Tags CategoryRules
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.CategoryRule
+// @Failure 500 {string} string "Failed to retrieve category rules"
+// @Router /category-rules [get]
+func (h *CategoryRuleHandler) GetRulesHandler(w http.ResponseWriter, r *http.Request) {
+	rules, err := h.Service.GetRules(currentUserID(r))
+	if err != nil {
+		http.Error(w, "Failed to retrieve category rules", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rules)
+}
+
+// UpdateRuleHandler изменяет правило категоризации.
+// @Summary Обновление правила категоризации
+// @Description Изменяет условия, категорию и приоритет правила. Категории уже созданных транзакций не меняются, для этого есть /category-rules/apply.
+// @Tags CategoryRules
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param rule body models.CategoryRule true "Rule body"
+// @Success 200 {string} string "Category rule updated successfully"
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not found"
+// @Failure 500 {string} string "Failed to update category rule"
+// @Router /category-rules/update [put]
+func (h *CategoryRuleHandler) UpdateRuleHandler(w http.ResponseWriter, r *http.Request) {
+	var rule models.CategoryRule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	rule.UserID = currentUserID(r)
+
+	if err := h.Service.UpdateRule(&rule); err != nil {
+		writeServiceError(w, err, "Failed to update category rule")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Category rule updated successfully"))
+}
+
+// DeleteRuleHandler удаляет правило категоризации.
+// @Summary Удаление правила категоризации
+// @Description Удаляет правило; категории транзакций остаются прежними
+// @Tags CategoryRules
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id query int true "Rule ID"
+// @Success 204 {string} string "Deleted"
+// @Failure 400 {string} string "Invalid rule ID"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not found"
+// @Failure 500 {string} string "Failed to delete category rule"
+// @Router /category-rules/delete [delete]
+func (h *CategoryRuleHandler) DeleteRuleHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		http.Error(w, "Invalid rule ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Service.DeleteRule(id, currentUserID(r)); err != nil {
+		writeServiceError(w, err, "Failed to delete category rule")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetRuleByIDHandler возвращает правило категоризации по ID.
+// @Summary Получение правила категоризации
+// @Description Возвращает правило категоризации по ID
+// @Tags CategoryRules
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Rule ID"
+// @Success 200 {object} models.CategoryRule
+// @Failure 400 {string} string "Invalid rule ID"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not found"
+// @Router /category-rules/{id} [get]
+func (h *CategoryRuleHandler) GetRuleByIDHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid rule ID", http.StatusBadRequest)
+		return
+	}
+
+	rule, err := h.Service.GetRuleByID(id, currentUserID(r))
+	if err != nil {
+		writeServiceError(w, err, "Failed to retrieve category rule")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rule)
+}
+
+// ApplyRulesHandler повторно применяет правила к транзакциям за период.
+// @Summary Применение правил к прошлым транзакциям
+// @Description Применяет правила к доходам и расходам за период from..to (по умолчанию to — сегодня) и возвращает изменения категорий. Транзакции, к которым не подошло ни одно правило, не меняются; only_uncategorized ограничивает изменения транзакциями без категории. С dry_run=true категории не меняются.
+// @Tags CategoryRules
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param from query string true "From date (YYYY-MM-DD)"
+// @Param to query string false "To date (YYYY-MM-DD)"
+// @Param only_uncategorized query bool false "Only transactions without a category"
+// @Param dry_run query bool false "Preview changes without saving"
+// @Success 200 {object} models.CategoryRuleApplyResult
+// @Failure 400 {string} string "Invalid parameters"
+// @Failure 500 {string} string "Failed to apply category rules"
+// @Router /category-rules/apply [post]
+func (h *CategoryRuleHandler) ApplyRulesHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	request := models.CategoryRuleApplyRequest{To: time.Now()}
+	var err error
+	if request.From, err = time.Parse("2006-01-02", query.Get("from")); err != nil {
+		http.Error(w, "Invalid from date", http.StatusBadRequest)
+		return
+	}
+	if raw := query.Get("to"); raw != "" {
+		if request.To, err = time.Parse("2006-01-02", raw); err != nil {
+			http.Error(w, "Invalid to date", http.StatusBadRequest)
+			return
+		}
+	}
+	request.OnlyUncategorized, _ = strconv.ParseBool(query.Get("only_uncategorized"))
+	request.DryRun, _ = strconv.ParseBool(query.Get("dry_run"))
+
+	result, err := h.Service.ApplyRules(currentUserID(r), request)
+	if err != nil {
+		writeServiceError(w, err, "Failed to apply category rules")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
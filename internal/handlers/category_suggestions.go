@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"finance_project/internal/models"
+	"finance_project/internal/money"
+	"finance_project/internal/services"
+
+	"github.com/gorilla/mux"
+)
+
+// CategorySuggestionHandler представляет обработчики подсказок категорий.
+type CategorySuggestionHandler struct {
+	Service *services.CategorySuggestionService
+}
+
+// NewCategorySuggestionHandler создает новый обработчик подсказок категорий.
+func NewCategorySuggestionHandler(service *services.CategorySuggestionService) *CategorySuggestionHandler {
+	return &CategorySuggestionHandler{Service: service}
+}
+
+// SuggestHandler предлагает категории для новой транзакции.
+// @Summary Подсказка категории
+// @Description Возвращает до трёх наиболее вероятных категорий с уверенностью от 0 до 1 для транзакции с указанным описанием, суммой и типом. Классификатор обучается на категоризированных транзакциях пользователя; без достаточной истории список пуст.
+// @Tags CategorySuggestions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param description query string true "Transaction description"
+// @Param amount query string false "Transaction amount"
+// @Param type query string false "income или expense (по умолчанию expense)"
+// @Success 200 {array} models.CategorySuggestion
+// @Failure 400 {string} string "Invalid parameters"
+// @Failure 500 {string} string "Failed to suggest a category"
+// @Router /category-suggestions [get]
+func (h *CategorySuggestionHandler) SuggestHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	transaction := models.Transaction{Description: query.Get("description"), Type: query.Get("type")}
+	if transaction.Type == "" {
+		transaction.Type = models.TransactionTypeExpense
+	}
+	if raw := query.Get("amount"); raw != "" {
+		amount, err := money.Parse(raw)
+		if err != nil {
+			http.Error(w, "Invalid amount", http.StatusBadRequest)
+			return
+		}
+		transaction.Amount = amount
+	}
+
+	suggestions, err := h.Service.Suggest(currentUserID(r), transaction)
+	if err != nil {
+		writeServiceError(w, err, "Failed to suggest a category")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(suggestions)
+}
+
+// SuggestForTransactionHandler предлагает категории для сохранённой транзакции.
+// @Summary Подсказка категории для транзакции
+// @Description Возвращает до трёх наиболее вероятных категорий транзакции с уверенностью от 0 до 1
+// @Tags CategorySuggestions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Transaction ID"
+// @Success 200 {array} models.CategorySuggestion
+// @Failure 400 {string} string "Invalid transaction ID"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not found"
+// @Failure 500 {string} string "Failed to suggest a category"
+// @Router /transactions/{id}/suggestions [get]
+func (h *CategorySuggestionHandler) SuggestForTransactionHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid transaction ID", http.StatusBadRequest)
+		return
+	}
+
+	suggestions, err := h.Service.SuggestForTransaction(id, currentUserID(r))
+	if err != nil {
+		writeServiceError(w, err, "Failed to suggest a category")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(suggestions)
+}
+
+// FeedbackHandler принимает или исправляет подсказку.
+// @Summary Ответ на подсказку категории
+// @Description Назначает транзакции выбранную категорию. Если она совпадает с подсказкой, подсказка считается принятой, иначе исправленной; в обоих случаях транзакция получает повышенный вес при обучении.
+// @Tags CategorySuggestions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param feedback body models.CategoryFeedback true "transaction_id и category"
+// @Success 200 {object} models.Transaction
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not found"
+// @Failure 500 {string} string "Failed to save feedback"
+// @Router /category-suggestions/feedback [post]
+func (h *CategorySuggestionHandler) FeedbackHandler(w http.ResponseWriter, r *http.Request) {
+	var feedback models.CategoryFeedback
+	if err := json.NewDecoder(r.Body).Decode(&feedback); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	feedback.UserID = currentUserID(r)
+
+	transaction, err := h.Service.Feedback(&feedback)
+	if err != nil {
+		writeServiceError(w, err, "Failed to save feedback")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(transaction)
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"finance_project/internal/models"
+	"finance_project/internal/money"
+	"finance_project/internal/services"
+)
+
+// CurrencyRateHandler представляет обработчики курсов валют. Курсы общие для всех пользователей,
+// поэтому через API они только читаются; записываются они из источников rate_feeds и командами CLI.
+type CurrencyRateHandler struct {
+	Service *services.CurrencyRateService
+}
+
+// NewCurrencyRateHandler создает новый обработчик курсов валют.
+func NewCurrencyRateHandler(service *services.CurrencyRateService) *CurrencyRateHandler {
+	return &CurrencyRateHandler{Service: service}
+}
+
+// GetRatesHandler возвращает сохранённые курсы валют.
+// @Summary Список курсов валют
+// @Description Возвращает курсы, новые первыми, с необязательными фильтрами по паре и дате
+// @Tags CurrencyRates
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param base query string false "Base currency"
+// @Param target query string false "Target currency"
+// @Param date query string false "Rate date (YYYY-MM-DD)"
+// @Success 200 {array} models.CurrencyRate
+// @Failure 400 {string} string "Invalid date format"
+// @Failure 500 {string} string "Failed to retrieve currency rates"
+// @Router /currency-rates [get]
+func (h *CurrencyRateHandler) GetRatesHandler(w http.ResponseWriter, r *http.Request) {
+	var date time.Time
+	if raw := r.URL.Query().Get("date"); raw != "" {
+		var err error
+		date, err = time.Parse("2006-01-02", raw)
+		if err != nil {
+			http.Error(w, "Invalid date format", http.StatusBadRequest)
+			return
+		}
+	}
+
+	rates, err := h.Service.GetRates(r.URL.Query().Get("base"), r.URL.Query().Get("target"), date)
+	if err != nil {
+		http.Error(w, "Failed to retrieve currency rates", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rates)
+}
+
+// ConvertHandler пересчитывает сумму между валютами.
+// @Summary Конвертация суммы
+// @Description Пересчитывает сумму по курсу на дату (по умолчанию сегодня), при необходимости через базовую валюту
+// @Tags CurrencyRates
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param amount query string true "Amount"
+// @Param from query string true "Source currency"
+// @Param to query string true "Target currency"
+// @Param date query string false "Date (YYYY-MM-DD)"
+// @Success 200 {object} models.Conversion
+// @Failure 400 {string} string "Invalid parameters"
+// @Failure 422 {string} string "Exchange rate not found"
+// @Failure 500 {string} string "Failed to convert amount"
+// @Router /currency-rates/convert [get]
+func (h *CurrencyRateHandler) ConvertHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	amount, err := money.Parse(query.Get("amount"))
+	if err != nil {
+		http.Error(w, "Invalid amount", http.StatusBadRequest)
+		return
+	}
+
+	from := strings.ToUpper(query.Get("from"))
+	to := strings.ToUpper(query.Get("to"))
+	if from == "" || to == "" {
+		http.Error(w, "Currencies from and to are required", http.StatusBadRequest)
+		return
+	}
+
+	date := time.Now()
+	if raw := query.Get("date"); raw != "" {
+		date, err = time.Parse("2006-01-02", raw)
+		if err != nil {
+			http.Error(w, "Invalid date format", http.StatusBadRequest)
+			return
+		}
+	}
+
+	converted, rate, err := h.Service.Convert(amount, from, to, date)
+	if err != nil {
+		writeServiceError(w, err, "Failed to convert amount")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.Conversion{
+		Amount:          amount,
+		FromCurrency:    from,
+		ToCurrency:      to,
+		Rate:            rate,
+		Date:            date,
+		ConvertedAmount: converted,
+	})
+}
@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"finance_project/internal/models"
+	"finance_project/internal/services"
+
+	"github.com/gorilla/mux"
+)
+
+// DebtHandler представляет обработчики долгов.
+type DebtHandler struct {
+	Service *services.DebtService
+}
+
+// NewDebtHandler создает новый обработчик долгов.
+func NewDebtHandler(service *services.DebtService) *DebtHandler {
+	return &DebtHandler{Service: service}
+}
+
+// CreateDebtHandler создает долг.
+// @Summary Создание долга
+// @Description Создает долг: lent — вы дали в долг, borrowed — вы взяли в долг. Если указан account_id, выдача проводится по счёту.
+// @Tags Debts
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param debt body models.Debt true "Debt body"
+// @Success 201 {object} models.Debt
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not found"
+// @Failure 500 {string} string "Failed to create debt"
+// @Router /debts/create [post]
+func (h *DebtHandler) CreateDebtHandler(w http.ResponseWriter, r *http.Request) {
+	var debt models.Debt
+	if err := json.NewDecoder(r.Body).Decode(&debt); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	debt.UserID = currentUserID(r)
+
+	id, err := h.Service.CreateDebt(&debt)
+	if err != nil {
+		writeServiceError(w, err, "Failed to create debt")
+		return
+	}
+
+	created, err := h.Service.GetDebtByID(id, debt.UserID)
+	if err != nil {
+		writeServiceError(w, err, "Failed to create debt")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+// GetDebtsHandler возвращает долги пользователя.
+// @Summary Список долгов
+// @Description Возвращает долги текущего пользователя с суммой возвратов и остатком; можно отфильтровать по контакту
+// @Tags Debts
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param contact query string false "Contact"
+// @Success 200 {array} models.Debt
+// @Failure 500 {string} string "Failed to retrieve debts"
+// @Router /debts [get]
+func (h *DebtHandler) GetDebtsHandler(w http.ResponseWriter, r *http.Request) {
+	debts, err := h.Service.GetDebts(currentUserID(r), r.URL.Query().Get("contact"))
+	if err != nil {
+		http.Error(w, "Failed to retrieve debts", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(debts)
+}
+
+// GetOverdueDebtsHandler возвращает просроченные долги.
+// @Summary Просроченные долги
+// @Description Возвращает непогашенные долги, срок которых уже прошёл
+// @Tags Debts
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.Debt
+// @Failure 500 {string} string "Failed to retrieve overdue debts"
+// @Router /debts/overdue [get]
+func (h *DebtHandler) GetOverdueDebtsHandler(w http.ResponseWriter, r *http.Request) {
+	debts, err := h.Service.GetOverdueDebts(currentUserID(r))
+	if err != nil {
+		http.Error(w, "Failed to retrieve overdue debts", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(debts)
+}
+
+// GetContactBalancesHandler возвращает остатки долгов по контактам.
+// @Summary Баланс по контактам
+// @Description Возвращает непогашенные суммы по каждому контакту и валюте; net положителен, если должны вам
+// @Tags Debts
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.ContactDebt
+// @Failure 500 {string} string "Failed to retrieve contact balances"
+// @Router /debts/contacts [get]
+func (h *DebtHandler) GetContactBalancesHandler(w http.ResponseWriter, r *http.Request) {
+	balances, err := h.Service.GetContactBalances(currentUserID(r))
+	if err != nil {
+		http.Error(w, "Failed to retrieve contact balances", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(balances)
+}
+
+// GetDebtByIDHandler возвращает долг по ID.
+// @Summary Получение долга
+// @Description Возвращает долг вместе с историей возвратов
+// @Tags Debts
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Debt ID"
+// @Success 200 {object} models.Debt
+// @Failure 400 {string} string "Invalid debt ID"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not found"
+// @Router /debts/{id} [get]
+func (h *DebtHandler) GetDebtByIDHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid debt ID", http.StatusBadRequest)
+		return
+	}
+
+	debt, err := h.Service.GetDebtByID(id, currentUserID(r))
+	if err != nil {
+		writeServiceError(w, err, "Failed to retrieve debt")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(debt)
+}
+
+// UpdateDebtHandler изменяет долг.
+// @Summary Обновление долга
+// @Description Изменяет контакт, сумму, описание и срок; сумма не может быть меньше уже возвращённой. Транзакция выдачи долга меняется вместе с суммой; account_id переносит её на другой счёт в валюте долга
+// @Tags Debts
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param debt body models.Debt true "Debt body"
+// @Success 200 {string} string "Debt updated successfully"
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not found"
+// @Failure 500 {string} string "Failed to update debt"
+// @Router /debts/update [put]
+func (h *DebtHandler) UpdateDebtHandler(w http.ResponseWriter, r *http.Request) {
+	var debt models.Debt
+	if err := json.NewDecoder(r.Body).Decode(&debt); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	debt.UserID = currentUserID(r)
+
+	if err := h.Service.UpdateDebt(debt); err != nil {
+		writeServiceError(w, err, "Failed to update debt")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Debt updated successfully"))
+}
+
+// DeleteDebtHandler удаляет долг.
+// @Summary Удаление долга
+// @Description Удаляет долг вместе с выдачей и возвратами и откатывает балансы счетов
+// @Tags Debts
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id query int true "Debt ID"
+// @Success 204 {string} string "Deleted"
+// @Failure 400 {string} string "Invalid debt ID"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not found"
+// @Failure 500 {string} string "Failed to delete debt"
+// @Router /debts/delete [delete]
+func (h *DebtHandler) DeleteDebtHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		http.Error(w, "Invalid debt ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Service.DeleteDebt(id, currentUserID(r)); err != nil {
+		writeServiceError(w, err, "Failed to delete debt")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AddRepaymentHandler проводит возврат долга.
+// @Summary Возврат долга
+// @Description Проводит частичный или полный возврат по счёту: для lent деньги зачисляются, для borrowed — списываются
+// @Tags Debts
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Debt ID"
+// @Param repayment body models.DebtRepayment true "Repayment body"
+// @Success 201 {object} models.DebtRepayment
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not found"
+// @Failure 500 {string} string "Failed to add repayment"
+// @Router /debts/{id}/repayments [post]
+func (h *DebtHandler) AddRepaymentHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid debt ID", http.StatusBadRequest)
+		return
+	}
+
+	var repayment models.DebtRepayment
+	if err := json.NewDecoder(r.Body).Decode(&repayment); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	repayment.DebtID = id
+
+	if err := h.Service.AddRepayment(currentUserID(r), &repayment); err != nil {
+		writeServiceError(w, err, "Failed to add repayment")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(repayment)
+}
@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"finance_project/internal/models"
+	"finance_project/internal/services"
+
+	"github.com/gorilla/mux"
+)
+
+// DepositHandler представляет обработчики вкладов.
+type DepositHandler struct {
+	Service *services.DepositService
+}
+
+// NewDepositHandler создает новый обработчик вкладов.
+func NewDepositHandler(service *services.DepositService) *DepositHandler {
+	return &DepositHandler{Service: service}
+}
+
+// CreateDepositHandler открывает вклад.
+// @Summary Создание вклада
+// @Description Открывает вклад. interest_rate — годовая ставка в процентах, interest_type — simple или compound, capitalization — monthly, quarterly или maturity. Проценты зачисляются доходом на счёт account_id.
+// @Tags Deposits
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param deposit body models.Deposit true "Deposit body"
+// @Success 201 {object} models.Deposit
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not found"
+// @Failure 500 {string} string "Failed to create deposit"
+// @Router /deposits/create [post]
+func (h *DepositHandler) CreateDepositHandler(w http.ResponseWriter, r *http.Request) {
+	var deposit models.Deposit
+	if err := json.NewDecoder(r.Body).Decode(&deposit); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	deposit.UserID = currentUserID(r)
+
+	id, err := h.Service.CreateDeposit(&deposit)
+	if err != nil {
+		writeServiceError(w, err, "Failed to create deposit")
+		return
+	}
+
+	created, err := h.Service.GetDepositByID(id, deposit.UserID)
+	if err != nil {
+		writeServiceError(w, err, "Failed to create deposit")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+// GetDepositsHandler возвращает вклады пользователя.
+// @Summary Список вкладов
+// @Description Возвращает все вклады текущего пользователя
+// @Tags Deposits
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.Deposit
+// @Failure 500 {string} string "Failed to retrieve deposits"
+// @Router /deposits [get]
+func (h *DepositHandler) GetDepositsHandler(w http.ResponseWriter, r *http.Request) {
+	deposits, err := h.Service.GetDeposits(currentUserID(r))
+	if err != nil {
+		http.Error(w, "Failed to retrieve deposits", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(deposits)
+}
+
+// GetDepositByIDHandler возвращает вклад по ID.
+// @Summary Получение вклада
+// @Description Возвращает вклад, сумму зачисленных процентов и дату следующего начисления
+// @Tags Deposits
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Deposit ID"
+// @Success 200 {object} models.Deposit
+// @Failure 400 {string} string "Invalid deposit ID"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not found"
+// @Router /deposits/{id} [get]
+func (h *DepositHandler) GetDepositByIDHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid deposit ID", http.StatusBadRequest)
+		return
+	}
+
+	deposit, err := h.Service.GetDepositByID(id, currentUserID(r))
+	if err != nil {
+		writeServiceError(w, err, "Failed to retrieve deposit")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(deposit)
+}
+
+// GetProjectionHandler возвращает график начисления процентов по вкладу.
+// @Summary График начислений по вкладу
+// @Description Возвращает начисления процентов по каждому периоду до даты окончания вклада; зачисленные периоды отмечены posted
+// @Tags Deposits
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Deposit ID"
+// @Success 200 {array} models.DepositAccrual
+// @Failure 400 {string} string "Invalid deposit ID"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not found"
+// @Router /deposits/{id}/projection [get]
+func (h *DepositHandler) GetProjectionHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid deposit ID", http.StatusBadRequest)
+		return
+	}
+
+	projection, err := h.Service.GetProjection(id, currentUserID(r))
+	if err != nil {
+		writeServiceError(w, err, "Failed to calculate deposit projection")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(projection)
+}
+
+// UpdateDepositHandler изменяет вклад.
+// @Summary Обновление вклада
+// @Description Изменяет условия вклада; после первого начисления процентов можно сменить только счёт
+// @Tags Deposits
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param deposit body models.Deposit true "Deposit body"
+// @Success 200 {string} string "Deposit updated successfully"
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not found"
+// @Failure 500 {string} string "Failed to update deposit"
+// @Router /deposits/update [put]
+func (h *DepositHandler) UpdateDepositHandler(w http.ResponseWriter, r *http.Request) {
+	var deposit models.Deposit
+	if err := json.NewDecoder(r.Body).Decode(&deposit); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	deposit.UserID = currentUserID(r)
+
+	if err := h.Service.UpdateDeposit(&deposit); err != nil {
+		writeServiceError(w, err, "Failed to update deposit")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Deposit updated successfully"))
+}
+
+// DeleteDepositHandler удаляет вклад.
+// @Summary Удаление вклада
+// @Description Удаляет вклад; зачисленные проценты остаются на счёте
+// @Tags Deposits
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id query int true "Deposit ID"
+// @Success 204 {string} string "Deleted"
+// @Failure 400 {string} string "Invalid deposit ID"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not found"
+// @Failure 500 {string} string "Failed to delete deposit"
+// @Router /deposits/delete [delete]
+func (h *DepositHandler) DeleteDepositHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		http.Error(w, "Invalid deposit ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Service.DeleteDeposit(id, currentUserID(r)); err != nil {
+		writeServiceError(w, err, "Failed to delete deposit")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
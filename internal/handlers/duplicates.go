@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"finance_project/internal/services"
+
+	"github.com/gorilla/mux"
+)
+
+// DuplicateHandler представляет обработчики проверки вероятных дублей транзакций.
+type DuplicateHandler struct {
+	Service *services.DuplicateService
+}
+
+// NewDuplicateHandler создает новый обработчик дублей.
+func NewDuplicateHandler(service *services.DuplicateService) *DuplicateHandler {
+	return &DuplicateHandler{Service: service}
+}
+
+// GetDuplicatesHandler возвращает пары вероятных дублей.
+// @Summary Список вероятных дублей
+// @Description Возвращает пары транзакций, отмеченные как вероятные дубли при создании или импорте выписки, с оценкой уверенности от 0 до 1. transaction — новая транзакция, original — существующая, на которую она похожа.
+// @Tags Duplicates
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param status query string false "pending (по умолчанию) или dismissed"
+// @Success 200 {array} models.TransactionDuplicate
+// @Failure 400 {string} string "Invalid input"
+// @Failure 500 {string} string "Failed to retrieve duplicates"
+// @Router /duplicates [get]
+func (h *DuplicateHandler) GetDuplicatesHandler(w http.ResponseWriter, r *http.Request) {
+	duplicates, err := h.Service.GetDuplicates(currentUserID(r), r.URL.Query().Get("status"))
+	if err != nil {
+		writeServiceError(w, err, "Failed to retrieve duplicates")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(duplicates)
+}
+
+// MergeDuplicateHandler объединяет пару дублей.
+// @Summary Объединение дублей
+// @Description Удаляет одну транзакцию пары с откатом баланса и оставляет другую, дополняя её пустые поля (категория, описание, реквизиты контрагента). По умолчанию остаётся существующая транзакция (original).
+// @Tags Duplicates
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Duplicate pair ID"
+// @Param keep query int false "ID транзакции, которую нужно оставить"
+// @Success 200 {object} models.Transaction
+// @Failure 400 {string} string "Invalid input"
+// @Failure 404 {string} string "Not found"
+// @Failure 500 {string} string "Failed to merge duplicates"
+// @Router /duplicates/{id}/merge [post]
+func (h *DuplicateHandler) MergeDuplicateHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid duplicate ID", http.StatusBadRequest)
+		return
+	}
+	var keepID int
+	if keep := r.URL.Query().Get("keep"); keep != "" {
+		if keepID, err = strconv.Atoi(keep); err != nil {
+			http.Error(w, "Invalid keep parameter", http.StatusBadRequest)
+			return
+		}
+	}
+
+	kept, err := h.Service.Merge(id, currentUserID(r), keepID)
+	if err != nil {
+		writeServiceError(w, err, "Failed to merge duplicates")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(kept)
+}
+
+// DismissDuplicateHandler отклоняет пару дублей.
+// @Summary Отклонение дубля
+// @Description Отмечает, что транзакции пары не являются дублями; обе транзакции остаются.
+// @Tags Duplicates
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Duplicate pair ID"
+// @Success 204 {string} string "Dismissed"
+// @Failure 400 {string} string "Invalid duplicate ID"
+// @Failure 404 {string} string "Not found"
+// @Failure 500 {string} string "Failed to dismiss duplicate"
+// @Router /duplicates/{id}/dismiss [post]
+func (h *DuplicateHandler) DismissDuplicateHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid duplicate ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Service.Dismiss(id, currentUserID(r)); err != nil {
+		writeServiceError(w, err, "Failed to dismiss duplicate")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"finance_project/internal/models"
+	"finance_project/internal/services"
+)
+
+// EnvelopeHandler представляет обработчики конвертного бюджета.
+type EnvelopeHandler struct {
+	Service *services.EnvelopeService
+}
+
+// NewEnvelopeHandler создает новый обработчик конвертов.
+func NewEnvelopeHandler(service *services.EnvelopeService) *EnvelopeHandler {
+	return &EnvelopeHandler{Service: service}
+}
+
+// CreateEnvelopeHandler создает конверт.
+// @Summary Создание конверта
+// @Description Создает конверт и привязывает к нему категории расходов (categories); категория может входить только в один конверт
+// @Tags Envelopes
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param envelope body models.Envelope true "Envelope body"
+// @Success 201 {object} models.Envelope
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not found"
+// @Failure 500 {string} string "Failed to create envelope"
+// @Router /envelopes/create [post]
+func (h *EnvelopeHandler) CreateEnvelopeHandler(w http.ResponseWriter, r *http.Request) {
+	var envelope models.Envelope
+	if err := json.NewDecoder(r.Body).Decode(&envelope); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	envelope.UserID = currentUserID(r)
+
+	id, err := h.Service.CreateEnvelope(&envelope)
+	if err != nil {
+		writeServiceError(w, err, "Failed to create envelope")
+		return
+	}
+
+	created, err := h.Service.GetEnvelopeByID(id, envelope.UserID)
+	if err != nil {
+		writeServiceError(w, err, "Failed to create envelope")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+// GetEnvelopesHandler возвращает конверты пользователя.
+// @Summary Список конвертов
+// @Description Возвращает конверты текущего пользователя с их категориями
+// @Tags Envelopes
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.Envelope
+// @Failure 500 {string} string "Failed to retrieve envelopes"
+// @Router /envelopes [get]
+func (h *EnvelopeHandler) GetEnvelopesHandler(w http.ResponseWriter, r *http.Request) {
+	envelopes, err := h.Service.GetEnvelopes(currentUserID(r))
+	if err != nil {
+		http.Error(w, "Failed to retrieve envelopes", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(envelopes)
+}
+
+// UpdateEnvelopeHandler изменяет конверт.
+// @Summary Обновление конверта
+// @Description Переименовывает конверт и заменяет список его категорий
+// @Tags Envelopes
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param envelope body models.Envelope true "Envelope body"
+// @Success 200 {string} string "Envelope updated successfully"
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not found"
+// @Failure 500 {string} string "Failed to update envelope"
+// @Router /envelopes/update [put]
+func (h *EnvelopeHandler) UpdateEnvelopeHandler(w http.ResponseWriter, r *http.Request) {
+	var envelope models.Envelope
+	if err := json.NewDecoder(r.Body).Decode(&envelope); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	envelope.UserID = currentUserID(r)
+
+	if err := h.Service.UpdateEnvelope(&envelope); err != nil {
+		writeServiceError(w, err, "Failed to update envelope")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Envelope updated successfully"))
+}
+
+// DeleteEnvelopeHandler удаляет конверт.
+// @Summary Удаление конверта
+// @Description Удаляет конверт; распределённые в него суммы возвращаются в ready_to_assign
+// @Tags Envelopes
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id query int true "Envelope ID"
+// @Success 204 {string} string "Deleted"
+// @Failure 400 {string} string "Invalid envelope ID"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not found"
+// @Failure 500 {string} string "Failed to delete envelope"
+// @Router /envelopes/delete [delete]
+func (h *EnvelopeHandler) DeleteEnvelopeHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		http.Error(w, "Invalid envelope ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Service.DeleteEnvelope(id, currentUserID(r)); err != nil {
+		writeServiceError(w, err, "Failed to delete envelope")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetMonthHandler возвращает состояние конвертов за месяц.
+// @Summary Конвертный бюджет за месяц
+// @Description Возвращает распределённые суммы, расходы и остатки конвертов, сумму ready_to_assign и список конвертов с перерасходом
+// @Tags Envelopes
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param month query string false "Month (YYYY-MM), current month by default"
+// @Success 200 {object} models.EnvelopeMonth
+// @Failure 400 {string} string "Invalid month"
+// @Failure 422 {string} string "Exchange rate not found"
+// @Failure 500 {string} string "Failed to calculate envelopes"
+// @Router /envelopes/month [get]
+func (h *EnvelopeHandler) GetMonthHandler(w http.ResponseWriter, r *http.Request) {
+	month := time.Now()
+	if raw := r.URL.Query().Get("month"); raw != "" {
+		var err error
+		if month, err = time.Parse("2006-01", raw); err != nil {
+			http.Error(w, "Invalid month", http.StatusBadRequest)
+			return
+		}
+	}
+
+	summary, err := h.Service.GetMonth(currentUserID(r), month)
+	if err != nil {
+		writeServiceError(w, err, "Failed to calculate envelopes")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summary)
+}
+
+// AssignHandler распределяет деньги в конверт.
+// @Summary Распределение в конверт
+// @Description Переносит сумму из ready_to_assign в конверт за месяц; отрицательная сумма возвращает деньги обратно, но не больше остатка конверта
+// @Tags Envelopes
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param assignment body models.EnvelopeAssignment true "Assignment body"
+// @Success 204 {string} string "Assigned"
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not found"
+// @Failure 500 {string} string "Failed to assign money"
+// @Router /envelopes/assign [post]
+func (h *EnvelopeHandler) AssignHandler(w http.ResponseWriter, r *http.Request) {
+	var assignment models.EnvelopeAssignment
+	if err := json.NewDecoder(r.Body).Decode(&assignment); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Service.Assign(currentUserID(r), assignment); err != nil {
+		writeServiceError(w, err, "Failed to assign money")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// MoveHandler перемещает деньги между конвертами.
+// @Summary Перемещение между конвертами
+// @Description Перемещает сумму между конвертами за месяц, например чтобы покрыть перерасход; в исходном конверте должно быть достаточно денег
+// @Tags Envelopes
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param move body models.EnvelopeMove true "Move body"
+// @Success 204 {string} string "Moved"
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not found"
+// @Failure 422 {string} string "Exchange rate not found"
+// @Failure 500 {string} string "Failed to move money"
+// @Router /envelopes/move [post]
+func (h *EnvelopeHandler) MoveHandler(w http.ResponseWriter, r *http.Request) {
+	var move models.EnvelopeMove
+	if err := json.NewDecoder(r.Body).Decode(&move); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Service.Move(currentUserID(r), move); err != nil {
+		writeServiceError(w, err, "Failed to move money")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -26,15 +26,16 @@ func NewFinancialGoalsHandler(service *services.FinancialGoalsService) *Financia
 // @Tags Financial Goals
 // @Accept json
 // @Produce json
-// @Param user_id query int true "User ID"
+// @Security BearerAuth
+// @Param user_id query int false "User ID (defaults to the current user)"
 // @Success 200 {array} models.FinancialGoal "Список финансовых целей"
 // @Failure 400 {string} string "Invalid user_id"
+// @Failure 403 {string} string "Forbidden"
 // @Failure 500 {string} string "Failed to retrieve financial goals"
 // @Router /financial-goals [get]
 func (h *FinancialGoalsHandler) GetFinancialGoalsHandler(w http.ResponseWriter, r *http.Request) {
-	userID, err := strconv.Atoi(r.URL.Query().Get("user_id"))
-	if err != nil {
-		http.Error(w, "Invalid user_id", http.StatusBadRequest)
+	userID, ok := resolveUserID(w, r, r.URL.Query().Get("user_id"))
+	if !ok {
 		return
 	}
 
@@ -55,6 +56,7 @@ func (h *FinancialGoalsHandler) GetFinancialGoalsHandler(w http.ResponseWriter,
 // @Tags Financial Goals
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param goal body models.FinancialGoal true "Financial Goal body"
 // @Success 201 {string} string "Created"
 // @Failure 400 {string} string "Invalid request body"
@@ -66,6 +68,7 @@ func (h *FinancialGoalsHandler) CreateFinancialGoalHandler(w http.ResponseWriter
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
+	goal.UserID = currentUserID(r)
 
 	if err := h.Service.CreateFinancialGoal(goal); err != nil {
 		http.Error(w, "Failed to create financial goal", http.StatusInternalServerError)
@@ -81,9 +84,12 @@ func (h *FinancialGoalsHandler) CreateFinancialGoalHandler(w http.ResponseWriter
 // @Tags Financial Goals
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param goal body models.FinancialGoal true "Financial Goal body"
 // @Success 200 {string} string "Updated"
 // @Failure 400 {string} string "Invalid request body"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not found"
 // @Failure 500 {string} string "Failed to update financial goal"
 // @Router /financial-goals/update [put]
 func (h *FinancialGoalsHandler) UpdateFinancialGoalHandler(w http.ResponseWriter, r *http.Request) {
@@ -92,9 +98,10 @@ func (h *FinancialGoalsHandler) UpdateFinancialGoalHandler(w http.ResponseWriter
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
+	goal.UserID = currentUserID(r)
 
 	if err := h.Service.UpdateFinancialGoal(goal); err != nil {
-		http.Error(w, "Failed to update financial goal", http.StatusInternalServerError)
+		writeServiceError(w, err, "Failed to update financial goal")
 		return
 	}
 
@@ -107,9 +114,12 @@ func (h *FinancialGoalsHandler) UpdateFinancialGoalHandler(w http.ResponseWriter
 // @Tags Financial Goals
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param id query int true "Financial Goal ID"
 // @Success 200 {string} string "Deleted"
 // @Failure 400 {string} string "Invalid financial goal ID"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not found"
 // @Failure 500 {string} string "Failed to delete financial goal"
 // @Router /financial-goals/delete [delete]
 func (h *FinancialGoalsHandler) DeleteFinancialGoalHandler(w http.ResponseWriter, r *http.Request) {
@@ -119,8 +129,8 @@ func (h *FinancialGoalsHandler) DeleteFinancialGoalHandler(w http.ResponseWriter
 		return
 	}
 
-	if err := h.Service.DeleteFinancialGoal(id); err != nil {
-		http.Error(w, "Failed to delete financial goal", http.StatusInternalServerError)
+	if err := h.Service.DeleteFinancialGoal(id, currentUserID(r)); err != nil {
+		writeServiceError(w, err, "Failed to delete financial goal")
 		return
 	}
 
@@ -131,16 +141,17 @@ func (h *FinancialGoalsHandler) DeleteFinancialGoalHandler(w http.ResponseWriter
 // @Summary Получить прогресс выполнения финансовых целей
 // @Description Возвращает процент выполнения для каждой финансовой цели пользователя
 // @Tags Financial Goals
+// @Security BearerAuth
 // @Param id path int true "User ID"
 // @Success 200 {array} models.GoalProgress
 // @Failure 400 {string} string "Invalid user ID"
+// @Failure 403 {string} string "Forbidden"
 // @Failure 500 {string} string "Failed to retrieve goal progress"
 // @Router /users/{id}/goals/progress [get]
 func (h *FinancialGoalsHandler) GetGoalProgressHandler(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
-	userID, err := strconv.Atoi(params["id"])
-	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+	userID, ok := resolveUserID(w, r, params["id"])
+	if !ok {
 		return
 	}
 
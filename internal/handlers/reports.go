@@ -20,27 +20,30 @@ func NewReportsHandler(service *services.ReportsService) *ReportsHandler {
 
 // GetSummaryHandler возвращает или создает сводный отчет.
 // @Summary Сводный отчет
-// @Description Возвращает общий отчет или создает новый и сохраняет его в таблицу reports
+// @Description Возвращает общий отчет или создает новый и сохраняет его в таблицу reports.
+// @Description Суммы пересчитаны в предпочитаемую валюту пользователя (поле currency).
 // @Tags Reports
 // @Accept json
 // @Produce json
-// @Param user_id query int true "User ID"
+// @Security BearerAuth
+// @Param user_id query int false "User ID (defaults to the current user)"
 // @Success 200 {object} map[string]interface{}
 // @Failure 400 {string} string "Invalid user ID"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 422 {string} string "Exchange rate not found"
 // @Failure 500 {string} string "Failed to retrieve or generate summary report"
 // @Router /reports/summary [get]
 func (h *ReportsHandler) GetSummaryHandler(w http.ResponseWriter, r *http.Request) {
 	// Получаем user_id из параметров запроса
-	userID, err := strconv.Atoi(r.URL.Query().Get("user_id"))
-	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+	userID, ok := resolveUserID(w, r, r.URL.Query().Get("user_id"))
+	if !ok {
 		return
 	}
 
 	// Получаем или создаем сводный отчет
 	report, err := h.Service.GetOrCreateSummaryReport(userID)
 	if err != nil {
-		http.Error(w, "Failed to retrieve or generate summary report", http.StatusInternalServerError)
+		writeServiceError(w, err, "Failed to retrieve or generate summary report")
 		return
 	}
 
@@ -50,22 +53,26 @@ func (h *ReportsHandler) GetSummaryHandler(w http.ResponseWriter, r *http.Reques
 
 // GetExpensesByCategoryHandler возвращает расходы, сгруппированные по категориям за период.
 // @Summary Расходы по категориям
-// @Description Возвращает расходы, сгруппированные по категориям за указанный период
+// @Description Возвращает расходы, сгруппированные по категориям за указанный период, в предпочитаемой валюте пользователя. Разбитые транзакции учитываются по категориям своих частей. По умолчанию — список категорий с суммами без подкатегорий, где path — полный путь категории; с view=tree — дерево категорий, где total включает расходы подкатегорий, а depth ограничивает глубину дерева
 // @Tags Reports
 // @Accept json
 // @Produce json
-// @Param user_id query int true "User ID"
+// @Security BearerAuth
+// @Param user_id query int false "User ID (defaults to the current user)"
 // @Param start_date query string true "Start Date (YYYY-MM-DD)"
 // @Param end_date query string true "End Date (YYYY-MM-DD)"
-// @Success 200 {array} map[string]float64
+// @Param view query string false "flat (по умолчанию) или tree"
+// @Param depth query int false "Глубина дерева для view=tree (0 — без ограничения)"
+// @Success 200 {array} models.CategoryTotal
 // @Failure 400 {string} string "Invalid parameters"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 422 {string} string "Exchange rate not found"
 // @Failure 500 {string} string "Failed to retrieve expenses"
 // @Router /reports/by-category [get]
 func (h *ReportsHandler) GetExpensesByCategoryHandler(w http.ResponseWriter, r *http.Request) {
 	// Получаем параметры из запроса
-	userID, err := strconv.Atoi(r.URL.Query().Get("user_id"))
-	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+	userID, ok := resolveUserID(w, r, r.URL.Query().Get("user_id"))
+	if !ok {
 		return
 	}
 
@@ -77,7 +84,7 @@ func (h *ReportsHandler) GetExpensesByCategoryHandler(w http.ResponseWriter, r *
 	}
 
 	// Проверка формата дат
-	_, err = time.Parse("2006-01-02", startDate)
+	_, err := time.Parse("2006-01-02", startDate)
 	if err != nil {
 		http.Error(w, "Invalid start_date format", http.StatusBadRequest)
 		return
@@ -88,13 +95,171 @@ func (h *ReportsHandler) GetExpensesByCategoryHandler(w http.ResponseWriter, r *
 		return
 	}
 
+	switch r.URL.Query().Get("view") {
+	case "", "flat":
+	case "tree":
+		depth := 0
+		if raw := r.URL.Query().Get("depth"); raw != "" {
+			depth, err = strconv.Atoi(raw)
+			if err != nil || depth < 0 {
+				http.Error(w, "Invalid depth", http.StatusBadRequest)
+				return
+			}
+		}
+		tree, err := h.Service.GetExpensesByCategoryTree(userID, startDate, endDate, depth)
+		if err != nil {
+			writeServiceError(w, err, "Failed to retrieve expenses")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(tree)
+		return
+	default:
+		http.Error(w, "Invalid view", http.StatusBadRequest)
+		return
+	}
+
 	// Получаем данные из сервиса
 	expenses, err := h.Service.GetExpensesByCategory(userID, startDate, endDate)
 	if err != nil {
-		http.Error(w, "Failed to retrieve expenses", http.StatusInternalServerError)
+		writeServiceError(w, err, "Failed to retrieve expenses")
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(expenses)
 }
+
+// GetTotalsByTagHandler возвращает доходы и расходы по меткам за период.
+// @Summary Итоги по меткам
+// @Description Возвращает доходы, расходы и число транзакций по каждой метке за период в предпочитаемой валюте пользователя, с разбивкой по категориям и счетам. Транзакция с несколькими метками входит в итоги каждой из них; переводы и долги не учитываются
+// @Tags Reports
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param user_id query int false "User ID (defaults to the current user)"
+// @Param start_date query string true "Start Date (YYYY-MM-DD)"
+// @Param end_date query string true "End Date (YYYY-MM-DD)"
+// @Success 200 {object} models.TagReport
+// @Failure 400 {string} string "Invalid parameters"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 422 {string} string "Exchange rate not found"
+// @Failure 500 {string} string "Failed to retrieve totals by tag"
+// @Router /reports/by-tag [get]
+func (h *ReportsHandler) GetTotalsByTagHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := resolveUserID(w, r, r.URL.Query().Get("user_id"))
+	if !ok {
+		return
+	}
+
+	startDate := r.URL.Query().Get("start_date")
+	endDate := r.URL.Query().Get("end_date")
+	if _, err := time.Parse("2006-01-02", startDate); err != nil {
+		http.Error(w, "Invalid start_date format", http.StatusBadRequest)
+		return
+	}
+	if _, err := time.Parse("2006-01-02", endDate); err != nil {
+		http.Error(w, "Invalid end_date format", http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.Service.GetTotalsByTag(userID, startDate, endDate)
+	if err != nil {
+		writeServiceError(w, err, "Failed to retrieve totals by tag")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// GetNetWorthHandler возвращает собственный капитал во времени.
+// @Summary Собственный капитал
+// @Description Возвращает временной ряд собственного капитала по ежедневным снимкам в предпочитаемой валюте пользователя: балансы счетов (assets), долги, которые должны нам (receivables) и мы (liabilities), и net_worth = assets + receivables - liabilities. Для week и month берётся последний снимок внутри периода
+// @Tags Reports
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param user_id query int false "User ID (defaults to the current user)"
+// @Param granularity query string false "day (по умолчанию), week или month"
+// @Param from query string false "Start Date (YYYY-MM-DD)"
+// @Param to query string false "End Date (YYYY-MM-DD), по умолчанию сегодня"
+// @Success 200 {object} models.NetWorthReport
+// @Failure 400 {string} string "Invalid parameters"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 422 {string} string "Exchange rate not found"
+// @Failure 500 {string} string "Failed to retrieve net worth"
+// @Router /reports/net-worth [get]
+func (h *ReportsHandler) GetNetWorthHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := resolveUserID(w, r, r.URL.Query().Get("user_id"))
+	if !ok {
+		return
+	}
+	from, to, ok := parseDateRange(w, r)
+	if !ok {
+		return
+	}
+
+	report, err := h.Service.GetNetWorth(userID, r.URL.Query().Get("granularity"), from, to)
+	if err != nil {
+		writeServiceError(w, err, "Failed to retrieve net worth")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// BackfillNetWorthHandler восстанавливает историю собственного капитала по журналу транзакций.
+// @Summary Восстановление истории капитала
+// @Description Пересчитывает ежедневные снимки собственного капитала с from по to по журналу транзакций и долгам, заменяя записанные. Без from — с первого счёта, транзакции или долга, без to — по сегодняшний день; за раз не больше 3660 дней
+// @Tags Reports
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param user_id query int false "User ID (defaults to the current user)"
+// @Param from query string false "Start Date (YYYY-MM-DD)"
+// @Param to query string false "End Date (YYYY-MM-DD)"
+// @Success 200 {object} models.NetWorthBackfill
+// @Failure 400 {string} string "Invalid parameters"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 422 {string} string "Exchange rate not found"
+// @Failure 500 {string} string "Failed to backfill net worth"
+// @Router /reports/net-worth/backfill [post]
+func (h *ReportsHandler) BackfillNetWorthHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := resolveUserID(w, r, r.URL.Query().Get("user_id"))
+	if !ok {
+		return
+	}
+	from, to, ok := parseDateRange(w, r)
+	if !ok {
+		return
+	}
+
+	result, err := h.Service.BackfillNetWorth(userID, from, to)
+	if err != nil {
+		writeServiceError(w, err, "Failed to backfill net worth")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// parseDateRange разбирает необязательные параметры from и to в формате YYYY-MM-DD.
+func parseDateRange(w http.ResponseWriter, r *http.Request) (from, to time.Time, ok bool) {
+	var err error
+	if raw := r.URL.Query().Get("from"); raw != "" {
+		if from, err = time.Parse("2006-01-02", raw); err != nil {
+			http.Error(w, "Invalid from format", http.StatusBadRequest)
+			return from, to, false
+		}
+	}
+	if raw := r.URL.Query().Get("to"); raw != "" {
+		if to, err = time.Parse("2006-01-02", raw); err != nil {
+			http.Error(w, "Invalid to format", http.StatusBadRequest)
+			return from, to, false
+		}
+	}
+	return from, to, true
+}
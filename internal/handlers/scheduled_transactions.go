@@ -0,0 +1,360 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"finance_project/internal/models"
+	"finance_project/internal/services"
+
+	"github.com/gorilla/mux"
+)
+
+// ScheduledTransactionHandler представляет обработчики регулярных транзакций.
+type ScheduledTransactionHandler struct {
+	Service *services.ScheduledTransactionService
+}
+
+// NewScheduledTransactionHandler создает новый обработчик регулярных транзакций.
+func NewScheduledTransactionHandler(service *services.ScheduledTransactionService) *ScheduledTransactionHandler {
+	return &ScheduledTransactionHandler{Service: service}
+}
+
+// CreateScheduleHandler создает расписание регулярной транзакции.
+// @Summary Создание регулярной транзакции
+// @Description Создает расписание. Поле schedule принимает daily, weekly, monthly или правило RRULE, например FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1.
+// @Tags ScheduledTransactions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param schedule body models.ScheduledTransaction true "Schedule"
+// @Success 201 {object} models.ScheduledTransaction
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not found"
+// @Failure 500 {string} string "Failed to create scheduled transaction"
+// @Router /scheduled-transactions/create [post]
+func (h *ScheduledTransactionHandler) CreateScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	var schedule models.ScheduledTransaction
+	if err := json.NewDecoder(r.Body).Decode(&schedule); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	schedule.UserID = currentUserID(r)
+
+	id, err := h.Service.CreateSchedule(&schedule)
+	if err != nil {
+		writeServiceError(w, err, "Failed to create scheduled transaction")
+		return
+	}
+
+	created, err := h.Service.GetScheduleByID(id, schedule.UserID)
+	if err != nil {
+		writeServiceError(w, err, "Failed to create scheduled transaction")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+// GetSchedulesHandler возвращает расписания пользователя.
+// @Summary Список регулярных транзакций
+// @Description Возвращает все расписания текущего пользователя
+// @Tags ScheduledTransactions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.ScheduledTransaction
+// @Failure 500 {string} string "Failed to retrieve scheduled transactions"
+// @Router /scheduled-transactions [get]
+func (h *ScheduledTransactionHandler) GetSchedulesHandler(w http.ResponseWriter, r *http.Request) {
+	schedules, err := h.Service.GetSchedules(currentUserID(r))
+	if err != nil {
+		http.Error(w, "Failed to retrieve scheduled transactions", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(schedules)
+}
+
+// GetScheduleByIDHandler возвращает расписание по ID.
+// @Summary Получение регулярной транзакции
+// @Description Возвращает расписание и дату следующего выполнения
+// @Tags ScheduledTransactions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Schedule ID"
+// @Success 200 {object} models.ScheduledTransaction
+// @Failure 400 {string} string "Invalid schedule ID"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not found"
+// @Router /scheduled-transactions/{id} [get]
+func (h *ScheduledTransactionHandler) GetScheduleByIDHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid schedule ID", http.StatusBadRequest)
+		return
+	}
+
+	schedule, err := h.Service.GetScheduleByID(id, currentUserID(r))
+	if err != nil {
+		writeServiceError(w, err, "Failed to retrieve scheduled transaction")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(schedule)
+}
+
+// UpdateScheduleHandler изменяет расписание.
+// @Summary Обновление регулярной транзакции
+// @Description Изменяет расписание; новое правило действует с сегодняшнего дня
+// @Tags ScheduledTransactions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param schedule body models.ScheduledTransaction true "Schedule"
+// @Success 200 {string} string "Scheduled transaction updated successfully"
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not found"
+// @Failure 500 {string} string "Failed to update scheduled transaction"
+// @Router /scheduled-transactions/update [put]
+func (h *ScheduledTransactionHandler) UpdateScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	var schedule models.ScheduledTransaction
+	if err := json.NewDecoder(r.Body).Decode(&schedule); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	schedule.UserID = currentUserID(r)
+
+	if err := h.Service.UpdateSchedule(&schedule); err != nil {
+		writeServiceError(w, err, "Failed to update scheduled transaction")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Scheduled transaction updated successfully"))
+}
+
+// DeleteScheduleHandler удаляет расписание.
+// @Summary Удаление регулярной транзакции
+// @Description Удаляет расписание; уже созданные транзакции остаются
+// @Tags ScheduledTransactions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id query int true "Schedule ID"
+// @Success 204 {string} string "Deleted"
+// @Failure 400 {string} string "Invalid schedule ID"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not found"
+// @Failure 500 {string} string "Failed to delete scheduled transaction"
+// @Router /scheduled-transactions/delete [delete]
+func (h *ScheduledTransactionHandler) DeleteScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		http.Error(w, "Invalid schedule ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Service.DeleteSchedule(id, currentUserID(r)); err != nil {
+		writeServiceError(w, err, "Failed to delete scheduled transaction")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PauseScheduleHandler приостанавливает расписание.
+// @Summary Пауза регулярной транзакции
+// @Description Приостанавливает расписание целиком
+// @Tags ScheduledTransactions
+// @Security BearerAuth
+// @Param id path int true "Schedule ID"
+// @Success 204 {string} string "Paused"
+// @Failure 400 {string} string "Invalid schedule ID"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not found"
+// @Router /scheduled-transactions/{id}/pause [post]
+func (h *ScheduledTransactionHandler) PauseScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	h.setPaused(w, r, true)
+}
+
+// ResumeScheduleHandler возобновляет расписание.
+// @Summary Возобновление регулярной транзакции
+// @Description Возобновляет расписание; выполнения, пропущенные за время паузы, не создаются
+// @Tags ScheduledTransactions
+// @Security BearerAuth
+// @Param id path int true "Schedule ID"
+// @Success 204 {string} string "Resumed"
+// @Failure 400 {string} string "Invalid schedule ID"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not found"
+// @Router /scheduled-transactions/{id}/resume [post]
+func (h *ScheduledTransactionHandler) ResumeScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	h.setPaused(w, r, false)
+}
+
+func (h *ScheduledTransactionHandler) setPaused(w http.ResponseWriter, r *http.Request, paused bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid schedule ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Service.SetPaused(id, currentUserID(r), paused); err != nil {
+		writeServiceError(w, err, "Failed to update scheduled transaction")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetOccurrencesHandler возвращает выполнения расписания за период.
+// @Summary Выполнения регулярной транзакции
+// @Description Возвращает созданные, пропущенные, приостановленные и предстоящие выполнения (по умолчанию на 90 дней вперёд)
+// @Tags ScheduledTransactions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Schedule ID"
+// @Param from query string false "From date (YYYY-MM-DD)"
+// @Param to query string false "To date (YYYY-MM-DD)"
+// @Success 200 {array} models.ScheduledOccurrence
+// @Failure 400 {string} string "Invalid parameters"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not found"
+// @Router /scheduled-transactions/{id}/occurrences [get]
+func (h *ScheduledTransactionHandler) GetOccurrencesHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid schedule ID", http.StatusBadRequest)
+		return
+	}
+
+	from, to := time.Now(), time.Now().AddDate(0, 0, 90)
+	if raw := r.URL.Query().Get("from"); raw != "" {
+		if from, err = time.Parse("2006-01-02", raw); err != nil {
+			http.Error(w, "Invalid from date", http.StatusBadRequest)
+			return
+		}
+	}
+	if raw := r.URL.Query().Get("to"); raw != "" {
+		if to, err = time.Parse("2006-01-02", raw); err != nil {
+			http.Error(w, "Invalid to date", http.StatusBadRequest)
+			return
+		}
+	}
+
+	occurrences, err := h.Service.GetOccurrences(id, currentUserID(r), from, to)
+	if err != nil {
+		writeServiceError(w, err, "Failed to retrieve occurrences")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(occurrences)
+}
+
+// SkipOccurrenceHandler пропускает одно выполнение расписания.
+// @Summary Пропуск выполнения
+// @Description Транзакция за указанную дату не будет создана
+// @Tags ScheduledTransactions
+// @Security BearerAuth
+// @Param id path int true "Schedule ID"
+// @Param date query string true "Occurrence date (YYYY-MM-DD)"
+// @Success 204 {string} string "Skipped"
+// @Failure 400 {string} string "Invalid parameters"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not found"
+// @Router /scheduled-transactions/{id}/skip [post]
+func (h *ScheduledTransactionHandler) SkipOccurrenceHandler(w http.ResponseWriter, r *http.Request) {
+	id, date, ok := occurrenceParams(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.Service.SkipOccurrence(id, currentUserID(r), date); err != nil {
+		writeServiceError(w, err, "Failed to skip occurrence")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HoldOccurrenceHandler приостанавливает одно выполнение расписания.
+// @Summary Пауза выполнения
+// @Description Транзакция за указанную дату не создаётся, пока выполнение не будет возобновлено
+// @Tags ScheduledTransactions
+// @Security BearerAuth
+// @Param id path int true "Schedule ID"
+// @Param date query string true "Occurrence date (YYYY-MM-DD)"
+// @Success 204 {string} string "Held"
+// @Failure 400 {string} string "Invalid parameters"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not found"
+// @Router /scheduled-transactions/{id}/hold [post]
+func (h *ScheduledTransactionHandler) HoldOccurrenceHandler(w http.ResponseWriter, r *http.Request) {
+	id, date, ok := occurrenceParams(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.Service.HoldOccurrence(id, currentUserID(r), date); err != nil {
+		writeServiceError(w, err, "Failed to hold occurrence")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ReleaseOccurrenceHandler снимает пропуск или паузу с выполнения.
+// @Summary Возобновление выполнения
+// @Description Снимает пропуск или паузу; если дата уже прошла, транзакция создаётся сразу
+// @Tags ScheduledTransactions
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Schedule ID"
+// @Param date query string true "Occurrence date (YYYY-MM-DD)"
+// @Success 200 {object} models.ScheduledOccurrence
+// @Failure 400 {string} string "Invalid parameters"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not found"
+// @Router /scheduled-transactions/{id}/release [post]
+func (h *ScheduledTransactionHandler) ReleaseOccurrenceHandler(w http.ResponseWriter, r *http.Request) {
+	id, date, ok := occurrenceParams(w, r)
+	if !ok {
+		return
+	}
+
+	occurrence, err := h.Service.ReleaseOccurrence(id, currentUserID(r), date)
+	if err != nil {
+		writeServiceError(w, err, "Failed to release occurrence")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(occurrence)
+}
+
+// occurrenceParams читает ID расписания из пути и дату выполнения из параметра date.
+func occurrenceParams(w http.ResponseWriter, r *http.Request) (int, time.Time, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid schedule ID", http.StatusBadRequest)
+		return 0, time.Time{}, false
+	}
+	date, err := time.Parse("2006-01-02", r.URL.Query().Get("date"))
+	if err != nil {
+		http.Error(w, "Invalid date format", http.StatusBadRequest)
+		return 0, time.Time{}, false
+	}
+	return id, date, true
+}
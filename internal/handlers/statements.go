@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+
+	"finance_project/internal/models"
+	"finance_project/internal/services"
+)
+
+// maxStatementSize ограничивает размер загружаемой выписки.
+const maxStatementSize = 10 << 20
+
+// StatementHandler представляет обработчики импорта банковских выписок.
+type StatementHandler struct {
+	Service *services.StatementService
+}
+
+// NewStatementHandler создает новый обработчик выписок.
+func NewStatementHandler(service *services.StatementService) *StatementHandler {
+	return &StatementHandler{Service: service}
+}
+
+// CreateProfileHandler создает профиль CSV-выписки.
+// @Summary Создание профиля выписки
+// @Description Сохраняет описание CSV-выписки банка: разделитель, колонки (по названию или номеру с 1), формат даты, знак суммы или колонки дебета/кредита, валюту
+// @Tags Statements
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param profile body models.StatementProfile true "Profile body"
+// @Success 201 {object} models.StatementProfile
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 500 {string} string "Failed to create statement profile"
+// @Router /statement-profiles/create [post]
+func (h *StatementHandler) CreateProfileHandler(w http.ResponseWriter, r *http.Request) {
+	var profile models.StatementProfile
+	if err := json.NewDecoder(r.Body).Decode(&profile); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	profile.UserID = currentUserID(r)
+
+	id, err := h.Service.CreateProfile(&profile)
+	if err != nil {
+		writeServiceError(w, err, "Failed to create statement profile")
+		return
+	}
+
+	created, err := h.Service.GetProfileByID(id, profile.UserID)
+	if err != nil {
+		writeServiceError(w, err, "Failed to create statement profile")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+// GetProfilesHandler возвращает профили выписок пользователя.
+// @Summary Список профилей выписок
+// @Description Возвращает сохранённые профили CSV-выписок текущего пользователя
+// @Tags Statements
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.StatementProfile
+// @Failure 500 {string} string "Failed to retrieve statement profiles"
+// @Router /statement-profiles [get]
+func (h *StatementHandler) GetProfilesHandler(w http.ResponseWriter, r *http.Request) {
+	profiles, err := h.Service.GetProfiles(currentUserID(r))
+	if err != nil {
+		http.Error(w, "Failed to retrieve statement profiles", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(profiles)
+}
+
+// UpdateProfileHandler изменяет профиль выписки.
+// @Summary Обновление профиля выписки
+// @Description Изменяет профиль CSV-выписки
+// @Tags Statements
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param profile body models.StatementProfile true "Profile body"
+// @Success 200 {string} string "Statement profile updated successfully"
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not found"
+// @Failure 500 {string} string "Failed to update statement profile"
+// @Router /statement-profiles/update [put]
+func (h *StatementHandler) UpdateProfileHandler(w http.ResponseWriter, r *http.Request) {
+	var profile models.StatementProfile
+	if err := json.NewDecoder(r.Body).Decode(&profile); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	profile.UserID = currentUserID(r)
+
+	if err := h.Service.UpdateProfile(&profile); err != nil {
+		writeServiceError(w, err, "Failed to update statement profile")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Statement profile updated successfully"))
+}
+
+// DeleteProfileHandler удаляет профиль выписки.
+// @Summary Удаление профиля выписки
+// @Description Удаляет профиль CSV-выписки; импортированные транзакции остаются
+// @Tags Statements
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id query int true "Profile ID"
+// @Success 204 {string} string "Deleted"
+// @Failure 400 {string} string "Invalid profile ID"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not found"
+// @Failure 500 {string} string "Failed to delete statement profile"
+// @Router /statement-profiles/delete [delete]
+func (h *StatementHandler) DeleteProfileHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		http.Error(w, "Invalid profile ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Service.DeleteProfile(id, currentUserID(r)); err != nil {
+		writeServiceError(w, err, "Failed to delete statement profile")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ImportHandler импортирует банковскую выписку.
+// @Summary Импорт выписки
+// @Description Разбирает выписку CSV (по профилю), OFX/QFX, QIF, camt.053 или MT940 и создаёт доходы и расходы на счёте. Файл передаётся полем file формы multipart/form-data или телом запроса; формат определяется по расширению и содержимому, если не задан. Строки OFX (по FITID), QIF, camt.053 и MT940, уже импортированные на счёт, пропускаются, а остаток выписки сверяется с балансом счёта. Файлы camt.053 и MT940 могут содержать несколько выписок; берутся выписки в валюте счёта, а если в файле несколько счетов банка, нужный задаётся statement_account. Дата валютирования и реквизиты контрагента сохраняются в транзакции. С dry_run=true возвращает предпросмотр без создания транзакций. Строки с ошибками перечислены в отчёте и не импортируются.
+// @Tags Statements
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param account_id query int true "Account ID"
+// @Param format query string false "csv, ofx, qfx, qif, camt053 or mt940"
+// @Param statement_account query string false "Bank account (IBAN or number) to take from a camt.053/MT940 file"
+// @Param profile_id query int false "Statement profile ID (CSV)"
+// @Param date_format query string false "Date order for QIF, e.g. DD/MM/YYYY (default MM/DD/YYYY)"
+// @Param decimal_separator query string false "Decimal separator for QIF (. or ,)"
+// @Param category query int false "Category assigned to imported transactions"
+// @Param dry_run query bool false "Preview without importing"
+// @Param file formData file false "Statement file"
+// @Success 200 {object} models.StatementImportResult
+// @Failure 400 {string} string "Invalid parameters"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not found"
+// @Failure 500 {string} string "Failed to import statement"
+// @Router /statements/import [post]
+func (h *StatementHandler) ImportHandler(w http.ResponseWriter, r *http.Request) {
+	request, ok := importParams(w, r)
+	if !ok {
+		return
+	}
+	if raw := r.FormValue("profile_id"); raw != "" {
+		profileID, err := strconv.Atoi(raw)
+		if err != nil {
+			http.Error(w, "Invalid profile ID", http.StatusBadRequest)
+			return
+		}
+		request.ProfileID = profileID
+	}
+	request.Format = r.FormValue("format")
+	request.DateFormat = r.FormValue("date_format")
+	request.DecimalSeparator = r.FormValue("decimal_separator")
+	request.StatementAccount = r.FormValue("statement_account")
+
+	file, name, ok := statementFile(w, r)
+	if !ok {
+		return
+	}
+	defer file.Close()
+	request.FileName = name
+
+	result, err := h.Service.Import(currentUserID(r), request, file)
+	if err != nil {
+		writeServiceError(w, err, "Failed to import statement")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// importParams ограничивает размер запроса и читает общие параметры импорта:
+// счёт, категорию и режим предпросмотра.
+func importParams(w http.ResponseWriter, r *http.Request) (models.StatementImportRequest, bool) {
+	var request models.StatementImportRequest
+	r.Body = http.MaxBytesReader(w, r.Body, maxStatementSize)
+	accountID, err := strconv.Atoi(r.FormValue("account_id"))
+	if err != nil {
+		http.Error(w, "Invalid account ID", http.StatusBadRequest)
+		return request, false
+	}
+	request.AccountID = accountID
+	if raw := r.FormValue("category"); raw != "" {
+		if request.CategoryID, err = strconv.Atoi(raw); err != nil {
+			http.Error(w, "Invalid category ID", http.StatusBadRequest)
+			return request, false
+		}
+	}
+	if raw := r.FormValue("dry_run"); raw != "" {
+		if request.DryRun, err = strconv.ParseBool(raw); err != nil {
+			http.Error(w, "Invalid dry_run value", http.StatusBadRequest)
+			return request, false
+		}
+	}
+	return request, true
+}
+
+// statementFile возвращает загруженный файл выписки и его имя: поле file формы или тело запроса.
+func statementFile(w http.ResponseWriter, r *http.Request) (io.ReadCloser, string, bool) {
+	if r.MultipartForm != nil {
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, "Missing statement file", http.StatusBadRequest)
+			return nil, "", false
+		}
+		return file, header.Filename, true
+	}
+	return r.Body, "", true
+}
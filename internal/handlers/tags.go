@@ -0,0 +1,237 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"finance_project/internal/models"
+	"finance_project/internal/services"
+
+	"github.com/gorilla/mux"
+)
+
+// TagHandler представляет обработчики меток транзакций.
+type TagHandler struct {
+	Service *services.TagService
+}
+
+// NewTagHandler создает новый обработчик меток.
+func NewTagHandler(service *services.TagService) *TagHandler {
+	return &TagHandler{Service: service}
+}
+
+// GetTagsHandler возвращает метки пользователя.
+// @Summary Список меток
+// @Description Возвращает метки текущего пользователя по алфавиту с числом помеченных транзакций
+// @Tags Tags
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.Tag
+// @Failure 500 {string} string "Failed to retrieve tags"
+// @Router /tags [get]
+func (h *TagHandler) GetTagsHandler(w http.ResponseWriter, r *http.Request) {
+	tags, err := h.Service.GetTags(currentUserID(r))
+	if err != nil {
+		http.Error(w, "Failed to retrieve tags", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tags)
+}
+
+// CreateTagHandler создает метку.
+// @Summary Создание метки
+// @Description Создает метку. Имя уникально без учёта регистра; метки также создаются автоматически, когда транзакция сохраняется с новой меткой в tags
+// @Tags Tags
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param tag body models.Tag true "Tag body"
+// @Success 201 {object} models.Tag
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 500 {string} string "Failed to create tag"
+// @Router /tags/create [post]
+func (h *TagHandler) CreateTagHandler(w http.ResponseWriter, r *http.Request) {
+	var tag models.Tag
+	if err := json.NewDecoder(r.Body).Decode(&tag); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	tag.UserID = currentUserID(r)
+
+	id, err := h.Service.CreateTag(&tag)
+	if err != nil {
+		writeServiceError(w, err, "Failed to create tag")
+		return
+	}
+
+	created, err := h.Service.GetTagByID(id, tag.UserID)
+	if err != nil {
+		writeServiceError(w, err, "Failed to create tag")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+// GetTagByIDHandler возвращает метку по ID.
+// @Summary Получение метки
+// @Description Возвращает метку по ID с числом помеченных транзакций
+// @Tags Tags
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Tag ID"
+// @Success 200 {object} models.Tag
+// @Failure 400 {string} string "Invalid tag ID"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not found"
+// @Failure 500 {string} string "Failed to retrieve tag"
+// @Router /tags/{id} [get]
+func (h *TagHandler) GetTagByIDHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid tag ID", http.StatusBadRequest)
+		return
+	}
+
+	tag, err := h.Service.GetTagByID(id, currentUserID(r))
+	if err != nil {
+		writeServiceError(w, err, "Failed to retrieve tag")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tag)
+}
+
+// UpdateTagHandler переименовывает метку.
+// @Summary Переименование метки
+// @Description Переименовывает метку; все помеченные транзакции получают новое имя
+// @Tags Tags
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param tag body models.Tag true "Tag body"
+// @Success 200 {string} string "Tag updated successfully"
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not found"
+// @Failure 500 {string} string "Failed to update tag"
+// @Router /tags/update [put]
+func (h *TagHandler) UpdateTagHandler(w http.ResponseWriter, r *http.Request) {
+	var tag models.Tag
+	if err := json.NewDecoder(r.Body).Decode(&tag); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	tag.UserID = currentUserID(r)
+
+	if err := h.Service.UpdateTag(&tag); err != nil {
+		writeServiceError(w, err, "Failed to update tag")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Tag updated successfully"))
+}
+
+// DeleteTagHandler удаляет метку.
+// @Summary Удаление метки
+// @Description Удаляет метку и снимает её со всех транзакций; сами транзакции не меняются
+// @Tags Tags
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id query int true "Tag ID"
+// @Success 204 {string} string "Deleted"
+// @Failure 400 {string} string "Invalid tag ID"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not found"
+// @Failure 500 {string} string "Failed to delete tag"
+// @Router /tags/delete [delete]
+func (h *TagHandler) DeleteTagHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		http.Error(w, "Invalid tag ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Service.DeleteTag(id, currentUserID(r)); err != nil {
+		writeServiceError(w, err, "Failed to delete tag")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// TagTransactionsHandler помечает транзакции, подходящие под фильтр.
+// @Summary Массовая расстановка меток
+// @Description Добавляет метки tag всем транзакциям, подходящим под фильтр, или снимает их с remove=true. Условия фильтра (from, to, account_id, category_id, type, description — подстрока без учёта регистра, with_tag — уже имеющиеся метки) объединяются по И; нужно хотя бы одно. Недостающие метки создаются.
+// @Tags Tags
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param tag query []string true "Tag names" collectionFormat(multi)
+// @Param remove query bool false "Remove the tags instead of adding them"
+// @Param from query string false "From date (YYYY-MM-DD)"
+// @Param to query string false "To date (YYYY-MM-DD)"
+// @Param account_id query int false "Account ID"
+// @Param category_id query int false "Category ID, including split lines"
+// @Param type query string false "Transaction type"
+// @Param description query string false "Description substring"
+// @Param with_tag query []string false "Tags the transactions already carry" collectionFormat(multi)
+// @Success 200 {object} models.TransactionTagResult
+// @Failure 400 {string} string "Invalid parameters"
+// @Failure 500 {string} string "Failed to tag transactions"
+// @Router /tags/apply [post]
+func (h *TagHandler) TagTransactionsHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	request := models.TransactionTagRequest{
+		Tags:                query["tag"],
+		Type:                query.Get("type"),
+		DescriptionContains: query.Get("description"),
+		WithTags:            query["with_tag"],
+	}
+	var err error
+	if raw := query.Get("from"); raw != "" {
+		if request.From, err = time.Parse("2006-01-02", raw); err != nil {
+			http.Error(w, "Invalid from date", http.StatusBadRequest)
+			return
+		}
+	}
+	if raw := query.Get("to"); raw != "" {
+		if request.To, err = time.Parse("2006-01-02", raw); err != nil {
+			http.Error(w, "Invalid to date", http.StatusBadRequest)
+			return
+		}
+	}
+	if raw := query.Get("account_id"); raw != "" {
+		if request.AccountID, err = strconv.Atoi(raw); err != nil {
+			http.Error(w, "Invalid account_id", http.StatusBadRequest)
+			return
+		}
+	}
+	if raw := query.Get("category_id"); raw != "" {
+		if request.CategoryID, err = strconv.Atoi(raw); err != nil {
+			http.Error(w, "Invalid category_id", http.StatusBadRequest)
+			return
+		}
+	}
+	request.Remove, _ = strconv.ParseBool(query.Get("remove"))
+
+	result, err := h.Service.TagTransactions(currentUserID(r), request)
+	if err != nil {
+		writeServiceError(w, err, "Failed to tag transactions")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
@@ -6,8 +6,10 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	"finance_project/internal/models"
+	"finance_project/internal/money"
 	"finance_project/internal/services"
 
 	"github.com/gorilla/mux"
@@ -22,49 +24,118 @@ func NewTransactionHandler(service *services.TransactionService) *TransactionHan
 }
 
 // GetAllTransactionsHandler godoc
-// @Summary Retrieve all transactions
-// @Description Retrieves all transactions for a specific user
+// @Summary Search transactions
+// @Description Returns a page of a user's transactions matching the filters. Filters are combined with AND; repeated type, account_id, category_id and currency parameters match any of the values, repeated tag parameters require all of the tags, and category_id includes subcategories and split lines. q searches the description: words, "quoted phrases", OR and -exclusions. Pass next_cursor from the response as cursor, with the same filters and sort, to get the next page
 // @Tags Transactions
-// @Param userID query int true "User ID"
-// @Success 200 {array} models.Transaction
-// @Failure 400 {string} string "Invalid User ID"
+// @Security BearerAuth
+// @Param userID query int false "User ID (defaults to the current user)"
+// @Param from query string false "From date (YYYY-MM-DD)"
+// @Param to query string false "To date (YYYY-MM-DD)"
+// @Param min_amount query string false "Minimum amount"
+// @Param max_amount query string false "Maximum amount"
+// @Param type query []string false "Transaction types" collectionFormat(multi)
+// @Param account_id query []int false "Account IDs" collectionFormat(multi)
+// @Param category_id query []int false "Category IDs" collectionFormat(multi)
+// @Param currency query []string false "Currencies" collectionFormat(multi)
+// @Param q query string false "Description full-text search"
+// @Param tag query []string false "Tag names" collectionFormat(multi)
+// @Param sort query string false "date, amount or description, prefixed with - for descending (default -date)"
+// @Param limit query int false "Page size (default 50, at most 500)"
+// @Param cursor query string false "Cursor of the next page"
+// @Success 200 {object} models.TransactionPage
+// @Failure 400 {string} string "Invalid parameters"
+// @Failure 403 {string} string "Forbidden"
 // @Failure 500 {string} string "Failed to retrieve transactions"
 // @Router /transactions [get]
 func (h *TransactionHandler) GetAllTransactionsHandler(w http.ResponseWriter, r *http.Request) {
-	userID, err := strconv.Atoi(r.URL.Query().Get("userID"))
-	if err != nil {
-		http.Error(w, "Invalid User ID", http.StatusBadRequest)
+	query := r.URL.Query()
+	userID, ok := resolveUserID(w, r, query.Get("userID"))
+	if !ok {
+		return
+	}
+
+	search := models.TransactionQuery{
+		Types:      query["type"],
+		Currencies: query["currency"],
+		Search:     query.Get("q"),
+		Tags:       query["tag"],
+		Sort:       query.Get("sort"),
+		Cursor:     query.Get("cursor"),
+	}
+	var err error
+	if raw := query.Get("from"); raw != "" {
+		if search.From, err = time.Parse("2006-01-02", raw); err != nil {
+			http.Error(w, "Invalid from date", http.StatusBadRequest)
+			return
+		}
+	}
+	if raw := query.Get("to"); raw != "" {
+		if search.To, err = time.Parse("2006-01-02", raw); err != nil {
+			http.Error(w, "Invalid to date", http.StatusBadRequest)
+			return
+		}
+	}
+	if raw := query.Get("min_amount"); raw != "" {
+		amount, err := money.Parse(raw)
+		if err != nil {
+			http.Error(w, "Invalid min_amount", http.StatusBadRequest)
+			return
+		}
+		search.MinAmount = &amount
+	}
+	if raw := query.Get("max_amount"); raw != "" {
+		amount, err := money.Parse(raw)
+		if err != nil {
+			http.Error(w, "Invalid max_amount", http.StatusBadRequest)
+			return
+		}
+		search.MaxAmount = &amount
+	}
+	if search.AccountIDs, err = parseIDs(query["account_id"]); err != nil {
+		http.Error(w, "Invalid account_id", http.StatusBadRequest)
 		return
 	}
+	if search.CategoryIDs, err = parseIDs(query["category_id"]); err != nil {
+		http.Error(w, "Invalid category_id", http.StatusBadRequest)
+		return
+	}
+	if raw := query.Get("limit"); raw != "" {
+		if search.Limit, err = strconv.Atoi(raw); err != nil {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
 
-	transactions, err := h.Service.GetAllTransactions(userID)
+	page, err := h.Service.SearchTransactions(userID, search)
 	if err != nil {
-		http.Error(w, "Failed to retrieve transactions", http.StatusInternalServerError)
+		writeServiceError(w, err, "Failed to retrieve transactions")
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(transactions)
+	json.NewEncoder(w).Encode(page)
 }
 
 // GetAllTransactionsWithCacheHandler godoc
 // @Summary Retrieve all transactions with cache
-// @Description Retrieves all transactions for a user, using Redis caching
+// @Description Retrieves all transactions for a user, using Redis caching. Repeated tag parameters keep only transactions carrying all of the tags
 // @Tags Transactions
+// @Security BearerAuth
 // @Param userID path int true "User ID"
+// @Param tag query []string false "Tag names" collectionFormat(multi)
 // @Success 200 {array} models.Transaction
 // @Failure 400 {string} string "Invalid User ID"
+// @Failure 403 {string} string "Forbidden"
 // @Failure 500 {string} string "Failed to retrieve transactions"
 // @Router /transactions/{userID}/cache [get]
 func (h *TransactionHandler) GetAllTransactionsWithCacheHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
-	userID, err := strconv.Atoi(vars["userID"])
-	if err != nil {
-		http.Error(w, "Invalid User ID", http.StatusBadRequest)
+	userID, ok := resolveUserID(w, r, vars["userID"])
+	if !ok {
 		return
 	}
 
-	transactions, err := h.Service.GetAllTransactionsWithCache(userID)
+	transactions, err := h.Service.GetAllTransactionsWithCache(userID, r.URL.Query()["tag"])
 	if err != nil {
 		http.Error(w, "Failed to retrieve transactions", http.StatusInternalServerError)
 		return
@@ -90,11 +161,14 @@ func (h *TransactionHandler) GetAllTransactionsWithCacheHandler(w http.ResponseW
 
 // CreateTransactionHandler godoc
 // @Summary Create a transaction
-// @Description Creates a new transaction
+// @Description Creates a new transaction. An income or expense can be split across categories with splits (category, amount, memo) that add up to the amount; the transaction's own category stays empty. Likely duplicates of existing transactions are listed in possible_duplicates and can be merged or dismissed through /duplicates. An uncategorized transaction gets a suggested_category learned from the user's history. Tags are given by name; missing tags are created
 // @Tags Transactions
+// @Security BearerAuth
 // @Param transaction body models.Transaction true "Transaction Data"
-// @Success 201 {string} string "Transaction created successfully"
+// @Success 201 {object} models.Transaction
 // @Failure 400 {string} string "Invalid input"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not found"
 // @Failure 500 {string} string "Failed to create transaction"
 // @Router /transactions/create [post]
 func (h *TransactionHandler) CreateTransactionHandler(w http.ResponseWriter, r *http.Request) {
@@ -103,24 +177,118 @@ func (h *TransactionHandler) CreateTransactionHandler(w http.ResponseWriter, r *
 		http.Error(w, "Invalid input", http.StatusBadRequest)
 		return
 	}
+	transaction.UserID = currentUserID(r)
 
-	if err := h.Service.CreateTransaction(transaction); err != nil {
-		http.Error(w, "Failed to create transaction", http.StatusInternalServerError)
+	id, err := h.Service.CreateTransaction(transaction)
+	if err != nil {
+		writeServiceError(w, err, "Failed to create transaction")
 		return
 	}
 
+	created, err := h.Service.GetTransactionByID(id)
+	if err != nil {
+		writeServiceError(w, err, "Failed to create transaction")
+		return
+	}
+	if h.Service.Duplicates != nil {
+		if created.PossibleDuplicates, err = h.Service.Duplicates.GetPendingForTransaction(id, transaction.UserID); err != nil {
+			writeServiceError(w, err, "Failed to create transaction")
+			return
+		}
+	}
+	if h.Service.Suggestions != nil {
+		if created.SuggestedCategory, err = h.Service.Suggestions.BestSuggestion(*created); err != nil {
+			writeServiceError(w, err, "Failed to create transaction")
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+// UpdateTransactionHandler godoc
+// @Summary Update a transaction
+// @Description Replaces a transaction, including its splits and tags, and moves the difference between the account balances
+// @Tags Transactions
+// @Security BearerAuth
+// @Param transaction body models.Transaction true "Transaction Data"
+// @Success 200 {object} models.Transaction
+// @Failure 400 {string} string "Invalid input"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not found"
+// @Failure 500 {string} string "Failed to update transaction"
+// @Router /transactions/update [put]
+func (h *TransactionHandler) UpdateTransactionHandler(w http.ResponseWriter, r *http.Request) {
+	var transaction models.Transaction
+	if err := json.NewDecoder(r.Body).Decode(&transaction); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	transaction.UserID = currentUserID(r)
+
+	if err := h.Service.UpdateTransaction(transaction); err != nil {
+		writeServiceError(w, err, "Failed to update transaction")
+		return
+	}
+
+	updated, err := h.Service.GetTransactionByID(transaction.ID)
+	if err != nil {
+		writeServiceError(w, err, "Failed to update transaction")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+// PatchTransactionHandler godoc
+// @Summary Partially update a transaction
+// @Description Changes only the fields present in the body and moves the difference between the account balances. Setting category drops the splits and setting splits clears the category, unless both are given; splits and tags are replaced as a whole. Moving to another account without a currency switches to that account's currency. Every change is recorded in the audit history
+// @Tags Transactions
+// @Security BearerAuth
+// @Param id path int true "Transaction ID"
+// @Param patch body models.TransactionPatch true "Fields to change"
+// @Success 200 {object} models.Transaction
+// @Failure 400 {string} string "Invalid input"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not found"
+// @Failure 500 {string} string "Failed to update transaction"
+// @Router /transactions/{id} [put]
+func (h *TransactionHandler) PatchTransactionHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid transaction ID", http.StatusBadRequest)
+		return
+	}
+	var patch models.TransactionPatch
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	updated, err := h.Service.PatchTransaction(id, currentUserID(r), patch)
+	if err != nil {
+		writeServiceError(w, err, "Failed to update transaction")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
 }
 
 // GetTransactionByIDHandler godoc
 // @Summary Retrieve transaction by ID
 // @Description Retrieves a transaction using its ID
 // @Tags Transactions
+// @Security BearerAuth
 // @Param id path int true "Transaction ID"
 // @Success 200 {object} models.Transaction
 // @Failure 400 {string} string "Invalid transaction ID"
-// @Failure 404 {string} string "Transaction not found"
-// @Failure 500 {string} string "Internal server error"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not found"
+// @Failure 500 {string} string "Failed to retrieve transaction"
 // @Router /transactions/{id} [get]
 func (h *TransactionHandler) GetTransactionByIDHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -132,7 +300,11 @@ func (h *TransactionHandler) GetTransactionByIDHandler(w http.ResponseWriter, r
 
 	transaction, err := h.Service.GetTransactionByID(id)
 	if err != nil {
-		http.Error(w, "Transaction not found", http.StatusNotFound)
+		writeServiceError(w, err, "Failed to retrieve transaction")
+		return
+	}
+	if transaction.UserID != currentUserID(r) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
 		return
 	}
 
@@ -142,23 +314,35 @@ func (h *TransactionHandler) GetTransactionByIDHandler(w http.ResponseWriter, r
 
 // DeleteTransactionHandler godoc
 // @Summary Delete a transaction
-// @Description Deletes a transaction using its ID
+// @Description Moves a transaction to the trash and reverts it from the account balance. A transfer leg is moved together with the other leg and the transfer. Restore it with /trash/{id}/restore
 // @Tags Transactions
-// @Param id path int true "Transaction ID"
+// @Security BearerAuth
+// @Param id query int true "Transaction ID"
 // @Success 204 {string} string "Transaction deleted successfully"
 // @Failure 400 {string} string "Invalid transaction ID"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Transaction not found"
 // @Failure 500 {string} string "Failed to delete transaction"
 // @Router /transactions/delete [delete]
 func (h *TransactionHandler) DeleteTransactionHandler(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id, err := strconv.Atoi(vars["id"])
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
 	if err != nil {
 		http.Error(w, "Invalid transaction ID", http.StatusBadRequest)
 		return
 	}
 
-	if err := h.Service.DeleteTransaction(id); err != nil {
-		http.Error(w, "Failed to delete transaction", http.StatusInternalServerError)
+	transaction, err := h.Service.GetTransactionByID(id)
+	if err != nil {
+		writeServiceError(w, err, "Failed to delete transaction")
+		return
+	}
+	if transaction.UserID != currentUserID(r) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	if err := h.Service.DeleteTransaction(id, transaction.UserID); err != nil {
+		writeServiceError(w, err, "Failed to delete transaction")
 		return
 	}
 
@@ -167,81 +351,29 @@ func (h *TransactionHandler) DeleteTransactionHandler(w http.ResponseWriter, r *
 
 // CompareIncomeAndExpensesHandler godoc
 // @Summary Compare income and expenses
-// @Description Compares income and expenses for a user
+// @Description Compares income and expenses for a user, converted to the user's preferred currency
 // @Tags Transactions
+// @Security BearerAuth
 // @Param id path int true "User ID"
 // @Success 200 {object} map[string]float64 "Comparison of income and expenses"
 // @Failure 400 {string} string "Invalid user ID"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 422 {string} string "Exchange rate not found"
 // @Failure 500 {string} string "Internal server error"
 // @Router /users/{id}/transactions/compare [get]
 func (h *TransactionHandler) CompareIncomeAndExpensesHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
-	userID, err := strconv.Atoi(vars["id"])
-	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+	userID, ok := resolveUserID(w, r, vars["id"])
+	if !ok {
 		return
 	}
 
 	result, err := h.Service.CompareIncomeAndExpenses(userID)
 	if err != nil {
-		http.Error(w, "Failed to compare income and expenses", http.StatusInternalServerError)
+		writeServiceError(w, err, "Failed to compare income and expenses")
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(result)
 }
-
-// GetTransactionsByCategoryHandler godoc
-// @Summary Get transactions by category
-// @Description Retrieves all transactions for a specific category, with optional caching
-// @Tags Categories
-// @Param categoryID path int true "Category ID"
-// @Success 200 {array} services.Transaction "List of transactions"
-// @Failure 400 {string} string "Invalid Category ID"
-// @Failure 500 {string} string "Internal Server Error"
-// @Router /categories/{id}/transactions [get]
-func (h *CategoryHandler) GetTransactionsByCategoryHandler(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	categoryID, err := strconv.Atoi(vars["id"])
-	if err != nil {
-		http.Error(w, "Invalid Category ID", http.StatusBadRequest)
-		return
-	}
-
-	transactions, err := h.Service.GetTransactionsByCategory(categoryID)
-	if err != nil {
-		http.Error(w, "Failed to retrieve transactions", http.StatusInternalServerError)
-		return
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(transactions)
-}
-
-// GetTransactionsByAccountHandler godoc
-// @Summary Get transactions by account
-// @Description Retrieves all transactions for a specific account, with optional caching
-// @Tags Categories
-// @Param accountID path int true "Account ID"
-// @Success 200 {array} services.Transaction "List of transactions"
-// @Failure 400 {string} string "Invalid Account ID"
-// @Failure 500 {string} string "Internal Server Error"
-// @Router /accounts/{id}/transactions [get]
-func (h *CategoryHandler) GetTransactionsByAccountHandler(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	accountID, err := strconv.Atoi(vars["id"])
-	if err != nil {
-		http.Error(w, "Invalid Account ID", http.StatusBadRequest)
-		return
-	}
-
-	transactions, err := h.Service.GetTransactionsByAccount(accountID)
-	if err != nil {
-		http.Error(w, "Failed to retrieve transactions", http.StatusInternalServerError)
-		return
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(transactions)
-}
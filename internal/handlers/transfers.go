@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"finance_project/internal/models"
+	"finance_project/internal/services"
+
+	"github.com/gorilla/mux"
+)
+
+// TransferHandler представляет обработчики переводов между счетами.
+type TransferHandler struct {
+	Service *services.TransferService
+}
+
+// NewTransferHandler создает новый обработчик переводов.
+func NewTransferHandler(service *services.TransferService) *TransferHandler {
+	return &TransferHandler{Service: service}
+}
+
+// CreateTransferHandler создает перевод между счетами.
+// @Summary Создание перевода
+// @Description Переводит деньги между двумя счетами пользователя. Для счетов в разных валютах нужен курс rate.
+// @Tags Transfers
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param transfer body models.Transfer true "Transfer body"
+// @Success 201 {object} models.Transfer
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not found"
+// @Failure 500 {string} string "Failed to create transfer"
+// @Router /transfers/create [post]
+func (h *TransferHandler) CreateTransferHandler(w http.ResponseWriter, r *http.Request) {
+	var transfer models.Transfer
+	if err := json.NewDecoder(r.Body).Decode(&transfer); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	transfer.UserID = currentUserID(r)
+
+	id, err := h.Service.CreateTransfer(transfer)
+	if err != nil {
+		writeServiceError(w, err, "Failed to create transfer")
+		return
+	}
+
+	created, err := h.Service.GetTransferByID(id, transfer.UserID)
+	if err != nil {
+		writeServiceError(w, err, "Failed to create transfer")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+// GetTransfersHandler возвращает все переводы пользователя.
+// @Summary Список переводов
+// @Description Возвращает все переводы текущего пользователя
+// @Tags Transfers
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.Transfer
+// @Failure 500 {string} string "Failed to retrieve transfers"
+// @Router /transfers [get]
+func (h *TransferHandler) GetTransfersHandler(w http.ResponseWriter, r *http.Request) {
+	transfers, err := h.Service.GetTransfers(currentUserID(r))
+	if err != nil {
+		http.Error(w, "Failed to retrieve transfers", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(transfers)
+}
+
+// GetTransferByIDHandler возвращает перевод по ID.
+// @Summary Получение перевода
+// @Description Возвращает перевод и ID обеих записей журнала
+// @Tags Transfers
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Transfer ID"
+// @Success 200 {object} models.Transfer
+// @Failure 400 {string} string "Invalid transfer ID"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not found"
+// @Router /transfers/{id} [get]
+func (h *TransferHandler) GetTransferByIDHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid transfer ID", http.StatusBadRequest)
+		return
+	}
+
+	transfer, err := h.Service.GetTransferByID(id, currentUserID(r))
+	if err != nil {
+		writeServiceError(w, err, "Failed to retrieve transfer")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(transfer)
+}
+
+// DeleteTransferHandler удаляет перевод.
+// @Summary Удаление перевода
+// @Description Переносит перевод вместе с обеими записями журнала в корзину и откатывает балансы счетов. Восстанавливается через /trash/{id}/restore
+// @Tags Transfers
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id query int true "Transfer ID"
+// @Success 204 {string} string "Deleted"
+// @Failure 400 {string} string "Invalid transfer ID"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not found"
+// @Failure 500 {string} string "Failed to delete transfer"
+// @Router /transfers/delete [delete]
+func (h *TransferHandler) DeleteTransferHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		http.Error(w, "Invalid transfer ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Service.DeleteTransfer(id, currentUserID(r)); err != nil {
+		writeServiceError(w, err, "Failed to delete transfer")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
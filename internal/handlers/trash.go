@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"finance_project/internal/services"
+
+	"github.com/gorilla/mux"
+)
+
+// TrashHandler представляет обработчики корзины.
+type TrashHandler struct {
+	Service *services.TrashService
+}
+
+// NewTrashHandler создает новый обработчик корзины.
+func NewTrashHandler(service *services.TrashService) *TrashHandler {
+	return &TrashHandler{Service: service}
+}
+
+// GetTrashHandler возвращает содержимое корзины.
+// @Summary Корзина
+// @Description Возвращает удалённые транзакции, переводы, счета и категории текущего пользователя, последние удалённые первыми. Каждая запись включает всё, что удалено вместе с объектом, и окончательно удаляется в purge_at
+// @Tags Trash
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.TrashItem
+// @Failure 500 {string} string "Failed to retrieve trash"
+// @Router /trash [get]
+func (h *TrashHandler) GetTrashHandler(w http.ResponseWriter, r *http.Request) {
+	items, err := h.Service.GetTrash(currentUserID(r))
+	if err != nil {
+		http.Error(w, "Failed to retrieve trash", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(items)
+}
+
+// RestoreTrashHandler восстанавливает запись корзины.
+// @Summary Восстановление из корзины
+// @Description Восстанавливает запись корзины вместе со всем, что было удалено с ней, и возвращает транзакции в балансы счетов. Если запись ссылается на счёт или категорию, которые тоже лежат в корзине, их нужно восстановить первыми
+// @Tags Trash
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Trash item ID"
+// @Success 204 {string} string "No Content"
+// @Failure 400 {string} string "Invalid parameters"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not found"
+// @Failure 500 {string} string "Failed to restore"
+// @Router /trash/{id}/restore [post]
+func (h *TrashHandler) RestoreTrashHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid trash item ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Service.RestoreTrash(id, currentUserID(r)); err != nil {
+		writeServiceError(w, err, "Failed to restore")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -12,56 +12,33 @@ import (
 // UserHandler представляет обработчики пользователей.
 type UserHandler struct {
 	Service *services.UserService
+	Auth    *services.AuthService
 }
 
 // NewUserHandler создает новый обработчик пользователей.
-func NewUserHandler(service *services.UserService) *UserHandler {
-	return &UserHandler{Service: service}
+func NewUserHandler(service *services.UserService, auth *services.AuthService) *UserHandler {
+	return &UserHandler{Service: service, Auth: auth}
 }
 
-// CreateUserHandler добавляет нового пользователя.
-// @Summary Регистрация пользователя
-// @Description Создает нового пользователя
+// GetCurrentUserHandler возвращает профиль текущего пользователя.
+// @Summary Текущий пользователь
+// @Description Возвращает данные пользователя, которому принадлежит токен
 // @Tags Users
 // @Accept json
 // @Produce json
-// @Param user body models.User true "User body"
-// @Success 201 {string} string "Created"
-// @Failure 400 {string} string "Invalid request body"
-// @Failure 500 {string} string "Failed to create user"
-// @Router /users/create [post]
-func (h *UserHandler) CreateUserHandler(w http.ResponseWriter, r *http.Request) {
-	var user models.User
-	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
-		return
-	}
-
-	if err := h.Service.CreateUser(user); err != nil {
-		http.Error(w, "Failed to create user", http.StatusInternalServerError)
-		return
-	}
-
-	w.WriteHeader(http.StatusCreated)
-}
-
-// GetAllUsersHandler возвращает список всех пользователей.
-// @Summary Список пользователей
-// @Description Возвращает список всех зарегистрированных пользователей
-// @Tags Users
-// @Accept json
-// @Produce json
-// @Success 200 {array} models.User
-// @Router /users [get]
-func (h *UserHandler) GetAllUsersHandler(w http.ResponseWriter, r *http.Request) {
-	users, err := h.Service.GetAllUsers()
+// @Security BearerAuth
+// @Success 200 {object} models.User
+// @Failure 404 {string} string "User not found"
+// @Router /users/me [get]
+func (h *UserHandler) GetCurrentUserHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := h.Service.GetUserByID(currentUserID(r))
 	if err != nil {
-		http.Error(w, "Failed to retrieve users", http.StatusInternalServerError)
+		http.Error(w, "User not found", http.StatusNotFound)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(users)
+	json.NewEncoder(w).Encode(user)
 }
 
 // GetUserByIDHandler возвращает пользователя по ID.
@@ -71,13 +48,14 @@ func (h *UserHandler) GetAllUsersHandler(w http.ResponseWriter, r *http.Request)
 // @Accept json
 // @Produce json
 // @Param id query int true "User ID"
+// @Security BearerAuth
 // @Success 200 {object} models.User
+// @Failure 403 {string} string "Forbidden"
 // @Failure 404 {string} string "User not found"
 // @Router /users/get [get]
 func (h *UserHandler) GetUserByIDHandler(w http.ResponseWriter, r *http.Request) {
-	id, err := strconv.Atoi(r.URL.Query().Get("id"))
-	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+	id, ok := resolveUserID(w, r, r.URL.Query().Get("id"))
+	if !ok {
 		return
 	}
 
@@ -98,8 +76,10 @@ func (h *UserHandler) GetUserByIDHandler(w http.ResponseWriter, r *http.Request)
 // @Accept json
 // @Produce json
 // @Param user body models.User true "User body"
+// @Security BearerAuth
 // @Success 200 {string} string "Updated"
-// @Failure 400 {string} string "Invalid request body"
+// @Failure 400 {string} string "Invalid request body, invalid or already registered email"
+// @Failure 403 {string} string "Forbidden"
 // @Failure 500 {string} string "Failed to update user"
 // @Router /users/update [put]
 func (h *UserHandler) UpdateUserHandler(w http.ResponseWriter, r *http.Request) {
@@ -109,8 +89,16 @@ func (h *UserHandler) UpdateUserHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	if user.ID == 0 {
+		user.ID = currentUserID(r)
+	}
+	if user.ID != currentUserID(r) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
 	if err := h.Service.UpdateUser(user); err != nil {
-		http.Error(w, "Failed to update user", http.StatusInternalServerError)
+		writeServiceError(w, err, "Failed to update user")
 		return
 	}
 
@@ -119,13 +107,15 @@ func (h *UserHandler) UpdateUserHandler(w http.ResponseWriter, r *http.Request)
 
 // DeleteUserHandler удаляет пользователя.
 // @Summary Удаление пользователя
-// @Description Удаляет пользователя по указанному ID
+// @Description Помечает пользователя удалённым и отзывает все его токены: войти он больше не может, но до истечения срока хранения корзины профиль восстанавливается через /auth/restore
 // @Tags Users
 // @Accept json
 // @Produce json
 // @Param id query int true "User ID"
+// @Security BearerAuth
 // @Success 200 {string} string "Deleted"
 // @Failure 400 {string} string "Invalid user ID"
+// @Failure 403 {string} string "Forbidden"
 // @Failure 500 {string} string "Failed to delete user"
 // @Router /users/delete [delete]
 func (h *UserHandler) DeleteUserHandler(w http.ResponseWriter, r *http.Request) {
@@ -134,7 +124,16 @@ func (h *UserHandler) DeleteUserHandler(w http.ResponseWriter, r *http.Request)
 		http.Error(w, "Invalid user ID", http.StatusBadRequest)
 		return
 	}
+	if id != currentUserID(r) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
 
+	// Токены отзываются до удаления: если отозвать их не удалось, профиль остаётся активным
+	if err := h.Auth.RevokeUserTokens(id); err != nil {
+		http.Error(w, "Failed to delete user", http.StatusInternalServerError)
+		return
+	}
 	if err := h.Service.DeleteUser(id); err != nil {
 		http.Error(w, "Failed to delete user", http.StatusInternalServerError)
 		return
@@ -0,0 +1,24 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"finance_project/internal/services"
+)
+
+// BudgetAlerts создает задачу, проверяющую бюджеты и создающую уведомления о достигнутых порогах.
+func BudgetAlerts(budgets *services.BudgetService, interval time.Duration) Job {
+	return Job{
+		Name:     "budget-alerts",
+		Interval: interval,
+		Run: func(ctx context.Context) error {
+			raised, err := budgets.CheckAlerts(time.Now())
+			if raised > 0 {
+				log.Printf("Raised %d budget alert(s)", raised)
+			}
+			return err
+		},
+	}
+}
@@ -0,0 +1,24 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"finance_project/internal/services"
+)
+
+// DepositAccruals создает задачу, зачисляющую проценты по вкладам и закрывающую вклады по окончании срока.
+func DepositAccruals(deposits *services.DepositService, interval time.Duration) Job {
+	return Job{
+		Name:     "deposit-accruals",
+		Interval: interval,
+		Run: func(ctx context.Context) error {
+			posted, err := deposits.ProcessAccruals(time.Now())
+			if posted > 0 {
+				log.Printf("Posted %d deposit interest accrual(s)", posted)
+			}
+			return err
+		},
+	}
+}
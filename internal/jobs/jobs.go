@@ -0,0 +1,71 @@
+// Package jobs runs periodic background jobs of the application.
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Job — фоновая задача, выполняемая с интервалом Interval.
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) error
+}
+
+// Scheduler запускает зарегистрированные задачи.
+type Scheduler struct {
+	jobs []Job
+}
+
+// NewScheduler создает пустой планировщик.
+func NewScheduler() *Scheduler {
+	return &Scheduler{}
+}
+
+// Add регистрирует задачу. Задачи без интервала игнорируются.
+func (s *Scheduler) Add(job Job) {
+	if job.Interval <= 0 {
+		log.Printf("Job %s has no interval, skipping", job.Name)
+		return
+	}
+	s.jobs = append(s.jobs, job)
+}
+
+// Start запускает каждую задачу сразу, а затем с её интервалом, пока ctx не отменён.
+// Запуски одной задачи не пересекаются.
+func (s *Scheduler) Start(ctx context.Context) {
+	for _, job := range s.jobs {
+		go s.loop(ctx, job)
+	}
+}
+
+func (s *Scheduler) loop(ctx context.Context, job Job) {
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+
+	for {
+		runOnce(ctx, job)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func runOnce(ctx context.Context, job Job) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Job %s panicked: %v", job.Name, r)
+		}
+	}()
+
+	started := time.Now()
+	if err := job.Run(ctx); err != nil {
+		log.Printf("Job %s failed: %v", job.Name, err)
+		return
+	}
+	log.Printf("Job %s finished in %s", job.Name, time.Since(started).Round(time.Millisecond))
+}
@@ -0,0 +1,25 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"finance_project/internal/services"
+)
+
+// NetWorthSnapshots создает задачу, записывающую ежедневный снимок собственного капитала каждого пользователя.
+// Каждый запуск обновляет снимок текущего дня.
+func NetWorthSnapshots(reports *services.ReportsService, interval time.Duration) Job {
+	return Job{
+		Name:     "net-worth-snapshots",
+		Interval: interval,
+		Run: func(ctx context.Context) error {
+			recorded, err := reports.RecordNetWorthSnapshots(time.Now())
+			if recorded > 0 {
+				log.Printf("Recorded net worth of %d user(s)", recorded)
+			}
+			return err
+		},
+	}
+}
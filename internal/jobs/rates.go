@@ -0,0 +1,30 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"finance_project/internal/config"
+	"finance_project/internal/services"
+)
+
+// RateImport создает задачу загрузки курсов валют из источника feed.
+func RateImport(rates *services.CurrencyRateService, feed config.RateFeedConfig) Job {
+	return Job{
+		Name:     "rate-import:" + feed.Name,
+		Interval: feed.Interval,
+		Run: func(ctx context.Context) error {
+			result, err := rates.ImportRates(feed.Source, feed.Format)
+			if err != nil {
+				return err
+			}
+			log.Printf("Imported rates from %s: %d new, %d updated, %d unchanged",
+				feed.Name, result.Inserted, result.Updated, result.Unchanged)
+			if len(result.Missing) > 0 {
+				log.Printf("Rates from %s: missing pairs %s", feed.Name, strings.Join(result.Missing, ", "))
+			}
+			return nil
+		},
+	}
+}
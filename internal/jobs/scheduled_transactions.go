@@ -0,0 +1,24 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"finance_project/internal/services"
+)
+
+// ScheduledTransactions создает задачу, проводящую наступившие регулярные транзакции.
+func ScheduledTransactions(schedules *services.ScheduledTransactionService, interval time.Duration) Job {
+	return Job{
+		Name:     "scheduled-transactions",
+		Interval: interval,
+		Run: func(ctx context.Context) error {
+			posted, err := schedules.ProcessDue(time.Now())
+			if posted > 0 {
+				log.Printf("Posted %d scheduled transaction(s)", posted)
+			}
+			return err
+		},
+	}
+}
@@ -0,0 +1,24 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"finance_project/internal/services"
+)
+
+// TrashPurge создает задачу, окончательно удаляющую записи корзины и профили пользователей старше срока хранения.
+func TrashPurge(trash *services.TrashService, interval time.Duration) Job {
+	return Job{
+		Name:     "trash-purge",
+		Interval: interval,
+		Run: func(ctx context.Context) error {
+			purged, err := trash.PurgeTrash(time.Now())
+			if purged > 0 {
+				log.Printf("Purged %d trash item(s)", purged)
+			}
+			return err
+		},
+	}
+}
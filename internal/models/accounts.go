@@ -1,13 +1,37 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"finance_project/internal/money"
+)
 
 type Account struct {
-	ID        int       `json:"id"`
-	UserID    int       `json:"user_id"`
-	Name      string    `json:"name"`
-	Balance   float64   `json:"balance"`
-	Currency  string    `json:"currency"`
-	Type      string    `json:"type"`
-	CreatedAt time.Time `json:"created_at"`
+	ID        int          `json:"id"`
+	UserID    int          `json:"user_id"`
+	Name      string       `json:"name"`
+	Balance   money.Amount `json:"balance"`
+	Currency  string       `json:"currency"`
+	Type      string       `json:"type"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+// AccountUpdate — изменение счёта: меняются только переданные поля.
+type AccountUpdate struct {
+	ID       int           `json:"id"`
+	Name     *string       `json:"name"`
+	Balance  *money.Amount `json:"balance"`
+	Currency *string       `json:"currency"`
+	Type     *string       `json:"type"`
+}
+
+// BalanceDiscrepancy описывает расхождение между сохраненным балансом счёта и балансом по журналу транзакций.
+type BalanceDiscrepancy struct {
+	AccountID       int          `json:"account_id"`
+	AccountName     string       `json:"account_name"`
+	Currency        string       `json:"currency"`
+	RecordedBalance money.Amount `json:"recorded_balance"`
+	LedgerBalance   money.Amount `json:"ledger_balance"`
+	Difference      money.Amount `json:"difference"`
+	Corrected       bool         `json:"corrected"`
 }
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Типы записей, изменения которых попадают в журнал аудита.
+const (
+	AuditEntityTransaction   = "transaction"
+	AuditEntityAccount       = "account"
+	AuditEntityFinancialGoal = "financial_goal"
+)
+
+// AuditEntry — запись журнала изменений: кто (Actor), когда и что сделал с записью.
+// Before пуст у вставки, After — у удаления. Actor — "user:<id>" для изменений через API
+// или "job:<имя>" для фоновых задач.
+type AuditEntry struct {
+	ID         int64           `json:"id"`
+	EntityType string          `json:"entity_type"`
+	EntityID   int             `json:"entity_id"`
+	UserID     int             `json:"user_id"`
+	Action     string          `json:"action"` // "insert", "update" или "delete"
+	Actor      string          `json:"actor"`
+	Before     json.RawMessage `json:"before,omitempty"`
+	After      json.RawMessage `json:"after,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
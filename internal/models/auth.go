@@ -0,0 +1,28 @@
+package models
+
+// Credentials — тело запроса на вход.
+type Credentials struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// RegisterRequest — тело запроса на регистрацию. Пароль передается в открытом виде и хранится только как хеш.
+type RegisterRequest struct {
+	Name              string `json:"name"`
+	Email             string `json:"email"`
+	Password          string `json:"password"`
+	PreferredCurrency string `json:"preferred_currency"`
+}
+
+// TokenPair — пара токенов, выдаваемая при входе и обновлении.
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+// RefreshRequest — тело запроса на обновление токенов и выход.
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
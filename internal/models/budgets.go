@@ -0,0 +1,62 @@
+package models
+
+import (
+	"time"
+
+	"finance_project/internal/money"
+)
+
+// Периоды бюджета.
+const (
+	BudgetWeekly    = "weekly"
+	BudgetMonthly   = "monthly"
+	BudgetQuarterly = "quarterly"
+	BudgetYearly    = "yearly"
+)
+
+// Budget — лимит расходов по категории на период.
+type Budget struct {
+	ID         int          `json:"id"`
+	UserID     int          `json:"user_id"`
+	CategoryID int          `json:"category"`
+	Amount     money.Amount `json:"amount"`
+	Currency   string       `json:"currency"`
+	Period     string       `json:"period"` //"weekly", "monthly", "quarterly" or "yearly"
+	// Rollover переносит неизрасходованный остаток периода на следующий.
+	Rollover bool `json:"rollover"`
+	// Thresholds — пороги в процентах от доступной суммы, при достижении которых создаётся уведомление.
+	Thresholds []int     `json:"thresholds"`
+	StartDate  time.Time `json:"start_date"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// BudgetStatus — исполнение бюджета в текущем периоде.
+type BudgetStatus struct {
+	BudgetID    int          `json:"budget_id"`
+	CategoryID  int          `json:"category"`
+	Category    string       `json:"category_name"`
+	Period      string       `json:"period"`
+	Currency    string       `json:"currency"`
+	PeriodStart time.Time    `json:"period_start"`
+	PeriodEnd   time.Time    `json:"period_end"`
+	Amount      money.Amount `json:"amount"`
+	CarriedOver money.Amount `json:"carried_over"` // остаток, перенесённый из прошлых периодов
+	Available   money.Amount `json:"available"`    // amount + carried_over
+	Spent       money.Amount `json:"spent"`
+	Remaining   money.Amount `json:"remaining"`
+	PercentUsed float64      `json:"percent_used"`
+}
+
+// BudgetAlert — уведомление о достижении порога бюджета.
+type BudgetAlert struct {
+	ID          int          `json:"id"`
+	BudgetID    int          `json:"budget_id"`
+	CategoryID  int          `json:"category"`
+	PeriodStart time.Time    `json:"period_start"`
+	Threshold   int          `json:"threshold"`
+	Spent       money.Amount `json:"spent"`
+	Available   money.Amount `json:"available"`
+	Currency    string       `json:"currency"`
+	Read        bool         `json:"read"`
+	CreatedAt   time.Time    `json:"created_at"`
+}
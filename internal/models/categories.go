@@ -1,11 +1,28 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"finance_project/internal/money"
+)
 
 type Category struct {
 	ID        int       `json:"id"`
 	UserID    int       `json:"user_id"`
+	ParentID  int       `json:"parent_id,omitempty"` // 0 — категория верхнего уровня
 	Name      string    `json:"name"`
 	Type      string    `json:"type"` // "income" or "expense"
 	CreatedAt time.Time `json:"created_at"`
 }
+
+// CategoryTotal — узел дерева расходов по категориям.
+// Amount — расходы, отнесённые непосредственно к категории, Total — вместе со всеми подкатегориями.
+// Path — полный путь категории ("Еда / Кафе"), заполняется в плоском отчёте.
+type CategoryTotal struct {
+	CategoryID int             `json:"category_id"`
+	Name       string          `json:"name"`
+	Path       string          `json:"path,omitempty"`
+	Amount     money.Amount    `json:"amount"`
+	Total      money.Amount    `json:"total"`
+	Children   []CategoryTotal `json:"children,omitempty"`
+}
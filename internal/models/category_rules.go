@@ -0,0 +1,62 @@
+package models
+
+import (
+	"time"
+
+	"finance_project/internal/money"
+)
+
+// CategoryRule — правило автоматической категоризации транзакций. Заданные условия объединяются по И,
+// пустые не проверяются. Правила применяются по возрастанию Priority (при равенстве — по ID),
+// транзакция получает категорию первого подошедшего правила.
+type CategoryRule struct {
+	ID         int    `json:"id"`
+	UserID     int    `json:"user_id"`
+	Name       string `json:"name"`
+	CategoryID int    `json:"category"`
+	Priority   int    `json:"priority"`
+	// DescriptionContains — подстрока описания, DescriptionPattern — регулярное выражение (RE2);
+	// оба сравниваются без учёта регистра.
+	DescriptionContains string `json:"description_contains"`
+	DescriptionPattern  string `json:"description_pattern"`
+	Type                string `json:"type"` // "income", "expense" или пусто — любой
+	AccountID           int    `json:"account_id"`
+	// MinAmount и MaxAmount — границы суммы транзакции включительно, в валюте транзакции.
+	MinAmount *money.Amount `json:"min_amount,omitempty"`
+	MaxAmount *money.Amount `json:"max_amount,omitempty"`
+	Disabled  bool          `json:"disabled"`
+	CreatedAt time.Time     `json:"created_at"`
+}
+
+// CategoryRuleApplyRequest — повторное применение правил к транзакциям за период [From, To].
+// По умолчанию меняется категория любой транзакции, для которой сработало правило;
+// OnlyUncategorized ограничивает изменения транзакциями без категории.
+type CategoryRuleApplyRequest struct {
+	From              time.Time
+	To                time.Time
+	OnlyUncategorized bool
+	DryRun            bool
+}
+
+// CategoryChange — изменение категории транзакции правилом.
+type CategoryChange struct {
+	TransactionID int          `json:"transaction_id"`
+	Date          time.Time    `json:"date"`
+	Type          string       `json:"type"`
+	Amount        money.Amount `json:"amount"`
+	Currency      string       `json:"currency"`
+	Description   string       `json:"description"`
+	OldCategoryID int          `json:"old_category"`
+	NewCategoryID int          `json:"new_category"`
+	RuleID        int          `json:"rule_id"`
+}
+
+// CategoryRuleApplyResult — отчёт о применении правил. При DryRun категории не меняются.
+type CategoryRuleApplyResult struct {
+	DryRun  bool             `json:"dry_run"`
+	From    time.Time        `json:"from"`
+	To      time.Time        `json:"to"`
+	Checked int              `json:"checked"`
+	Changed int              `json:"changed"`
+	Changes []CategoryChange `json:"changes"`
+}
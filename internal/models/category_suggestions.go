@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// CategorySuggestion — категория, предложенная классификатором по истории пользователя,
+// и его уверенность (вероятность от 0 до 1).
+type CategorySuggestion struct {
+	CategoryID int     `json:"category"`
+	Name       string  `json:"name"`
+	Confidence float64 `json:"confidence"`
+}
+
+// CategoryFeedback — ответ пользователя на подсказку: категория, выбранная для транзакции.
+// Accepted показывает, совпала ли она с подсказкой классификатора на момент ответа.
+type CategoryFeedback struct {
+	ID                  int       `json:"id"`
+	UserID              int       `json:"user_id"`
+	TransactionID       int       `json:"transaction_id"`
+	CategoryID          int       `json:"category"`
+	SuggestedCategoryID int       `json:"suggested_category,omitempty"`
+	SuggestedConfidence float64   `json:"suggested_confidence,omitempty"`
+	Accepted            bool      `json:"accepted"`
+	CreatedAt           time.Time `json:"created_at"`
+}
@@ -1,8 +1,38 @@
 package models
 
+import (
+	"time"
+
+	"finance_project/internal/money"
+)
+
+// CurrencyRate — курс на дату: 1 единица BaseCurrency = Rate единиц TargetCurrency.
 type CurrencyRate struct {
-    ID           int     `json:"id"`
-    BaseCurrency string  `json:"base_currency"`
-    TargetCurrency string `json:"target_currency"`
-    Rate         float64 `json:"rate"`
+	ID             int        `json:"id"`
+	BaseCurrency   string     `json:"base_currency"`
+	TargetCurrency string     `json:"target_currency"`
+	Rate           money.Rate `json:"rate"`
+	Date           time.Time  `json:"date"`
+	Source         string     `json:"source"`
+}
+
+// Conversion — результат пересчёта суммы из одной валюты в другую.
+type Conversion struct {
+	Amount          money.Amount `json:"amount"`
+	FromCurrency    string       `json:"from_currency"`
+	ToCurrency      string       `json:"to_currency"`
+	Rate            money.Rate   `json:"rate"`
+	Date            time.Time    `json:"date"`
+	ConvertedAmount money.Amount `json:"converted_amount"`
+}
+
+// RateImportResult — итог загрузки курсов из внешнего источника.
+type RateImportResult struct {
+	Source    string   `json:"source"`
+	Dates     []string `json:"dates"`
+	Inserted  int      `json:"inserted"`
+	Updated   int      `json:"updated"`
+	Unchanged int      `json:"unchanged"`
+	// Missing — пары «валюта/базовая валюта», которые после загрузки нельзя пересчитать на последнюю дату.
+	Missing []string `json:"missing"`
 }
@@ -1,12 +1,48 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"finance_project/internal/money"
+)
+
+const (
+	DebtLent     = "lent"     // деньги дали в долг, контакт должен нам
+	DebtBorrowed = "borrowed" // деньги взяли в долг, мы должны контакту
+)
 
 type Debt struct {
-    ID        int       `json:"id"`
-    UserID    int       `json:"user_id"`
-    Contact   string    `json:"contact"`
-    Amount    float64   `json:"amount"`
-    DueDate   time.Time `json:"due_date"`
-    CreatedAt time.Time `json:"created_at"`
+	ID          int          `json:"id"`
+	UserID      int          `json:"user_id"`
+	Contact     string       `json:"contact"`
+	Direction   string       `json:"direction"` //"lent" or "borrowed"
+	Amount      money.Amount `json:"amount"`
+	Currency    string       `json:"currency"`
+	Description string       `json:"description"`
+	DueDate     *time.Time   `json:"due_date,omitempty"`
+	// AccountID — счёт, через который прошла выдача долга; если задан при создании, создаётся транзакция.
+	AccountID   int             `json:"account_id,omitempty"`
+	Repaid      money.Amount    `json:"repaid"`
+	Outstanding money.Amount    `json:"outstanding"`
+	Repayments  []DebtRepayment `json:"repayments,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+// DebtRepayment — частичный или полный возврат долга, проведённый транзакцией по счёту.
+type DebtRepayment struct {
+	TransactionID int          `json:"transaction_id"`
+	DebtID        int          `json:"debt_id"`
+	AccountID     int          `json:"account_id"`
+	Amount        money.Amount `json:"amount"`
+	Description   string       `json:"description"`
+	Date          time.Time    `json:"date"`
+}
+
+// ContactDebt — непогашенные долги по контакту в одной валюте.
+type ContactDebt struct {
+	Contact  string       `json:"contact"`
+	Currency string       `json:"currency"`
+	Lent     money.Amount `json:"lent"`     // нам должны
+	Borrowed money.Amount `json:"borrowed"` // мы должны
+	Net      money.Amount `json:"net"`      // lent - borrowed
 }
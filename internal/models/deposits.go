@@ -1,13 +1,57 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"finance_project/internal/money"
+)
+
+// Способы начисления процентов по вкладу.
+const (
+	InterestSimple   = "simple"   // проценты на первоначальную сумму
+	InterestCompound = "compound" // проценты на сумму с капитализацией
+)
+
+// Периодичность начисления (капитализации) процентов.
+const (
+	CapitalizationMonthly   = "monthly"
+	CapitalizationQuarterly = "quarterly"
+	CapitalizationMaturity  = "maturity" // один раз в конце срока
+)
+
+// Статусы вклада.
+const (
+	DepositActive = "active"
+	DepositClosed = "closed"
+)
 
 type Deposit struct {
-    ID          int       `json:"id"`
-    UserID      int       `json:"user_id"`
-    AccountID   int       `json:"account_id"`
-    InitialAmount float64 `json:"initial_amount"`
-    InterestRate float64  `json:"interest_rate"`
-    CreatedAt   time.Time `json:"created_at"`
-    EndDate     time.Time `json:"end_date"`
+	ID            int          `json:"id"`
+	UserID        int          `json:"user_id"`
+	AccountID     int          `json:"account_id"`
+	InitialAmount money.Amount `json:"initial_amount"`
+	InterestRate  money.Rate   `json:"interest_rate"` // годовая ставка в процентах, например 14.5
+	Currency      string       `json:"currency"`
+	// InterestType — "simple" или "compound"; Capitalization — "monthly", "quarterly" или "maturity"
+	InterestType   string    `json:"interest_type"`
+	Capitalization string    `json:"capitalization"`
+	StartDate      time.Time `json:"start_date"`
+	CreatedAt      time.Time `json:"created_at"`
+	EndDate        time.Time `json:"end_date"`
+	Status         string    `json:"status"`
+	// AccruedInterest — сумма уже зачисленных на счёт процентов.
+	AccruedInterest money.Amount `json:"accrued_interest"`
+	NextAccrualDate *time.Time   `json:"next_accrual_date,omitempty"`
+	LastError       string       `json:"last_error,omitempty"`
+}
+
+// DepositAccrual — начисление процентов за один период вклада.
+type DepositAccrual struct {
+	Date     time.Time    `json:"date"`
+	Days     int          `json:"days"`
+	Interest money.Amount `json:"interest"`
+	// Balance — сумма вклада вместе с процентами, начисленными по эту дату включительно.
+	Balance       money.Amount `json:"balance"`
+	Posted        bool         `json:"posted"`
+	TransactionID int          `json:"transaction_id,omitempty"`
 }
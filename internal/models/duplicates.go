@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// Состояния пары вероятных дублей.
+const (
+	DuplicatePending   = "pending"
+	DuplicateDismissed = "dismissed"
+)
+
+// TransactionDuplicate — пара транзакций, похожих на дубль: TransactionID добавлена позже DuplicateOf.
+// Score — уверенность от 0 до 1 по совпадению суммы и счёта, близости дат и сходству описаний.
+type TransactionDuplicate struct {
+	ID            int        `json:"id"`
+	UserID        int        `json:"user_id"`
+	TransactionID int        `json:"transaction_id"`
+	DuplicateOf   int        `json:"duplicate_of"`
+	Score         float64    `json:"score"`
+	Status        string     `json:"status"` //"pending" or "dismissed"
+	CreatedAt     time.Time  `json:"created_at"`
+	ResolvedAt    *time.Time `json:"resolved_at,omitempty"`
+	// Сами транзакции пары; заполняются в списке пар
+	Transaction *Transaction `json:"transaction,omitempty"`
+	Original    *Transaction `json:"original,omitempty"`
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+
+	"finance_project/internal/money"
+)
+
+// Envelope — конверт zero-based бюджета; расходы по его категориям уменьшают остаток конверта.
+type Envelope struct {
+	ID          int       `json:"id"`
+	UserID      int       `json:"user_id"`
+	Name        string    `json:"name"`
+	CategoryIDs []int     `json:"categories"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// EnvelopeBalance — состояние конверта за месяц.
+type EnvelopeBalance struct {
+	EnvelopeID  int          `json:"envelope_id"`
+	Name        string       `json:"name"`
+	CarriedOver money.Amount `json:"carried_over"` // остаток прошлого месяца; перерасход не переносится
+	Assigned    money.Amount `json:"assigned"`
+	Activity    money.Amount `json:"activity"` // расходы по категориям конверта
+	Available   money.Amount `json:"available"`
+}
+
+// EnvelopeMonth — сводка конвертного бюджета за месяц.
+type EnvelopeMonth struct {
+	Month    string       `json:"month"` // YYYY-MM
+	Currency string       `json:"currency"`
+	Income   money.Amount `json:"income"`
+	Assigned money.Amount `json:"assigned"`
+	Activity money.Amount `json:"activity"`
+	// Unbudgeted — расходы без конверта; как и перерасход прошлых месяцев, уменьшают ReadyToAssign.
+	Unbudgeted    money.Amount      `json:"unbudgeted"`
+	ReadyToAssign money.Amount      `json:"ready_to_assign"`
+	Envelopes     []EnvelopeBalance `json:"envelopes"`
+	Overspent     []EnvelopeBalance `json:"overspent"`
+}
+
+// EnvelopeAssignment распределяет сумму из «готово к распределению» в конверт; отрицательная сумма возвращает её обратно.
+type EnvelopeAssignment struct {
+	EnvelopeID int          `json:"envelope_id"`
+	Month      string       `json:"month"` // YYYY-MM, по умолчанию текущий месяц
+	Amount     money.Amount `json:"amount"`
+}
+
+// EnvelopeMove перемещает сумму между конвертами, например чтобы покрыть перерасход.
+type EnvelopeMove struct {
+	FromEnvelopeID int          `json:"from_envelope_id"`
+	ToEnvelopeID   int          `json:"to_envelope_id"`
+	Month          string       `json:"month"` // YYYY-MM, по умолчанию текущий месяц
+	Amount         money.Amount `json:"amount"`
+}
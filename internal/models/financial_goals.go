@@ -1,15 +1,19 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"finance_project/internal/money"
+)
 
 type FinancialGoal struct {
-	ID            int       `json:"id"`
-	UserID        int       `json:"user_id"`
-	Name          string    `json:"name"`
-	TargetAmount  float64   `json:"target_amount"`
-	CurrentAmount float64   `json:"current_amount"`
-	Deadline      time.Time `json:"deadline"`
-	Priority      int       `json:"priority"`
-	Description   string    `json:"description"`
-	CreatedAt     time.Time `json:"created_at"`
+	ID            int          `json:"id"`
+	UserID        int          `json:"user_id"`
+	Name          string       `json:"name"`
+	TargetAmount  money.Amount `json:"target_amount"`
+	CurrentAmount money.Amount `json:"current_amount"`
+	Deadline      time.Time    `json:"deadline"`
+	Priority      int          `json:"priority"`
+	Description   string       `json:"description"`
+	CreatedAt     time.Time    `json:"created_at"`
 }
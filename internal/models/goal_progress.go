@@ -1,9 +1,11 @@
 package models
 
+import "finance_project/internal/money"
+
 type GoalProgress struct {
-	ID           int     `json:"id"`
-	Name         string  `json:"name"`
-	TargetAmount float64 `json:"target_amount"`
-	SavedAmount  float64 `json:"saved_amount"`
-	Progress     float64 `json:"progress"`
+	ID           int          `json:"id"`
+	Name         string       `json:"name"`
+	TargetAmount money.Amount `json:"target_amount"`
+	SavedAmount  money.Amount `json:"saved_amount"`
+	Progress     float64      `json:"progress"`
 }
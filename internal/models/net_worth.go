@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+
+	"finance_project/internal/money"
+)
+
+// Шаг временного ряда собственного капитала.
+const (
+	NetWorthDaily   = "day"
+	NetWorthWeekly  = "week"
+	NetWorthMonthly = "month"
+)
+
+// NetWorthAccount — строка снимка собственного капитала: счёт или непогашенные долги в одной валюте.
+const NetWorthAccount = "account"
+
+// NetWorthReport — собственный капитал пользователя во времени в валюте Currency.
+type NetWorthReport struct {
+	Currency    string          `json:"currency"`
+	Granularity string          `json:"granularity"` //"day", "week" or "month"
+	Points      []NetWorthPoint `json:"points"`
+}
+
+// NetWorthPoint — собственный капитал на конец периода по последнему снимку внутри него.
+type NetWorthPoint struct {
+	Period      time.Time         `json:"period"`      // первый день периода; неделя начинается с понедельника
+	Date        time.Time         `json:"date"`        // дата снимка
+	Assets      money.Amount      `json:"assets"`      // сумма балансов счетов
+	Receivables money.Amount      `json:"receivables"` // нам должны
+	Liabilities money.Amount      `json:"liabilities"` // мы должны
+	NetWorth    money.Amount      `json:"net_worth"`   // assets + receivables - liabilities
+	Accounts    []NetWorthBalance `json:"accounts"`
+}
+
+// NetWorthBalance — баланс счёта в снимке: в валюте счёта и в валюте отчёта.
+type NetWorthBalance struct {
+	AccountID int          `json:"account_id"`
+	Name      string       `json:"name"`
+	Currency  string       `json:"currency"`
+	Balance   money.Amount `json:"balance"`
+	Converted money.Amount `json:"converted_balance"`
+}
+
+// NetWorthBackfill — результат восстановления истории снимков по журналу транзакций.
+type NetWorthBackfill struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+	Days int       `json:"days"`
+}
@@ -1,13 +1,42 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"finance_project/internal/money"
+)
+
+// Статусы отдельных выполнений расписания.
+const (
+	OccurrenceScheduled = "scheduled" // ещё не наступило и не обработано
+	OccurrencePosted    = "posted"    // создана транзакция
+	OccurrenceSkipped   = "skipped"   // пропущено пользователем
+	OccurrenceHeld      = "held"      // приостановлено до снятия паузы
+)
 
 type ScheduledTransaction struct {
-    ID          int       `json:"id"`
-    UserID      int       `json:"user_id"`
-    AccountID   int       `json:"account_id"`
-    Amount      float64   `json:"amount"`
-    Type        string    `json:"type"` //"income" or "expense"
-    Schedule    string    `json:"schedule"` //"daily", "weekly", "monthly"
-    CreatedAt   time.Time `json:"created_at"`
+	ID          int          `json:"id"`
+	UserID      int          `json:"user_id"`
+	AccountID   int          `json:"account_id"`
+	Amount      money.Amount `json:"amount"`
+	Type        string       `json:"type"` //"income" or "expense"
+	CategoryID  int          `json:"category"`
+	Description string       `json:"description"`
+	// "daily", "weekly", "monthly" или правило RRULE, например "FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1"
+	Schedule  string     `json:"schedule"`
+	StartDate time.Time  `json:"start_date"`
+	EndDate   *time.Time `json:"end_date,omitempty"`
+	Paused    bool       `json:"paused"`
+	// NextRunDate — дата, начиная с которой выполнения ещё не обработаны; пусто, если расписание завершено.
+	NextRunDate *time.Time `json:"next_run_date,omitempty"`
+	LastError   string     `json:"last_error,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// ScheduledOccurrence — одно выполнение расписания на дату.
+type ScheduledOccurrence struct {
+	ScheduleID    int       `json:"schedule_id"`
+	Date          time.Time `json:"date"`
+	Status        string    `json:"status"`
+	TransactionID int       `json:"transaction_id,omitempty"`
 }
@@ -0,0 +1,88 @@
+package models
+
+import (
+	"time"
+
+	"finance_project/internal/money"
+)
+
+// Знаки сумм в выписке.
+const (
+	SignNegativeExpense = "negative_expense" // отрицательные суммы — расходы (обычная выписка по счёту)
+	SignPositiveExpense = "positive_expense" // положительные суммы — расходы (например, выписка по кредитной карте)
+)
+
+// StatementProfile — сохранённое описание CSV-выписки конкретного банка.
+// Колонки задаются по названию в заголовке или по номеру, начиная с 1.
+type StatementProfile struct {
+	ID        int    `json:"id"`
+	UserID    int    `json:"user_id"`
+	Name      string `json:"name"`
+	Delimiter string `json:"delimiter"` // по умолчанию ","
+	SkipRows  int    `json:"skip_rows"` // строки перед заголовком
+	NoHeader  bool   `json:"no_header"`
+	// DateFormat — раскладка Go ("02.01.2006") или шаблон вида DD.MM.YYYY; по умолчанию YYYY-MM-DD.
+	DateColumn string `json:"date_column"`
+	DateFormat string `json:"date_format"`
+	// AmountColumn — сумма со знаком; вместо неё можно задать DebitColumn и CreditColumn.
+	AmountColumn     string `json:"amount_column"`
+	SignConvention   string `json:"sign_convention"` // "negative_expense" или "positive_expense"
+	DebitColumn      string `json:"debit_column"`
+	CreditColumn     string `json:"credit_column"`
+	DecimalSeparator string `json:"decimal_separator"` // "." или ","
+	// DescriptionColumn может перечислять несколько колонок через "+", например "Payee+Memo".
+	DescriptionColumn string `json:"description_column"`
+	CurrencyColumn    string `json:"currency_column"`
+	// Currency — валюта строк без CurrencyColumn; по умолчанию валюта счёта.
+	Currency  string    `json:"currency"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// StatementImportRequest — параметры импорта выписки.
+type StatementImportRequest struct {
+	AccountID  int
+	CategoryID int    // назначается импортированным транзакциям, к которым не подошло ни одно правило категоризации
+	ProfileID  int    // профиль CSV-выписки
+	Format     string // csv, ofx, qfx, qif, camt053 или mt940; пусто — определяется по имени файла и содержимому
+	FileName   string
+	// StatementAccount выбирает счёт банка (IBAN или номер) в файлах camt.053 и MT940 с выписками нескольких счетов
+	StatementAccount string
+	// Для QIF: порядок дня, месяца и года (по умолчанию MM/DD/YYYY) и десятичный разделитель
+	DateFormat       string
+	DecimalSeparator string
+	DryRun           bool
+}
+
+// StatementRow — результат разбора и импорта одной строки выписки.
+// Duplicate означает, что строка с тем же ExternalID уже импортирована на этот счёт.
+type StatementRow struct {
+	Line        int          `json:"line"`
+	ExternalID  string       `json:"external_id,omitempty"`
+	Transaction *Transaction `json:"transaction,omitempty"`
+	Duplicate   bool         `json:"duplicate,omitempty"`
+	Error       string       `json:"error,omitempty"`
+}
+
+// StatementReconciliation — сверка остатка по выписке с балансом счёта на дату остатка.
+type StatementReconciliation struct {
+	Date             *time.Time   `json:"date,omitempty"`
+	StatementBalance money.Amount `json:"statement_balance"`
+	AccountBalance   money.Amount `json:"account_balance"`
+	Difference       money.Amount `json:"difference"`
+	Reconciled       bool         `json:"reconciled"`
+}
+
+// StatementImportResult — отчёт об импорте выписки. При DryRun транзакции не создаются.
+// Balance заполняется, если выписка содержит остаток (LEDGERBAL в OFX).
+// Flagged — число строк, похожих на уже существующие транзакции (см. Transaction.PossibleDuplicates).
+type StatementImportResult struct {
+	DryRun   bool                     `json:"dry_run"`
+	Format   string                   `json:"format"`
+	Total    int                      `json:"total"`
+	Imported int                      `json:"imported"`
+	Skipped  int                      `json:"skipped"`
+	Failed   int                      `json:"failed"`
+	Flagged  int                      `json:"flagged"`
+	Rows     []StatementRow           `json:"rows"`
+	Balance  *StatementReconciliation `json:"balance,omitempty"`
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"time"
+
+	"finance_project/internal/money"
+)
+
+// Tag — метка транзакций пользователя. В отличие от категории, у транзакции может быть сколько угодно меток.
+type Tag struct {
+	ID     int    `json:"id"`
+	UserID int    `json:"user_id"`
+	Name   string `json:"name"`
+	// Transactions — число транзакций с меткой; при создании и изменении не используется
+	Transactions int       `json:"transactions"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// TransactionTagRequest — массовое добавление (или снятие при Remove) меток у транзакций,
+// отобранных фильтром. Заданные условия фильтра объединяются по И, пустые не проверяются.
+type TransactionTagRequest struct {
+	Tags   []string
+	Remove bool
+	// From и To — границы даты транзакции включительно
+	From                time.Time
+	To                  time.Time
+	AccountID           int
+	CategoryID          int
+	Type                string
+	DescriptionContains string
+	// WithTags отбирает транзакции, у которых уже есть все перечисленные метки
+	WithTags []string
+}
+
+// TransactionTagResult — итог массовой расстановки меток.
+type TransactionTagResult struct {
+	Matched int `json:"matched"` // транзакций подошло под фильтр
+	Changed int `json:"changed"` // меток добавлено или снято
+}
+
+// TagReport — доходы и расходы по меткам за период в предпочитаемой валюте пользователя.
+type TagReport struct {
+	Currency string     `json:"currency"`
+	Tags     []TagTotal `json:"tags"`
+}
+
+// TagTotal — итоги одной метки по всем категориям и счетам с разбивкой по ним.
+type TagTotal struct {
+	TagID        int            `json:"tag_id"`
+	Name         string         `json:"name"`
+	Transactions int            `json:"transactions"`
+	Income       money.Amount   `json:"income"`
+	Expenses     money.Amount   `json:"expenses"`
+	Categories   []TagBreakdown `json:"categories"`
+	Accounts     []TagBreakdown `json:"accounts"`
+}
+
+// TagBreakdown — доходы и расходы метки в одной категории или на одном счёте.
+// Транзакции без категории собраны под ID 0.
+type TagBreakdown struct {
+	ID       int          `json:"id"`
+	Name     string       `json:"name"`
+	Income   money.Amount `json:"income"`
+	Expenses money.Amount `json:"expenses"`
+}
@@ -1,15 +1,98 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"finance_project/internal/money"
+)
+
+const (
+	TransactionTypeIncome  = "income"
+	TransactionTypeExpense = "expense"
+	// Части перевода между счетами; в отчетах о доходах и расходах не учитываются
+	TransactionTypeTransferIn  = "transfer_in"
+	TransactionTypeTransferOut = "transfer_out"
+	// Выдача и возврат долгов; тоже не являются доходами и расходами
+	TransactionTypeDebtIn  = "debt_in"
+	TransactionTypeDebtOut = "debt_out"
+)
 
 type Transaction struct {
-	ID          int       `json:"id"`
-	UserID      int       `json:"user_id"`
-	AccountID   int       `json:"account_id"`
-	Amount      float64   `json:"amount"`
-	Type        string    `json:"type"` //"income" or "expense"
-	CategoryID  int       `json:"category"`
-	Currency    string    `json:"currency"`
-	Description string    `json:"description"`
-	CreatedAt   time.Time `json:"created_at"`
+	ID          int          `json:"id"`
+	UserID      int          `json:"user_id"`
+	AccountID   int          `json:"account_id"`
+	Amount      money.Amount `json:"amount"`
+	Type        string       `json:"type"` //"income", "expense", "transfer_in", "transfer_out", "debt_in" or "debt_out"
+	CategoryID  int          `json:"category"`
+	Currency    string       `json:"currency"`
+	Description string       `json:"description"`
+	TransferID  int          `json:"transfer_id,omitempty"`
+	DebtID      int          `json:"debt_id,omitempty"`
+	CreatedAt   time.Time    `json:"created_at"`
+	// Данные банковской выписки: дата валютирования, контрагент и исходная сумма платежа в другой валюте
+	ValueDate           *time.Time    `json:"value_date,omitempty"`
+	CounterpartyName    string        `json:"counterparty_name,omitempty"`
+	CounterpartyAccount string        `json:"counterparty_account,omitempty"` // IBAN или номер счёта
+	CounterpartyBank    string        `json:"counterparty_bank,omitempty"`    // BIC или код банка
+	OriginalAmount      *money.Amount `json:"original_amount,omitempty"`
+	OriginalCurrency    string        `json:"original_currency,omitempty"`
+	// Splits — разбивка по категориям; сумма частей равна Amount, а CategoryID при разбивке пуст
+	Splits []TransactionSplit `json:"splits,omitempty"`
+	// Tags — имена меток транзакции; недостающие метки создаются при сохранении
+	Tags []string `json:"tags,omitempty"`
+	// PossibleDuplicates — найденные при создании или импорте вероятные дубли; в БД не хранится
+	PossibleDuplicates []TransactionDuplicate `json:"possible_duplicates,omitempty"`
+	// SuggestedCategory — подсказка классификатора для транзакции без категории; в БД не хранится
+	SuggestedCategory *CategorySuggestion `json:"suggested_category,omitempty"`
+}
+
+// TransactionSplit — часть дохода или расхода со своей категорией и суммой.
+type TransactionSplit struct {
+	ID         int          `json:"id"`
+	CategoryID int          `json:"category"`
+	Amount     money.Amount `json:"amount"`
+	Memo       string       `json:"memo"`
+}
+
+// TransactionPatch — частичное изменение транзакции: меняются только переданные поля.
+// Splits и Tags заменяются целиком; пустой список снимает разбивку или метки.
+type TransactionPatch struct {
+	AccountID   *int                `json:"account_id"`
+	Amount      *money.Amount       `json:"amount"`
+	Type        *string             `json:"type"`
+	CategoryID  *int                `json:"category"`
+	Currency    *string             `json:"currency"`
+	Description *string             `json:"description"`
+	CreatedAt   *time.Time          `json:"created_at"`
+	Splits      *[]TransactionSplit `json:"splits"`
+	Tags        *[]string           `json:"tags"`
+}
+
+// TransactionQuery — фильтры, сортировка и страница поиска транзакций. Заданные условия
+// объединяются по И, пустые не проверяются; в списках (типы, счета, категории, валюты)
+// достаточно совпадения с любым значением.
+type TransactionQuery struct {
+	// From и To — границы даты транзакции включительно
+	From        time.Time
+	To          time.Time
+	MinAmount   *money.Amount
+	MaxAmount   *money.Amount
+	Types       []string
+	AccountIDs  []int
+	CategoryIDs []int // вместе с подкатегориями и частями разбитых транзакций
+	Currencies  []string
+	// Search — полнотекстовый поиск по описанию: слова, "фразы в кавычках", OR и -исключения
+	Search string
+	Tags   []string
+	// Sort — date, amount или description, с минусом — по убыванию; по умолчанию -date
+	Sort   string
+	Limit  int
+	Cursor string
+}
+
+// TransactionPage — страница результатов поиска. NextCursor передаётся в следующий запрос
+// с теми же фильтрами и сортировкой; на последней странице он пуст.
+type TransactionPage struct {
+	Transactions []Transaction `json:"transactions"`
+	NextCursor   string        `json:"next_cursor,omitempty"`
 }
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"finance_project/internal/money"
+)
+
+// Transfer — перевод между двумя счетами одного пользователя.
+// Amount списывается со счёта-источника в его валюте, ConvertedAmount = Amount * Rate зачисляется на счёт-получатель.
+type Transfer struct {
+	ID                    int          `json:"id"`
+	UserID                int          `json:"user_id"`
+	FromAccountID         int          `json:"from_account_id"`
+	ToAccountID           int          `json:"to_account_id"`
+	Amount                money.Amount `json:"amount"`
+	Rate                  money.Rate   `json:"rate"`
+	ConvertedAmount       money.Amount `json:"converted_amount"`
+	FromCurrency          string       `json:"from_currency"`
+	ToCurrency            string       `json:"to_currency"`
+	Description           string       `json:"description"`
+	OutgoingTransactionID int          `json:"outgoing_transaction_id"`
+	IncomingTransactionID int          `json:"incoming_transaction_id"`
+	CreatedAt             time.Time    `json:"created_at"`
+}
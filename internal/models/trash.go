@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// Типы записей корзины: что именно удалил пользователь.
+const (
+	TrashEntityTransaction = "transaction"
+	TrashEntityTransfer    = "transfer"
+	TrashEntityAccount     = "account"
+	TrashEntityCategory    = "category"
+)
+
+// TrashItem — удалённый объект вместе со всем, что удалено вместе с ним. Восстанавливается целиком
+// до PurgeAt, после чего удаляется окончательно.
+type TrashItem struct {
+	ID         int    `json:"id"`
+	EntityType string `json:"entity_type"` // "transaction", "transfer", "account" или "category"
+	EntityID   int    `json:"entity_id"`
+	Name       string `json:"name"` // описание транзакции или перевода, название счёта или категории
+	// Transactions — число транзакций, удалённых вместе с объектом (для транзакции и перевода — её записи журнала)
+	Transactions int       `json:"transactions"`
+	DeletedAt    time.Time `json:"deleted_at"`
+	PurgeAt      time.Time `json:"purge_at"`
+}
@@ -6,7 +6,7 @@ type User struct {
     ID               int       `json:"id"`
     Name             string    `json:"name"`
     Email            string    `json:"email"`
-    PasswordHash     string    `json:"password_hash"`
+    PasswordHash     string    `json:"-"`
     PreferredCurrency string   `json:"preferred_currency"`
     CreatedAt        time.Time `json:"created_at"`
 }
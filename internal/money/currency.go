@@ -0,0 +1,44 @@
+package money
+
+import (
+	"fmt"
+	"strings"
+)
+
+// minorUnits lists ISO 4217 currencies whose minor unit is not 2 decimals.
+var minorUnits = map[string]int{
+	// No minor unit
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0, "KRW": 0,
+	"PYG": 0, "RWF": 0, "UGX": 0, "UYI": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0, "XPF": 0,
+	// Three decimals
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+	// Four decimals
+	"CLF": 4, "UYW": 4,
+}
+
+// Precision returns the number of decimals of a currency's minor unit (2 if unknown).
+func Precision(currency string) int {
+	if p, ok := minorUnits[strings.ToUpper(currency)]; ok {
+		return p
+	}
+	return 2
+}
+
+// CheckPrecision reports an error if a has more decimals than currency allows,
+// e.g. 1.5 JPY or 1.2345 KWD.
+func CheckPrecision(a Amount, currency string) error {
+	if a.Round(Precision(currency)) != a {
+		return fmt.Errorf("%w: %s allows %d, got %s", ErrPrecision, strings.ToUpper(currency), Precision(currency), a)
+	}
+	return nil
+}
+
+// RoundTo rounds a half away from zero to the minor unit of currency.
+func RoundTo(a Amount, currency string) Amount {
+	return a.Round(Precision(currency))
+}
+
+// Format formats a with exactly the number of decimals of currency.
+func Format(a Amount, currency string) string {
+	return a.StringFixed(Precision(currency))
+}
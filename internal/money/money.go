@@ -0,0 +1,317 @@
+// Package money implements exact decimal amounts and exchange rates.
+//
+// Amounts are stored as int64 counts of 1/10000 of a currency unit, which covers every
+// ISO 4217 minor unit (at most 3 decimals) without the rounding noise of float64.
+// They are read from and written to NUMERIC columns as decimal strings and are
+// marshaled to JSON as exact number literals.
+package money
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Scale is the number of fractional digits an Amount keeps.
+const Scale = 4
+
+const unit = 10000 // 10^Scale
+
+var (
+	// ErrSyntax is returned for strings that are not plain decimal numbers.
+	ErrSyntax = errors.New("invalid decimal amount")
+	// ErrRange is returned for amounts that do not fit into an Amount.
+	ErrRange = errors.New("amount out of range")
+	// ErrPrecision is returned when an amount has more decimals than its currency allows.
+	ErrPrecision = errors.New("too many decimal places for currency")
+)
+
+// Amount is a fixed-point decimal amount with Scale fractional digits.
+// The zero value is 0.
+type Amount int64
+
+// New returns the amount units + fraction/10^Scale, e.g. New(12, 3400) == 12.34.
+func New(units, fraction int64) Amount {
+	return Amount(units*unit + fraction)
+}
+
+// FromInt returns a whole amount.
+func FromInt(units int64) Amount {
+	return Amount(units * unit)
+}
+
+// FromFloat converts a float64, rounding half away from zero to Scale digits.
+// It is meant for values that are already approximate, such as percentages.
+func FromFloat(f float64) Amount {
+	return Amount(math.Round(f * unit))
+}
+
+// Parse parses a plain decimal string such as "-1234.56".
+func Parse(s string) (Amount, error) {
+	v, err := parseFixed(s, Scale)
+	return Amount(v), err
+}
+
+// MustParse is like Parse but panics on error. It is intended for constants.
+func MustParse(s string) Amount {
+	a, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
+
+// String formats the amount with at least two and at most Scale decimals.
+func (a Amount) String() string {
+	return formatFixed(int64(a), Scale, 2)
+}
+
+// StringFixed formats the amount with exactly places decimals (0..Scale), rounding if needed.
+func (a Amount) StringFixed(places int) string {
+	return formatFixed(int64(a.Round(places)), Scale, places)
+}
+
+// Float64 returns the nearest float64. Use it only for ratios and display.
+func (a Amount) Float64() float64 {
+	return float64(a) / unit
+}
+
+// IsZero reports whether the amount is zero.
+func (a Amount) IsZero() bool { return a == 0 }
+
+// IsNegative reports whether the amount is below zero.
+func (a Amount) IsNegative() bool { return a < 0 }
+
+// IsPositive reports whether the amount is above zero.
+func (a Amount) IsPositive() bool { return a > 0 }
+
+// Neg returns -a.
+func (a Amount) Neg() Amount { return -a }
+
+// Abs returns |a|.
+func (a Amount) Abs() Amount {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
+
+// Round rounds the amount half away from zero to places decimals (0..Scale).
+func (a Amount) Round(places int) Amount {
+	if places >= Scale {
+		return a
+	}
+	if places < 0 {
+		places = 0
+	}
+	step := int64(math.Pow10(Scale - places))
+	v := int64(a)
+	rem := v % step
+	v -= rem
+	if rem*2 >= step {
+		v += step
+	} else if rem*2 <= -step {
+		v -= step
+	}
+	return Amount(v)
+}
+
+// Ratio returns a/b as a float64, or 0 if b is zero.
+func (a Amount) Ratio(b Amount) float64 {
+	if b == 0 {
+		return 0
+	}
+	return float64(a) / float64(b)
+}
+
+// Mul multiplies the amount by a rate, rounding half away from zero to Scale digits.
+func (a Amount) Mul(r Rate) Amount {
+	return Amount(mulDivRound(int64(a), int64(r), rateUnit))
+}
+
+// MulFloat multiplies the amount by an approximate factor such as an interest rate,
+// rounding half away from zero to Scale digits.
+func (a Amount) MulFloat(f float64) Amount {
+	return a.Mul(RateFromFloat(f))
+}
+
+// Sum adds up amounts.
+func Sum(amounts ...Amount) Amount {
+	var total Amount
+	for _, a := range amounts {
+		total += a
+	}
+	return total
+}
+
+// Scan implements sql.Scanner for NUMERIC, integer and float columns. NULL scans as zero.
+func (a *Amount) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*a = 0
+		return nil
+	case []byte:
+		return a.scanString(string(v))
+	case string:
+		return a.scanString(v)
+	case int64:
+		*a = FromInt(v)
+		return nil
+	case float64:
+		return a.scanString(strconv.FormatFloat(v, 'f', Scale, 64))
+	default:
+		return fmt.Errorf("money: cannot scan %T into Amount", src)
+	}
+}
+
+func (a *Amount) scanString(s string) error {
+	parsed, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*a = parsed
+	return nil
+}
+
+// Value implements driver.Valuer, writing the amount as an exact decimal string.
+func (a Amount) Value() (driver.Value, error) {
+	return a.String(), nil
+}
+
+// MarshalJSON writes the amount as an exact JSON number.
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalJSON accepts both JSON numbers and decimal strings.
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	s := strings.TrimSpace(string(data))
+	if s == "null" {
+		return nil
+	}
+	s = strings.Trim(s, `"`)
+	parsed, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*a = parsed
+	return nil
+}
+
+// parseFixed parses a decimal string into an integer scaled by 10^scale.
+// Digits beyond scale are only accepted when they are zeros.
+func parseFixed(s string, scale int) (int64, error) {
+	return parseDecimal(s, scale, false)
+}
+
+// parseDecimal parses a decimal string into an integer scaled by 10^scale. Digits beyond scale
+// are rounded half away from zero when round is set and must be zeros otherwise.
+func parseDecimal(s string, scale int, round bool) (int64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, ErrSyntax
+	}
+
+	negative := false
+	switch s[0] {
+	case '-':
+		negative = true
+		s = s[1:]
+	case '+':
+		s = s[1:]
+	}
+
+	whole, frac := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		whole, frac = s[:i], s[i+1:]
+	}
+	if whole == "" && frac == "" {
+		return 0, ErrSyntax
+	}
+	if !isDigits(whole) || !isDigits(frac) {
+		return 0, fmt.Errorf("%w: %q", ErrSyntax, s)
+	}
+
+	roundUp := false
+	if len(frac) > scale {
+		if strings.Trim(frac[scale:], "0") != "" {
+			if !round {
+				return 0, fmt.Errorf("%w: %q has more than %d decimals", ErrSyntax, s, scale)
+			}
+			roundUp = frac[scale] >= '5'
+		}
+		frac = frac[:scale]
+	}
+	frac += strings.Repeat("0", scale-len(frac))
+
+	digits := strings.TrimLeft(whole+frac, "0")
+	var v int64
+	if digits != "" {
+		var err error
+		if v, err = strconv.ParseInt(digits, 10, 64); err != nil {
+			return 0, ErrRange
+		}
+	}
+	if roundUp {
+		if v == math.MaxInt64 {
+			return 0, ErrRange
+		}
+		v++
+	}
+	if negative {
+		v = -v
+	}
+	return v, nil
+}
+
+// formatFixed formats v/10^scale, trimming trailing zeros down to minDecimals.
+func formatFixed(v int64, scale, minDecimals int) string {
+	sign := ""
+	u := uint64(v)
+	if v < 0 {
+		sign = "-"
+		u = uint64(-v)
+	}
+
+	digits := strconv.FormatUint(u, 10)
+	if len(digits) <= scale {
+		digits = strings.Repeat("0", scale-len(digits)+1) + digits
+	}
+	whole, frac := digits[:len(digits)-scale], digits[len(digits)-scale:]
+
+	for len(frac) > minDecimals && frac[len(frac)-1] == '0' {
+		frac = frac[:len(frac)-1]
+	}
+	if frac == "" {
+		return sign + whole
+	}
+	return sign + whole + "." + frac
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// mulDivRound returns a*b/d rounded half away from zero, without intermediate overflow.
+func mulDivRound(a, b, d int64) int64 {
+	n := new(big.Int).Mul(big.NewInt(a), big.NewInt(b))
+	q, r := new(big.Int).QuoRem(n, big.NewInt(d), new(big.Int))
+	r.Abs(r).Mul(r, big.NewInt(2))
+	if r.Cmp(big.NewInt(d)) >= 0 {
+		if n.Sign() < 0 {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	return q.Int64()
+}
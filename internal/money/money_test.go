@@ -0,0 +1,90 @@
+package money
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Amount
+		err  error
+	}{
+		{in: "12.34", want: New(12, 3400)},
+		{in: "-0.5", want: New(0, -5000)},
+		{in: "+7", want: FromInt(7)},
+		{in: " 1000 ", want: FromInt(1000)},
+		{in: ".25", want: New(0, 2500)},
+		{in: "3.", want: FromInt(3)},
+		{in: "1.2345", want: New(1, 2345)},
+		{in: "1.234500", want: New(1, 2345)},
+		{in: "1.23456", err: ErrSyntax},
+		{in: "", err: ErrSyntax},
+		{in: "-", err: ErrSyntax},
+		{in: "1,5", err: ErrSyntax},
+		{in: "1e3", err: ErrSyntax},
+		{in: "NaN", err: ErrSyntax},
+		{in: "Inf", err: ErrSyntax},
+		{in: "922337203685477.5807", want: Amount(9223372036854775807)},
+		{in: "922337203685477.5808", err: ErrRange},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.in)
+		if tt.err != nil {
+			if !errors.Is(err, tt.err) {
+				t.Errorf("Parse(%q) error = %v, want %v", tt.in, err, tt.err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("Parse(%q) = %s, %v, want %s", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestCheckPrecision(t *testing.T) {
+	tests := []struct {
+		amount   string
+		currency string
+		ok       bool
+	}{
+		{"100", "JPY", true},
+		{"1.5", "JPY", false},
+		{"1.23", "USD", true},
+		{"1.234", "usd", false},
+		{"1.234", "KWD", true},
+		{"1.2345", "KWD", false},
+		{"1.2345", "CLF", true},
+		{"0.01", "XXX", true}, // unknown currencies have 2 decimals
+		{"0.001", "XXX", false},
+	}
+	for _, tt := range tests {
+		err := CheckPrecision(MustParse(tt.amount), tt.currency)
+		if tt.ok && err != nil {
+			t.Errorf("CheckPrecision(%s, %s) = %v, want nil", tt.amount, tt.currency, err)
+		}
+		if !tt.ok && !errors.Is(err, ErrPrecision) {
+			t.Errorf("CheckPrecision(%s, %s) = %v, want ErrPrecision", tt.amount, tt.currency, err)
+		}
+	}
+}
+
+func TestRoundTo(t *testing.T) {
+	tests := []struct {
+		amount   string
+		currency string
+		want     string
+	}{
+		{"1.005", "USD", "1.01"},
+		{"-1.005", "USD", "-1.01"},
+		{"1.004", "USD", "1.00"},
+		{"2.5", "JPY", "3.00"},
+		{"1.2345", "KWD", "1.235"},
+	}
+	for _, tt := range tests {
+		if got := RoundTo(MustParse(tt.amount), tt.currency); got != MustParse(tt.want) {
+			t.Errorf("RoundTo(%s, %s) = %s, want %s", tt.amount, tt.currency, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package money
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// RateScale is the number of fractional digits a Rate keeps.
+const RateScale = 10
+
+const rateUnit = 10000000000 // 10^RateScale
+
+// Rate is a fixed-point exchange rate or multiplier with RateScale fractional digits.
+type Rate int64
+
+// One is the identity rate.
+const One Rate = rateUnit
+
+// ErrRateValue is returned for rates that are zero or negative.
+var ErrRateValue = errors.New("rate must be positive")
+
+// ParseRate parses a plain decimal string such as "0.0021345". Exchange rates are published with
+// arbitrary precision, so digits beyond RateScale are rounded half away from zero. Exponents,
+// NaN and infinities are rejected, as are rates that are not positive.
+func ParseRate(s string) (Rate, error) {
+	v, err := parseDecimal(s, RateScale, true)
+	if err != nil {
+		return 0, err
+	}
+	if v <= 0 {
+		return 0, fmt.Errorf("%w: %q", ErrRateValue, strings.TrimSpace(s))
+	}
+	return Rate(v), nil
+}
+
+// RateFromFloat converts a float64, rounding to RateScale digits.
+func RateFromFloat(f float64) Rate {
+	return Rate(math.Round(f * rateUnit))
+}
+
+// String formats the rate without trailing zeros.
+func (r Rate) String() string {
+	return formatFixed(int64(r), RateScale, 0)
+}
+
+// Float64 returns the nearest float64.
+func (r Rate) Float64() float64 {
+	return float64(r) / rateUnit
+}
+
+// IsZero reports whether the rate is zero.
+func (r Rate) IsZero() bool { return r == 0 }
+
+// Inverse returns 1/r rounded to RateScale digits, or 0 if r is zero.
+func (r Rate) Inverse() Rate {
+	if r == 0 {
+		return 0
+	}
+	return Rate(mulDivRound(rateUnit, rateUnit, int64(r)))
+}
+
+// Mul chains two rates, e.g. EUR→KZT × KZT→USD = EUR→USD.
+func (r Rate) Mul(other Rate) Rate {
+	return Rate(mulDivRound(int64(r), int64(other), rateUnit))
+}
+
+// Div returns r/other, e.g. KZT per USD ÷ KZT per EUR = EUR per USD.
+func (r Rate) Div(other Rate) Rate {
+	if other == 0 {
+		return 0
+	}
+	return Rate(mulDivRound(int64(r), rateUnit, int64(other)))
+}
+
+// Scan implements sql.Scanner. NULL scans as zero.
+func (r *Rate) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*r = 0
+		return nil
+	case []byte:
+		return r.scanString(string(v))
+	case string:
+		return r.scanString(v)
+	case int64:
+		*r = Rate(v * rateUnit)
+		return nil
+	case float64:
+		*r = RateFromFloat(v)
+		return nil
+	default:
+		return fmt.Errorf("money: cannot scan %T into Rate", src)
+	}
+}
+
+func (r *Rate) scanString(s string) error {
+	parsed, err := ParseRate(s)
+	if err != nil {
+		return err
+	}
+	*r = parsed
+	return nil
+}
+
+// Value implements driver.Valuer.
+func (r Rate) Value() (driver.Value, error) {
+	return r.String(), nil
+}
+
+// MarshalJSON writes the rate as an exact JSON number.
+func (r Rate) MarshalJSON() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// UnmarshalJSON accepts both JSON numbers and decimal strings.
+func (r *Rate) UnmarshalJSON(data []byte) error {
+	s := strings.TrimSpace(string(data))
+	if s == "null" {
+		return nil
+	}
+	parsed, err := ParseRate(strings.Trim(s, `"`))
+	if err != nil {
+		return err
+	}
+	*r = parsed
+	return nil
+}
@@ -0,0 +1,87 @@
+package money
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Rate
+		err  error
+	}{
+		{in: "0.0021345", want: 21345000},
+		{in: " 470.25 ", want: 4702500000000},
+		{in: "1", want: One},
+		{in: "1.12345678904", want: 11234567890},
+		{in: "1.12345678905", want: 11234567891},
+		{in: "1.123456789049999", want: 11234567890},
+		{in: "0.00000000005", want: 1},
+		{in: "0.00000000004", err: ErrRateValue},
+		{in: "0", err: ErrRateValue},
+		{in: "-1.5", err: ErrRateValue},
+		{in: "NaN", err: ErrSyntax},
+		{in: "Inf", err: ErrSyntax},
+		{in: "+Inf", err: ErrSyntax},
+		{in: "1e-3", err: ErrSyntax},
+		{in: "0x10", err: ErrSyntax},
+		{in: "", err: ErrSyntax},
+		{in: "922337203.6854775808", err: ErrRange},
+	}
+	for _, tt := range tests {
+		got, err := ParseRate(tt.in)
+		if tt.err != nil {
+			if !errors.Is(err, tt.err) {
+				t.Errorf("ParseRate(%q) error = %v, want %v", tt.in, err, tt.err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ParseRate(%q) = %s, %v, want %s", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestRateUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Rate
+		wantErr bool
+	}{
+		{in: `1.5`, want: 15000000000},
+		{in: `"0.25"`, want: 2500000000},
+		{in: `null`, want: 0},
+		{in: `"NaN"`, wantErr: true},
+		{in: `0`, wantErr: true},
+	}
+	for _, tt := range tests {
+		var r Rate
+		err := r.UnmarshalJSON([]byte(tt.in))
+		if (err != nil) != tt.wantErr || (!tt.wantErr && r != tt.want) {
+			t.Errorf("UnmarshalJSON(%s) = %s, %v, want %s (error %t)", tt.in, r, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestAmountMulRate(t *testing.T) {
+	tests := []struct {
+		amount string
+		rate   string
+		want   string
+	}{
+		{"100", "470.25", "47025"},
+		{"1", "0.0021345", "0.0021"},
+		{"10", "0.00015", "0.0015"},
+		{"-3", "1.5", "-4.5"},
+	}
+	for _, tt := range tests {
+		rate, err := ParseRate(tt.rate)
+		if err != nil {
+			t.Fatalf("ParseRate(%q): %v", tt.rate, err)
+		}
+		if got := MustParse(tt.amount).Mul(rate); got != MustParse(tt.want) {
+			t.Errorf("%s × %s = %s, want %s", tt.amount, tt.rate, got, tt.want)
+		}
+	}
+}
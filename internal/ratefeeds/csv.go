@@ -0,0 +1,101 @@
+package ratefeeds
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+
+	"finance_project/internal/models"
+	"finance_project/internal/money"
+)
+
+// csvColumns maps accepted header names to the column they fill.
+var csvColumns = map[string]string{
+	"date":            "date",
+	"rate_date":       "date",
+	"base":            "base",
+	"base_currency":   "base",
+	"target":          "target",
+	"target_currency": "target",
+	"currency":        "target",
+	"rate":            "rate",
+	"source":          "source",
+}
+
+// ParseCSV parses a CSV file with a header row containing date, base, target and rate
+// columns (and optionally source), e.g.
+//
+//	date,base,target,rate
+//	2024-05-10,USD,KZT,441.5
+//
+// Commas and semicolons are accepted as separators; with semicolons the rate may use a decimal comma.
+func ParseCSV(r io.Reader) ([]models.CurrencyRate, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	text := strings.TrimPrefix(string(data), "\ufeff")
+
+	reader := csv.NewReader(strings.NewReader(text))
+	reader.TrimLeadingSpace = true
+	if firstLine := strings.SplitN(text, "\n", 2)[0]; strings.Count(firstLine, ";") > strings.Count(firstLine, ",") {
+		reader.Comma = ';'
+	}
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("parsing CSV feed: reading header: %w", err)
+	}
+	index := make(map[string]int)
+	for i, name := range header {
+		if column, ok := csvColumns[strings.ToLower(strings.TrimSpace(name))]; ok {
+			index[column] = i
+		}
+	}
+	for _, column := range []string{"date", "base", "target", "rate"} {
+		if _, ok := index[column]; !ok {
+			return nil, fmt.Errorf("parsing CSV feed: missing %q column", column)
+		}
+	}
+
+	var rates []models.CurrencyRate
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parsing CSV feed: %w", err)
+		}
+
+		date, err := parseDate(record[index["date"]])
+		if err != nil {
+			return nil, fmt.Errorf("parsing CSV feed: line %d: %w", line, err)
+		}
+		rawRate := record[index["rate"]]
+		if reader.Comma == ';' {
+			rawRate = strings.Replace(rawRate, ",", ".", 1)
+		}
+		rate, err := money.ParseRate(rawRate)
+		if err != nil || rate <= 0 {
+			return nil, fmt.Errorf("parsing CSV feed: line %d: invalid rate %q", line, record[index["rate"]])
+		}
+		source := FormatCSV
+		if i, ok := index["source"]; ok && strings.TrimSpace(record[i]) != "" {
+			source = strings.TrimSpace(record[i])
+		}
+
+		rates = append(rates, models.CurrencyRate{
+			BaseCurrency:   strings.ToUpper(strings.TrimSpace(record[index["base"]])),
+			TargetCurrency: strings.ToUpper(strings.TrimSpace(record[index["target"]])),
+			Rate:           rate,
+			Date:           date,
+			Source:         source,
+		})
+	}
+	if len(rates) == 0 {
+		return nil, fmt.Errorf("parsing CSV feed: no rates found")
+	}
+	return rates, nil
+}
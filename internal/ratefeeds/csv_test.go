@@ -0,0 +1,51 @@
+package ratefeeds
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseCSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []string
+		wantErr string
+	}{
+		{
+			name:  "comma separated",
+			input: "date,base,target,rate\n2024-05-10,USD,KZT,440.87\n2024-05-10, eur , kzt ,474.56\n",
+			want: []string{
+				"2024-05-10 USD/KZT 440.87 csv",
+				"2024-05-10 EUR/KZT 474.56 csv",
+			},
+		},
+		{
+			name:  "semicolons with a decimal comma",
+			input: "Rate_Date;Base_Currency;Target_Currency;Rate\r\n10.05.2024;USD;RUB;91,7\r\n",
+			want:  []string{"2024-05-10 USD/RUB 91.7 csv"},
+		},
+		{
+			name:  "byte order mark, column order and source",
+			input: "\ufeffcurrency,rate,base,date,source\nKZT,440.87,USD,2024-05-10,bank\nKZT,441,USD,2024-05-09,\n",
+			want: []string{
+				"2024-05-10 USD/KZT 440.87 bank",
+				"2024-05-09 USD/KZT 441 csv",
+			},
+		},
+		{name: "missing column", input: "date,base,rate\n2024-05-10,USD,440.87\n", wantErr: `missing "target" column`},
+		{name: "zero rate", input: "date,base,target,rate\n2024-05-10,USD,KZT,440.87\n2024-05-10,EUR,KZT,0\n", wantErr: `line 3: invalid rate "0"`},
+		{name: "negative rate", input: "date,base,target,rate\n2024-05-10,USD,KZT,-440.87\n", wantErr: `line 2: invalid rate "-440.87"`},
+		{name: "invalid rate", input: "date,base,target,rate\n2024-05-10,USD,KZT,n/a\n", wantErr: `line 2: invalid rate "n/a"`},
+		{name: "invalid date", input: "date,base,target,rate\n05/10/2024,USD,KZT,440.87\n", wantErr: `line 2: invalid date "05/10/2024"`},
+		{name: "header only", input: "date,base,target,rate\n", wantErr: "no rates found"},
+		{name: "empty file", input: "", wantErr: "reading header"},
+		{name: "ragged row", input: "date,base,target,rate\n2024-05-10,USD\n", wantErr: "parsing CSV feed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rates, err := ParseCSV(strings.NewReader(tt.input))
+			checkParse(t, rates, err, tt.want, tt.wantErr)
+		})
+	}
+}
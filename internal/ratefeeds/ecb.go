@@ -0,0 +1,57 @@
+package ratefeeds
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"finance_project/internal/models"
+	"finance_project/internal/money"
+)
+
+// ecbEnvelope matches both eurofxref-daily.xml and eurofxref-hist.xml:
+// <Cube><Cube time="..."><Cube currency="USD" rate="1.0776"/>...</Cube>...</Cube>
+type ecbEnvelope struct {
+	Days []struct {
+		Time  string `xml:"time,attr"`
+		Rates []struct {
+			Currency string `xml:"currency,attr"`
+			Rate     string `xml:"rate,attr"`
+		} `xml:"Cube"`
+	} `xml:"Cube>Cube"`
+}
+
+// ParseECB parses the ECB euro foreign exchange reference rates.
+// Each rate is the amount of the currency per 1 EUR.
+func ParseECB(r io.Reader) ([]models.CurrencyRate, error) {
+	var envelope ecbEnvelope
+	if err := xml.NewDecoder(r).Decode(&envelope); err != nil {
+		return nil, fmt.Errorf("parsing ECB feed: %w", err)
+	}
+
+	var rates []models.CurrencyRate
+	for _, day := range envelope.Days {
+		date, err := parseDate(day.Time)
+		if err != nil {
+			return nil, fmt.Errorf("parsing ECB feed: %w", err)
+		}
+		for _, item := range day.Rates {
+			rate, err := money.ParseRate(item.Rate)
+			if err != nil || rate <= 0 {
+				return nil, fmt.Errorf("parsing ECB feed: invalid rate %q for %s on %s", item.Rate, item.Currency, day.Time)
+			}
+			rates = append(rates, models.CurrencyRate{
+				BaseCurrency:   "EUR",
+				TargetCurrency: strings.ToUpper(strings.TrimSpace(item.Currency)),
+				Rate:           rate,
+				Date:           date,
+				Source:         FormatECB,
+			})
+		}
+	}
+	if len(rates) == 0 {
+		return nil, fmt.Errorf("parsing ECB feed: no rates found")
+	}
+	return rates, nil
+}
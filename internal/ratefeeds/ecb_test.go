@@ -0,0 +1,87 @@
+package ratefeeds
+
+import (
+	"strings"
+	"testing"
+)
+
+const ecbDaily = `<?xml version="1.0" encoding="UTF-8"?>
+<gesmes:Envelope xmlns:gesmes="http://www.gesmes.org/xml/2002-08-01" xmlns="http://www.ecb.int/vocabulary/2002-08-01/eurofxref">
+	<gesmes:subject>Reference rates</gesmes:subject>
+	<gesmes:Sender>
+		<gesmes:name>European Central Bank</gesmes:name>
+	</gesmes:Sender>
+	<Cube>
+		<Cube time='2024-05-10'>
+			<Cube currency='USD' rate='1.0783'/>
+			<Cube currency='JPY' rate='167.83'/>
+			<Cube currency='huf' rate=' 387.30 '/>
+		</Cube>
+	</Cube>
+</gesmes:Envelope>
+`
+
+const ecbHist = `<?xml version="1.0" encoding="UTF-8"?>
+<gesmes:Envelope xmlns:gesmes="http://www.gesmes.org/xml/2002-08-01" xmlns="http://www.ecb.int/vocabulary/2002-08-01/eurofxref">
+	<gesmes:subject>Reference rates</gesmes:subject>
+	<Cube>
+		<Cube time="2024-05-10">
+			<Cube currency="USD" rate="1.0783"/>
+			<Cube currency="GBP" rate="0.86075"/>
+		</Cube>
+		<Cube time="2024-05-09">
+			<Cube currency="USD" rate="1.0735"/>
+			<Cube currency="GBP" rate="0.85950"/>
+		</Cube>
+	</Cube>
+</gesmes:Envelope>
+`
+
+// ecbDay wraps cubes into a one-day ECB document.
+func ecbDay(time, cubes string) string {
+	return `<gesmes:Envelope xmlns:gesmes="http://www.gesmes.org/xml/2002-08-01" xmlns="http://www.ecb.int/vocabulary/2002-08-01/eurofxref">
+	<Cube><Cube time="` + time + `">` + cubes + `</Cube></Cube></gesmes:Envelope>`
+}
+
+func TestParseECB(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []string
+		wantErr string
+	}{
+		{
+			name:  "daily",
+			input: ecbDaily,
+			want: []string{
+				"2024-05-10 EUR/USD 1.0783 ecb",
+				"2024-05-10 EUR/JPY 167.83 ecb",
+				"2024-05-10 EUR/HUF 387.3 ecb",
+			},
+		},
+		{
+			name:  "historical",
+			input: ecbHist,
+			want: []string{
+				"2024-05-10 EUR/USD 1.0783 ecb",
+				"2024-05-10 EUR/GBP 0.86075 ecb",
+				"2024-05-09 EUR/USD 1.0735 ecb",
+				"2024-05-09 EUR/GBP 0.8595 ecb",
+			},
+		},
+		{name: "zero rate", input: ecbDay("2024-05-10", `<Cube currency="USD" rate="0"/>`), wantErr: `invalid rate "0" for USD`},
+		{name: "negative rate", input: ecbDay("2024-05-10", `<Cube currency="USD" rate="-1.07"/>`), wantErr: `invalid rate "-1.07"`},
+		{name: "invalid rate", input: ecbDay("2024-05-10", `<Cube currency="USD" rate="1,0783"/>`), wantErr: `invalid rate "1,0783"`},
+		{name: "missing rate", input: ecbDay("2024-05-10", `<Cube currency="USD"/>`), wantErr: `invalid rate ""`},
+		{name: "invalid date", input: ecbDay("10/05/2024", `<Cube currency="USD" rate="1.0783"/>`), wantErr: `invalid date "10/05/2024"`},
+		{name: "day without rates", input: ecbDay("2024-05-10", ""), wantErr: "no rates found"},
+		{name: "empty cube", input: `<gesmes:Envelope xmlns:gesmes="http://www.gesmes.org/xml/2002-08-01"><Cube></Cube></gesmes:Envelope>`, wantErr: "no rates found"},
+		{name: "not XML", input: "date,base,target,rate", wantErr: "parsing ECB feed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rates, err := ParseECB(strings.NewReader(tt.input))
+			checkParse(t, rates, err, tt.want, tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package ratefeeds
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"finance_project/internal/models"
+	"finance_project/internal/money"
+)
+
+// nbkItem is one currency of an NBK feed: Quant units of Title cost Description tenge.
+type nbkItem struct {
+	Title       string `xml:"title"`
+	PubDate     string `xml:"pubDate"`
+	Description string `xml:"description"`
+	Quant       string `xml:"quant"`
+}
+
+// nbkFeed matches both the RSS feed (rss/channel/item with pubDate) and
+// the get_rates.cfm document (rates/date and rates/item).
+type nbkFeed struct {
+	Date    string    `xml:"date"`
+	Items   []nbkItem `xml:"item"`
+	Channel struct {
+		Items []nbkItem `xml:"item"`
+	} `xml:"channel"`
+}
+
+// ParseNBK parses the official KZT rates of the National Bank of Kazakhstan.
+// Each rate is the amount of KZT per 1 unit of the currency.
+func ParseNBK(r io.Reader) ([]models.CurrencyRate, error) {
+	var feed nbkFeed
+	if err := xml.NewDecoder(r).Decode(&feed); err != nil {
+		return nil, fmt.Errorf("parsing NBK feed: %w", err)
+	}
+
+	items := append(feed.Items, feed.Channel.Items...)
+	var rates []models.CurrencyRate
+	for _, item := range items {
+		currency := strings.ToUpper(strings.TrimSpace(item.Title))
+
+		rawDate := item.PubDate
+		if rawDate == "" {
+			rawDate = feed.Date
+		}
+		date, err := parseDate(rawDate)
+		if err != nil {
+			return nil, fmt.Errorf("parsing NBK feed: %s: %w", currency, err)
+		}
+
+		rate, err := money.ParseRate(strings.TrimSpace(item.Description))
+		if err != nil || rate <= 0 {
+			return nil, fmt.Errorf("parsing NBK feed: invalid rate %q for %s", item.Description, currency)
+		}
+		quant := 1
+		if q := strings.TrimSpace(item.Quant); q != "" {
+			quant, err = strconv.Atoi(q)
+			if err != nil || quant <= 0 {
+				return nil, fmt.Errorf("parsing NBK feed: invalid quant %q for %s", item.Quant, currency)
+			}
+		}
+
+		rates = append(rates, models.CurrencyRate{
+			BaseCurrency:   currency,
+			TargetCurrency: "KZT",
+			Rate:           rate.Div(money.Rate(quant) * money.One),
+			Date:           date,
+			Source:         FormatNBK,
+		})
+	}
+	if len(rates) == 0 {
+		return nil, fmt.Errorf("parsing NBK feed: no rates found")
+	}
+	return rates, nil
+}
@@ -0,0 +1,133 @@
+package ratefeeds
+
+import (
+	"strings"
+	"testing"
+)
+
+// nbkRSS follows https://nationalbank.kz/rss/rates_all.xml: the date is given per item.
+const nbkRSS = `<?xml version="1.0" encoding="utf-8"?>
+<rss version="2.0">
+	<channel>
+		<generator>nationalbank.kz</generator>
+		<title>Официальные курсы валют Национального Банка Республики Казахстан</title>
+		<link>https://nationalbank.kz</link>
+		<description>Официальные курсы валют Национального Банка Республики Казахстан</description>
+		<item>
+			<title>USD</title>
+			<pubDate>10.05.24</pubDate>
+			<description>440.87</description>
+			<quant>1</quant>
+			<index>DOWN</index>
+			<change>-1.03</change>
+		</item>
+		<item>
+			<title>JPY</title>
+			<pubDate>10.05.24</pubDate>
+			<description>283.37</description>
+			<quant>100</quant>
+			<index>DOWN</index>
+			<change>-0.79</change>
+		</item>
+		<item>
+			<title>eur</title>
+			<pubDate>10.05.24</pubDate>
+			<description> 474.56 </description>
+		</item>
+	</channel>
+</rss>
+`
+
+// nbkRates follows https://nationalbank.kz/rss/get_rates.cfm?fdate=10.05.2024: one date for all items.
+const nbkRates = `<?xml version="1.0" encoding="utf-8"?>
+<rates>
+	<generator>nationalbank.kz</generator>
+	<title>Official exchange rates of National Bank of Republic Kazakhstan</title>
+	<link>https://nationalbank.kz</link>
+	<description>Official exchange rates of National Bank of Republic Kazakhstan</description>
+	<copyright>nationalbank.kz</copyright>
+	<date>10.05.2024</date>
+	<item>
+		<fullname>ДОЛЛАР США</fullname>
+		<title>USD</title>
+		<description>440.87</description>
+		<quant>1</quant>
+		<index>DOWN</index>
+		<change>-1.03</change>
+	</item>
+	<item>
+		<fullname>ВЕНГЕРСКИХ ФОРИНТОВ</fullname>
+		<title>HUF</title>
+		<description>12.25</description>
+		<quant>10</quant>
+		<index>UP</index>
+		<change>0.01</change>
+	</item>
+	<item>
+		<fullname>ИНДОНЕЗИЙСКИХ РУПИЙ</fullname>
+		<title>IDR</title>
+		<description>2.74</description>
+		<quant>100</quant>
+		<index>CHANGE</index>
+		<change>0.00</change>
+	</item>
+</rates>
+`
+
+// nbkItems wraps items into a get_rates.cfm document dated 10.05.2024.
+func nbkItems(items string) string {
+	return `<rates><date>10.05.2024</date>` + items + `</rates>`
+}
+
+func TestParseNBK(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []string
+		wantErr string
+	}{
+		{
+			name:  "rss",
+			input: nbkRSS,
+			want: []string{
+				"2024-05-10 USD/KZT 440.87 nbk",
+				"2024-05-10 JPY/KZT 2.8337 nbk",
+				"2024-05-10 EUR/KZT 474.56 nbk",
+			},
+		},
+		{
+			name:  "get_rates.cfm",
+			input: nbkRates,
+			want: []string{
+				"2024-05-10 USD/KZT 440.87 nbk",
+				"2024-05-10 HUF/KZT 1.225 nbk",
+				"2024-05-10 IDR/KZT 0.0274 nbk",
+			},
+		},
+		{
+			name:  "item date overrides document date",
+			input: nbkItems(`<item><title>USD</title><pubDate>09.05.2024</pubDate><description>441.9</description></item>`),
+			want:  []string{"2024-05-09 USD/KZT 441.9 nbk"},
+		},
+		{
+			name:  "quant rounding",
+			input: nbkItems(`<item><title>UZS</title><description>3.46</description><quant>1000000</quant></item>`),
+			want:  []string{"2024-05-10 UZS/KZT 0.00000346 nbk"},
+		},
+		{name: "zero rate", input: nbkItems(`<item><title>USD</title><description>0.00</description></item>`), wantErr: `invalid rate "0.00" for USD`},
+		{name: "negative rate", input: nbkItems(`<item><title>USD</title><description>-440.87</description></item>`), wantErr: `invalid rate "-440.87" for USD`},
+		{name: "invalid rate", input: nbkItems(`<item><title>USD</title><description>440,87</description></item>`), wantErr: `invalid rate "440,87" for USD`},
+		{name: "zero quant", input: nbkItems(`<item><title>JPY</title><description>283.37</description><quant>0</quant></item>`), wantErr: `invalid quant "0" for JPY`},
+		{name: "invalid quant", input: nbkItems(`<item><title>JPY</title><description>283.37</description><quant>1e2</quant></item>`), wantErr: `invalid quant "1e2" for JPY`},
+		{name: "missing date", input: `<rates><item><title>USD</title><description>440.87</description></item></rates>`, wantErr: `USD: invalid date ""`},
+		{name: "empty rss", input: `<rss version="2.0"><channel><title>NBK</title></channel></rss>`, wantErr: "no rates found"},
+		{name: "empty rates", input: nbkItems(""), wantErr: "no rates found"},
+		{name: "not XML", input: "USD 440.87", wantErr: "parsing NBK feed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rates, err := ParseNBK(strings.NewReader(tt.input))
+			checkParse(t, rates, err, tt.want, tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,112 @@
+// Package ratefeeds parses published exchange-rate feeds into dated currency rates.
+//
+// Supported formats are the ECB eurofxref XML (daily and historical files), the
+// National Bank of Kazakhstan RSS/XML feeds and a generic CSV with a header row.
+package ratefeeds
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"finance_project/internal/models"
+)
+
+// Feed formats accepted by Parse.
+const (
+	FormatAuto = "auto"
+	FormatECB  = "ecb"
+	FormatNBK  = "nbk"
+	FormatCSV  = "csv"
+)
+
+// maxFeedSize limits how much of a file or HTTP response is read.
+const maxFeedSize = 32 << 20
+
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+// Load reads the feed at source, a local path or an http(s) URL, and parses it.
+// With FormatAuto (or an empty format) the format is detected from the name and content.
+func Load(source, format string) ([]models.CurrencyRate, error) {
+	data, err := read(source)
+	if err != nil {
+		return nil, err
+	}
+	if format == "" || format == FormatAuto {
+		format = Detect(source, data)
+	}
+	return Parse(format, bytes.NewReader(data))
+}
+
+// Parse parses a feed of the given format.
+func Parse(format string, r io.Reader) ([]models.CurrencyRate, error) {
+	switch strings.ToLower(format) {
+	case FormatECB:
+		return ParseECB(r)
+	case FormatNBK:
+		return ParseNBK(r)
+	case FormatCSV:
+		return ParseCSV(r)
+	default:
+		return nil, fmt.Errorf("unknown rate feed format %q", format)
+	}
+}
+
+// Detect guesses the feed format from the source name and the beginning of its content.
+func Detect(source string, data []byte) string {
+	if strings.EqualFold(filepath.Ext(strings.SplitN(source, "?", 2)[0]), ".csv") {
+		return FormatCSV
+	}
+	head := data
+	if len(head) > 1024 {
+		head = head[:1024]
+	}
+	text := string(head)
+	switch {
+	case strings.Contains(text, "eurofxref") || strings.Contains(text, "gesmes"):
+		return FormatECB
+	case strings.Contains(text, "<rss") || strings.Contains(text, "<rates"):
+		return FormatNBK
+	case !strings.HasPrefix(strings.TrimSpace(text), "<"):
+		return FormatCSV
+	default:
+		return FormatAuto
+	}
+}
+
+func read(source string) ([]byte, error) {
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		resp, err := httpClient.Get(source)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("fetching %s: unexpected status %s", source, resp.Status)
+		}
+		return io.ReadAll(io.LimitReader(resp.Body, maxFeedSize))
+	}
+
+	f, err := os.Open(source)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(io.LimitReader(f, maxFeedSize))
+}
+
+// parseDate accepts ISO dates and the dd.mm.yyyy / dd.mm.yy dates used by NBK.
+func parseDate(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range []string{"2006-01-02", "02.01.2006", "02.01.06"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", s)
+}
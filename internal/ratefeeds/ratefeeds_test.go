@@ -0,0 +1,80 @@
+package ratefeeds
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"finance_project/internal/models"
+)
+
+// formatRates renders rates as "date base/target rate source" for comparison.
+func formatRates(rates []models.CurrencyRate) []string {
+	var out []string
+	for _, r := range rates {
+		out = append(out, r.Date.Format("2006-01-02")+" "+r.BaseCurrency+"/"+r.TargetCurrency+" "+r.Rate.String()+" "+r.Source)
+	}
+	return out
+}
+
+// checkParse compares the result of a feed parser with the expected rates or error.
+func checkParse(t *testing.T, rates []models.CurrencyRate, err error, want []string, wantErr string) {
+	t.Helper()
+	if wantErr != "" {
+		if err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Fatalf("error = %v, want one containing %q", err, wantErr)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := formatRates(rates); !reflect.DeepEqual(got, want) {
+		t.Errorf("rates =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		source string
+		data   string
+		want   string
+	}{
+		{"https://www.ecb.europa.eu/stats/eurofxref/eurofxref-daily.xml", ecbDaily, FormatECB},
+		{"hist.xml", ecbHist, FormatECB},
+		{"https://nationalbank.kz/rss/rates_all.xml", nbkRSS, FormatNBK},
+		{"https://nationalbank.kz/rss/get_rates.cfm?fdate=10.05.2024", nbkRates, FormatNBK},
+		{"rates.csv?token=1", "<not really xml>", FormatCSV},
+		{"rates.txt", "date,base,target,rate\n", FormatCSV},
+		{"rates.xml", "<unknown/>", FormatAuto},
+	}
+	for _, tt := range tests {
+		if got := Detect(tt.source, []byte(tt.data)); got != tt.want {
+			t.Errorf("Detect(%q) = %q, want %q", tt.source, got, tt.want)
+		}
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"2024-05-10", "2024-05-10"},
+		{" 10.05.2024 ", "2024-05-10"},
+		{"10.05.24", "2024-05-10"},
+		{"05/10/2024", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		got, err := parseDate(tt.in)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("parseDate(%q) = %v, want error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil || got.Format("2006-01-02") != tt.want {
+			t.Errorf("parseDate(%q) = %v, %v, want %s", tt.in, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,495 @@
+// Package rrule implements the date-based subset of iCalendar (RFC 5545) recurrence rules
+// used by scheduled transactions.
+//
+// Supported parts are FREQ (DAILY, WEEKLY, MONTHLY, YEARLY), INTERVAL, BYDAY (with ordinals
+// such as 2FR or -1MO in monthly and yearly rules), BYMONTHDAY (negative values count from the
+// end of the month), BYMONTH, BYSETPOS, COUNT and UNTIL. Weeks start on Monday. For
+// compatibility the plain words "daily", "weekly", "monthly" and "yearly" are accepted too.
+//
+// Examples:
+//
+//	FREQ=WEEKLY;INTERVAL=2;BYDAY=FR                  every other Friday
+//	FREQ=MONTHLY;BYDAY=2FR                           the second Friday of each month
+//	FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1    the last business day of each month
+//
+// Occurrences are dates (midnight UTC); the time of day is ignored. As in RFC 5545, a monthly
+// rule started on the 31st skips shorter months; use BYMONTHDAY=-1 for the last day of the month.
+package rrule
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Frequencies.
+const (
+	Daily   = "DAILY"
+	Weekly  = "WEEKLY"
+	Monthly = "MONTHLY"
+	Yearly  = "YEARLY"
+)
+
+// maxEmptyPeriods stops iteration of rules that can never produce another occurrence,
+// e.g. FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=30.
+const maxEmptyPeriods = 5000
+
+// ErrSyntax is returned for rules that cannot be parsed.
+var ErrSyntax = errors.New("invalid recurrence rule")
+
+var weekdays = map[string]time.Weekday{
+	"MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday, "TH": time.Thursday,
+	"FR": time.Friday, "SA": time.Saturday, "SU": time.Sunday,
+}
+
+// WeekdayNum is a BYDAY entry: a weekday with an optional ordinal (0 means every such weekday).
+type WeekdayNum struct {
+	N       int
+	Weekday time.Weekday
+}
+
+// Rule is a parsed recurrence rule.
+type Rule struct {
+	Freq       string
+	Interval   int
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	ByMonth    []int
+	BySetPos   []int
+	Count      int
+	Until      time.Time
+}
+
+// Parse parses an RRULE value such as "FREQ=MONTHLY;BYMONTHDAY=-1" (an "RRULE:" prefix is allowed)
+// or one of the words daily, weekly, monthly and yearly.
+func Parse(s string) (*Rule, error) {
+	s = strings.TrimSpace(s)
+	switch strings.ToLower(s) {
+	case "daily", "weekly", "monthly", "yearly":
+		return &Rule{Freq: strings.ToUpper(s), Interval: 1}, nil
+	}
+	s = strings.TrimPrefix(strings.ToUpper(s), "RRULE:")
+	if s == "" {
+		return nil, fmt.Errorf("%w: empty rule", ErrSyntax)
+	}
+
+	r := &Rule{Interval: 1}
+	for _, part := range strings.Split(s, ";") {
+		if part == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			return nil, fmt.Errorf("%w: %q", ErrSyntax, part)
+		}
+
+		var err error
+		switch name {
+		case "FREQ":
+			switch value {
+			case Daily, Weekly, Monthly, Yearly:
+				r.Freq = value
+			default:
+				err = fmt.Errorf("unsupported FREQ %q", value)
+			}
+		case "INTERVAL":
+			r.Interval, err = strconv.Atoi(value)
+			if err == nil && r.Interval < 1 {
+				err = fmt.Errorf("INTERVAL must be positive")
+			}
+		case "COUNT":
+			r.Count, err = strconv.Atoi(value)
+			if err == nil && r.Count < 1 {
+				err = fmt.Errorf("COUNT must be positive")
+			}
+		case "UNTIL":
+			r.Until, err = parseUntil(value)
+		case "BYDAY":
+			r.ByDay, err = parseByDay(value)
+		case "BYMONTHDAY":
+			r.ByMonthDay, err = parseInts(value, -31, 31)
+		case "BYMONTH":
+			r.ByMonth, err = parseInts(value, 1, 12)
+		case "BYSETPOS":
+			r.BySetPos, err = parseInts(value, -366, 366)
+		case "WKST":
+			if value != "MO" {
+				err = fmt.Errorf("only WKST=MO is supported")
+			}
+		default:
+			err = fmt.Errorf("unsupported part %s", name)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrSyntax, err)
+		}
+	}
+
+	if err := r.validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSyntax, err)
+	}
+	return r, nil
+}
+
+func (r *Rule) validate() error {
+	if r.Freq == "" {
+		return fmt.Errorf("FREQ is required")
+	}
+	if r.Count > 0 && !r.Until.IsZero() {
+		return fmt.Errorf("COUNT and UNTIL cannot be combined")
+	}
+	for _, d := range r.ByDay {
+		if d.N == 0 {
+			continue
+		}
+		if r.Freq != Monthly && r.Freq != Yearly {
+			return fmt.Errorf("BYDAY ordinals are only allowed in MONTHLY and YEARLY rules")
+		}
+		if r.Freq == Yearly && len(r.ByMonth) == 0 {
+			return fmt.Errorf("BYDAY ordinals in YEARLY rules require BYMONTH")
+		}
+	}
+	if len(r.ByMonthDay) > 0 && r.Freq == Weekly {
+		return fmt.Errorf("BYMONTHDAY is not allowed in WEEKLY rules")
+	}
+	return nil
+}
+
+// String formats the rule as an RRULE value.
+func (r *Rule) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByMonth) > 0 {
+		parts = append(parts, "BYMONTH="+joinInts(r.ByMonth))
+	}
+	if len(r.ByMonthDay) > 0 {
+		parts = append(parts, "BYMONTHDAY="+joinInts(r.ByMonthDay))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, d := range r.ByDay {
+			code := strings.ToUpper(d.Weekday.String()[:2])
+			if d.N != 0 {
+				code = strconv.Itoa(d.N) + code
+			}
+			days[i] = code
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.BySetPos) > 0 {
+		parts = append(parts, "BYSETPOS="+joinInts(r.BySetPos))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.Format("20060102"))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Date truncates t to its calendar date at midnight UTC.
+func Date(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// Next returns the first occurrence on or after from of the rule started at start.
+func (r *Rule) Next(start, from time.Time) (time.Time, bool) {
+	from = Date(from)
+	it := r.Iter(start)
+	for {
+		d, ok := it.Next()
+		if !ok {
+			return time.Time{}, false
+		}
+		if !d.Before(from) {
+			return d, true
+		}
+	}
+}
+
+// Between returns the occurrences in [from, to] of the rule started at start.
+func (r *Rule) Between(start, from, to time.Time) []time.Time {
+	from, to = Date(from), Date(to)
+	var dates []time.Time
+	it := r.Iter(start)
+	for {
+		d, ok := it.Next()
+		if !ok || d.After(to) {
+			return dates
+		}
+		if !d.Before(from) {
+			dates = append(dates, d)
+		}
+	}
+}
+
+// Iterator yields the occurrences of a rule in chronological order.
+type Iterator struct {
+	rule   *Rule
+	start  time.Time
+	period time.Time
+	buf    []time.Time
+	count  int
+	done   bool
+}
+
+// Iter returns an iterator over the occurrences of the rule on or after start.
+func (r *Rule) Iter(start time.Time) *Iterator {
+	start = Date(start)
+	it := &Iterator{rule: r, start: start}
+	switch r.Freq {
+	case Weekly:
+		it.period = start.AddDate(0, 0, -((int(start.Weekday()) + 6) % 7))
+	case Monthly:
+		it.period = time.Date(start.Year(), start.Month(), 1, 0, 0, 0, 0, time.UTC)
+	case Yearly:
+		it.period = time.Date(start.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+	default:
+		it.period = start
+	}
+	return it
+}
+
+// Next returns the next occurrence, or false when the rule has ended.
+func (it *Iterator) Next() (time.Time, bool) {
+	for empty := 0; len(it.buf) == 0; empty++ {
+		if it.done || empty > maxEmptyPeriods {
+			it.done = true
+			return time.Time{}, false
+		}
+		for _, d := range it.rule.expand(it.period, it.start) {
+			if !d.Before(it.start) {
+				it.buf = append(it.buf, d)
+			}
+		}
+		it.advance()
+	}
+
+	d := it.buf[0]
+	it.buf = it.buf[1:]
+	if !it.rule.Until.IsZero() && d.After(Date(it.rule.Until)) {
+		it.done, it.buf = true, nil
+		return time.Time{}, false
+	}
+	it.count++
+	if it.rule.Count > 0 && it.count > it.rule.Count {
+		it.done, it.buf = true, nil
+		return time.Time{}, false
+	}
+	return d, true
+}
+
+func (it *Iterator) advance() {
+	n := it.rule.Interval
+	switch it.rule.Freq {
+	case Daily:
+		it.period = it.period.AddDate(0, 0, n)
+	case Weekly:
+		it.period = it.period.AddDate(0, 0, 7*n)
+	case Monthly:
+		it.period = it.period.AddDate(0, n, 0)
+	case Yearly:
+		it.period = it.period.AddDate(n, 0, 0)
+	}
+	if !it.rule.Until.IsZero() && it.period.After(Date(it.rule.Until)) {
+		it.done = true
+	}
+}
+
+// expand returns the sorted candidate dates of the period starting at period.
+func (r *Rule) expand(period, start time.Time) []time.Time {
+	var dates []time.Time
+	switch r.Freq {
+	case Daily:
+		if r.matchesMonth(period) && r.matchesMonthDay(period) && r.matchesWeekday(period) {
+			dates = append(dates, period)
+		}
+	case Weekly:
+		for i := 0; i < 7; i++ {
+			d := period.AddDate(0, 0, i)
+			if len(r.ByDay) == 0 && d.Weekday() != start.Weekday() {
+				continue
+			}
+			if r.matchesMonth(d) && r.matchesWeekday(d) {
+				dates = append(dates, d)
+			}
+		}
+	case Monthly:
+		if r.matchesMonth(period) {
+			dates = r.expandMonth(period.Year(), period.Month(), start)
+		}
+	case Yearly:
+		months := r.ByMonth
+		if len(months) == 0 {
+			months = []int{int(start.Month())}
+		}
+		for _, m := range months {
+			dates = append(dates, r.expandMonth(period.Year(), time.Month(m), start)...)
+		}
+	}
+
+	sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+	dates = dedupe(dates)
+	if len(r.BySetPos) == 0 {
+		return dates
+	}
+
+	var selected []time.Time
+	for _, pos := range r.BySetPos {
+		i := pos - 1
+		if pos < 0 {
+			i = len(dates) + pos
+		}
+		if i >= 0 && i < len(dates) {
+			selected = append(selected, dates[i])
+		}
+	}
+	sort.Slice(selected, func(i, j int) bool { return selected[i].Before(selected[j]) })
+	return dedupe(selected)
+}
+
+// expandMonth returns the days of a month selected by BYMONTHDAY and BYDAY,
+// or the start's day of month if neither is set.
+func (r *Rule) expandMonth(year int, month time.Month, start time.Time) []time.Time {
+	first := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	days := first.AddDate(0, 1, -1).Day()
+
+	var dates []time.Time
+	if len(r.ByMonthDay) == 0 && len(r.ByDay) == 0 {
+		if start.Day() <= days {
+			dates = append(dates, time.Date(year, month, start.Day(), 0, 0, 0, 0, time.UTC))
+		}
+		return dates
+	}
+
+	for day := 1; day <= days; day++ {
+		d := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+		if r.matchesMonthDay(d) && r.matchesMonthWeekday(d, days) {
+			dates = append(dates, d)
+		}
+	}
+	return dates
+}
+
+func (r *Rule) matchesMonth(d time.Time) bool {
+	if len(r.ByMonth) == 0 {
+		return true
+	}
+	for _, m := range r.ByMonth {
+		if time.Month(m) == d.Month() {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Rule) matchesMonthDay(d time.Time) bool {
+	if len(r.ByMonthDay) == 0 {
+		return true
+	}
+	days := time.Date(d.Year(), d.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	for _, md := range r.ByMonthDay {
+		if md == d.Day() || (md < 0 && days+md+1 == d.Day()) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Rule) matchesWeekday(d time.Time) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	for _, wd := range r.ByDay {
+		if wd.Weekday == d.Weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesMonthWeekday checks BYDAY entries with ordinals counted within the month.
+func (r *Rule) matchesMonthWeekday(d time.Time, daysInMonth int) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	nth := (d.Day()-1)/7 + 1
+	nthFromEnd := -((daysInMonth-d.Day())/7 + 1)
+	for _, wd := range r.ByDay {
+		if wd.Weekday != d.Weekday() {
+			continue
+		}
+		if wd.N == 0 || wd.N == nth || wd.N == nthFromEnd {
+			return true
+		}
+	}
+	return false
+}
+
+func parseUntil(value string) (time.Time, error) {
+	for _, layout := range []string{"20060102", "20060102T150405Z", "20060102T150405", "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return Date(t), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid UNTIL %q", value)
+}
+
+func parseByDay(value string) ([]WeekdayNum, error) {
+	var days []WeekdayNum
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if len(item) < 2 {
+			return nil, fmt.Errorf("invalid BYDAY %q", item)
+		}
+		wd, ok := weekdays[item[len(item)-2:]]
+		if !ok {
+			return nil, fmt.Errorf("invalid BYDAY %q", item)
+		}
+		n := 0
+		if prefix := item[:len(item)-2]; prefix != "" {
+			var err error
+			n, err = strconv.Atoi(prefix)
+			if err != nil || n == 0 || n < -53 || n > 53 {
+				return nil, fmt.Errorf("invalid BYDAY %q", item)
+			}
+		}
+		days = append(days, WeekdayNum{N: n, Weekday: wd})
+	}
+	return days, nil
+}
+
+func parseInts(value string, min, max int) ([]int, error) {
+	var values []int
+	for _, item := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(item))
+		if err != nil || n == 0 || n < min || n > max {
+			return nil, fmt.Errorf("invalid value %q", item)
+		}
+		values = append(values, n)
+	}
+	return values, nil
+}
+
+func joinInts(values []int) string {
+	s := make([]string, len(values))
+	for i, v := range values {
+		s[i] = strconv.Itoa(v)
+	}
+	return strings.Join(s, ",")
+}
+
+func dedupe(dates []time.Time) []time.Time {
+	out := dates[:0]
+	for i, d := range dates {
+		if i == 0 || !d.Equal(dates[i-1]) {
+			out = append(out, d)
+		}
+	}
+	return out
+}
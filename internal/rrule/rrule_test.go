@@ -0,0 +1,162 @@
+package rrule
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func date(s string) time.Time {
+	d, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func formatDates(dates []time.Time) []string {
+	out := []string{}
+	for _, d := range dates {
+		out = append(out, d.Format("2006-01-02"))
+	}
+	return out
+}
+
+func TestIter(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  string
+		start string
+		limit int
+		want  []string
+	}{
+		{"daily across month end", "daily", "2026-01-30", 3,
+			[]string{"2026-01-30", "2026-01-31", "2026-02-01"}},
+		{"every other Friday", "FREQ=WEEKLY;INTERVAL=2;BYDAY=FR", "2026-01-01", 3,
+			[]string{"2026-01-02", "2026-01-16", "2026-01-30"}},
+		{"weekly on the start weekday", "weekly", "2026-01-01", 3,
+			[]string{"2026-01-01", "2026-01-08", "2026-01-15"}},
+		{"last business day", "FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1", "2026-01-01", 5,
+			[]string{"2026-01-30", "2026-02-27", "2026-03-31", "2026-04-30", "2026-05-29"}},
+		{"first business day", "FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=1", "2026-08-01", 2,
+			[]string{"2026-08-03", "2026-09-01"}},
+		{"BYMONTHDAY=31 skips short months", "FREQ=MONTHLY;BYMONTHDAY=31", "2026-01-01", 4,
+			[]string{"2026-01-31", "2026-03-31", "2026-05-31", "2026-07-31"}},
+		{"monthly from the 31st skips short months", "monthly", "2026-01-31", 3,
+			[]string{"2026-01-31", "2026-03-31", "2026-05-31"}},
+		{"last day of month", "FREQ=MONTHLY;BYMONTHDAY=-1", "2028-01-15", 3,
+			[]string{"2028-01-31", "2028-02-29", "2028-03-31"}},
+		{"second Friday", "FREQ=MONTHLY;BYDAY=2FR", "2026-01-01", 3,
+			[]string{"2026-01-09", "2026-02-13", "2026-03-13"}},
+		{"last Monday", "FREQ=MONTHLY;BYDAY=-1MO", "2026-01-01", 3,
+			[]string{"2026-01-26", "2026-02-23", "2026-03-30"}},
+		{"leap day", "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=29", "2026-01-01", 2,
+			[]string{"2028-02-29", "2032-02-29"}},
+		{"impossible date ends", "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=30", "2026-01-01", 2,
+			[]string{}},
+		{"COUNT limits occurrences", "FREQ=DAILY;COUNT=3", "2026-03-01", 10,
+			[]string{"2026-03-01", "2026-03-02", "2026-03-03"}},
+		{"COUNT counts from start", "FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1;COUNT=2", "2026-01-31", 10,
+			[]string{"2026-02-27", "2026-03-31"}},
+		{"UNTIL is inclusive", "FREQ=WEEKLY;UNTIL=20260115", "2026-01-01", 10,
+			[]string{"2026-01-01", "2026-01-08", "2026-01-15"}},
+		{"UNTIL before the first occurrence", "FREQ=MONTHLY;BYMONTHDAY=20;UNTIL=2026-01-10", "2026-01-01", 10,
+			[]string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := Parse(tt.rule)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.rule, err)
+			}
+			var got []time.Time
+			it := rule.Iter(date(tt.start))
+			for len(got) < tt.limit {
+				d, ok := it.Next()
+				if !ok {
+					break
+				}
+				got = append(got, d)
+			}
+			if g := formatDates(got); !reflect.DeepEqual(g, tt.want) {
+				t.Errorf("occurrences = %v, want %v", g, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextAndBetween(t *testing.T) {
+	rule, err := Parse("FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := date("2026-01-01")
+
+	next, ok := rule.Next(start, time.Date(2026, 2, 27, 15, 30, 0, 0, time.UTC))
+	if !ok || !next.Equal(date("2026-02-27")) {
+		t.Errorf("Next = %s, %t, want 2026-02-27", next.Format("2006-01-02"), ok)
+	}
+	next, ok = rule.Next(start, date("2026-02-28"))
+	if !ok || !next.Equal(date("2026-03-31")) {
+		t.Errorf("Next = %s, %t, want 2026-03-31", next.Format("2006-01-02"), ok)
+	}
+
+	got := formatDates(rule.Between(start, date("2026-02-01"), date("2026-04-30")))
+	want := []string{"2026-02-27", "2026-03-31", "2026-04-30"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Between = %v, want %v", got, want)
+	}
+
+	limited, _ := Parse("FREQ=DAILY;COUNT=2")
+	if _, ok := limited.Next(start, date("2026-01-03")); ok {
+		t.Errorf("Next after the last counted occurrence should report false")
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"RRULE:",
+		"INTERVAL=2",
+		"FREQ=HOURLY",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=DAILY;COUNT=0",
+		"FREQ=DAILY;COUNT=2;UNTIL=20260101",
+		"FREQ=DAILY;UNTIL=tomorrow",
+		"FREQ=WEEKLY;BYDAY=2FR",
+		"FREQ=YEARLY;BYDAY=1MO",
+		"FREQ=WEEKLY;BYMONTHDAY=1",
+		"FREQ=MONTHLY;BYMONTHDAY=32",
+		"FREQ=MONTHLY;BYMONTHDAY=0",
+		"FREQ=MONTHLY;BYDAY=XX",
+		"FREQ=MONTHLY;WKST=SU",
+		"FREQ=MONTHLY;BYHOUR=9",
+	} {
+		if _, err := Parse(s); !errors.Is(err, ErrSyntax) {
+			t.Errorf("Parse(%q) error = %v, want ErrSyntax", s, err)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"monthly", "FREQ=MONTHLY"},
+		{"RRULE:freq=monthly;byday=mo,tu,we,th,fr;bysetpos=-1", "FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1"},
+		{"FREQ=WEEKLY;INTERVAL=2;BYDAY=FR;UNTIL=20261231T000000Z", "FREQ=WEEKLY;INTERVAL=2;BYDAY=FR;UNTIL=20261231"},
+		{"FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=-1;COUNT=5", "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=-1;COUNT=5"},
+		{"FREQ=MONTHLY;BYDAY=-1MO", "FREQ=MONTHLY;BYDAY=-1MO"},
+	}
+	for _, tt := range tests {
+		rule, err := Parse(tt.in)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tt.in, err)
+		}
+		if got := rule.String(); got != tt.want {
+			t.Errorf("Parse(%q).String() = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
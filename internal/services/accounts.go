@@ -3,23 +3,38 @@ package services
 import (
 	"database/sql"
 	"finance_project/internal/models"
+	"fmt"
 	"log"
+
+	"finance_project/internal/money"
+
+	"github.com/lib/pq"
 )
 
 type AccountService struct {
-	DB *sql.DB
+	DB           *sql.DB
+	Transactions *TransactionService
 }
 
 // NewAccountService создаёт новый сервис для работы со счетами
-func NewAccountService(db *sql.DB) *AccountService {
-	return &AccountService{DB: db}
+func NewAccountService(db *sql.DB, transactions *TransactionService) *AccountService {
+	return &AccountService{DB: db, Transactions: transactions}
 }
 
-// CreateAccount добавляет новый счёт
+// CreateAccount добавляет новый счёт. Код валюты приводится к верхнему регистру.
 func (s *AccountService) CreateAccount(account models.Account) error {
-	query := `INSERT INTO accounts (user_id, name, balance, currency, type, created_at)
-			  VALUES ($1, $2, $3, $4, $5, NOW())`
-	_, err := s.DB.Exec(query, account.UserID, account.Name, account.Balance, account.Currency, account.Type)
+	currency, err := normalizeCurrency(account.Currency)
+	if err != nil {
+		return err
+	}
+	account.Currency = currency
+	if err := money.CheckPrecision(account.Balance, account.Currency); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+
+	query := `INSERT INTO accounts (user_id, name, balance, opening_balance, currency, type, created_at)
+			  VALUES ($1, $2, $3, $3, $4, $5, NOW())`
+	_, err = s.DB.Exec(query, account.UserID, account.Name, account.Balance, account.Currency, account.Type)
 	if err != nil {
 		log.Printf("Error creating account: %v", err)
 		return err
@@ -30,7 +45,7 @@ func (s *AccountService) CreateAccount(account models.Account) error {
 // GetAllAccounts возвращает все счета пользователя
 func (s *AccountService) GetAllAccounts(userID int) ([]models.Account, error) {
 	query := `SELECT id, user_id, name, balance, currency, type, created_at 
-			  FROM accounts WHERE user_id = $1`
+			  FROM accounts WHERE user_id = $1 AND trash_id IS NULL`
 
 	rows, err := s.DB.Query(query, userID)
 	if err != nil {
@@ -51,13 +66,16 @@ func (s *AccountService) GetAllAccounts(userID int) ([]models.Account, error) {
 	return accounts, nil
 }
 
-// GetAccountByID возвращает счёт по ID
+// GetAccountByID возвращает счёт по ID. Счета в корзине не находятся.
 func (s *AccountService) GetAccountByID(id int) (*models.Account, error) {
 	query := `SELECT id, user_id, name, balance, currency, type, created_at 
-			  FROM accounts WHERE id = $1`
+			  FROM accounts WHERE id = $1 AND trash_id IS NULL`
 
 	var account models.Account
 	err := s.DB.QueryRow(query, id).Scan(&account.ID, &account.UserID, &account.Name, &account.Balance, &account.Currency, &account.Type, &account.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
 	if err != nil {
 		log.Printf("Error retrieving account by ID: %v", err)
 		return nil, err
@@ -65,24 +83,177 @@ func (s *AccountService) GetAccountByID(id int) (*models.Account, error) {
 	return &account, nil
 }
 
-// UpdateAccount обновляет данные счёта
-func (s *AccountService) UpdateAccount(account models.Account) error {
-	query := `UPDATE accounts SET name = $1, balance = $2, currency = $3, type = $4 WHERE id = $5`
-	_, err := s.DB.Exec(query, account.Name, account.Balance, account.Currency, account.Type, account.ID)
+// UpdateAccount обновляет переданные поля счёта пользователя.
+// Ручное изменение баланса переносится в начальный баланс, чтобы счёт оставался сверенным с транзакциями.
+// Валюту можно сменить только у счёта без транзакций (включая лежащие в корзине): суммы транзакций
+// хранятся в валюте счёта.
+func (s *AccountService) UpdateAccount(update models.AccountUpdate, userID int) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	currency, err := lockAccount(tx, update.ID, userID)
+	if err != nil {
+		return err
+	}
+	var account models.Account
+	err = tx.QueryRow(`SELECT name, balance, type FROM accounts WHERE id = $1`, update.ID).Scan(&account.Name, &account.Balance, &account.Type)
+	if err != nil {
+		return err
+	}
+	account.Currency = currency
+
+	if update.Currency != nil {
+		normalized, err := normalizeCurrency(*update.Currency)
+		if err != nil {
+			return err
+		}
+		update.Currency = &normalized
+	}
+	if update.Currency != nil && *update.Currency != currency {
+		var used bool
+		err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM transactions WHERE account_id = $1)`, update.ID).Scan(&used)
+		if err != nil {
+			return err
+		}
+		if used {
+			return fmt.Errorf("%w: currency of an account with transactions cannot be changed", ErrInvalidInput)
+		}
+		account.Currency = *update.Currency
+	}
+	if update.Name != nil {
+		account.Name = *update.Name
+	}
+	if update.Balance != nil {
+		account.Balance = *update.Balance
+	}
+	if update.Type != nil {
+		account.Type = *update.Type
+	}
+	if err := money.CheckPrecision(account.Balance, account.Currency); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+
+	query := `UPDATE accounts
+			  SET name = $1, opening_balance = opening_balance + ($2 - balance), balance = $2, currency = $3, type = $4
+			  WHERE id = $5`
+	_, err = tx.Exec(query, account.Name, account.Balance, account.Currency, account.Type, update.ID)
 	if err != nil {
 		log.Printf("Error updating account: %v", err)
 		return err
 	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	s.Transactions.invalidateCache(userID)
 	return nil
 }
 
-// DeleteAccount удаляет счёт
-func (s *AccountService) DeleteAccount(id int) error {
-	query := `DELETE FROM accounts WHERE id = $1`
-	_, err := s.DB.Exec(query, id)
+// DeleteAccount переносит счёт пользователя в корзину вместе со всеми его транзакциями.
+// Переводы с участием счёта попадают в корзину целиком, поэтому меняется и баланс второго счёта.
+func (s *AccountService) DeleteAccount(id, userID int) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := lockAccount(tx, id, userID); err != nil {
+		return err
+	}
+	var name string
+	var transactions []int64
+	err = tx.QueryRow(`SELECT name, ARRAY(SELECT t.id FROM transactions t WHERE t.account_id = a.id AND t.trash_id IS NULL)
+					   FROM accounts a WHERE a.id = $1`, id).Scan(&name, pq.Array(&transactions))
 	if err != nil {
+		return err
+	}
+
+	trashID, err := createTrashEntryTx(tx, userID, models.TrashEntityAccount, id, name)
+	if err != nil {
+		return err
+	}
+	if err := trashTransactionsTx(tx, trashID, userID, transactions); err != nil {
 		log.Printf("Error deleting account: %v", err)
 		return err
 	}
+	if _, err := tx.Exec(`UPDATE accounts SET trash_id = $2 WHERE id = $1`, id, trashID); err != nil {
+		log.Printf("Error deleting account: %v", err)
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	s.Transactions.invalidateCache(userID)
 	return nil
 }
+
+// ReconcileBalances пересчитывает баланс каждого счёта пользователя по его транзакциям
+// и возвращает счета, у которых сохраненный баланс расходится с расчетным.
+// Если apply = true, расходящиеся балансы исправляются.
+func (s *AccountService) ReconcileBalances(userID int, apply bool) ([]models.BalanceDiscrepancy, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Блокируем счета, чтобы новые транзакции не изменили баланс во время сверки
+	query := `
+		SELECT a.id, a.name, a.currency, a.balance,
+		       a.opening_balance + COALESCE((
+		           SELECT SUM(CASE WHEN t.type IN ('income', 'transfer_in', 'debt_in') THEN t.amount ELSE -t.amount END)
+		           FROM transactions t
+		           WHERE t.account_id = a.id AND t.trash_id IS NULL
+		       ), 0) AS ledger_balance
+		FROM accounts a
+		WHERE a.user_id = $1 AND a.trash_id IS NULL
+		ORDER BY a.id
+		FOR UPDATE OF a
+	`
+	rows, err := tx.Query(query, userID)
+	if err != nil {
+		log.Printf("Error reconciling accounts: %v", err)
+		return nil, err
+	}
+
+	discrepancies := []models.BalanceDiscrepancy{}
+	for rows.Next() {
+		var d models.BalanceDiscrepancy
+		if err := rows.Scan(&d.AccountID, &d.AccountName, &d.Currency, &d.RecordedBalance, &d.LedgerBalance); err != nil {
+			rows.Close()
+			log.Printf("Error scanning account balance: %v", err)
+			return nil, err
+		}
+		d.Difference = d.RecordedBalance - d.LedgerBalance
+		if !d.Difference.IsZero() {
+			discrepancies = append(discrepancies, d)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if !apply {
+		return discrepancies, nil
+	}
+
+	for i := range discrepancies {
+		d := &discrepancies[i]
+		if _, err := tx.Exec(`UPDATE accounts SET balance = $1 WHERE id = $2`, d.LedgerBalance, d.AccountID); err != nil {
+			log.Printf("Error correcting account balance: %v", err)
+			return nil, err
+		}
+		d.Corrected = true
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return discrepancies, nil
+}
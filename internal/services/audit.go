@@ -0,0 +1,71 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+
+	"finance_project/internal/models"
+)
+
+// AuditService читает журнал изменений. Сам журнал пишут триггеры базы данных (миграция 029),
+// поэтому в него попадают все изменения транзакций, счетов и целей, в том числе массовые и каскадные.
+type AuditService struct {
+	DB *sql.DB
+}
+
+// NewAuditService создает новый сервис журнала изменений.
+func NewAuditService(db *sql.DB) *AuditService {
+	return &AuditService{DB: db}
+}
+
+// GetHistory возвращает историю записи в порядке изменений. История удалённой записи тоже доступна
+// её владельцу, поэтому принадлежность проверяется по журналу, а не по самой записи.
+func (s *AuditService) GetHistory(entityType string, entityID, userID int) ([]models.AuditEntry, error) {
+	switch entityType {
+	case models.AuditEntityTransaction, models.AuditEntityAccount, models.AuditEntityFinancialGoal:
+	default:
+		return nil, fmt.Errorf("%w: unknown entity type %q", ErrInvalidInput, entityType)
+	}
+
+	query := `SELECT id, entity_type, entity_id, user_id, action, actor, before, after, created_at
+			  FROM audit_log
+			  WHERE entity_type = $1 AND entity_id = $2
+			  ORDER BY id`
+	rows, err := s.DB.Query(query, entityType, entityID)
+	if err != nil {
+		log.Printf("Error retrieving audit history: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var history []models.AuditEntry
+	for rows.Next() {
+		var entry models.AuditEntry
+		var before, after []byte
+		err := rows.Scan(&entry.ID, &entry.EntityType, &entry.EntityID, &entry.UserID, &entry.Action, &entry.Actor,
+			&before, &after, &entry.CreatedAt)
+		if err != nil {
+			log.Printf("Error scanning audit entry: %v", err)
+			return nil, err
+		}
+		entry.Before, entry.After = before, after
+		history = append(history, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(history) == 0 {
+		return nil, ErrNotFound
+	}
+	if history[0].UserID != userID {
+		return nil, ErrForbidden
+	}
+	return history, nil
+}
+
+// setAuditActor записывает изменения транзакции tx в журнал от имени actor вместо владельца записи.
+func setAuditActor(tx *sql.Tx, actor string) error {
+	_, err := tx.Exec(`SELECT set_config('finance.actor', $1, true)`, actor)
+	return err
+}
@@ -0,0 +1,241 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"finance_project/internal/config"
+	"finance_project/internal/models"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	accessTokenType  = "access"
+	refreshTokenType = "refresh"
+)
+
+// ErrInvalidToken возвращается для поддельных, просроченных или отозванных токенов.
+var ErrInvalidToken = errors.New("invalid token")
+
+func init() {
+	// Время выдачи хранится с точностью до микросекунд: иначе токен, выданный в ту же секунду,
+	// что и отзыв всех токенов пользователя (например, сразу после восстановления профиля), был бы отклонён.
+	jwt.TimePrecision = time.Microsecond
+}
+
+// tokenClaims — полезная нагрузка JWT.
+type tokenClaims struct {
+	TokenType string `json:"typ"`
+	jwt.RegisteredClaims
+}
+
+// AuthService выдаёт, проверяет и отзывает JWT-токены.
+// Активные refresh-токены и отозванные access-токены хранятся в Redis.
+type AuthService struct {
+	RedisClient     *redis.Client
+	secret          []byte
+	accessTokenTTL  time.Duration
+	refreshTokenTTL time.Duration
+}
+
+// NewAuthService создает новый сервис аутентификации.
+func NewAuthService(redisClient *redis.Client, cfg config.AuthConfig) *AuthService {
+	return &AuthService{
+		RedisClient:     redisClient,
+		secret:          []byte(cfg.JWTSecret),
+		accessTokenTTL:  cfg.AccessTokenTTL,
+		refreshTokenTTL: cfg.RefreshTokenTTL,
+	}
+}
+
+// IssueTokens выдает новую пару токенов пользователю.
+func (s *AuthService) IssueTokens(userID int) (*models.TokenPair, error) {
+	ctx := context.Background()
+
+	accessToken, _, err := s.sign(userID, accessTokenType, s.accessTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, refreshID, err := s.sign(userID, refreshTokenType, s.refreshTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	// Refresh-токен действителен, пока его идентификатор есть в Redis
+	if err := s.RedisClient.Set(ctx, refreshKey(refreshID), userID, s.refreshTokenTTL).Err(); err != nil {
+		return nil, fmt.Errorf("failed to store refresh token: %w", err)
+	}
+
+	return &models.TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(s.accessTokenTTL.Seconds()),
+	}, nil
+}
+
+// ParseAccessToken проверяет access-токен и возвращает ID пользователя.
+func (s *AuthService) ParseAccessToken(token string) (int, error) {
+	claims, err := s.parse(token, accessTokenType)
+	if err != nil {
+		return 0, err
+	}
+
+	revoked, err := s.RedisClient.Exists(context.Background(), revokedKey(claims.ID)).Result()
+	if err != nil {
+		return 0, err
+	}
+	if revoked > 0 {
+		return 0, ErrInvalidToken
+	}
+	if err := s.checkUserRevoked(claims); err != nil {
+		return 0, err
+	}
+
+	return strconv.Atoi(claims.Subject)
+}
+
+// Refresh обменивает refresh-токен на новую пару токенов. Старый refresh-токен отзывается.
+func (s *AuthService) Refresh(refreshToken string) (*models.TokenPair, error) {
+	claims, err := s.parse(refreshToken, refreshTokenType)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkUserRevoked(claims); err != nil {
+		return nil, err
+	}
+
+	deleted, err := s.RedisClient.Del(context.Background(), refreshKey(claims.ID)).Result()
+	if err != nil {
+		return nil, err
+	}
+	if deleted == 0 {
+		return nil, ErrInvalidToken
+	}
+
+	userID, err := strconv.Atoi(claims.Subject)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	return s.IssueTokens(userID)
+}
+
+// Logout отзывает access-токен до истечения его срока действия и удаляет refresh-токен.
+func (s *AuthService) Logout(accessToken, refreshToken string) error {
+	ctx := context.Background()
+
+	claims, err := s.parse(accessToken, accessTokenType)
+	if err != nil {
+		return err
+	}
+	if ttl := time.Until(claims.ExpiresAt.Time); ttl > 0 {
+		if err := s.RedisClient.Set(ctx, revokedKey(claims.ID), 1, ttl).Err(); err != nil {
+			return err
+		}
+	}
+
+	if refreshToken == "" {
+		return nil
+	}
+	refreshClaims, err := s.parse(refreshToken, refreshTokenType)
+	if err != nil {
+		return err
+	}
+	if refreshClaims.Subject != claims.Subject {
+		return ErrInvalidToken
+	}
+	return s.RedisClient.Del(ctx, refreshKey(refreshClaims.ID)).Err()
+}
+
+// RevokeUserTokens отзывает все access- и refresh-токены пользователя, выданные до этого момента,
+// например при удалении профиля. Токены, выданные позже, действуют.
+func (s *AuthService) RevokeUserTokens(userID int) error {
+	return s.RedisClient.Set(context.Background(), userRevokedKey(userID), time.Now().UnixMicro(), s.refreshTokenTTL).Err()
+}
+
+// checkUserRevoked отклоняет токен, выданный до отзыва всех токенов его пользователя.
+func (s *AuthService) checkUserRevoked(claims *tokenClaims) error {
+	userID, err := strconv.Atoi(claims.Subject)
+	if err != nil {
+		return ErrInvalidToken
+	}
+	revokedAt, err := s.RedisClient.Get(context.Background(), userRevokedKey(userID)).Int64()
+	if err == redis.Nil {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if claims.IssuedAt == nil || claims.IssuedAt.UnixMicro() <= revokedAt {
+		return ErrInvalidToken
+	}
+	return nil
+}
+
+// sign подписывает токен указанного типа и возвращает его вместе с идентификатором (jti).
+func (s *AuthService) sign(userID int, tokenType string, ttl time.Duration) (string, string, error) {
+	id, err := newTokenID()
+	if err != nil {
+		return "", "", err
+	}
+
+	now := time.Now()
+	claims := tokenClaims{
+		TokenType: tokenType,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        id,
+			Subject:   strconv.Itoa(userID),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	}
+
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.secret)
+	if err != nil {
+		return "", "", err
+	}
+	return signed, id, nil
+}
+
+// parse проверяет подпись, срок действия и тип токена.
+func (s *AuthService) parse(token, tokenType string) (*tokenClaims, error) {
+	var claims tokenClaims
+	_, err := jwt.ParseWithClaims(token, &claims, func(t *jwt.Token) (interface{}, error) {
+		return s.secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	if claims.TokenType != tokenType || claims.ID == "" {
+		return nil, ErrInvalidToken
+	}
+	return &claims, nil
+}
+
+func newTokenID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+func refreshKey(id string) string {
+	return "auth:refresh:" + id
+}
+
+func revokedKey(id string) string {
+	return "auth:revoked:" + id
+}
+
+func userRevokedKey(userID int) string {
+	return "auth:user-revoked:" + strconv.Itoa(userID)
+}
@@ -0,0 +1,28 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTokenIssuedAtKeepsMicroseconds(t *testing.T) {
+	s := &AuthService{secret: []byte("secret")}
+	before := time.Now().UnixMicro()
+	token, _, err := s.sign(1, accessTokenType, time.Minute)
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	after := time.Now().UnixMicro()
+
+	claims, err := s.parse(token, accessTokenType)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	// A revocation stored a moment before signing must not cover the token even within the same second.
+	if issued := claims.IssuedAt.UnixMicro(); issued < before || issued > after {
+		t.Errorf("issued at %d µs, want between %d and %d", issued, before, after)
+	}
+	if _, err := s.parse(token, refreshTokenType); err != ErrInvalidToken {
+		t.Errorf("parse as refresh token: err = %v, want ErrInvalidToken", err)
+	}
+}
@@ -0,0 +1,445 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"finance_project/internal/models"
+	"finance_project/internal/money"
+	"finance_project/internal/rrule"
+
+	"github.com/lib/pq"
+)
+
+const budgetColumns = `b.id, b.user_id, b.category_id, b.amount, b.currency, b.period, b.rollover, b.thresholds,
+	b.start_date, b.created_at`
+
+// budgetTruncUnits сопоставляет период бюджета единице date_trunc.
+var budgetTruncUnits = map[string]string{
+	models.BudgetWeekly:    "week",
+	models.BudgetMonthly:   "month",
+	models.BudgetQuarterly: "quarter",
+	models.BudgetYearly:    "year",
+}
+
+// BudgetService управляет бюджетами по категориям и уведомлениями о превышении порогов.
+type BudgetService struct {
+	DB      *sql.DB
+	Reports *ReportsService
+	// Thresholds — пороги уведомлений по умолчанию для новых бюджетов, в процентах.
+	Thresholds []int
+}
+
+// NewBudgetService создает новый сервис бюджетов.
+func NewBudgetService(db *sql.DB, reports *ReportsService, thresholds []int) *BudgetService {
+	return &BudgetService{DB: db, Reports: reports, Thresholds: thresholds}
+}
+
+// CreateBudget добавляет бюджет. Валюта по умолчанию — предпочитаемая валюта пользователя.
+func (s *BudgetService) CreateBudget(budget *models.Budget) (int, error) {
+	if err := s.validate(budget); err != nil {
+		return 0, err
+	}
+
+	query := `INSERT INTO budgets (user_id, category_id, amount, currency, period, rollover, thresholds, start_date)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			  RETURNING id`
+	err := s.DB.QueryRow(query, budget.UserID, budget.CategoryID, budget.Amount, budget.Currency, budget.Period,
+		budget.Rollover, pq.Array(budget.Thresholds), budget.StartDate).Scan(&budget.ID)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		return 0, fmt.Errorf("%w: a %s budget for this category already exists", ErrInvalidInput, budget.Period)
+	}
+	if err != nil {
+		log.Printf("Error creating budget: %v", err)
+		return 0, err
+	}
+	return budget.ID, nil
+}
+
+// GetBudgets возвращает все бюджеты пользователя.
+func (s *BudgetService) GetBudgets(userID int) ([]models.Budget, error) {
+	rows, err := s.DB.Query(`SELECT `+budgetColumns+` FROM budgets b WHERE b.user_id = $1 ORDER BY b.id`, userID)
+	if err != nil {
+		log.Printf("Error retrieving budgets: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var budgets []models.Budget
+	for rows.Next() {
+		budget, err := scanBudget(rows)
+		if err != nil {
+			log.Printf("Error scanning budget: %v", err)
+			return nil, err
+		}
+		budgets = append(budgets, budget)
+	}
+	return budgets, rows.Err()
+}
+
+// GetBudgetByID возвращает бюджет пользователя по ID.
+func (s *BudgetService) GetBudgetByID(id, userID int) (*models.Budget, error) {
+	if err := checkOwnership(s.DB, "budgets", id, userID); err != nil {
+		return nil, err
+	}
+	budget, err := scanBudget(s.DB.QueryRow(`SELECT `+budgetColumns+` FROM budgets b WHERE b.id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		log.Printf("Error retrieving budget: %v", err)
+		return nil, err
+	}
+	return &budget, nil
+}
+
+// UpdateBudget изменяет бюджет. Новый лимит применяется ко всем периодам, включая перенос остатков.
+func (s *BudgetService) UpdateBudget(budget *models.Budget) error {
+	if err := checkOwnership(s.DB, "budgets", budget.ID, budget.UserID); err != nil {
+		return err
+	}
+	if err := s.validate(budget); err != nil {
+		return err
+	}
+
+	query := `UPDATE budgets
+			  SET category_id = $1, amount = $2, currency = $3, period = $4, rollover = $5, thresholds = $6, start_date = $7
+			  WHERE id = $8`
+	_, err := s.DB.Exec(query, budget.CategoryID, budget.Amount, budget.Currency, budget.Period, budget.Rollover,
+		pq.Array(budget.Thresholds), budget.StartDate, budget.ID)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		return fmt.Errorf("%w: a %s budget for this category already exists", ErrInvalidInput, budget.Period)
+	}
+	if err != nil {
+		log.Printf("Error updating budget: %v", err)
+	}
+	return err
+}
+
+// DeleteBudget удаляет бюджет вместе с его уведомлениями.
+func (s *BudgetService) DeleteBudget(id, userID int) error {
+	if err := checkOwnership(s.DB, "budgets", id, userID); err != nil {
+		return err
+	}
+	_, err := s.DB.Exec(`DELETE FROM budgets WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		log.Printf("Error deleting budget: %v", err)
+	}
+	return err
+}
+
+// GetStatus возвращает исполнение бюджетов пользователя в периоде, содержащем date,
+// и создаёт уведомления о достигнутых порогах.
+func (s *BudgetService) GetStatus(userID int, date time.Time) ([]models.BudgetStatus, error) {
+	statuses, _, err := s.evaluate(userID, rrule.Date(date))
+	return statuses, err
+}
+
+// CheckAlerts пересчитывает бюджеты всех пользователей на дату now и возвращает число новых уведомлений.
+func (s *BudgetService) CheckAlerts(now time.Time) (int, error) {
+	rows, err := s.DB.Query(`SELECT DISTINCT user_id FROM budgets`)
+	if err != nil {
+		return 0, err
+	}
+	var userIDs []int
+	for rows.Next() {
+		var userID int
+		if err := rows.Scan(&userID); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for _, userID := range userIDs {
+		_, raised, err := s.evaluate(userID, rrule.Date(now))
+		if err != nil {
+			// Ошибка одного пользователя (например, нет курса валюты) не должна останавливать остальных
+			log.Printf("Error checking budgets of user %d: %v", userID, err)
+			continue
+		}
+		total += raised
+	}
+	return total, nil
+}
+
+// GetAlerts возвращает уведомления по бюджетам пользователя, новые первыми.
+func (s *BudgetService) GetAlerts(userID int, unreadOnly bool) ([]models.BudgetAlert, error) {
+	query := `SELECT a.id, a.budget_id, b.category_id, a.period_start, a.threshold, a.spent, a.available, b.currency,
+			         a.read, a.created_at
+			  FROM budget_alerts a
+			  JOIN budgets b ON b.id = a.budget_id
+			  WHERE b.user_id = $1 AND (NOT $2 OR NOT a.read)
+			  ORDER BY a.created_at DESC, a.id DESC`
+	rows, err := s.DB.Query(query, userID, unreadOnly)
+	if err != nil {
+		log.Printf("Error retrieving budget alerts: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var alerts []models.BudgetAlert
+	for rows.Next() {
+		var alert models.BudgetAlert
+		err := rows.Scan(&alert.ID, &alert.BudgetID, &alert.CategoryID, &alert.PeriodStart, &alert.Threshold,
+			&alert.Spent, &alert.Available, &alert.Currency, &alert.Read, &alert.CreatedAt)
+		if err != nil {
+			log.Printf("Error scanning budget alert: %v", err)
+			return nil, err
+		}
+		alert.PeriodStart = rrule.Date(alert.PeriodStart)
+		alerts = append(alerts, alert)
+	}
+	return alerts, rows.Err()
+}
+
+// MarkAlertRead отмечает уведомление прочитанным.
+func (s *BudgetService) MarkAlertRead(id, userID int) error {
+	result, err := s.DB.Exec(`UPDATE budget_alerts a SET read = TRUE
+							  FROM budgets b
+							  WHERE a.id = $1 AND b.id = a.budget_id AND b.user_id = $2`, id, userID)
+	if err != nil {
+		log.Printf("Error marking budget alert as read: %v", err)
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// evaluate рассчитывает исполнение всех бюджетов пользователя на дату и создаёт уведомления.
+// Возвращает также число созданных уведомлений.
+func (s *BudgetService) evaluate(userID int, date time.Time) ([]models.BudgetStatus, int, error) {
+	query := `SELECT ` + budgetColumns + `, c.name
+			  FROM budgets b
+			  JOIN categories c ON c.id = b.category_id
+			  WHERE b.user_id = $1 AND c.trash_id IS NULL
+			  ORDER BY b.id`
+	rows, err := s.DB.Query(query, userID)
+	if err != nil {
+		log.Printf("Error retrieving budgets: %v", err)
+		return nil, 0, err
+	}
+	var budgets []models.Budget
+	var names []string
+	for rows.Next() {
+		var name string
+		budget, err := scanBudget(rows, &name)
+		if err != nil {
+			rows.Close()
+			return nil, 0, err
+		}
+		budgets = append(budgets, budget)
+		names = append(names, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	converter := s.Reports.Rates.NewConverter()
+	statuses := []models.BudgetStatus{}
+	raised := 0
+	for i, budget := range budgets {
+		status, err := s.budgetStatus(converter, budget, date)
+		if err != nil {
+			log.Printf("Error calculating budget %d: %v", budget.ID, err)
+			return nil, 0, err
+		}
+		status.Category = names[i]
+		n, err := s.raiseAlerts(budget, status)
+		if err != nil {
+			log.Printf("Error creating budget alerts: %v", err)
+			return nil, 0, err
+		}
+		statuses = append(statuses, status)
+		raised += n
+	}
+	return statuses, raised, nil
+}
+
+// budgetStatus считает расходы категории вместе с её подкатегориями в валюте бюджета по курсу
+// на дату каждой транзакции.
+// При rollover учитываются все периоды начиная с start_date: неизрасходованный остаток переходит
+// в следующий период, перерасход не переносится.
+func (s *BudgetService) budgetStatus(converter *Converter, budget models.Budget, date time.Time) (models.BudgetStatus, error) {
+	current := periodStart(budget.Period, date)
+	from := current
+	if budget.Rollover && budget.StartDate.Before(current) {
+		from = budget.StartDate
+	}
+
+	spent, err := s.Reports.sumExpenses(converter, budget.Currency,
+		"to_char(date_trunc($5, t.created_at), 'YYYY-MM-DD')",
+		`t.user_id = $1 AND t.created_at >= $3 AND t.created_at < $4 AND t.category_id IN (
+			WITH RECURSIVE budget_categories AS (
+				SELECT id FROM categories WHERE user_id = $1 AND id = $2
+				UNION
+				SELECT c.id FROM categories c JOIN budget_categories b ON c.parent_id = b.id
+			)
+			SELECT id FROM budget_categories)`,
+		budget.UserID, budget.CategoryID, from, nextPeriod(budget.Period, current), budgetTruncUnits[budget.Period])
+	if err != nil {
+		return models.BudgetStatus{}, err
+	}
+
+	var carried money.Amount
+	for p := from; p.Before(current); p = nextPeriod(budget.Period, p) {
+		left := budget.Amount + carried - spent[p.Format(rateDateLayout)]
+		if left < 0 {
+			left = 0
+		}
+		carried = left
+	}
+
+	status := models.BudgetStatus{
+		BudgetID:    budget.ID,
+		CategoryID:  budget.CategoryID,
+		Period:      budget.Period,
+		Currency:    budget.Currency,
+		PeriodStart: current,
+		PeriodEnd:   nextPeriod(budget.Period, current).AddDate(0, 0, -1),
+		Amount:      budget.Amount,
+		CarriedOver: carried,
+		Available:   budget.Amount + carried,
+		Spent:       spent[current.Format(rateDateLayout)],
+	}
+	status.Remaining = status.Available - status.Spent
+	status.PercentUsed = math.Round(status.Spent.Ratio(status.Available)*10000) / 100
+	return status, nil
+}
+
+// raiseAlerts создаёт уведомления для достигнутых порогов, о которых в этом периоде ещё не сообщалось.
+func (s *BudgetService) raiseAlerts(budget models.Budget, status models.BudgetStatus) (int, error) {
+	raised := 0
+	for _, threshold := range budget.Thresholds {
+		if status.PercentUsed < float64(threshold) {
+			break
+		}
+		result, err := s.DB.Exec(`INSERT INTO budget_alerts (budget_id, period_start, threshold, spent, available)
+								  VALUES ($1, $2, $3, $4, $5)
+								  ON CONFLICT (budget_id, period_start, threshold) DO NOTHING`,
+			budget.ID, status.PeriodStart, threshold, status.Spent, status.Available)
+		if err != nil {
+			return raised, err
+		}
+		if n, err := result.RowsAffected(); err == nil {
+			raised += int(n)
+		}
+	}
+	return raised, nil
+}
+
+// validate проверяет бюджет и подставляет значения по умолчанию.
+func (s *BudgetService) validate(budget *models.Budget) error {
+	if !budget.Amount.IsPositive() {
+		return fmt.Errorf("%w: amount must be positive", ErrInvalidInput)
+	}
+	if budget.Period == "" {
+		budget.Period = models.BudgetMonthly
+	}
+	if _, ok := budgetTruncUnits[budget.Period]; !ok {
+		return fmt.Errorf("%w: period must be weekly, monthly, quarterly or yearly", ErrInvalidInput)
+	}
+
+	if budget.Currency == "" {
+		currency, err := s.Reports.Rates.PreferredCurrency(budget.UserID)
+		if err != nil {
+			return err
+		}
+		budget.Currency = currency
+	}
+	currency, err := normalizeCurrency(budget.Currency)
+	if err != nil {
+		return err
+	}
+	budget.Currency = currency
+	if err := money.CheckPrecision(budget.Amount, budget.Currency); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+
+	if budget.Thresholds == nil {
+		budget.Thresholds = append([]int(nil), s.Thresholds...)
+	}
+	thresholds, err := normalizeThresholds(budget.Thresholds)
+	if err != nil {
+		return err
+	}
+	budget.Thresholds = thresholds
+
+	if budget.StartDate.IsZero() {
+		budget.StartDate = today()
+	}
+	budget.StartDate = periodStart(budget.Period, rrule.Date(budget.StartDate))
+
+	return checkOwnership(s.DB, "categories", budget.CategoryID, budget.UserID)
+}
+
+// normalizeThresholds проверяет пороги и возвращает их по возрастанию без повторов.
+func normalizeThresholds(thresholds []int) ([]int, error) {
+	sorted := append([]int(nil), thresholds...)
+	sort.Ints(sorted)
+	result := []int{}
+	for _, t := range sorted {
+		if t <= 0 || t > 1000 {
+			return nil, fmt.Errorf("%w: thresholds must be percentages between 1 and 1000", ErrInvalidInput)
+		}
+		if len(result) == 0 || result[len(result)-1] != t {
+			result = append(result, t)
+		}
+	}
+	return result, nil
+}
+
+// periodStart возвращает первый день периода бюджета, содержащего date; неделя начинается с понедельника.
+func periodStart(period string, date time.Time) time.Time {
+	year, month, day := date.Date()
+	switch period {
+	case models.BudgetWeekly:
+		return time.Date(year, month, day-(int(date.Weekday())+6)%7, 0, 0, 0, 0, time.UTC)
+	case models.BudgetQuarterly:
+		return time.Date(year, (month-1)/3*3+1, 1, 0, 0, 0, 0, time.UTC)
+	case models.BudgetYearly:
+		return time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+}
+
+// nextPeriod возвращает начало периода, следующего за периодом, начинающимся в start.
+func nextPeriod(period string, start time.Time) time.Time {
+	switch period {
+	case models.BudgetWeekly:
+		return start.AddDate(0, 0, 7)
+	case models.BudgetQuarterly:
+		return start.AddDate(0, 3, 0)
+	case models.BudgetYearly:
+		return start.AddDate(1, 0, 0)
+	}
+	return start.AddDate(0, 1, 0)
+}
+
+func scanBudget(row rowScanner, extra ...interface{}) (models.Budget, error) {
+	var budget models.Budget
+	var thresholds pq.Int64Array
+	dest := []interface{}{&budget.ID, &budget.UserID, &budget.CategoryID, &budget.Amount, &budget.Currency,
+		&budget.Period, &budget.Rollover, &thresholds, &budget.StartDate, &budget.CreatedAt}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return budget, err
+	}
+	budget.Thresholds = make([]int, len(thresholds))
+	for i, t := range thresholds {
+		budget.Thresholds[i] = int(t)
+	}
+	budget.StartDate = rrule.Date(budget.StartDate)
+	return budget, nil
+}
@@ -1,31 +1,31 @@
 package services
 
 import (
-	"context"
 	"database/sql"
-	"encoding/json"
+	"fmt"
 	"log"
-	"strconv"
 
 	"finance_project/internal/models"
 
-	"github.com/go-redis/redis/v8"
+	"github.com/lib/pq"
 )
 
+const categoryColumns = `id, user_id, COALESCE(parent_id, 0), name, type, created_at`
+
 type CategoryService struct {
-	DB          *sql.DB
-	RedisClient *redis.Client // Убедитесь, что это поле существует
+	DB           *sql.DB
+	Transactions *TransactionService
 }
 
 // NewCategoryService создает новый сервис для работы с категориями.
-func NewCategoryService(db *sql.DB) *CategoryService {
-	return &CategoryService{DB: db}
+func NewCategoryService(db *sql.DB, transactions *TransactionService) *CategoryService {
+	return &CategoryService{DB: db, Transactions: transactions}
 }
 
-// GetAllCategories возвращает все категории.
-func (s *CategoryService) GetAllCategories() ([]models.Category, error) {
-	query := `SELECT id, user_id, name, type, created_at FROM categories`
-	rows, err := s.DB.Query(query)
+// GetAllCategories возвращает все категории пользователя.
+func (s *CategoryService) GetAllCategories(userID int) ([]models.Category, error) {
+	query := `SELECT ` + categoryColumns + ` FROM categories WHERE user_id = $1 AND trash_id IS NULL`
+	rows, err := s.DB.Query(query, userID)
 	if err != nil {
 		log.Printf("Error retrieving categories: %v", err)
 		return nil, err
@@ -35,7 +35,7 @@ func (s *CategoryService) GetAllCategories() ([]models.Category, error) {
 	var categories []models.Category
 	for rows.Next() {
 		var c models.Category
-		err := rows.Scan(&c.ID, &c.UserID, &c.Name, &c.Type, &c.CreatedAt)
+		err := rows.Scan(&c.ID, &c.UserID, &c.ParentID, &c.Name, &c.Type, &c.CreatedAt)
 		if err != nil {
 			log.Printf("Error scanning category row: %v", err)
 			return nil, err
@@ -48,8 +48,12 @@ func (s *CategoryService) GetAllCategories() ([]models.Category, error) {
 
 // CreateCategory добавляет новую категорию.
 func (s *CategoryService) CreateCategory(category models.Category) error {
-	query := `INSERT INTO categories (user_id, name, type, created_at) VALUES ($1, $2, $3, NOW())`
-	_, err := s.DB.Exec(query, category.UserID, category.Name, category.Type)
+	if err := checkParentCategory(s.DB, category); err != nil {
+		return err
+	}
+
+	query := `INSERT INTO categories (user_id, parent_id, name, type, created_at) VALUES ($1, NULLIF($2, 0), $3, $4, NOW())`
+	_, err := s.DB.Exec(query, category.UserID, category.ParentID, category.Name, category.Type)
 	if err != nil {
 		log.Printf("Error creating category: %v", err)
 		return err
@@ -57,13 +61,13 @@ func (s *CategoryService) CreateCategory(category models.Category) error {
 	return nil
 }
 
-// GetCategoryByID возвращает категорию по ID.
+// GetCategoryByID возвращает категорию по ID. Категории в корзине не находятся.
 func (s *CategoryService) GetCategoryByID(id int) (*models.Category, error) {
-	query := `SELECT id, user_id, name, type, created_at FROM categories WHERE id = $1`
+	query := `SELECT ` + categoryColumns + ` FROM categories WHERE id = $1 AND trash_id IS NULL`
 	row := s.DB.QueryRow(query, id)
 
 	var c models.Category
-	err := row.Scan(&c.ID, &c.UserID, &c.Name, &c.Type, &c.CreatedAt)
+	err := row.Scan(&c.ID, &c.UserID, &c.ParentID, &c.Name, &c.Type, &c.CreatedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			log.Printf("Category with ID %d not found", id)
@@ -75,10 +79,24 @@ func (s *CategoryService) GetCategoryByID(id int) (*models.Category, error) {
 	return &c, nil
 }
 
-// UpdateCategory обновляет категорию.
+// UpdateCategory обновляет категорию пользователя, включая её место в иерархии.
 func (s *CategoryService) UpdateCategory(category models.Category) error {
-	query := `UPDATE categories SET name = $1, type = $2 WHERE id = $3`
-	_, err := s.DB.Exec(query, category.Name, category.Type, category.ID)
+	if err := checkParentCategory(s.DB, category); err != nil {
+		return err
+	}
+	var mismatched bool
+	err := s.DB.QueryRow(`SELECT EXISTS (SELECT 1 FROM categories WHERE parent_id = $1 AND type <> $2 AND trash_id IS NULL)`,
+		category.ID, category.Type).Scan(&mismatched)
+	if err != nil {
+		log.Printf("Error checking subcategories: %v", err)
+		return err
+	}
+	if mismatched {
+		return fmt.Errorf("%w: category %d has subcategories of another type", ErrInvalidInput, category.ID)
+	}
+
+	query := `UPDATE categories SET name = $1, type = $2, parent_id = NULLIF($3, 0) WHERE id = $4 AND user_id = $5 AND trash_id IS NULL`
+	_, err = s.DB.Exec(query, category.Name, category.Type, category.ParentID, category.ID, category.UserID)
 	if err != nil {
 		log.Printf("Error updating category: %v", err)
 		return err
@@ -86,93 +104,197 @@ func (s *CategoryService) UpdateCategory(category models.Category) error {
 	return nil
 }
 
-// DeleteCategory удаляет категорию по ID.
-func (s *CategoryService) DeleteCategory(id int) error {
-	query := `DELETE FROM categories WHERE id = $1`
-	_, err := s.DB.Exec(query, id)
+// MoveCategory переносит категорию вместе с подкатегориями под другого родителя;
+// parentID = 0 делает её категорией верхнего уровня. Транзакции остаются в своих категориях
+// и в отчётах суммируются уже под новым родителем.
+func (s *CategoryService) MoveCategory(id, userID, parentID int) error {
+	if err := checkOwnership(s.DB, "categories", id, userID); err != nil {
+		return err
+	}
+	category, err := s.GetCategoryByID(id)
 	if err != nil {
-		log.Printf("Error deleting category: %v", err)
+		return err
+	}
+	category.ParentID = parentID
+	if err := checkParentCategory(s.DB, *category); err != nil {
+		return err
+	}
+
+	if _, err := s.DB.Exec(`UPDATE categories SET parent_id = NULLIF($1, 0) WHERE id = $2`, parentID, id); err != nil {
+		log.Printf("Error moving category: %v", err)
 		return err
 	}
 	return nil
 }
 
-// GetTransactionsByCategory retrieves transactions by category, with optional caching
-func (s *CategoryService) GetTransactionsByCategory(categoryID int) ([]models.Transaction, error) {
-	ctx := context.Background()
-	cacheKey := "transactions:category:" + strconv.Itoa(categoryID)
-
-	// Check Redis cache
-	cachedData, err := s.RedisClient.Get(ctx, cacheKey).Result()
-	if err == nil {
-		var transactions []models.Transaction
-		if json.Unmarshal([]byte(cachedData), &transactions) == nil {
-			return transactions, nil
-		}
+// MergeCategory сливает категорию в категорию targetID того же типа и удаляет её. Транзакции,
+// части разбитых транзакций, плановые транзакции, правила и ответы на подсказки переходят
+// к targetID, подкатегории — тоже. Бюджет и привязка к конверту переходят, только если
+// у targetID их ещё нет; иначе они удаляются вместе с категорией.
+func (s *CategoryService) MergeCategory(id, userID, targetID int) error {
+	if id == targetID {
+		return fmt.Errorf("%w: cannot merge a category into itself", ErrInvalidInput)
 	}
 
-	// Fetch from DB if cache missed
-	query := `SELECT id, user_id, account_id, amount, type, category_id, currency, description, created_at 
-			  FROM transactions WHERE category_id = $1`
-	rows, err := s.DB.Query(query, categoryID)
+	tx, err := s.DB.Begin()
 	if err != nil {
-		return nil, err
+		return err
 	}
-	defer rows.Close()
+	defer tx.Rollback()
 
-	var transactions []models.Transaction
-	for rows.Next() {
-		var t models.Transaction
-		if err := rows.Scan(&t.ID, &t.UserID, &t.AccountID, &t.Amount, &t.Type, &t.CategoryID, &t.Currency, &t.Description, &t.CreatedAt); err != nil {
-			return nil, err
-		}
-		transactions = append(transactions, t)
+	if err := checkOwnership(tx, "categories", id, userID); err != nil {
+		return err
+	}
+	if err := checkOwnership(tx, "categories", targetID, userID); err != nil {
+		return err
+	}
+	var sameType bool
+	err = tx.QueryRow(`SELECT s.type = t.type FROM categories s, categories t WHERE s.id = $1 AND t.id = $2 FOR UPDATE`,
+		id, targetID).Scan(&sameType)
+	if err != nil {
+		log.Printf("Error retrieving categories to merge: %v", err)
+		return err
+	}
+	if !sameType {
+		return fmt.Errorf("%w: categories %d and %d have different types", ErrInvalidInput, id, targetID)
+	}
+	descendant, err := isCategoryDescendant(tx, targetID, id)
+	if err != nil {
+		return err
+	}
+	if descendant {
+		return fmt.Errorf("%w: cannot merge category %d into its own subcategory", ErrInvalidInput, id)
 	}
 
-	// Cache results in Redis
-	if data, err := json.Marshal(transactions); err == nil {
-		s.RedisClient.Set(ctx, cacheKey, data, 0)
+	statements := []string{
+		`UPDATE transactions SET category_id = $2 WHERE category_id = $1`,
+		`UPDATE transaction_splits SET category_id = $2 WHERE category_id = $1`,
+		`UPDATE scheduled_transactions SET category_id = $2 WHERE category_id = $1`,
+		`UPDATE category_rules SET category_id = $2 WHERE category_id = $1`,
+		`UPDATE category_feedback SET category_id = $2 WHERE category_id = $1`,
+		`UPDATE category_feedback SET suggested_category_id = $2 WHERE suggested_category_id = $1`,
+		`UPDATE budgets b SET category_id = $2
+		 WHERE b.category_id = $1 AND NOT EXISTS (SELECT 1 FROM budgets o WHERE o.category_id = $2 AND o.period = b.period)`,
+		`UPDATE envelope_categories SET category_id = $2
+		 WHERE category_id = $1 AND NOT EXISTS (SELECT 1 FROM envelope_categories WHERE category_id = $2)`,
+		`UPDATE categories SET parent_id = $2 WHERE parent_id = $1`,
+		`DELETE FROM categories WHERE id = $1`,
+	}
+	for _, statement := range statements {
+		if _, err := tx.Exec(statement, id, targetID); err != nil {
+			log.Printf("Error merging category %d into %d: %v", id, targetID, err)
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
 	}
 
-	return transactions, nil
+	s.Transactions.invalidateCache(userID)
+	return nil
 }
 
-// GetTransactionsByAccount retrieves transactions by account, with optional caching
-func (s *CategoryService) GetTransactionsByAccount(accountID int) ([]models.Transaction, error) {
-	ctx := context.Background()
-	cacheKey := "transactions:account:" + strconv.Itoa(accountID)
-
-	// Check Redis cache
-	cachedData, err := s.RedisClient.Get(ctx, cacheKey).Result()
-	if err == nil {
-		var transactions []models.Transaction
-		if json.Unmarshal([]byte(cachedData), &transactions) == nil {
-			return transactions, nil
-		}
+// DeleteCategory переносит категорию пользователя в корзину вместе с подкатегориями и транзакциями,
+// у которых есть категория или часть разбивки из них. Чтобы сохранить транзакции, категорию
+// нужно сначала слить с другой через MergeCategory.
+func (s *CategoryService) DeleteCategory(id, userID int) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
 	}
+	defer tx.Rollback()
 
-	// Fetch from DB if cache missed
-	query := `SELECT id, user_id, account_id, amount, type, category_id, currency, description, created_at 
-			  FROM transactions WHERE account_id = $1`
-	rows, err := s.DB.Query(query, accountID)
-	if err != nil {
-		return nil, err
+	var ownerID int
+	var name string
+	err = tx.QueryRow(`SELECT user_id, name FROM categories WHERE id = $1 AND trash_id IS NULL FOR UPDATE`, id).Scan(&ownerID, &name)
+	if err == sql.ErrNoRows {
+		return ErrNotFound
+	} else if err != nil {
+		return err
+	}
+	if ownerID != userID {
+		return ErrForbidden
 	}
-	defer rows.Close()
 
-	var transactions []models.Transaction
-	for rows.Next() {
-		var t models.Transaction
-		if err := rows.Scan(&t.ID, &t.UserID, &t.AccountID, &t.Amount, &t.Type, &t.CategoryID, &t.Currency, &t.Description, &t.CreatedAt); err != nil {
-			return nil, err
-		}
-		transactions = append(transactions, t)
+	trashID, err := createTrashEntryTx(tx, userID, models.TrashEntityCategory, id, name)
+	if err != nil {
+		return err
+	}
+	subtree := `WITH RECURSIVE subtree AS (
+					SELECT id FROM categories WHERE id = $1
+					UNION
+					SELECT c.id FROM categories c JOIN subtree s ON c.parent_id = s.id WHERE c.trash_id IS NULL
+				)`
+	var transactions []int64
+	err = tx.QueryRow(subtree+`
+		SELECT ARRAY(SELECT t.id FROM transactions t
+					 WHERE t.user_id = $2 AND t.trash_id IS NULL
+					   AND (t.category_id IN (SELECT id FROM subtree)
+					        OR t.id IN (SELECT transaction_id FROM transaction_splits WHERE category_id IN (SELECT id FROM subtree))))`,
+		id, userID).Scan(pq.Array(&transactions))
+	if err != nil {
+		log.Printf("Error deleting category: %v", err)
+		return err
 	}
+	if err := trashTransactionsTx(tx, trashID, userID, transactions); err != nil {
+		log.Printf("Error deleting category: %v", err)
+		return err
+	}
+	if _, err := tx.Exec(subtree+` UPDATE categories SET trash_id = $2 WHERE id IN (SELECT id FROM subtree)`, id, trashID); err != nil {
+		log.Printf("Error deleting category: %v", err)
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	s.Transactions.invalidateCache(userID)
+	return nil
+}
 
-	// Cache results in Redis
-	if data, err := json.Marshal(transactions); err == nil {
-		s.RedisClient.Set(ctx, cacheKey, data, 0)
+// checkParentCategory проверяет, что родитель категории принадлежит тому же пользователю,
+// имеет тот же тип и не является самой категорией или её подкатегорией.
+func checkParentCategory(q queryRower, category models.Category) error {
+	if category.ParentID == 0 {
+		return nil
+	}
+	if err := checkOwnership(q, "categories", category.ParentID, category.UserID); err != nil {
+		return err
+	}
+	var parentType string
+	if err := q.QueryRow(`SELECT type FROM categories WHERE id = $1`, category.ParentID).Scan(&parentType); err != nil {
+		log.Printf("Error retrieving parent category: %v", err)
+		return err
+	}
+	if parentType != category.Type {
+		return fmt.Errorf("%w: parent category %d is not of type %s", ErrInvalidInput, category.ParentID, category.Type)
+	}
+	if category.ID == 0 {
+		return nil
+	}
+	descendant, err := isCategoryDescendant(q, category.ParentID, category.ID)
+	if err != nil {
+		return err
+	}
+	if descendant {
+		return fmt.Errorf("%w: category %d cannot be placed under itself or its subcategory", ErrInvalidInput, category.ID)
 	}
+	return nil
+}
 
-	return transactions, nil
+// isCategoryDescendant сообщает, является ли категория id самой ancestorID или её потомком.
+// UNION вместо UNION ALL останавливает обход, даже если в данных уже есть цикл.
+func isCategoryDescendant(q queryRower, id, ancestorID int) (bool, error) {
+	query := `WITH RECURSIVE ancestors AS (
+				  SELECT id, parent_id FROM categories WHERE id = $1
+				  UNION
+				  SELECT c.id, c.parent_id FROM categories c JOIN ancestors a ON c.id = a.parent_id
+			  )
+			  SELECT EXISTS (SELECT 1 FROM ancestors WHERE id = $2)`
+	var descendant bool
+	if err := q.QueryRow(query, id, ancestorID).Scan(&descendant); err != nil {
+		log.Printf("Error checking category hierarchy: %v", err)
+		return false, err
+	}
+	return descendant, nil
 }
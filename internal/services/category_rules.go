@@ -0,0 +1,324 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"finance_project/internal/models"
+	"finance_project/internal/rrule"
+
+	"github.com/lib/pq"
+)
+
+const categoryRuleColumns = `id, user_id, name, category_id, priority, description_contains, description_pattern, type,
+	COALESCE(account_id, 0), min_amount, max_amount, disabled, created_at`
+
+// CategoryRuleService управляет правилами автоматической категоризации и применяет их к транзакциям.
+type CategoryRuleService struct {
+	DB           *sql.DB
+	Transactions *TransactionService
+}
+
+// NewCategoryRuleService создает новый сервис правил категоризации.
+func NewCategoryRuleService(db *sql.DB, transactions *TransactionService) *CategoryRuleService {
+	return &CategoryRuleService{DB: db, Transactions: transactions}
+}
+
+// categoryRule — правило с заранее подготовленными условиями по описанию.
+type categoryRule struct {
+	models.CategoryRule
+	contains string
+	pattern  *regexp.Regexp
+}
+
+// CreateRule добавляет правило категоризации.
+func (s *CategoryRuleService) CreateRule(rule *models.CategoryRule) (int, error) {
+	if err := s.validate(rule); err != nil {
+		return 0, err
+	}
+
+	query := `INSERT INTO category_rules (user_id, name, category_id, priority, description_contains, description_pattern,
+			  type, account_id, min_amount, max_amount, disabled)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, 0), $9, $10, $11)
+			  RETURNING id`
+	err := s.DB.QueryRow(query, rule.UserID, rule.Name, rule.CategoryID, rule.Priority, rule.DescriptionContains,
+		rule.DescriptionPattern, rule.Type, rule.AccountID, rule.MinAmount, rule.MaxAmount, rule.Disabled).Scan(&rule.ID)
+	if err != nil {
+		log.Printf("Error creating category rule: %v", err)
+		return 0, err
+	}
+	return rule.ID, nil
+}
+
+// GetRules возвращает правила пользователя в порядке применения.
+func (s *CategoryRuleService) GetRules(userID int) ([]models.CategoryRule, error) {
+	rows, err := s.DB.Query(`SELECT `+categoryRuleColumns+` FROM category_rules WHERE user_id = $1 ORDER BY priority, id`, userID)
+	if err != nil {
+		log.Printf("Error retrieving category rules: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := []models.CategoryRule{}
+	for rows.Next() {
+		rule, err := scanCategoryRule(rows)
+		if err != nil {
+			log.Printf("Error scanning category rule: %v", err)
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+// GetRuleByID возвращает правило пользователя по ID.
+func (s *CategoryRuleService) GetRuleByID(id, userID int) (*models.CategoryRule, error) {
+	if err := checkOwnership(s.DB, "category_rules", id, userID); err != nil {
+		return nil, err
+	}
+	rule, err := scanCategoryRule(s.DB.QueryRow(`SELECT `+categoryRuleColumns+` FROM category_rules WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		log.Printf("Error retrieving category rule: %v", err)
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// UpdateRule изменяет правило. Уже категоризированные транзакции не меняются, для этого есть ApplyRules.
+func (s *CategoryRuleService) UpdateRule(rule *models.CategoryRule) error {
+	if err := checkOwnership(s.DB, "category_rules", rule.ID, rule.UserID); err != nil {
+		return err
+	}
+	if err := s.validate(rule); err != nil {
+		return err
+	}
+
+	query := `UPDATE category_rules
+			  SET name = $1, category_id = $2, priority = $3, description_contains = $4, description_pattern = $5,
+			      type = $6, account_id = NULLIF($7, 0), min_amount = $8, max_amount = $9, disabled = $10
+			  WHERE id = $11`
+	_, err := s.DB.Exec(query, rule.Name, rule.CategoryID, rule.Priority, rule.DescriptionContains, rule.DescriptionPattern,
+		rule.Type, rule.AccountID, rule.MinAmount, rule.MaxAmount, rule.Disabled, rule.ID)
+	if err != nil {
+		log.Printf("Error updating category rule: %v", err)
+	}
+	return err
+}
+
+// DeleteRule удаляет правило; категории транзакций остаются прежними.
+func (s *CategoryRuleService) DeleteRule(id, userID int) error {
+	if err := checkOwnership(s.DB, "category_rules", id, userID); err != nil {
+		return err
+	}
+	_, err := s.DB.Exec(`DELETE FROM category_rules WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		log.Printf("Error deleting category rule: %v", err)
+	}
+	return err
+}
+
+// ApplyRules повторно применяет правила к доходам и расходам пользователя за период.
+// Транзакции, для которых не сработало ни одно правило, и транзакции с разбивкой не меняются.
+// При DryRun возвращается только список изменений.
+func (s *CategoryRuleService) ApplyRules(userID int, request models.CategoryRuleApplyRequest) (*models.CategoryRuleApplyResult, error) {
+	from, to := rrule.Date(request.From), rrule.Date(request.To)
+	if to.Before(from) {
+		return nil, fmt.Errorf("%w: to must not be before from", ErrInvalidInput)
+	}
+	rules, err := s.loadRules(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + transactionColumns + `
+			  FROM transactions
+			  WHERE user_id = $1 AND type IN ('income', 'expense') AND created_at >= $2 AND created_at < $3 AND trash_id IS NULL
+			    AND NOT EXISTS (SELECT 1 FROM transaction_splits s WHERE s.transaction_id = transactions.id)
+			  ORDER BY created_at, id`
+	transactions, err := s.Transactions.queryTransactions(query, userID, from, to.AddDate(0, 0, 1))
+	if err != nil {
+		log.Printf("Error retrieving transactions for category rules: %v", err)
+		return nil, err
+	}
+
+	result := &models.CategoryRuleApplyResult{DryRun: request.DryRun, From: from, To: to, Checked: len(transactions),
+		Changes: []models.CategoryChange{}}
+	var ids, categoryIDs []int64
+	for _, t := range transactions {
+		if request.OnlyUncategorized && t.CategoryID != 0 {
+			continue
+		}
+		rule := matchRule(rules, t)
+		if rule == nil || rule.CategoryID == t.CategoryID {
+			continue
+		}
+		result.Changes = append(result.Changes, models.CategoryChange{
+			TransactionID: t.ID,
+			Date:          t.CreatedAt,
+			Type:          t.Type,
+			Amount:        t.Amount,
+			Currency:      t.Currency,
+			Description:   t.Description,
+			OldCategoryID: t.CategoryID,
+			NewCategoryID: rule.CategoryID,
+			RuleID:        rule.ID,
+		})
+		ids = append(ids, int64(t.ID))
+		categoryIDs = append(categoryIDs, int64(rule.CategoryID))
+	}
+	result.Changed = len(result.Changes)
+	if request.DryRun || result.Changed == 0 {
+		return result, nil
+	}
+
+	query = `UPDATE transactions t
+			 SET category_id = c.category_id
+			 FROM unnest($1::int[], $2::int[]) AS c (id, category_id)
+			 WHERE t.id = c.id AND t.user_id = $3`
+	if _, err := s.DB.Exec(query, pq.Array(ids), pq.Array(categoryIDs), userID); err != nil {
+		log.Printf("Error applying category rules: %v", err)
+		return nil, err
+	}
+	s.Transactions.invalidateCache(userID)
+	return result, nil
+}
+
+// Categorize присваивает транзакции без категории категорию первого подошедшего правила.
+// Переводы, движения по долгам и транзакции с разбивкой не категоризируются.
+func (s *CategoryRuleService) Categorize(t *models.Transaction) error {
+	if t.CategoryID != 0 || len(t.Splits) > 0 || (t.Type != models.TransactionTypeIncome && t.Type != models.TransactionTypeExpense) {
+		return nil
+	}
+	rules, err := s.loadRules(t.UserID)
+	if err != nil {
+		return err
+	}
+	if rule := matchRule(rules, *t); rule != nil {
+		t.CategoryID = rule.CategoryID
+	}
+	return nil
+}
+
+// loadRules возвращает включённые правила пользователя в порядке применения.
+// Правила категорий и счетов, лежащих в корзине, не применяются.
+func (s *CategoryRuleService) loadRules(userID int) ([]categoryRule, error) {
+	query := `SELECT ` + categoryRuleColumns + `
+			  FROM category_rules
+			  WHERE user_id = $1 AND NOT disabled
+			    AND category_id IN (SELECT id FROM categories WHERE trash_id IS NULL)
+			    AND (account_id IS NULL OR account_id IN (SELECT id FROM accounts WHERE trash_id IS NULL))
+			  ORDER BY priority, id`
+	rows, err := s.DB.Query(query, userID)
+	if err != nil {
+		log.Printf("Error retrieving category rules: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []categoryRule
+	for rows.Next() {
+		stored, err := scanCategoryRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		rule, err := compileRule(stored)
+		if err != nil {
+			// сохранённое выражение всегда проверено при создании правила
+			log.Printf("Error compiling category rule %d: %v", stored.ID, err)
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+// matchRule возвращает первое правило, подходящее транзакции, или nil.
+func matchRule(rules []categoryRule, t models.Transaction) *categoryRule {
+	for i := range rules {
+		if rules[i].matches(t) {
+			return &rules[i]
+		}
+	}
+	return nil
+}
+
+func (r *categoryRule) matches(t models.Transaction) bool {
+	if r.Type != "" && r.Type != t.Type {
+		return false
+	}
+	if r.AccountID != 0 && r.AccountID != t.AccountID {
+		return false
+	}
+	amount := t.Amount.Abs()
+	if r.MinAmount != nil && amount < *r.MinAmount {
+		return false
+	}
+	if r.MaxAmount != nil && amount > *r.MaxAmount {
+		return false
+	}
+	if r.contains != "" && !strings.Contains(strings.ToLower(t.Description), r.contains) {
+		return false
+	}
+	if r.pattern != nil && !r.pattern.MatchString(t.Description) {
+		return false
+	}
+	return true
+}
+
+func compileRule(rule models.CategoryRule) (categoryRule, error) {
+	compiled := categoryRule{CategoryRule: rule, contains: strings.ToLower(rule.DescriptionContains)}
+	if rule.DescriptionPattern != "" {
+		pattern, err := regexp.Compile("(?i)" + rule.DescriptionPattern)
+		if err != nil {
+			return compiled, err
+		}
+		compiled.pattern = pattern
+	}
+	return compiled, nil
+}
+
+// validate проверяет правило, его категорию и счёт.
+func (s *CategoryRuleService) validate(rule *models.CategoryRule) error {
+	rule.Name = strings.TrimSpace(rule.Name)
+	rule.DescriptionContains = strings.TrimSpace(rule.DescriptionContains)
+	if rule.Type != "" && rule.Type != models.TransactionTypeIncome && rule.Type != models.TransactionTypeExpense {
+		return fmt.Errorf("%w: type must be income, expense or empty", ErrInvalidInput)
+	}
+	if len(rule.DescriptionContains) > 255 || len(rule.DescriptionPattern) > 500 {
+		return fmt.Errorf("%w: description condition is too long", ErrInvalidInput)
+	}
+	if _, err := compileRule(*rule); err != nil {
+		return fmt.Errorf("%w: invalid description_pattern: %v", ErrInvalidInput, err)
+	}
+	if (rule.MinAmount != nil && rule.MinAmount.IsNegative()) || (rule.MaxAmount != nil && rule.MaxAmount.IsNegative()) {
+		return fmt.Errorf("%w: amount bounds must not be negative", ErrInvalidInput)
+	}
+	if rule.MinAmount != nil && rule.MaxAmount != nil && *rule.MinAmount > *rule.MaxAmount {
+		return fmt.Errorf("%w: min_amount must not exceed max_amount", ErrInvalidInput)
+	}
+	if rule.DescriptionContains == "" && rule.DescriptionPattern == "" && rule.Type == "" && rule.AccountID == 0 &&
+		rule.MinAmount == nil && rule.MaxAmount == nil {
+		return fmt.Errorf("%w: rule needs at least one condition", ErrInvalidInput)
+	}
+
+	if rule.AccountID != 0 {
+		if err := checkOwnership(s.DB, "accounts", rule.AccountID, rule.UserID); err != nil {
+			return err
+		}
+	}
+	return checkOwnership(s.DB, "categories", rule.CategoryID, rule.UserID)
+}
+
+func scanCategoryRule(row rowScanner) (models.CategoryRule, error) {
+	var rule models.CategoryRule
+	err := row.Scan(&rule.ID, &rule.UserID, &rule.Name, &rule.CategoryID, &rule.Priority, &rule.DescriptionContains,
+		&rule.DescriptionPattern, &rule.Type, &rule.AccountID, &rule.MinAmount, &rule.MaxAmount, &rule.Disabled,
+		&rule.CreatedAt)
+	return rule, err
+}
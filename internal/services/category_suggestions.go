@@ -0,0 +1,219 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"math"
+
+	"finance_project/internal/classifier"
+	"finance_project/internal/models"
+	"finance_project/internal/money"
+)
+
+const (
+	// suggestionHistoryLimit — сколько последних категоризированных транзакций используется для обучения.
+	suggestionHistoryLimit = 2000
+	// suggestionFeedbackWeight — вес транзакции, категорию которой пользователь подтвердил или исправил.
+	suggestionFeedbackWeight = 3
+	// suggestionMinDocuments — минимальный объём истории одного типа, с которого даются подсказки.
+	suggestionMinDocuments = 5
+	// suggestionMinConfidence — уверенность, с которой подсказка прикладывается к новой транзакции.
+	suggestionMinConfidence = 0.5
+	// suggestionLimit — сколько вариантов возвращает список подсказок.
+	suggestionLimit = 3
+)
+
+// CategorySuggestionService предлагает категории для транзакций по истории пользователя.
+// Классификатор (наивный Байес по словам описания и порядку суммы) обучается заново при каждом
+// запросе на последних категоризированных доходах и расходах, поэтому любое изменение категории,
+// в том числе ответ на подсказку, сразу учитывается.
+type CategorySuggestionService struct {
+	DB           *sql.DB
+	Transactions *TransactionService
+}
+
+// NewCategorySuggestionService создает новый сервис подсказок категорий.
+func NewCategorySuggestionService(db *sql.DB, transactions *TransactionService) *CategorySuggestionService {
+	return &CategorySuggestionService{DB: db, Transactions: transactions}
+}
+
+// categoryClassifier — классификатор, обученный на истории пользователя, отдельно для доходов и расходов.
+type categoryClassifier struct {
+	models map[string]*classifier.Model
+	names  map[int]string
+}
+
+// Suggest возвращает наиболее вероятные категории для ещё не созданной транзакции.
+func (s *CategorySuggestionService) Suggest(userID int, t models.Transaction) ([]models.CategorySuggestion, error) {
+	if t.Type != models.TransactionTypeIncome && t.Type != models.TransactionTypeExpense {
+		return nil, fmt.Errorf("%w: type must be income or expense", ErrInvalidInput)
+	}
+	c, err := s.train(userID, 0)
+	if err != nil {
+		return nil, err
+	}
+	return c.suggest(t), nil
+}
+
+// SuggestForTransaction возвращает наиболее вероятные категории для сохранённой транзакции;
+// сама транзакция в обучении не участвует.
+func (s *CategorySuggestionService) SuggestForTransaction(id, userID int) ([]models.CategorySuggestion, error) {
+	if err := checkOwnership(s.DB, "transactions", id, userID); err != nil {
+		return nil, err
+	}
+	t, err := s.Transactions.GetTransactionByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if t.Type != models.TransactionTypeIncome && t.Type != models.TransactionTypeExpense {
+		return nil, fmt.Errorf("%w: only income and expenses have categories", ErrInvalidInput)
+	}
+	c, err := s.train(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	return c.suggest(*t), nil
+}
+
+// BestSuggestion возвращает подсказку для транзакции без категории, если классификатор достаточно уверен.
+func (s *CategorySuggestionService) BestSuggestion(t models.Transaction) (*models.CategorySuggestion, error) {
+	if t.CategoryID != 0 || len(t.Splits) > 0 || (t.Type != models.TransactionTypeIncome && t.Type != models.TransactionTypeExpense) {
+		return nil, nil
+	}
+	c, err := s.train(t.UserID, t.ID)
+	if err != nil {
+		return nil, err
+	}
+	return c.best(t), nil
+}
+
+// Feedback назначает транзакции выбранную пользователем категорию и запоминает, совпала ли она
+// с подсказкой. Подтверждённые категории получают больший вес при следующем обучении.
+func (s *CategorySuggestionService) Feedback(feedback *models.CategoryFeedback) (*models.Transaction, error) {
+	if err := checkOwnership(s.DB, "transactions", feedback.TransactionID, feedback.UserID); err != nil {
+		return nil, err
+	}
+	if err := checkOwnership(s.DB, "categories", feedback.CategoryID, feedback.UserID); err != nil {
+		return nil, err
+	}
+	t, err := s.Transactions.GetTransactionByID(feedback.TransactionID)
+	if err != nil {
+		return nil, err
+	}
+	if t.Type != models.TransactionTypeIncome && t.Type != models.TransactionTypeExpense {
+		return nil, fmt.Errorf("%w: only income and expenses have categories", ErrInvalidInput)
+	}
+	if len(t.Splits) > 0 {
+		return nil, fmt.Errorf("%w: transaction %d is split, change the categories of its splits", ErrInvalidInput, t.ID)
+	}
+
+	c, err := s.train(feedback.UserID, t.ID)
+	if err != nil {
+		return nil, err
+	}
+	feedback.SuggestedCategoryID, feedback.SuggestedConfidence = 0, 0
+	if suggestions := c.suggest(*t); len(suggestions) > 0 {
+		feedback.SuggestedCategoryID, feedback.SuggestedConfidence = suggestions[0].CategoryID, suggestions[0].Confidence
+	}
+	feedback.Accepted = feedback.SuggestedCategoryID == feedback.CategoryID
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`UPDATE transactions SET category_id = $1 WHERE id = $2`, feedback.CategoryID, t.ID); err != nil {
+		log.Printf("Error updating transaction category: %v", err)
+		return nil, err
+	}
+	query := `INSERT INTO category_feedback (user_id, transaction_id, suggested_category_id, suggested_confidence, category_id, accepted)
+			  VALUES ($1, $2, NULLIF($3, 0), NULLIF($4::numeric, 0), $5, $6)
+			  ON CONFLICT (transaction_id) DO UPDATE
+			  SET suggested_category_id = EXCLUDED.suggested_category_id, suggested_confidence = EXCLUDED.suggested_confidence,
+			      category_id = EXCLUDED.category_id, accepted = EXCLUDED.accepted, created_at = CURRENT_TIMESTAMP
+			  RETURNING id, created_at`
+	err = tx.QueryRow(query, feedback.UserID, t.ID, feedback.SuggestedCategoryID, feedback.SuggestedConfidence,
+		feedback.CategoryID, feedback.Accepted).Scan(&feedback.ID, &feedback.CreatedAt)
+	if err != nil {
+		log.Printf("Error saving category feedback: %v", err)
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	s.Transactions.invalidateCache(feedback.UserID)
+	return s.Transactions.GetTransactionByID(t.ID)
+}
+
+// train обучает классификатор на последних категоризированных доходах и расходах пользователя,
+// не считая транзакции excludeID.
+func (s *CategorySuggestionService) train(userID, excludeID int) (*categoryClassifier, error) {
+	query := `SELECT t.category_id, c.name, t.type, t.description, t.amount, f.id IS NOT NULL
+			  FROM transactions t
+			  JOIN categories c ON c.id = t.category_id
+			  LEFT JOIN category_feedback f ON f.transaction_id = t.id AND f.category_id = t.category_id
+			  WHERE t.user_id = $1 AND t.type IN ('income', 'expense') AND t.id <> $2 AND t.trash_id IS NULL
+			  ORDER BY t.created_at DESC, t.id DESC
+			  LIMIT $3`
+	rows, err := s.DB.Query(query, userID, excludeID, suggestionHistoryLimit)
+	if err != nil {
+		log.Printf("Error retrieving category history: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	c := &categoryClassifier{models: make(map[string]*classifier.Model), names: make(map[int]string)}
+	for rows.Next() {
+		var categoryID int
+		var name, kind, description string
+		var amount money.Amount
+		var confirmed bool
+		if err := rows.Scan(&categoryID, &name, &kind, &description, &amount, &confirmed); err != nil {
+			return nil, err
+		}
+		model := c.models[kind]
+		if model == nil {
+			model = classifier.New()
+			c.models[kind] = model
+		}
+		weight := 1.0
+		if confirmed {
+			weight = suggestionFeedbackWeight
+		}
+		model.Add(categoryID, classifier.Features(description, amount), weight)
+		c.names[categoryID] = name
+	}
+	return c, rows.Err()
+}
+
+// suggest возвращает до suggestionLimit самых вероятных категорий транзакции.
+func (c *categoryClassifier) suggest(t models.Transaction) []models.CategorySuggestion {
+	suggestions := []models.CategorySuggestion{}
+	model := c.models[t.Type]
+	if model == nil || model.Documents() < suggestionMinDocuments {
+		return suggestions
+	}
+	for _, p := range model.Predict(classifier.Features(t.Description, t.Amount)) {
+		if len(suggestions) == suggestionLimit {
+			break
+		}
+		suggestions = append(suggestions, models.CategorySuggestion{
+			CategoryID: p.Label,
+			Name:       c.names[p.Label],
+			Confidence: math.Round(p.Probability*10000) / 10000,
+		})
+	}
+	return suggestions
+}
+
+// best возвращает самую вероятную категорию, если её уверенность не ниже suggestionMinConfidence.
+func (c *categoryClassifier) best(t models.Transaction) *models.CategorySuggestion {
+	suggestions := c.suggest(t)
+	if len(suggestions) == 0 || suggestions[0].Confidence < suggestionMinConfidence {
+		return nil
+	}
+	return &suggestions[0]
+}
@@ -0,0 +1,388 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"finance_project/internal/models"
+	"finance_project/internal/money"
+	"finance_project/internal/ratefeeds"
+)
+
+// ErrRateNotFound возвращается, если для пары валют нет курса на нужную дату.
+var ErrRateNotFound = errors.New("exchange rate not found")
+
+const rateDateLayout = "2006-01-02"
+
+// CurrencyRateService хранит курсы валют на даты и пересчитывает суммы между валютами.
+type CurrencyRateService struct {
+	DB *sql.DB
+	// BaseCurrency используется для кросс-курса, если прямого курса между валютами нет.
+	BaseCurrency string
+}
+
+// NewCurrencyRateService создает новый сервис курсов валют.
+func NewCurrencyRateService(db *sql.DB, baseCurrency string) *CurrencyRateService {
+	return &CurrencyRateService{DB: db, BaseCurrency: strings.ToUpper(baseCurrency)}
+}
+
+// SetRate сохраняет курс на дату. Повторная запись той же пары на ту же дату заменяет курс.
+// Нормализованные коды валют, дата и источник записываются обратно в rate.
+func (s *CurrencyRateService) SetRate(rate *models.CurrencyRate) (int, error) {
+	if err := normalizeRate(rate); err != nil {
+		return 0, err
+	}
+
+	query := `INSERT INTO currency_rates (base_currency, target_currency, rate, rate_date, source)
+			  VALUES ($1, $2, $3, $4, $5)
+			  ON CONFLICT (base_currency, target_currency, rate_date)
+			  DO UPDATE SET rate = EXCLUDED.rate, source = EXCLUDED.source, created_at = CURRENT_TIMESTAMP
+			  RETURNING id`
+	err := s.DB.QueryRow(query, rate.BaseCurrency, rate.TargetCurrency, rate.Rate, rate.Date.Format(rateDateLayout), rate.Source).Scan(&rate.ID)
+	if err != nil {
+		log.Printf("Error saving currency rate: %v", err)
+		return 0, err
+	}
+	return rate.ID, nil
+}
+
+// ImportRates загружает курсы из файла или по HTTP (source) в формате format и сохраняет их.
+// Повторная загрузка тех же дат не создаёт дублей: курсы пары на дату заменяются.
+func (s *CurrencyRateService) ImportRates(source, format string) (*models.RateImportResult, error) {
+	rates, err := ratefeeds.Load(source, format)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	return s.SaveRates(source, rates)
+}
+
+// SaveRates сохраняет набор курсов одной транзакцией и сообщает, каких пар не хватает
+// для пересчёта используемых валют в BaseCurrency на последнюю загруженную дату.
+func (s *CurrencyRateService) SaveRates(source string, rates []models.CurrencyRate) (*models.RateImportResult, error) {
+	result := &models.RateImportResult{Source: source, Dates: []string{}, Missing: []string{}}
+	if len(rates) == 0 {
+		return result, nil
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Изменённые курсы перезаписываются, совпадающие не трогаются
+	query := `INSERT INTO currency_rates (base_currency, target_currency, rate, rate_date, source)
+			  VALUES ($1, $2, $3, $4, $5)
+			  ON CONFLICT (base_currency, target_currency, rate_date)
+			  DO UPDATE SET rate = EXCLUDED.rate, source = EXCLUDED.source, created_at = CURRENT_TIMESTAMP
+			  WHERE currency_rates.rate <> EXCLUDED.rate OR currency_rates.source <> EXCLUDED.source
+			  RETURNING xmax = 0`
+	dates := make(map[string]bool)
+	var latest time.Time
+	for i := range rates {
+		rate := &rates[i]
+		if err := normalizeRate(rate); err != nil {
+			return nil, err
+		}
+
+		var inserted bool
+		err := tx.QueryRow(query, rate.BaseCurrency, rate.TargetCurrency, rate.Rate,
+			rate.Date.Format(rateDateLayout), rate.Source).Scan(&inserted)
+		switch {
+		case err == sql.ErrNoRows:
+			result.Unchanged++
+		case err != nil:
+			log.Printf("Error importing currency rate: %v", err)
+			return nil, err
+		case inserted:
+			result.Inserted++
+		default:
+			result.Updated++
+		}
+
+		if day := rate.Date.Format(rateDateLayout); !dates[day] {
+			dates[day] = true
+			result.Dates = append(result.Dates, day)
+		}
+		if rate.Date.After(latest) {
+			latest = rate.Date
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	sort.Strings(result.Dates)
+
+	result.Missing, err = s.missingPairs(latest)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// missingPairs возвращает пары «валюта/BaseCurrency» для валют счетов и отчётов пользователей,
+// которые нельзя пересчитать на дату date ни напрямую, ни через кросс-курс.
+func (s *CurrencyRateService) missingPairs(date time.Time) ([]string, error) {
+	rows, err := s.DB.Query(`
+		SELECT DISTINCT UPPER(currency) FROM accounts WHERE COALESCE(currency, '') <> '' AND trash_id IS NULL
+		UNION
+		SELECT DISTINCT UPPER(preferred_currency) FROM users WHERE COALESCE(preferred_currency, '') <> '' AND deleted_at IS NULL
+		ORDER BY 1
+	`)
+	if err != nil {
+		log.Printf("Error retrieving currencies in use: %v", err)
+		return nil, err
+	}
+	var currencies []string
+	for rows.Next() {
+		var currency string
+		if err := rows.Scan(&currency); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		currencies = append(currencies, currency)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	missing := []string{}
+	for _, currency := range currencies {
+		if currency == s.BaseCurrency {
+			continue
+		}
+		_, err := s.GetRate(currency, s.BaseCurrency, date)
+		if errors.Is(err, ErrRateNotFound) {
+			missing = append(missing, currency+"/"+s.BaseCurrency)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return missing, nil
+}
+
+// GetRates возвращает сохранённые курсы, новые первыми. Пустые base, target и нулевая дата не фильтруют.
+func (s *CurrencyRateService) GetRates(base, target string, date time.Time) ([]models.CurrencyRate, error) {
+	var dateFilter interface{}
+	if !date.IsZero() {
+		dateFilter = date.Format(rateDateLayout)
+	}
+
+	query := `SELECT id, base_currency, target_currency, rate, rate_date, source
+			  FROM currency_rates
+			  WHERE ($1 = '' OR base_currency = $1)
+			    AND ($2 = '' OR target_currency = $2)
+			    AND ($3::date IS NULL OR rate_date = $3::date)
+			  ORDER BY rate_date DESC, base_currency, target_currency`
+	rows, err := s.DB.Query(query, strings.ToUpper(base), strings.ToUpper(target), dateFilter)
+	if err != nil {
+		log.Printf("Error retrieving currency rates: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rates []models.CurrencyRate
+	for rows.Next() {
+		var rate models.CurrencyRate
+		if err := rows.Scan(&rate.ID, &rate.BaseCurrency, &rate.TargetCurrency, &rate.Rate, &rate.Date, &rate.Source); err != nil {
+			log.Printf("Error scanning currency rate: %v", err)
+			return nil, err
+		}
+		rates = append(rates, rate)
+	}
+	return rates, rows.Err()
+}
+
+// DeleteRate удаляет курс по ID.
+func (s *CurrencyRateService) DeleteRate(id int) error {
+	result, err := s.DB.Exec(`DELETE FROM currency_rates WHERE id = $1`, id)
+	if err != nil {
+		log.Printf("Error deleting currency rate: %v", err)
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// GetRate возвращает курс from→to, действовавший на дату date (последний курс не позже этой даты).
+// Если прямого курса нет, используется обратный, а затем кросс-курс через BaseCurrency.
+func (s *CurrencyRateService) GetRate(from, to string, date time.Time) (money.Rate, error) {
+	from, to = strings.ToUpper(from), strings.ToUpper(to)
+	if from == to {
+		return money.One, nil
+	}
+
+	rate, ok, err := s.lookupRate(from, to, date)
+	if err != nil || ok {
+		return rate, err
+	}
+
+	base := s.BaseCurrency
+	if base != "" && base != from && base != to {
+		toBase, ok, err := s.lookupRate(from, base, date)
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			fromBase, ok, err := s.lookupRate(base, to, date)
+			if err != nil {
+				return 0, err
+			}
+			if ok {
+				return toBase.Mul(fromBase), nil
+			}
+		}
+	}
+
+	return 0, fmt.Errorf("%w: %s to %s on %s", ErrRateNotFound, from, to, date.Format(rateDateLayout))
+}
+
+// lookupRate ищет прямой или обратный курс пары. ok равен false, если курса нет.
+func (s *CurrencyRateService) lookupRate(from, to string, date time.Time) (rate money.Rate, ok bool, err error) {
+	var base string
+	query := `SELECT base_currency, rate
+			  FROM currency_rates
+			  WHERE ((base_currency = $1 AND target_currency = $2) OR (base_currency = $2 AND target_currency = $1))
+			    AND rate_date <= $3::date
+			  ORDER BY rate_date DESC, base_currency = $1 DESC
+			  LIMIT 1`
+	err = s.DB.QueryRow(query, from, to, date.Format(rateDateLayout)).Scan(&base, &rate)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		log.Printf("Error retrieving currency rate: %v", err)
+		return 0, false, err
+	}
+	if base != from {
+		rate = rate.Inverse()
+	}
+	return rate, true, nil
+}
+
+// Convert пересчитывает сумму из валюты from в валюту to по курсу на дату date
+// и округляет результат до минимальной единицы валюты to.
+func (s *CurrencyRateService) Convert(amount money.Amount, from, to string, date time.Time) (money.Amount, money.Rate, error) {
+	rate, err := s.GetRate(from, to, date)
+	if err != nil {
+		return 0, 0, err
+	}
+	return money.RoundTo(amount.Mul(rate), to), rate, nil
+}
+
+// PreferredCurrency возвращает валюту отчётов пользователя, по умолчанию BaseCurrency.
+func (s *CurrencyRateService) PreferredCurrency(userID int) (string, error) {
+	var currency string
+	err := s.DB.QueryRow(`SELECT COALESCE(preferred_currency, '') FROM users WHERE id = $1`, userID).Scan(&currency)
+	if err == sql.ErrNoRows {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		log.Printf("Error retrieving preferred currency: %v", err)
+		return "", err
+	}
+	if currency = strings.ToUpper(strings.TrimSpace(currency)); currency == "" {
+		currency = s.BaseCurrency
+	}
+	return currency, nil
+}
+
+// NewConverter создает конвертер, запоминающий найденные курсы на время одного отчёта.
+func (s *CurrencyRateService) NewConverter() *Converter {
+	return &Converter{rates: s, cache: make(map[string]money.Rate)}
+}
+
+// Converter пересчитывает суммы, запрашивая каждый курс на дату не более одного раза.
+type Converter struct {
+	rates *CurrencyRateService
+	cache map[string]money.Rate
+}
+
+// Convert пересчитывает сумму из валюты from в валюту to по курсу на дату date.
+func (c *Converter) Convert(amount money.Amount, from, to string, date time.Time) (money.Amount, error) {
+	key := strings.ToUpper(from) + "/" + strings.ToUpper(to) + "/" + date.Format(rateDateLayout)
+	rate, ok := c.cache[key]
+	if !ok {
+		var err error
+		rate, err = c.rates.GetRate(from, to, date)
+		if err != nil {
+			return 0, err
+		}
+		c.cache[key] = rate
+	}
+	return money.RoundTo(amount.Mul(rate), to), nil
+}
+
+// sumRows суммирует строки (ключ, валюта, дата, сумма) по ключу,
+// пересчитывая каждую сумму в currency по курсу на её дату.
+func (c *Converter) sumRows(rows *sql.Rows, currency string) (map[string]money.Amount, error) {
+	defer rows.Close()
+
+	totals := make(map[string]money.Amount)
+	for rows.Next() {
+		var key, from string
+		var date time.Time
+		var amount money.Amount
+		if err := rows.Scan(&key, &from, &date, &amount); err != nil {
+			return nil, err
+		}
+		converted, err := c.Convert(amount, from, currency, date)
+		if err != nil {
+			return nil, err
+		}
+		totals[key] += converted
+	}
+	return totals, rows.Err()
+}
+
+// normalizeRate проверяет курс и приводит коды валют, дату и источник к хранимому виду.
+func normalizeRate(rate *models.CurrencyRate) error {
+	base, err := normalizeCurrency(rate.BaseCurrency)
+	if err != nil {
+		return err
+	}
+	target, err := normalizeCurrency(rate.TargetCurrency)
+	if err != nil {
+		return err
+	}
+	if base == target {
+		return fmt.Errorf("%w: base and target currencies must differ", ErrInvalidInput)
+	}
+	if rate.Rate <= 0 {
+		return fmt.Errorf("%w: rate for %s/%s must be positive", ErrInvalidInput, base, target)
+	}
+
+	rate.BaseCurrency, rate.TargetCurrency = base, target
+	if rate.Date.IsZero() {
+		rate.Date = time.Now()
+	}
+	rate.Date, _ = time.Parse(rateDateLayout, rate.Date.Format(rateDateLayout))
+	if rate.Source == "" {
+		rate.Source = "manual"
+	}
+	return nil
+}
+
+// normalizeCurrency проверяет трехбуквенный код валюты ISO 4217 и приводит его к верхнему регистру.
+func normalizeCurrency(currency string) (string, error) {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if len(currency) != 3 {
+		return "", fmt.Errorf("%w: invalid currency code %q", ErrInvalidInput, currency)
+	}
+	for i := 0; i < len(currency); i++ {
+		if currency[i] < 'A' || currency[i] > 'Z' {
+			return "", fmt.Errorf("%w: invalid currency code %q", ErrInvalidInput, currency)
+		}
+	}
+	return currency, nil
+}
@@ -0,0 +1,496 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"finance_project/internal/models"
+	"finance_project/internal/money"
+)
+
+// debtQuery выбирает долги вместе со счётом выдачи и суммой возвратов. Для долга lent выдача — списание
+// (debt_out), а возвраты — поступления (debt_in); для borrowed наоборот.
+const debtQuery = `
+	SELECT d.id, d.user_id, d.contact, d.direction, d.amount, d.currency, d.description, d.due_date, d.created_at,
+	       COALESCE(MIN(t.account_id) FILTER (
+	           WHERE t.type = CASE d.direction WHEN 'lent' THEN 'debt_out' ELSE 'debt_in' END
+	       ), 0) AS account_id,
+	       COALESCE(SUM(t.amount) FILTER (
+	           WHERE t.type = CASE d.direction WHEN 'lent' THEN 'debt_in' ELSE 'debt_out' END
+	       ), 0) AS repaid
+	FROM debts d
+	LEFT JOIN transactions t ON t.debt_id = d.id AND t.trash_id IS NULL
+`
+
+// DebtService управляет долгами пользователя и их возвратами.
+type DebtService struct {
+	DB           *sql.DB
+	Transactions *TransactionService
+}
+
+// NewDebtService создает новый сервис долгов.
+func NewDebtService(db *sql.DB, transactions *TransactionService) *DebtService {
+	return &DebtService{DB: db, Transactions: transactions}
+}
+
+// CreateDebt записывает долг. Если указан AccountID, выдача долга проводится по счёту:
+// для lent деньги списываются со счёта, для borrowed — зачисляются на него.
+func (s *DebtService) CreateDebt(debt *models.Debt) (int, error) {
+	if err := validateDebt(debt); err != nil {
+		return 0, err
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if debt.AccountID != 0 {
+		currency, err := lockAccount(tx, debt.AccountID, debt.UserID)
+		if err != nil {
+			return 0, err
+		}
+		if debt.Currency == "" {
+			debt.Currency = currency
+		}
+	}
+	if debt.Currency == "" {
+		return 0, fmt.Errorf("%w: currency is required", ErrInvalidInput)
+	}
+	if err := money.CheckPrecision(debt.Amount, debt.Currency); err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	if debt.CreatedAt.IsZero() {
+		debt.CreatedAt = time.Now()
+	}
+
+	query := `INSERT INTO debts (user_id, contact, direction, amount, currency, description, due_date, created_at)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			  RETURNING id`
+	err = tx.QueryRow(query, debt.UserID, debt.Contact, debt.Direction, debt.Amount, debt.Currency,
+		debt.Description, debt.DueDate, debt.CreatedAt).Scan(&debt.ID)
+	if err != nil {
+		log.Printf("Error creating debt: %v", err)
+		return 0, err
+	}
+
+	if debt.AccountID != 0 {
+		principal := models.Transaction{
+			UserID:      debt.UserID,
+			AccountID:   debt.AccountID,
+			Amount:      debt.Amount,
+			Type:        principalType(debt.Direction),
+			Currency:    debt.Currency,
+			Description: debtDescription(*debt, "Debt"),
+			DebtID:      debt.ID,
+			CreatedAt:   debt.CreatedAt,
+		}
+		if _, err := createTransactionTx(tx, &principal); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	if debt.AccountID != 0 {
+		s.Transactions.invalidateCache(debt.UserID)
+	}
+	return debt.ID, nil
+}
+
+// GetDebts возвращает долги пользователя, при непустом contact — только по этому контакту.
+func (s *DebtService) GetDebts(userID int, contact string) ([]models.Debt, error) {
+	query := debtQuery + ` WHERE d.user_id = $1 AND ($2 = '' OR LOWER(d.contact) = LOWER($2))
+		GROUP BY d.id ORDER BY d.due_date NULLS LAST, d.id`
+	return s.queryDebts(query, userID, strings.TrimSpace(contact))
+}
+
+// GetOverdueDebts возвращает непогашенные долги пользователя со сроком возврата до сегодняшнего дня.
+func (s *DebtService) GetOverdueDebts(userID int) ([]models.Debt, error) {
+	query := debtQuery + ` WHERE d.user_id = $1 AND d.due_date < CURRENT_DATE
+		GROUP BY d.id
+		HAVING d.amount > COALESCE(SUM(t.amount) FILTER (
+		    WHERE t.type = CASE d.direction WHEN 'lent' THEN 'debt_in' ELSE 'debt_out' END
+		), 0)
+		ORDER BY d.due_date, d.id`
+	return s.queryDebts(query, userID)
+}
+
+// GetDebtByID возвращает долг пользователя вместе со списком возвратов.
+func (s *DebtService) GetDebtByID(id, userID int) (*models.Debt, error) {
+	if err := checkOwnership(s.DB, "debts", id, userID); err != nil {
+		return nil, err
+	}
+
+	debt, err := scanDebt(s.DB.QueryRow(debtQuery+` WHERE d.id = $1 GROUP BY d.id`, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		log.Printf("Error retrieving debt: %v", err)
+		return nil, err
+	}
+
+	rows, err := s.DB.Query(`SELECT id, account_id, amount, description, created_at
+							 FROM transactions
+							 WHERE debt_id = $1 AND type = $2 AND trash_id IS NULL
+							 ORDER BY created_at, id`, id, repaymentType(debt.Direction))
+	if err != nil {
+		log.Printf("Error retrieving debt repayments: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		repayment := models.DebtRepayment{DebtID: id}
+		if err := rows.Scan(&repayment.TransactionID, &repayment.AccountID, &repayment.Amount,
+			&repayment.Description, &repayment.Date); err != nil {
+			return nil, err
+		}
+		debt.Repayments = append(debt.Repayments, repayment)
+	}
+	return &debt, rows.Err()
+}
+
+// UpdateDebt изменяет контакт, сумму, описание и срок долга.
+// Направление и валюта не меняются; сумма не может быть меньше уже возвращённой.
+// Если выдача проведена по счёту, её транзакция и балансы счетов меняются вместе с суммой;
+// ненулевой AccountID переносит выдачу на другой счёт в той же валюте.
+func (s *DebtService) UpdateDebt(debt models.Debt) error {
+	if strings.TrimSpace(debt.Contact) == "" {
+		return fmt.Errorf("%w: contact is required", ErrInvalidInput)
+	}
+	if !debt.Amount.IsPositive() {
+		return fmt.Errorf("%w: amount must be positive", ErrInvalidInput)
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	current, err := lockDebt(tx, debt.ID, debt.UserID)
+	if err != nil {
+		return err
+	}
+	if err := money.CheckPrecision(debt.Amount, current.Currency); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	if debt.Amount < current.Repaid {
+		return fmt.Errorf("%w: amount is less than the %s already repaid", ErrInvalidInput, current.Repaid)
+	}
+
+	debt.Contact = strings.TrimSpace(debt.Contact)
+	debt.Direction, debt.Currency = current.Direction, current.Currency
+	changed, err := updatePrincipalTx(tx, current, debt)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE debts SET contact = $1, amount = $2, description = $3, due_date = $4 WHERE id = $5`
+	_, err = tx.Exec(query, debt.Contact, debt.Amount, debt.Description, debt.DueDate, debt.ID)
+	if err != nil {
+		log.Printf("Error updating debt: %v", err)
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	if changed {
+		s.Transactions.invalidateCache(debt.UserID)
+	}
+	return nil
+}
+
+// updatePrincipalTx приводит транзакцию выдачи долга к новой сумме, счёту и контакту и переносит
+// разницу на балансы счетов. Возвращает true, если транзакция выдачи есть и была изменена.
+// Долг без выдачи по счёту нельзя привязать к счёту задним числом, а выдачу из корзины — изменить.
+func updatePrincipalTx(tx *sql.Tx, current, debt models.Debt) (bool, error) {
+	var id, accountID int
+	var amount money.Amount
+	var trashed bool
+	err := tx.QueryRow(`SELECT id, account_id, amount, trash_id IS NOT NULL FROM transactions
+						WHERE debt_id = $1 AND type = $2 ORDER BY id LIMIT 1`,
+		debt.ID, principalType(current.Direction)).Scan(&id, &accountID, &amount, &trashed)
+	if err == sql.ErrNoRows {
+		if debt.AccountID != 0 {
+			return false, fmt.Errorf("%w: debt was not issued through an account", ErrInvalidInput)
+		}
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	newAccountID := accountID
+	if debt.AccountID != 0 {
+		newAccountID = debt.AccountID
+	}
+	if trashed {
+		if debt.Amount != amount || newAccountID != accountID {
+			return false, fmt.Errorf("%w: the debt transaction is in the trash", ErrInvalidInput)
+		}
+		return false, nil
+	}
+
+	// Счета блокируются по возрастанию id, как при удалении долга
+	accounts := []int{accountID, newAccountID}
+	if accounts[0] > accounts[1] {
+		accounts[0], accounts[1] = accounts[1], accounts[0]
+	}
+	for _, account := range accounts {
+		currency, err := lockAccount(tx, account, debt.UserID)
+		if err != nil {
+			return false, err
+		}
+		if currency != current.Currency {
+			return false, fmt.Errorf("%w: account currency %s does not match debt currency %s", ErrInvalidInput, currency, current.Currency)
+		}
+	}
+
+	oldDelta, err := signedAmount(models.Transaction{Type: principalType(current.Direction), Amount: amount})
+	if err != nil {
+		return false, err
+	}
+	newDelta, err := signedAmount(models.Transaction{Type: principalType(current.Direction), Amount: debt.Amount})
+	if err != nil {
+		return false, err
+	}
+	_, err = tx.Exec(`UPDATE transactions SET account_id = $1, amount = $2, description = $3 WHERE id = $4`,
+		newAccountID, debt.Amount, debtDescription(debt, "Debt"), id)
+	if err != nil {
+		log.Printf("Error updating debt transaction: %v", err)
+		return false, err
+	}
+	if err := adjustBalance(tx, accountID, -oldDelta); err != nil {
+		return false, err
+	}
+	if err := adjustBalance(tx, newAccountID, newDelta); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// DeleteDebt удаляет долг вместе с его транзакциями и откатывает балансы счетов.
+func (s *DebtService) DeleteDebt(id, userID int) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := lockDebt(tx, id, userID); err != nil {
+		return err
+	}
+
+	rows, err := tx.Query(`SELECT id, account_id FROM transactions WHERE debt_id = $1 AND trash_id IS NULL ORDER BY account_id, id`, id)
+	if err != nil {
+		return err
+	}
+	type entry struct{ id, accountID int }
+	var entries []entry
+	for rows.Next() {
+		var e entry
+		if err := rows.Scan(&e.id, &e.accountID); err != nil {
+			rows.Close()
+			return err
+		}
+		entries = append(entries, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		if err := deleteLedgerEntryTx(tx, e.id, e.accountID, userID); err != nil {
+			return err
+		}
+	}
+	// Записи в корзине уже откатаны из балансов и удаляются вместе с долгом
+	if _, err := tx.Exec(`DELETE FROM transactions WHERE debt_id = $1 AND trash_id IS NOT NULL`, id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM debts WHERE id = $1 AND user_id = $2`, id, userID); err != nil {
+		log.Printf("Error deleting debt: %v", err)
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	if len(entries) > 0 {
+		s.Transactions.invalidateCache(userID)
+	}
+	return nil
+}
+
+// AddRepayment проводит возврат долга по счёту: для lent деньги зачисляются на счёт,
+// для borrowed — списываются с него. Возврат не может превышать остаток долга.
+func (s *DebtService) AddRepayment(userID int, repayment *models.DebtRepayment) error {
+	if !repayment.Amount.IsPositive() {
+		return fmt.Errorf("%w: amount must be positive", ErrInvalidInput)
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	debt, err := lockDebt(tx, repayment.DebtID, userID)
+	if err != nil {
+		return err
+	}
+	if repayment.Amount > debt.Outstanding {
+		return fmt.Errorf("%w: repayment exceeds the outstanding %s %s", ErrInvalidInput, debt.Outstanding, debt.Currency)
+	}
+	if repayment.Date.IsZero() {
+		repayment.Date = time.Now()
+	}
+	if repayment.Description == "" {
+		repayment.Description = debtDescription(debt, "Debt repayment")
+	}
+
+	transaction := models.Transaction{
+		UserID:      userID,
+		AccountID:   repayment.AccountID,
+		Amount:      repayment.Amount,
+		Type:        repaymentType(debt.Direction),
+		Currency:    debt.Currency,
+		Description: repayment.Description,
+		DebtID:      debt.ID,
+		CreatedAt:   repayment.Date,
+	}
+	repayment.TransactionID, err = createTransactionTx(tx, &transaction)
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	s.Transactions.invalidateCache(userID)
+	return nil
+}
+
+// GetContactBalances возвращает непогашенные остатки долгов по контактам и валютам.
+func (s *DebtService) GetContactBalances(userID int) ([]models.ContactDebt, error) {
+	query := `
+		SELECT MIN(contact), currency,
+		       COALESCE(SUM(outstanding) FILTER (WHERE direction = 'lent'), 0),
+		       COALESCE(SUM(outstanding) FILTER (WHERE direction = 'borrowed'), 0)
+		FROM (` + debtQuery + ` WHERE d.user_id = $1 GROUP BY d.id) AS debt
+		CROSS JOIN LATERAL (SELECT debt.amount - debt.repaid AS outstanding) AS o
+		GROUP BY LOWER(contact), currency
+		HAVING SUM(outstanding) <> 0
+		ORDER BY LOWER(MIN(contact)), currency
+	`
+	rows, err := s.DB.Query(query, userID)
+	if err != nil {
+		log.Printf("Error retrieving debts by contact: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	balances := []models.ContactDebt{}
+	for rows.Next() {
+		var balance models.ContactDebt
+		if err := rows.Scan(&balance.Contact, &balance.Currency, &balance.Lent, &balance.Borrowed); err != nil {
+			return nil, err
+		}
+		balance.Net = balance.Lent - balance.Borrowed
+		balances = append(balances, balance)
+	}
+	return balances, rows.Err()
+}
+
+func (s *DebtService) queryDebts(query string, args ...interface{}) ([]models.Debt, error) {
+	rows, err := s.DB.Query(query, args...)
+	if err != nil {
+		log.Printf("Error retrieving debts: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var debts []models.Debt
+	for rows.Next() {
+		debt, err := scanDebt(rows)
+		if err != nil {
+			log.Printf("Error scanning debt: %v", err)
+			return nil, err
+		}
+		debts = append(debts, debt)
+	}
+	return debts, rows.Err()
+}
+
+// lockDebt блокирует долг пользователя до конца tx и возвращает его с текущей суммой возвратов.
+func lockDebt(tx *sql.Tx, id, userID int) (models.Debt, error) {
+	if err := checkOwnership(tx, "debts", id, userID); err != nil {
+		return models.Debt{}, err
+	}
+	if err := tx.QueryRow(`SELECT id FROM debts WHERE id = $1 FOR UPDATE`, id).Scan(&id); err != nil {
+		return models.Debt{}, err
+	}
+	return scanDebt(tx.QueryRow(debtQuery+` WHERE d.id = $1 GROUP BY d.id`, id))
+}
+
+func scanDebt(row rowScanner) (models.Debt, error) {
+	var debt models.Debt
+	var dueDate sql.NullTime
+	err := row.Scan(&debt.ID, &debt.UserID, &debt.Contact, &debt.Direction, &debt.Amount, &debt.Currency,
+		&debt.Description, &dueDate, &debt.CreatedAt, &debt.AccountID, &debt.Repaid)
+	if err != nil {
+		return debt, err
+	}
+	if dueDate.Valid {
+		debt.DueDate = &dueDate.Time
+	}
+	debt.Outstanding = debt.Amount - debt.Repaid
+	return debt, nil
+}
+
+func validateDebt(debt *models.Debt) error {
+	debt.Contact = strings.TrimSpace(debt.Contact)
+	if debt.Contact == "" {
+		return fmt.Errorf("%w: contact is required", ErrInvalidInput)
+	}
+	if debt.Direction != models.DebtLent && debt.Direction != models.DebtBorrowed {
+		return fmt.Errorf("%w: direction must be lent or borrowed", ErrInvalidInput)
+	}
+	if !debt.Amount.IsPositive() {
+		return fmt.Errorf("%w: amount must be positive", ErrInvalidInput)
+	}
+	debt.Currency = strings.ToUpper(strings.TrimSpace(debt.Currency))
+	return nil
+}
+
+// principalType возвращает тип транзакции выдачи долга.
+func principalType(direction string) string {
+	if direction == models.DebtLent {
+		return models.TransactionTypeDebtOut
+	}
+	return models.TransactionTypeDebtIn
+}
+
+// repaymentType возвращает тип транзакции возврата долга.
+func repaymentType(direction string) string {
+	if direction == models.DebtLent {
+		return models.TransactionTypeDebtIn
+	}
+	return models.TransactionTypeDebtOut
+}
+
+func debtDescription(debt models.Debt, prefix string) string {
+	if debt.Direction == models.DebtLent {
+		return fmt.Sprintf("%s: lent to %s", prefix, debt.Contact)
+	}
+	return fmt.Sprintf("%s: borrowed from %s", prefix, debt.Contact)
+}
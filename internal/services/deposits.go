@@ -0,0 +1,463 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"finance_project/internal/models"
+	"finance_project/internal/money"
+	"finance_project/internal/rrule"
+
+	"github.com/lib/pq"
+)
+
+const depositColumns = `id, user_id, account_id, initial_amount, interest_rate, currency, interest_type, capitalization,
+	start_date, created_at, end_date, status, next_accrual_date, last_error,
+	COALESCE((SELECT SUM(interest) FROM deposit_accruals WHERE deposit_id = deposits.id), 0)`
+
+// daysInYear — база расчёта процентов (фактическое число дней / 365).
+const daysInYear = 365
+
+// rateStep — шаг ставки, который хранит столбец interest_rate NUMERIC(9,4).
+const rateStep = money.One / 10000
+
+// DepositService управляет вкладами и зачисляет проценты по ним.
+type DepositService struct {
+	DB           *sql.DB
+	Transactions *TransactionService
+}
+
+// NewDepositService создает новый сервис вкладов.
+func NewDepositService(db *sql.DB, transactions *TransactionService) *DepositService {
+	return &DepositService{DB: db, Transactions: transactions}
+}
+
+// CreateDeposit открывает вклад. Проценты зачисляются на счёт AccountID, валюта вклада — валюта счёта.
+func (s *DepositService) CreateDeposit(deposit *models.Deposit) (int, error) {
+	if err := s.validate(deposit); err != nil {
+		return 0, err
+	}
+
+	query := `INSERT INTO deposits (user_id, account_id, initial_amount, interest_rate, currency, interest_type,
+			  capitalization, start_date, end_date, status, next_accrual_date)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+			  RETURNING id`
+	err := s.DB.QueryRow(query, deposit.UserID, deposit.AccountID, deposit.InitialAmount, deposit.InterestRate,
+		deposit.Currency, deposit.InterestType, deposit.Capitalization, deposit.StartDate, deposit.EndDate,
+		models.DepositActive, accrualDates(*deposit)[0]).Scan(&deposit.ID)
+	if err != nil {
+		log.Printf("Error creating deposit: %v", err)
+		return 0, err
+	}
+	return deposit.ID, nil
+}
+
+// GetDeposits возвращает все вклады пользователя.
+func (s *DepositService) GetDeposits(userID int) ([]models.Deposit, error) {
+	rows, err := s.DB.Query(`SELECT `+depositColumns+` FROM deposits WHERE user_id = $1 ORDER BY id`, userID)
+	if err != nil {
+		log.Printf("Error retrieving deposits: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deposits []models.Deposit
+	for rows.Next() {
+		deposit, err := scanDeposit(rows)
+		if err != nil {
+			log.Printf("Error scanning deposit: %v", err)
+			return nil, err
+		}
+		deposits = append(deposits, deposit)
+	}
+	return deposits, rows.Err()
+}
+
+// GetDepositByID возвращает вклад пользователя по ID.
+func (s *DepositService) GetDepositByID(id, userID int) (*models.Deposit, error) {
+	if err := checkOwnership(s.DB, "deposits", id, userID); err != nil {
+		return nil, err
+	}
+	deposit, err := scanDeposit(s.DB.QueryRow(`SELECT `+depositColumns+` FROM deposits WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		log.Printf("Error retrieving deposit: %v", err)
+		return nil, err
+	}
+	return &deposit, nil
+}
+
+// UpdateDeposit изменяет условия вклада. Пока проценты не зачислялись, можно менять любые условия;
+// после первого начисления менять можно только счёт в той же валюте.
+func (s *DepositService) UpdateDeposit(deposit *models.Deposit) error {
+	if err := s.validate(deposit); err != nil {
+		return err
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	current, err := lockDeposit(tx, deposit.ID, deposit.UserID)
+	if err != nil {
+		return err
+	}
+	if current.Status == models.DepositClosed {
+		return fmt.Errorf("%w: deposit is closed", ErrInvalidInput)
+	}
+	var accrued bool
+	if err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM deposit_accruals WHERE deposit_id = $1)`, deposit.ID).Scan(&accrued); err != nil {
+		return err
+	}
+
+	next := current.NextAccrualDate
+	if accrued {
+		if !sameTerms(current, *deposit) {
+			return fmt.Errorf("%w: interest has already been accrued, terms can no longer be changed", ErrInvalidInput)
+		}
+	} else {
+		next = &accrualDates(*deposit)[0]
+	}
+	query := `UPDATE deposits
+			  SET account_id = $1, initial_amount = $2, interest_rate = $3, currency = $4, interest_type = $5,
+			      capitalization = $6, start_date = $7, end_date = $8, next_accrual_date = $9, last_error = ''
+			  WHERE id = $10`
+	_, err = tx.Exec(query, deposit.AccountID, deposit.InitialAmount, deposit.InterestRate, deposit.Currency,
+		deposit.InterestType, deposit.Capitalization, deposit.StartDate, deposit.EndDate, next, deposit.ID)
+	if err != nil {
+		log.Printf("Error updating deposit: %v", err)
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeleteDeposit удаляет вклад. Зачисленные проценты остаются на счёте.
+func (s *DepositService) DeleteDeposit(id, userID int) error {
+	if err := checkOwnership(s.DB, "deposits", id, userID); err != nil {
+		return err
+	}
+	_, err := s.DB.Exec(`DELETE FROM deposits WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		log.Printf("Error deleting deposit: %v", err)
+	}
+	return err
+}
+
+// GetProjection возвращает график начислений процентов до EndDate; уже зачисленные периоды отмечены Posted.
+func (s *DepositService) GetProjection(id, userID int) ([]models.DepositAccrual, error) {
+	deposit, err := s.GetDepositByID(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.DB.Query(`SELECT accrual_date, interest, COALESCE(transaction_id, 0)
+							 FROM deposit_accruals WHERE deposit_id = $1`, id)
+	if err != nil {
+		log.Printf("Error retrieving deposit accruals: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	posted := map[time.Time]models.DepositAccrual{}
+	for rows.Next() {
+		var accrual models.DepositAccrual
+		if err := rows.Scan(&accrual.Date, &accrual.Interest, &accrual.TransactionID); err != nil {
+			return nil, err
+		}
+		accrual.Date = rrule.Date(accrual.Date)
+		accrual.Posted = true
+		posted[accrual.Date] = accrual
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return projectDeposit(*deposit, posted), nil
+}
+
+// ProcessAccruals зачисляет проценты за все периоды вкладов, закончившиеся не позже now,
+// и закрывает вклады, срок которых истёк. Возвращает число созданных транзакций.
+func (s *DepositService) ProcessAccruals(now time.Time) (int, error) {
+	day := rrule.Date(now)
+	failed := []int64{}
+	total := 0
+	for {
+		posted, depositID, err := s.processNext(day, failed)
+		if err == sql.ErrNoRows {
+			return total, nil
+		}
+		if err != nil {
+			if depositID == 0 {
+				return total, err
+			}
+			// Ошибка одного вклада не должна останавливать остальные
+			log.Printf("Error accruing interest on deposit %d: %v", depositID, err)
+			if _, uerr := s.DB.Exec(`UPDATE deposits SET last_error = $1 WHERE id = $2`, err.Error(), depositID); uerr != nil {
+				log.Printf("Error saving deposit error: %v", uerr)
+			}
+			failed = append(failed, int64(depositID))
+			continue
+		}
+		total += posted
+	}
+}
+
+// processNext обрабатывает один вклад с наступившим начислением, не заблокированный другим воркером.
+// Если таких нет, возвращается sql.ErrNoRows.
+func (s *DepositService) processNext(day time.Time, exclude []int64) (int, int, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback()
+	if err := setAuditActor(tx, "job:deposit-accruals"); err != nil {
+		return 0, 0, err
+	}
+
+	// Вклады на счетах в корзине не начисляются, пока счёт не восстановлен,
+	// а вклады удалённых пользователей — пока не восстановлен профиль
+	query := `SELECT ` + depositColumns + ` FROM deposits
+			  WHERE status = 'active' AND next_accrual_date <= $1 AND NOT (id = ANY($2))
+			    AND user_id IN (SELECT id FROM users WHERE deleted_at IS NULL)
+			    AND account_id IN (SELECT id FROM accounts WHERE trash_id IS NULL)
+			  ORDER BY next_accrual_date, id
+			  LIMIT 1
+			  FOR UPDATE SKIP LOCKED`
+	deposit, err := scanDeposit(tx.QueryRow(query, day, pq.Array(exclude)))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	posted := 0
+	var next *time.Time
+	for _, accrual := range projectDeposit(deposit, nil) {
+		if accrual.Date.Before(*deposit.NextAccrualDate) {
+			continue
+		}
+		if accrual.Date.After(day) {
+			date := accrual.Date
+			next = &date
+			break
+		}
+		ok, err := postAccrual(tx, deposit, accrual)
+		if err != nil {
+			return 0, deposit.ID, err
+		}
+		if ok {
+			posted++
+		}
+	}
+
+	status := models.DepositActive
+	if next == nil {
+		status = models.DepositClosed
+	}
+	_, err = tx.Exec(`UPDATE deposits SET next_accrual_date = $1, status = $2, last_error = '' WHERE id = $3`,
+		next, status, deposit.ID)
+	if err != nil {
+		return 0, deposit.ID, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, deposit.ID, err
+	}
+	if posted > 0 {
+		s.Transactions.invalidateCache(deposit.UserID)
+	}
+	return posted, deposit.ID, nil
+}
+
+// postAccrual фиксирует начисление за период и зачисляет проценты доходом на счёт вклада.
+// Если начисление за эту дату уже есть, возвращает false.
+func postAccrual(tx *sql.Tx, deposit models.Deposit, accrual models.DepositAccrual) (bool, error) {
+	var accrualID int
+	err := tx.QueryRow(`INSERT INTO deposit_accruals (deposit_id, accrual_date, interest)
+						VALUES ($1, $2, $3)
+						ON CONFLICT (deposit_id, accrual_date) DO NOTHING
+						RETURNING id`, deposit.ID, accrual.Date, accrual.Interest).Scan(&accrualID)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if !accrual.Interest.IsPositive() {
+		return true, nil
+	}
+
+	transaction := models.Transaction{
+		UserID:      deposit.UserID,
+		AccountID:   deposit.AccountID,
+		Amount:      accrual.Interest,
+		Type:        models.TransactionTypeIncome,
+		Currency:    deposit.Currency,
+		Description: fmt.Sprintf("Deposit #%d interest", deposit.ID),
+		CreatedAt:   accrual.Date,
+	}
+	id, err := createTransactionTx(tx, &transaction)
+	if err != nil {
+		return false, err
+	}
+	_, err = tx.Exec(`UPDATE deposit_accruals SET transaction_id = $1 WHERE id = $2`, id, accrualID)
+	return true, err
+}
+
+// validate проверяет условия вклада, приводит даты к дням и подставляет валюту счёта.
+func (s *DepositService) validate(deposit *models.Deposit) error {
+	if !deposit.InitialAmount.IsPositive() {
+		return fmt.Errorf("%w: initial_amount must be positive", ErrInvalidInput)
+	}
+	if deposit.InterestRate <= 0 || deposit.InterestRate > 1000*money.One {
+		return fmt.Errorf("%w: interest_rate must be an annual percentage between 0 and 1000", ErrInvalidInput)
+	}
+	if deposit.InterestRate%rateStep != 0 {
+		return fmt.Errorf("%w: interest_rate must have at most 4 decimal places", ErrInvalidInput)
+	}
+	if deposit.InterestType == "" {
+		deposit.InterestType = models.InterestSimple
+	}
+	if deposit.InterestType != models.InterestSimple && deposit.InterestType != models.InterestCompound {
+		return fmt.Errorf("%w: interest_type must be simple or compound", ErrInvalidInput)
+	}
+	if deposit.Capitalization == "" {
+		deposit.Capitalization = models.CapitalizationMaturity
+	}
+	if capitalizationMonths(deposit.Capitalization) < 0 {
+		return fmt.Errorf("%w: capitalization must be monthly, quarterly or maturity", ErrInvalidInput)
+	}
+	if deposit.StartDate.IsZero() {
+		deposit.StartDate = today()
+	}
+	deposit.StartDate = rrule.Date(deposit.StartDate)
+	deposit.EndDate = rrule.Date(deposit.EndDate)
+	if !deposit.EndDate.After(deposit.StartDate) {
+		return fmt.Errorf("%w: end_date must be after start_date", ErrInvalidInput)
+	}
+
+	if err := checkOwnership(s.DB, "accounts", deposit.AccountID, deposit.UserID); err != nil {
+		return err
+	}
+	if err := s.DB.QueryRow(`SELECT currency FROM accounts WHERE id = $1`, deposit.AccountID).Scan(&deposit.Currency); err != nil {
+		return err
+	}
+	if err := money.CheckPrecision(deposit.InitialAmount, deposit.Currency); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	return nil
+}
+
+// projectDeposit рассчитывает начисления по всем периодам вклада. Для периодов из posted
+// используется уже зачисленная сумма, чтобы график совпадал с транзакциями.
+func projectDeposit(deposit models.Deposit, posted map[time.Time]models.DepositAccrual) []models.DepositAccrual {
+	balance := deposit.InitialAmount
+	from := deposit.StartDate
+
+	var accruals []models.DepositAccrual
+	for _, date := range accrualDates(deposit) {
+		days := int(date.Sub(from).Hours() / 24)
+		base := deposit.InitialAmount
+		if deposit.InterestType == models.InterestCompound {
+			base = balance
+		}
+		// доля годовой ставки за период: rate% × days / 365
+		factor := deposit.InterestRate.Mul(money.Rate(days) * money.One).Div(100 * daysInYear * money.One)
+		accrual := models.DepositAccrual{
+			Date:     date,
+			Days:     days,
+			Interest: money.RoundTo(base.Mul(factor), deposit.Currency),
+		}
+		if p, ok := posted[date]; ok {
+			accrual.Interest = p.Interest
+			accrual.Posted = true
+			accrual.TransactionID = p.TransactionID
+		}
+		balance += accrual.Interest
+		accrual.Balance = balance
+		accruals = append(accruals, accrual)
+		from = date
+	}
+	return accruals
+}
+
+// accrualDates возвращает даты окончания периодов начисления; последняя дата — EndDate.
+func accrualDates(deposit models.Deposit) []time.Time {
+	months := capitalizationMonths(deposit.Capitalization)
+	if months == 0 {
+		return []time.Time{deposit.EndDate}
+	}
+
+	var dates []time.Time
+	for n := months; ; n += months {
+		date := addMonths(deposit.StartDate, n)
+		if !date.Before(deposit.EndDate) {
+			return append(dates, deposit.EndDate)
+		}
+		dates = append(dates, date)
+	}
+}
+
+// capitalizationMonths возвращает длину периода начисления в месяцах: 0 — в конце срока, -1 — неизвестное значение.
+func capitalizationMonths(capitalization string) int {
+	switch capitalization {
+	case models.CapitalizationMonthly:
+		return 1
+	case models.CapitalizationQuarterly:
+		return 3
+	case models.CapitalizationMaturity:
+		return 0
+	}
+	return -1
+}
+
+// addMonths прибавляет n месяцев к дате; если в месяце нет такого дня, берётся последний день месяца.
+func addMonths(date time.Time, n int) time.Time {
+	first := time.Date(date.Year(), date.Month()+time.Month(n), 1, 0, 0, 0, 0, time.UTC)
+	last := first.AddDate(0, 1, -1).Day()
+	day := date.Day()
+	if day > last {
+		day = last
+	}
+	return first.AddDate(0, 0, day-1)
+}
+
+func sameTerms(a, b models.Deposit) bool {
+	return a.Currency == b.Currency && a.InitialAmount == b.InitialAmount && a.InterestRate == b.InterestRate && a.InterestType == b.InterestType &&
+		a.Capitalization == b.Capitalization && a.StartDate.Equal(b.StartDate) && a.EndDate.Equal(b.EndDate)
+}
+
+// lockDeposit блокирует вклад пользователя до конца tx.
+func lockDeposit(tx *sql.Tx, id, userID int) (models.Deposit, error) {
+	deposit, err := scanDeposit(tx.QueryRow(`SELECT `+depositColumns+` FROM deposits WHERE id = $1 FOR UPDATE`, id))
+	if err == sql.ErrNoRows {
+		return deposit, ErrNotFound
+	}
+	if err != nil {
+		return deposit, err
+	}
+	if deposit.UserID != userID {
+		return deposit, ErrForbidden
+	}
+	return deposit, nil
+}
+
+func scanDeposit(row rowScanner) (models.Deposit, error) {
+	var deposit models.Deposit
+	var nextAccrualDate sql.NullTime
+	err := row.Scan(&deposit.ID, &deposit.UserID, &deposit.AccountID, &deposit.InitialAmount, &deposit.InterestRate,
+		&deposit.Currency, &deposit.InterestType, &deposit.Capitalization, &deposit.StartDate, &deposit.CreatedAt,
+		&deposit.EndDate, &deposit.Status, &nextAccrualDate, &deposit.LastError, &deposit.AccruedInterest)
+	if err != nil {
+		return deposit, err
+	}
+	deposit.StartDate = rrule.Date(deposit.StartDate)
+	deposit.EndDate = rrule.Date(deposit.EndDate)
+	if nextAccrualDate.Valid {
+		next := rrule.Date(nextAccrualDate.Time)
+		deposit.NextAccrualDate = &next
+	}
+	return deposit, nil
+}
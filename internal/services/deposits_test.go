@@ -0,0 +1,157 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"finance_project/internal/models"
+	"finance_project/internal/money"
+)
+
+func date(s string) time.Time {
+	d, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func TestAccrualDates(t *testing.T) {
+	tests := []struct {
+		name           string
+		capitalization string
+		start, end     string
+		want           []string
+	}{
+		{"maturity", models.CapitalizationMaturity, "2024-01-15", "2024-12-01", []string{"2024-12-01"}},
+		{"monthly", models.CapitalizationMonthly, "2024-01-15", "2024-04-15", []string{"2024-02-15", "2024-03-15", "2024-04-15"}},
+		{"monthly from month end", models.CapitalizationMonthly, "2024-01-31", "2024-04-30", []string{"2024-02-29", "2024-03-31", "2024-04-30"}},
+		{"quarterly from month end", models.CapitalizationQuarterly, "2024-11-30", "2025-08-30", []string{"2025-02-28", "2025-05-30", "2025-08-30"}},
+		{"last period cut at end date", models.CapitalizationQuarterly, "2024-01-15", "2024-12-01", []string{"2024-04-15", "2024-07-15", "2024-10-15", "2024-12-01"}},
+		{"term shorter than a period", models.CapitalizationMonthly, "2024-01-15", "2024-02-01", []string{"2024-02-01"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deposit := models.Deposit{Capitalization: tt.capitalization, StartDate: date(tt.start), EndDate: date(tt.end)}
+			var got []string
+			for _, d := range accrualDates(deposit) {
+				got = append(got, d.Format("2006-01-02"))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("accrualDates = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProjectDeposit(t *testing.T) {
+	type accrual struct {
+		date     string
+		days     int
+		interest string
+		balance  string
+	}
+	tests := []struct {
+		name           string
+		amount, rate   string
+		interestType   string
+		capitalization string
+		start, end     string
+		want           []accrual
+	}{
+		{
+			name: "simple monthly", amount: "10000", rate: "12",
+			interestType: models.InterestSimple, capitalization: models.CapitalizationMonthly,
+			start: "2024-01-31", end: "2024-04-30",
+			want: []accrual{
+				{"2024-02-29", 29, "95.34", "10095.34"},
+				{"2024-03-31", 31, "101.92", "10197.26"},
+				{"2024-04-30", 30, "98.63", "10295.89"},
+			},
+		},
+		{
+			name: "compound monthly", amount: "10000", rate: "12",
+			interestType: models.InterestCompound, capitalization: models.CapitalizationMonthly,
+			start: "2024-01-31", end: "2024-04-30",
+			want: []accrual{
+				{"2024-02-29", 29, "95.34", "10095.34"},
+				{"2024-03-31", 31, "102.89", "10198.23"},
+				{"2024-04-30", 30, "100.59", "10298.82"},
+			},
+		},
+		{
+			name: "compound quarterly with a short last period", amount: "5000", rate: "9.5",
+			interestType: models.InterestCompound, capitalization: models.CapitalizationQuarterly,
+			start: "2024-01-15", end: "2024-12-01",
+			want: []accrual{
+				{"2024-04-15", 91, "118.42", "5118.42"},
+				{"2024-07-15", 91, "121.23", "5239.65"},
+				{"2024-10-15", 92, "125.46", "5365.11"},
+				{"2024-12-01", 47, "65.63", "5430.74"},
+			},
+		},
+		{
+			name: "at maturity", amount: "5000", rate: "9.5",
+			interestType: models.InterestCompound, capitalization: models.CapitalizationMaturity,
+			start: "2024-01-15", end: "2024-12-01",
+			want: []accrual{
+				{"2024-12-01", 321, "417.74", "5417.74"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rate, err := money.ParseRate(tt.rate)
+			if err != nil {
+				t.Fatal(err)
+			}
+			deposit := models.Deposit{
+				InitialAmount:  money.MustParse(tt.amount),
+				InterestRate:   rate,
+				Currency:       "USD",
+				InterestType:   tt.interestType,
+				Capitalization: tt.capitalization,
+				StartDate:      date(tt.start),
+				EndDate:        date(tt.end),
+			}
+			got := projectDeposit(deposit, nil)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d accruals, want %d: %+v", len(got), len(tt.want), got)
+			}
+			for i, want := range tt.want {
+				a := got[i]
+				if !a.Date.Equal(date(want.date)) || a.Days != want.days ||
+					a.Interest != money.MustParse(want.interest) || a.Balance != money.MustParse(want.balance) || a.Posted {
+					t.Errorf("accrual %d = {%s %d %s %s %v}, want %+v", i, a.Date.Format("2006-01-02"), a.Days, a.Interest, a.Balance, a.Posted, want)
+				}
+			}
+		})
+	}
+}
+
+func TestProjectDepositUsesPostedInterest(t *testing.T) {
+	deposit := models.Deposit{
+		InitialAmount:  money.MustParse("10000"),
+		InterestRate:   12 * money.One,
+		Currency:       "USD",
+		InterestType:   models.InterestCompound,
+		Capitalization: models.CapitalizationMonthly,
+		StartDate:      date("2024-01-31"),
+		EndDate:        date("2024-03-31"),
+	}
+	posted := map[time.Time]models.DepositAccrual{
+		date("2024-02-29"): {Interest: money.MustParse("100"), TransactionID: 7},
+	}
+	got := projectDeposit(deposit, posted)
+	if len(got) != 2 {
+		t.Fatalf("got %d accruals, want 2", len(got))
+	}
+	if !got[0].Posted || got[0].TransactionID != 7 || got[0].Interest != money.MustParse("100") {
+		t.Errorf("first accrual = %+v, want the posted interest", got[0])
+	}
+	// 10100 × 12% × 31 / 365 = 102.94
+	if got[1].Posted || got[1].Interest != money.MustParse("102.94") || got[1].Balance != money.MustParse("10202.94") {
+		t.Errorf("second accrual = %+v, want interest on the posted balance", got[1])
+	}
+}
@@ -0,0 +1,317 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"finance_project/internal/config"
+	"finance_project/internal/models"
+	"finance_project/internal/rrule"
+
+	"github.com/lib/pq"
+)
+
+// Веса оценки дубля: совпадение суммы и счёта, близость дат и сходство описаний.
+const (
+	duplicateAmountWeight      = 0.4
+	duplicateDateWeight        = 0.25
+	duplicateDescriptionWeight = 0.35
+)
+
+const transactionDuplicateColumns = `id, user_id, transaction_id, duplicate_of, score, status, created_at, resolved_at`
+
+// DuplicateService находит вероятные дубли транзакций и разрешает найденные пары.
+// Кандидаты — доходы и расходы того же счёта с той же суммой, отстоящие не более чем на WindowDays дней;
+// пара отмечается, если её оценка не ниже Threshold.
+type DuplicateService struct {
+	DB           *sql.DB
+	Transactions *TransactionService
+	WindowDays   int
+	Threshold    float64
+}
+
+// NewDuplicateService создает новый сервис поиска дублей.
+func NewDuplicateService(db *sql.DB, transactions *TransactionService, cfg config.DuplicatesConfig) *DuplicateService {
+	return &DuplicateService{DB: db, Transactions: transactions, WindowDays: cfg.WindowDays, Threshold: cfg.Threshold}
+}
+
+// querier is implemented by both *sql.DB and *sql.Tx.
+type querier interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// GetDuplicates возвращает пары вероятных дублей пользователя с заданным статусом (по умолчанию pending)
+// вместе с самими транзакциями, самые уверенные первыми.
+func (s *DuplicateService) GetDuplicates(userID int, status string) ([]models.TransactionDuplicate, error) {
+	if status == "" {
+		status = models.DuplicatePending
+	}
+	if status != models.DuplicatePending && status != models.DuplicateDismissed {
+		return nil, fmt.Errorf("%w: status must be pending or dismissed", ErrInvalidInput)
+	}
+
+	query := `SELECT ` + transactionDuplicateColumns + `
+			  FROM transaction_duplicates
+			  WHERE user_id = $1 AND status = $2
+			    AND NOT EXISTS (SELECT 1 FROM transactions t
+			                    WHERE t.id IN (transaction_id, duplicate_of) AND t.trash_id IS NOT NULL)
+			  ORDER BY score DESC, id DESC`
+	duplicates, err := s.queryDuplicates(s.DB, query, userID, status)
+	if err != nil {
+		log.Printf("Error retrieving transaction duplicates: %v", err)
+		return nil, err
+	}
+	if len(duplicates) == 0 {
+		return duplicates, nil
+	}
+
+	ids := make([]int64, 0, 2*len(duplicates))
+	for _, d := range duplicates {
+		ids = append(ids, int64(d.TransactionID), int64(d.DuplicateOf))
+	}
+	transactions, err := s.Transactions.queryTransactions(`SELECT `+transactionColumns+` FROM transactions WHERE id = ANY($1)`, pq.Array(ids))
+	if err != nil {
+		log.Printf("Error retrieving duplicate transactions: %v", err)
+		return nil, err
+	}
+	byID := make(map[int]*models.Transaction, len(transactions))
+	for i := range transactions {
+		byID[transactions[i].ID] = &transactions[i]
+	}
+	for i := range duplicates {
+		duplicates[i].Transaction = byID[duplicates[i].TransactionID]
+		duplicates[i].Original = byID[duplicates[i].DuplicateOf]
+	}
+	return duplicates, nil
+}
+
+// GetPendingForTransaction возвращает нерешённые пары, в которых транзакция отмечена как вероятный дубль.
+func (s *DuplicateService) GetPendingForTransaction(transactionID, userID int) ([]models.TransactionDuplicate, error) {
+	query := `SELECT ` + transactionDuplicateColumns + `
+			  FROM transaction_duplicates
+			  WHERE transaction_id = $1 AND user_id = $2 AND status = 'pending'
+			  ORDER BY score DESC`
+	return s.queryDuplicates(s.DB, query, transactionID, userID)
+}
+
+// Dismiss отмечает пару как не являющуюся дублем.
+func (s *DuplicateService) Dismiss(id, userID int) error {
+	result, err := s.DB.Exec(`UPDATE transaction_duplicates SET status = 'dismissed', resolved_at = NOW()
+							  WHERE id = $1 AND user_id = $2 AND status = 'pending'`, id, userID)
+	if err != nil {
+		log.Printf("Error dismissing transaction duplicate: %v", err)
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Merge объединяет пару: транзакция keepID (по умолчанию более ранняя, DuplicateOf) остаётся,
+// а вторая удаляется с откатом баланса. Пустые у оставшейся транзакции категория, описание
+// и банковские реквизиты заполняются из удалённой, а строки импортированных выписок
+// переносятся на неё, чтобы повторный импорт не создал удалённую транзакцию снова.
+// Метки обеих транзакций объединяются.
+func (s *DuplicateService) Merge(id, userID, keepID int) (*models.Transaction, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var keep, remove int
+	var status string
+	err = tx.QueryRow(`SELECT duplicate_of, transaction_id, status FROM transaction_duplicates
+					   WHERE id = $1 AND user_id = $2
+					   FOR UPDATE`, id, userID).Scan(&keep, &remove, &status)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if status != models.DuplicatePending {
+		return nil, fmt.Errorf("%w: duplicate pair %d is already %s", ErrInvalidInput, id, status)
+	}
+	switch keepID {
+	case 0, keep:
+	case remove:
+		keep, remove = remove, keep
+	default:
+		return nil, fmt.Errorf("%w: keep must be transaction %d or %d", ErrInvalidInput, keep, remove)
+	}
+
+	merge := `UPDATE transactions k
+			  SET category_id = CASE WHEN EXISTS (SELECT 1 FROM transaction_splits s WHERE s.transaction_id = k.id)
+			                    THEN NULL ELSE COALESCE(k.category_id, r.category_id) END,
+			      description = COALESCE(NULLIF(k.description, ''), r.description),
+			      value_date = COALESCE(k.value_date, r.value_date),
+			      counterparty_name = COALESCE(NULLIF(k.counterparty_name, ''), r.counterparty_name),
+			      counterparty_account = COALESCE(NULLIF(k.counterparty_account, ''), r.counterparty_account),
+			      counterparty_bank = COALESCE(NULLIF(k.counterparty_bank, ''), r.counterparty_bank),
+			      original_amount = COALESCE(k.original_amount, r.original_amount),
+			      original_currency = CASE WHEN k.original_amount IS NULL THEN r.original_currency ELSE k.original_currency END
+			  FROM transactions r
+			  WHERE k.id = $1 AND r.id = $2`
+	if _, err := tx.Exec(merge, keep, remove); err != nil {
+		log.Printf("Error merging duplicate transactions: %v", err)
+		return nil, err
+	}
+	if _, err := tx.Exec(`UPDATE statement_lines SET transaction_id = $1 WHERE transaction_id = $2`, keep, remove); err != nil {
+		log.Printf("Error moving statement lines: %v", err)
+		return nil, err
+	}
+	_, err = tx.Exec(`INSERT INTO transaction_tags (transaction_id, tag_id)
+					  SELECT $1, tag_id FROM transaction_tags WHERE transaction_id = $2
+					  ON CONFLICT DO NOTHING`, keep, remove)
+	if err != nil {
+		log.Printf("Error merging transaction tags: %v", err)
+		return nil, err
+	}
+	// Пары удалённой транзакции, включая объединяемую, удаляются каскадно
+	if err := deleteTransactionTx(tx, remove, userID); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	s.Transactions.invalidateCache(userID)
+	return s.Transactions.GetTransactionByID(keep)
+}
+
+// findDuplicates возвращает вероятные дубли транзакции t среди сохранённых, самые уверенные первыми.
+// Транзакции из exclude (например, созданные тем же импортом) не рассматриваются.
+func (s *DuplicateService) findDuplicates(q querier, t models.Transaction, exclude []int64) ([]models.TransactionDuplicate, error) {
+	if t.Type != models.TransactionTypeIncome && t.Type != models.TransactionTypeExpense {
+		return nil, nil
+	}
+	if exclude == nil {
+		exclude = []int64{}
+	}
+	day := rrule.Date(t.CreatedAt)
+	query := `SELECT id, description, created_at
+			  FROM transactions
+			  WHERE user_id = $1 AND account_id = $2 AND type = $3 AND amount = $4 AND id <> $5 AND trash_id IS NULL
+			    AND created_at >= $6 AND created_at < $7 AND NOT (id = ANY($8))
+			  ORDER BY created_at DESC
+			  LIMIT 20`
+	rows, err := q.Query(query, t.UserID, t.AccountID, t.Type, t.Amount, t.ID,
+		day.AddDate(0, 0, -s.WindowDays), day.AddDate(0, 0, s.WindowDays+1), pq.Array(exclude))
+	if err != nil {
+		log.Printf("Error searching for duplicate transactions: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var matches []models.TransactionDuplicate
+	for rows.Next() {
+		var id int
+		var description string
+		var createdAt time.Time
+		if err := rows.Scan(&id, &description, &createdAt); err != nil {
+			return nil, err
+		}
+		score := s.score(t, description, createdAt)
+		if score < s.Threshold {
+			continue
+		}
+		matches = append(matches, models.TransactionDuplicate{
+			UserID:        t.UserID,
+			TransactionID: t.ID,
+			DuplicateOf:   id,
+			Score:         score,
+			Status:        models.DuplicatePending,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Score > matches[j].Score })
+	return matches, nil
+}
+
+// flagDuplicatesTx находит вероятные дубли только что созданной транзакции t и сохраняет пары для проверки.
+func (s *DuplicateService) flagDuplicatesTx(tx *sql.Tx, t models.Transaction, exclude []int64) ([]models.TransactionDuplicate, error) {
+	matches, err := s.findDuplicates(tx, t, exclude)
+	if err != nil {
+		return nil, err
+	}
+	for i := range matches {
+		m := &matches[i]
+		err := tx.QueryRow(`INSERT INTO transaction_duplicates (user_id, transaction_id, duplicate_of, score)
+							VALUES ($1, $2, $3, $4)
+							RETURNING id, created_at`, m.UserID, m.TransactionID, m.DuplicateOf, m.Score).Scan(&m.ID, &m.CreatedAt)
+		if err != nil {
+			log.Printf("Error flagging duplicate transaction: %v", err)
+			return nil, err
+		}
+	}
+	return matches, nil
+}
+
+// score оценивает, насколько кандидат с той же суммой похож на дубль t.
+func (s *DuplicateService) score(t models.Transaction, description string, createdAt time.Time) float64 {
+	days := math.Abs(rrule.Date(t.CreatedAt).Sub(rrule.Date(createdAt)).Hours() / 24)
+	closeness := math.Max(0, 1-days/float64(s.WindowDays+1))
+	score := duplicateAmountWeight + duplicateDateWeight*closeness +
+		duplicateDescriptionWeight*descriptionSimilarity(t.Description, description)
+	return math.Round(score*100) / 100
+}
+
+// descriptionSimilarity сравнивает нормализованные описания: доля слов более короткого описания,
+// встречающихся в другом. Два пустых описания считаются совпадающими, одно пустое — совпадающим наполовину.
+func descriptionSimilarity(a, b string) float64 {
+	wordsA, wordsB := descriptionWords(a), descriptionWords(b)
+	switch {
+	case len(wordsA) == 0 && len(wordsB) == 0:
+		return 1
+	case len(wordsA) == 0 || len(wordsB) == 0:
+		return 0.5
+	}
+	common := 0
+	for word := range wordsA {
+		if wordsB[word] {
+			common++
+		}
+	}
+	return float64(common) / float64(min(len(wordsA), len(wordsB)))
+}
+
+// descriptionWords нормализует описание: слова в нижнем регистре без цифр, знаков препинания
+// и однобуквенных обрывков, так что "POS 4417 COFFEE-SHOP 12/05" и "Coffee shop" совпадают.
+func descriptionWords(s string) map[string]bool {
+	words := make(map[string]bool)
+	for _, word := range strings.FieldsFunc(strings.ToLower(s), func(r rune) bool { return !unicode.IsLetter(r) }) {
+		if len([]rune(word)) > 1 {
+			words[word] = true
+		}
+	}
+	return words
+}
+
+func (s *DuplicateService) queryDuplicates(q querier, query string, args ...interface{}) ([]models.TransactionDuplicate, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	duplicates := []models.TransactionDuplicate{}
+	for rows.Next() {
+		var d models.TransactionDuplicate
+		if err := rows.Scan(&d.ID, &d.UserID, &d.TransactionID, &d.DuplicateOf, &d.Score, &d.Status,
+			&d.CreatedAt, &d.ResolvedAt); err != nil {
+			return nil, err
+		}
+		duplicates = append(duplicates, d)
+	}
+	return duplicates, rows.Err()
+}
@@ -0,0 +1,443 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"finance_project/internal/models"
+	"finance_project/internal/money"
+
+	"github.com/lib/pq"
+)
+
+// envelopeMonthLayout — формат месяца конвертного бюджета.
+const envelopeMonthLayout = "2006-01"
+
+// EnvelopeService управляет конвертным (zero-based) бюджетом. Все суммы ведутся в предпочитаемой
+// валюте пользователя; доходы и расходы пересчитываются по курсу на дату транзакции.
+type EnvelopeService struct {
+	DB    *sql.DB
+	Rates *CurrencyRateService
+}
+
+// NewEnvelopeService создает новый сервис конвертов.
+func NewEnvelopeService(db *sql.DB, rates *CurrencyRateService) *EnvelopeService {
+	return &EnvelopeService{DB: db, Rates: rates}
+}
+
+// CreateEnvelope создает конверт и привязывает к нему категории расходов.
+func (s *EnvelopeService) CreateEnvelope(envelope *models.Envelope) (int, error) {
+	envelope.Name = strings.TrimSpace(envelope.Name)
+	if envelope.Name == "" {
+		return 0, fmt.Errorf("%w: name is required", ErrInvalidInput)
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(`INSERT INTO envelopes (user_id, name) VALUES ($1, $2) RETURNING id`,
+		envelope.UserID, envelope.Name).Scan(&envelope.ID)
+	if err != nil {
+		log.Printf("Error creating envelope: %v", err)
+		return 0, err
+	}
+	if err := setEnvelopeCategories(tx, *envelope); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return envelope.ID, nil
+}
+
+// GetEnvelopes возвращает конверты пользователя вместе с их категориями.
+func (s *EnvelopeService) GetEnvelopes(userID int) ([]models.Envelope, error) {
+	return queryEnvelopes(s.DB, userID)
+}
+
+// queryEnvelopes читает конверты пользователя через q — базу или открытую транзакцию.
+func queryEnvelopes(q querier, userID int) ([]models.Envelope, error) {
+	query := `SELECT e.id, e.user_id, e.name, e.created_at,
+			         COALESCE(array_agg(ec.category_id ORDER BY ec.category_id) FILTER (WHERE ec.category_id IS NOT NULL), '{}')
+			  FROM envelopes e
+			  LEFT JOIN envelope_categories ec ON ec.envelope_id = e.id
+			  WHERE e.user_id = $1
+			  GROUP BY e.id
+			  ORDER BY e.id`
+	rows, err := q.Query(query, userID)
+	if err != nil {
+		log.Printf("Error retrieving envelopes: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	envelopes := []models.Envelope{}
+	for rows.Next() {
+		var envelope models.Envelope
+		var categories pq.Int64Array
+		if err := rows.Scan(&envelope.ID, &envelope.UserID, &envelope.Name, &envelope.CreatedAt, &categories); err != nil {
+			log.Printf("Error scanning envelope: %v", err)
+			return nil, err
+		}
+		envelope.CategoryIDs = make([]int, len(categories))
+		for i, id := range categories {
+			envelope.CategoryIDs[i] = int(id)
+		}
+		envelopes = append(envelopes, envelope)
+	}
+	return envelopes, rows.Err()
+}
+
+// GetEnvelopeByID возвращает конверт пользователя по ID.
+func (s *EnvelopeService) GetEnvelopeByID(id, userID int) (*models.Envelope, error) {
+	if err := checkOwnership(s.DB, "envelopes", id, userID); err != nil {
+		return nil, err
+	}
+	envelopes, err := s.GetEnvelopes(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, envelope := range envelopes {
+		if envelope.ID == id {
+			return &envelope, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// UpdateEnvelope переименовывает конверт и заменяет список его категорий.
+func (s *EnvelopeService) UpdateEnvelope(envelope *models.Envelope) error {
+	envelope.Name = strings.TrimSpace(envelope.Name)
+	if envelope.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidInput)
+	}
+	if err := checkOwnership(s.DB, "envelopes", envelope.ID, envelope.UserID); err != nil {
+		return err
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`UPDATE envelopes SET name = $1 WHERE id = $2`, envelope.Name, envelope.ID); err != nil {
+		log.Printf("Error updating envelope: %v", err)
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM envelope_categories WHERE envelope_id = $1`, envelope.ID); err != nil {
+		return err
+	}
+	if err := setEnvelopeCategories(tx, *envelope); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeleteEnvelope удаляет конверт. Распределённые в него суммы возвращаются в «готово к распределению»,
+// а расходы по его категориям становятся расходами без конверта.
+func (s *EnvelopeService) DeleteEnvelope(id, userID int) error {
+	if err := checkOwnership(s.DB, "envelopes", id, userID); err != nil {
+		return err
+	}
+	_, err := s.DB.Exec(`DELETE FROM envelopes WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		log.Printf("Error deleting envelope: %v", err)
+	}
+	return err
+}
+
+// Assign распределяет сумму из «готово к распределению» в конверт за месяц.
+// Отрицательная сумма возвращает деньги из конверта обратно, но не больше, чем в нём доступно.
+func (s *EnvelopeService) Assign(userID int, assignment models.EnvelopeAssignment) error {
+	month, err := parseEnvelopeMonth(assignment.Month)
+	if err != nil {
+		return err
+	}
+	if assignment.Amount.IsZero() {
+		return fmt.Errorf("%w: amount must not be zero", ErrInvalidInput)
+	}
+	if err := s.checkAmount(userID, assignment.Amount); err != nil {
+		return err
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := checkOwnership(tx, "envelopes", assignment.EnvelopeID, userID); err != nil {
+		return err
+	}
+	if assignment.Amount.IsNegative() {
+		if err := s.checkWithdrawal(tx, userID, assignment.EnvelopeID, month, -assignment.Amount); err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec(`INSERT INTO envelope_assignments (user_id, envelope_id, month, amount) VALUES ($1, $2, $3, $4)`,
+		userID, assignment.EnvelopeID, month, assignment.Amount)
+	if err != nil {
+		log.Printf("Error assigning money to envelope: %v", err)
+		return err
+	}
+	return tx.Commit()
+}
+
+// Move перемещает сумму между конвертами за месяц. В исходном конверте должно быть достаточно денег.
+func (s *EnvelopeService) Move(userID int, move models.EnvelopeMove) error {
+	month, err := parseEnvelopeMonth(move.Month)
+	if err != nil {
+		return err
+	}
+	if !move.Amount.IsPositive() {
+		return fmt.Errorf("%w: amount must be positive", ErrInvalidInput)
+	}
+	if move.FromEnvelopeID == move.ToEnvelopeID {
+		return fmt.Errorf("%w: source and target envelopes must differ", ErrInvalidInput)
+	}
+	if err := s.checkAmount(userID, move.Amount); err != nil {
+		return err
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, id := range []int{move.FromEnvelopeID, move.ToEnvelopeID} {
+		if err := checkOwnership(tx, "envelopes", id, userID); err != nil {
+			return err
+		}
+	}
+	if err := s.checkWithdrawal(tx, userID, move.FromEnvelopeID, month, move.Amount); err != nil {
+		return err
+	}
+
+	query := `INSERT INTO envelope_assignments (user_id, envelope_id, month, amount) VALUES ($1, $2, $3, $4)`
+	if _, err := tx.Exec(query, userID, move.FromEnvelopeID, month, -move.Amount); err != nil {
+		log.Printf("Error moving money between envelopes: %v", err)
+		return err
+	}
+	if _, err := tx.Exec(query, userID, move.ToEnvelopeID, month, move.Amount); err != nil {
+		log.Printf("Error moving money between envelopes: %v", err)
+		return err
+	}
+	return tx.Commit()
+}
+
+// checkWithdrawal блокирует конверт до конца tx и проверяет, что за месяц в нём доступно не меньше amount.
+// Остаток считается уже под блокировкой, поэтому параллельные возвраты и перемещения из одного
+// конверта не уводят его в минус.
+func (s *EnvelopeService) checkWithdrawal(tx *sql.Tx, userID, envelopeID int, month time.Time, amount money.Amount) error {
+	var locked int
+	if err := tx.QueryRow(`SELECT id FROM envelopes WHERE id = $1 FOR UPDATE`, envelopeID).Scan(&locked); err != nil {
+		return err
+	}
+
+	summary, err := s.monthSummary(tx, userID, month)
+	if err != nil {
+		return err
+	}
+	for _, balance := range summary.Envelopes {
+		if balance.EnvelopeID == envelopeID && balance.Available < amount {
+			return fmt.Errorf("%w: envelope %q has only %s %s available", ErrInvalidInput,
+				balance.Name, balance.Available, summary.Currency)
+		}
+	}
+	return nil
+}
+
+// GetMonth возвращает состояние конвертов за месяц month.
+//
+// Бюджет начинается с месяца создания первого конверта. Остаток конверта переходит в следующий месяц;
+// перерасход не переносится, а, как и расходы без конверта, уменьшает «готово к распределению»:
+// ready_to_assign = доходы − распределено − расходы без конверта − перерасход прошлых месяцев.
+func (s *EnvelopeService) GetMonth(userID int, month time.Time) (*models.EnvelopeMonth, error) {
+	return s.monthSummary(s.DB, userID, month)
+}
+
+// monthSummary считает состояние конвертов за месяц, читая данные через q — базу или открытую транзакцию.
+func (s *EnvelopeService) monthSummary(q querier, userID int, month time.Time) (*models.EnvelopeMonth, error) {
+	month = time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+	currency, err := s.Rates.PreferredCurrency(userID)
+	if err != nil {
+		return nil, err
+	}
+	summary := &models.EnvelopeMonth{
+		Month:     month.Format(envelopeMonthLayout),
+		Currency:  currency,
+		Envelopes: []models.EnvelopeBalance{},
+		Overspent: []models.EnvelopeBalance{},
+	}
+
+	envelopes, err := queryEnvelopes(q, userID)
+	if err != nil || len(envelopes) == 0 {
+		return summary, err
+	}
+	start := month
+	for _, envelope := range envelopes {
+		created := time.Date(envelope.CreatedAt.Year(), envelope.CreatedAt.Month(), 1, 0, 0, 0, 0, time.UTC)
+		if created.Before(start) {
+			start = created
+		}
+	}
+	end := month.AddDate(0, 1, 0)
+	converter := s.Rates.NewConverter()
+
+	rows, err := q.Query(`
+		SELECT to_char(date_trunc('month', created_at), 'YYYY-MM'), currency, created_at::date, SUM(amount)
+		FROM transactions
+		WHERE user_id = $1 AND type = 'income' AND created_at >= $2 AND created_at < $3 AND trash_id IS NULL
+		GROUP BY 1, 2, 3
+	`, userID, start, end)
+	if err != nil {
+		log.Printf("Error fetching envelope income: %v", err)
+		return nil, err
+	}
+	income, err := converter.sumRows(rows, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	// Ключ расходов — "<envelope_id>/<месяц>", расходы без конверта имеют envelope_id 0
+	rows, err = q.Query(`
+		SELECT COALESCE(ec.envelope_id, 0)::text || '/' || to_char(date_trunc('month', t.created_at), 'YYYY-MM'),
+		       t.currency, t.created_at::date, SUM(t.amount)
+		FROM transaction_lines t
+		LEFT JOIN envelope_categories ec ON ec.category_id = t.category_id
+		WHERE t.user_id = $1 AND t.type = 'expense' AND t.created_at >= $2 AND t.created_at < $3
+		GROUP BY 1, 2, 3
+	`, userID, start, end)
+	if err != nil {
+		log.Printf("Error fetching envelope activity: %v", err)
+		return nil, err
+	}
+	activity, err := converter.sumRows(rows, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	assigned, err := envelopeAssignments(q, userID, end)
+	if err != nil {
+		return nil, err
+	}
+
+	carry := make(map[int]money.Amount)
+	var totalIncome, totalAssigned, unbudgeted, overspent money.Amount
+	for m := start; m.Before(end); m = m.AddDate(0, 1, 0) {
+		key := m.Format(envelopeMonthLayout)
+		current := m.Equal(month)
+		totalIncome += income[key]
+		unbudgeted += activity["0/"+key]
+
+		for _, envelope := range envelopes {
+			balance := models.EnvelopeBalance{
+				EnvelopeID:  envelope.ID,
+				Name:        envelope.Name,
+				CarriedOver: carry[envelope.ID],
+				Assigned:    assigned[strconv.Itoa(envelope.ID)+"/"+key],
+				Activity:    activity[strconv.Itoa(envelope.ID)+"/"+key],
+			}
+			balance.Available = balance.CarriedOver + balance.Assigned - balance.Activity
+			totalAssigned += balance.Assigned
+
+			if current {
+				summary.Assigned += balance.Assigned
+				summary.Activity += balance.Activity
+				summary.Envelopes = append(summary.Envelopes, balance)
+				if balance.Available.IsNegative() {
+					summary.Overspent = append(summary.Overspent, balance)
+				}
+			} else if balance.Available.IsNegative() {
+				overspent -= balance.Available
+				carry[envelope.ID] = 0
+			} else {
+				carry[envelope.ID] = balance.Available
+			}
+		}
+	}
+
+	summary.Income = income[summary.Month]
+	summary.Unbudgeted = activity["0/"+summary.Month]
+	summary.ReadyToAssign = totalIncome - totalAssigned - unbudgeted - overspent
+	return summary, nil
+}
+
+// envelopeAssignments возвращает суммы распределений до end по ключу "<envelope_id>/<месяц>".
+func envelopeAssignments(q querier, userID int, end time.Time) (map[string]money.Amount, error) {
+	rows, err := q.Query(`
+		SELECT envelope_id::text || '/' || to_char(month, 'YYYY-MM'), SUM(amount)
+		FROM envelope_assignments
+		WHERE user_id = $1 AND month < $2
+		GROUP BY 1
+	`, userID, end)
+	if err != nil {
+		log.Printf("Error fetching envelope assignments: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	assigned := make(map[string]money.Amount)
+	for rows.Next() {
+		var key string
+		var amount money.Amount
+		if err := rows.Scan(&key, &amount); err != nil {
+			return nil, err
+		}
+		assigned[key] = amount
+	}
+	return assigned, rows.Err()
+}
+
+// checkAmount проверяет, что сумма не мельче минимальной единицы валюты бюджета.
+func (s *EnvelopeService) checkAmount(userID int, amount money.Amount) error {
+	currency, err := s.Rates.PreferredCurrency(userID)
+	if err != nil {
+		return err
+	}
+	if err := money.CheckPrecision(amount, currency); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	return nil
+}
+
+// setEnvelopeCategories привязывает категории пользователя к конверту.
+func setEnvelopeCategories(tx *sql.Tx, envelope models.Envelope) error {
+	for _, categoryID := range envelope.CategoryIDs {
+		if err := checkOwnership(tx, "categories", categoryID, envelope.UserID); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`INSERT INTO envelope_categories (category_id, envelope_id) VALUES ($1, $2)`,
+			categoryID, envelope.ID)
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return fmt.Errorf("%w: category %d already belongs to an envelope", ErrInvalidInput, categoryID)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseEnvelopeMonth разбирает месяц в формате YYYY-MM; пустая строка — текущий месяц.
+func parseEnvelopeMonth(s string) (time.Time, error) {
+	if s == "" {
+		now := today()
+		return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC), nil
+	}
+	month, err := time.Parse(envelopeMonthLayout, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: month must be in YYYY-MM format", ErrInvalidInput)
+	}
+	return month, nil
+}
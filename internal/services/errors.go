@@ -0,0 +1,47 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrNotFound возвращается, если запрошенная запись не существует.
+	ErrNotFound = errors.New("not found")
+	// ErrForbidden возвращается, если запись принадлежит другому пользователю.
+	ErrForbidden = errors.New("forbidden")
+	// ErrInvalidInput оборачивает ошибки валидации входных данных.
+	ErrInvalidInput = errors.New("invalid input")
+	// ErrInvalidCredentials возвращается при неверном email или пароле.
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// queryRower реализуется как *sql.DB, так и *sql.Tx.
+type queryRower interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// trashTables — таблицы, записи которых удаляются в корзину (см. TrashService).
+var trashTables = map[string]bool{"transactions": true, "transfers": true, "accounts": true, "categories": true}
+
+// checkOwnership проверяет, что запись таблицы table с указанным id принадлежит пользователю userID.
+// Записи в корзине считаются не найденными.
+func checkOwnership(q queryRower, table string, id, userID int) error {
+	var ownerID int
+	query := fmt.Sprintf(`SELECT user_id FROM %s WHERE id = $1`, table)
+	if trashTables[table] {
+		query += ` AND trash_id IS NULL`
+	}
+	err := q.QueryRow(query, id).Scan(&ownerID)
+	if err == sql.ErrNoRows {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if ownerID != userID {
+		return ErrForbidden
+	}
+	return nil
+}
@@ -53,12 +53,16 @@ func (s *FinancialGoalsService) CreateFinancialGoal(goal models.FinancialGoal) e
 	return nil
 }
 
-// UpdateFinancialGoal обновляет данные финансовой цели в базе данных.
+// UpdateFinancialGoal обновляет данные финансовой цели пользователя в базе данных.
 func (s *FinancialGoalsService) UpdateFinancialGoal(goal models.FinancialGoal) error {
+	if err := checkOwnership(s.DB, "financial_goals", goal.ID, goal.UserID); err != nil {
+		return err
+	}
+
 	query := `UPDATE financial_goals 
 	          SET name = $1, target_amount = $2, saved_amount = $3, deadline = $4, priority = $5, description = $6 
-	          WHERE id = $7`
-	_, err := s.DB.Exec(query, goal.Name, goal.TargetAmount, goal.CurrentAmount, goal.Deadline, goal.Priority, goal.Description, goal.ID)
+	          WHERE id = $7 AND user_id = $8`
+	_, err := s.DB.Exec(query, goal.Name, goal.TargetAmount, goal.CurrentAmount, goal.Deadline, goal.Priority, goal.Description, goal.ID, goal.UserID)
 	if err != nil {
 		log.Printf("Error updating financial goal: %v", err)
 		return err
@@ -66,10 +70,14 @@ func (s *FinancialGoalsService) UpdateFinancialGoal(goal models.FinancialGoal) e
 	return nil
 }
 
-// DeleteFinancialGoal удаляет финансовую цель из базы данных по ID.
-func (s *FinancialGoalsService) DeleteFinancialGoal(id int) error {
-	query := `DELETE FROM financial_goals WHERE id = $1`
-	_, err := s.DB.Exec(query, id)
+// DeleteFinancialGoal удаляет финансовую цель пользователя из базы данных по ID.
+func (s *FinancialGoalsService) DeleteFinancialGoal(id, userID int) error {
+	if err := checkOwnership(s.DB, "financial_goals", id, userID); err != nil {
+		return err
+	}
+
+	query := `DELETE FROM financial_goals WHERE id = $1 AND user_id = $2`
+	_, err := s.DB.Exec(query, id, userID)
 	if err != nil {
 		log.Printf("Error deleting financial goal: %v", err)
 		return err
@@ -0,0 +1,310 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"finance_project/internal/models"
+	"finance_project/internal/money"
+	"finance_project/internal/rrule"
+)
+
+// maxNetWorthBackfillDays ограничивает период, восстанавливаемый по журналу за один запрос.
+const maxNetWorthBackfillDays = 3660
+
+// netWorthLedgerQuery восстанавливает по журналу строки снимков за каждый день с $2 по $3: баланс счёта
+// на конец дня — начальный баланс плюс транзакции до этого дня включительно, долг — сумма минус возвраты
+// к этому дню. Счёт попадает в снимки с даты создания или первой транзакции, если она раньше.
+const netWorthLedgerQuery = `
+	WITH days AS (
+		SELECT day::date AS day FROM generate_series($2::date, $3::date, interval '1 day') AS day
+	), deltas AS (
+		SELECT account_id, created_at::date AS day,
+		       SUM(CASE WHEN type IN ('income', 'transfer_in', 'debt_in') THEN amount ELSE -amount END) AS delta
+		FROM transactions
+		WHERE user_id = $1 AND trash_id IS NULL
+		GROUP BY account_id, created_at::date
+	), balances AS (
+		SELECT g.day, a.id, a.currency,
+		       a.opening_balance
+		       + COALESCE((SELECT SUM(p.delta) FROM deltas p WHERE p.account_id = a.id AND p.day < $2::date), 0)
+		       + SUM(COALESCE(d.delta, 0)) OVER (PARTITION BY a.id ORDER BY g.day) AS balance,
+		       LEAST(a.created_at::date, (SELECT MIN(p.day) FROM deltas p WHERE p.account_id = a.id)) AS opened
+		FROM accounts a
+		CROSS JOIN days g
+		LEFT JOIN deltas d ON d.account_id = a.id AND d.day = g.day
+		WHERE a.user_id = $1 AND a.trash_id IS NULL
+	)
+	SELECT day, 'account', id, currency, balance FROM balances WHERE day >= opened
+	UNION ALL
+	SELECT g.day, d.direction, 0, d.currency, SUM(d.amount - COALESCE((
+	           SELECT SUM(t.amount) FROM transactions t
+	           WHERE t.debt_id = d.id AND t.trash_id IS NULL AND t.created_at < g.day + 1
+	             AND t.type = CASE d.direction WHEN 'lent' THEN 'debt_in' ELSE 'debt_out' END
+	       ), 0))
+	FROM debts d
+	JOIN days g ON g.day >= d.created_at::date
+	WHERE d.user_id = $1
+	GROUP BY g.day, d.direction, d.currency`
+
+// netWorthItem — строка снимка собственного капитала в исходной валюте.
+type netWorthItem struct {
+	date      time.Time
+	kind      string // models.NetWorthAccount, models.DebtLent или models.DebtBorrowed
+	accountID int
+	currency  string
+	amount    money.Amount
+}
+
+// RecordNetWorthSnapshots записывает снимок собственного капитала за дату now для каждого пользователя
+// по текущим балансам счетов и непогашенным долгам и возвращает число записанных снимков. Повторный
+// запуск в тот же день заменяет снимок, поэтому за день остаётся последнее состояние.
+func (s *ReportsService) RecordNetWorthSnapshots(now time.Time) (int, error) {
+	rows, err := s.DB.Query(`SELECT id FROM users WHERE deleted_at IS NULL ORDER BY id`)
+	if err != nil {
+		return 0, err
+	}
+	var userIDs []int
+	for rows.Next() {
+		var userID int
+		if err := rows.Scan(&userID); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	date := rrule.Date(now)
+	recorded := 0
+	for _, userID := range userIDs {
+		items, err := s.queryNetWorthItems(`
+			SELECT $2::date, 'account', id, currency, balance
+			FROM accounts
+			WHERE user_id = $1 AND trash_id IS NULL
+			UNION ALL
+			SELECT $2::date, direction, 0, currency, SUM(amount - repaid)
+			FROM (`+debtQuery+` WHERE d.user_id = $1 GROUP BY d.id) AS debt
+			GROUP BY direction, currency`, userID, date)
+		if err == nil {
+			err = s.saveNetWorthSnapshots(userID, date, date, items)
+		}
+		if err != nil {
+			// Ошибка одного пользователя (например, нет курса валюты) не должна останавливать остальных
+			log.Printf("Error recording net worth of user %d: %v", userID, err)
+			continue
+		}
+		recorded++
+	}
+	return recorded, nil
+}
+
+// BackfillNetWorth восстанавливает снимки собственного капитала за каждый день с from по to по журналу
+// транзакций, заменяя уже записанные. Без from история восстанавливается с первого счёта, транзакции
+// или долга пользователя, без to — по сегодняшний день.
+func (s *ReportsService) BackfillNetWorth(userID int, from, to time.Time) (*models.NetWorthBackfill, error) {
+	if to.IsZero() {
+		to = time.Now()
+	}
+	to = rrule.Date(to)
+	if from.IsZero() {
+		err := s.DB.QueryRow(`
+			SELECT LEAST(
+			    (SELECT MIN(created_at)::date FROM accounts WHERE user_id = $1 AND trash_id IS NULL),
+			    (SELECT MIN(created_at)::date FROM transactions WHERE user_id = $1 AND trash_id IS NULL),
+			    (SELECT MIN(created_at)::date FROM debts WHERE user_id = $1),
+			    $2::date)`, userID, to).Scan(&from)
+		if err != nil {
+			log.Printf("Error retrieving start of history: %v", err)
+			return nil, err
+		}
+	}
+	from = rrule.Date(from)
+	if from.After(to) {
+		return nil, fmt.Errorf("%w: from must not be after to", ErrInvalidInput)
+	}
+	days := int(to.Sub(from).Hours()/24) + 1
+	if days > maxNetWorthBackfillDays {
+		return nil, fmt.Errorf("%w: at most %d days can be backfilled at once", ErrInvalidInput, maxNetWorthBackfillDays)
+	}
+
+	items, err := s.queryNetWorthItems(netWorthLedgerQuery, userID, from, to)
+	if err != nil {
+		log.Printf("Error computing net worth history: %v", err)
+		return nil, err
+	}
+	if err := s.saveNetWorthSnapshots(userID, from, to, items); err != nil {
+		return nil, err
+	}
+	return &models.NetWorthBackfill{From: from, To: to, Days: days}, nil
+}
+
+// GetNetWorth возвращает собственный капитал пользователя с from по to в его предпочитаемой валюте.
+// granularity — day, week или month; для недели и месяца берётся последний снимок внутри периода.
+// Снимки, записанные в другой валюте отчёта, пересчитываются из исходных сумм по курсу на их дату.
+// Счета в корзине не учитываются.
+func (s *ReportsService) GetNetWorth(userID int, granularity string, from, to time.Time) (*models.NetWorthReport, error) {
+	switch granularity {
+	case "":
+		granularity = models.NetWorthDaily
+	case models.NetWorthDaily, models.NetWorthWeekly, models.NetWorthMonthly:
+	default:
+		return nil, fmt.Errorf("%w: granularity must be day, week or month", ErrInvalidInput)
+	}
+	if to.IsZero() {
+		to = time.Now()
+	}
+	if from.After(to) {
+		return nil, fmt.Errorf("%w: from must not be after to", ErrInvalidInput)
+	}
+
+	currency, err := s.Rates.PreferredCurrency(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT n.date, n.kind, COALESCE(n.account_id, 0), COALESCE(a.name, ''), n.currency, n.amount,
+			         n.report_currency, n.converted
+			  FROM net_worth_snapshots n
+			  LEFT JOIN accounts a ON a.id = n.account_id
+			  WHERE n.user_id = $1 AND (n.account_id IS NULL OR a.trash_id IS NULL)
+			    AND n.date IN (
+			        SELECT MAX(date) FROM net_worth_snapshots
+			        WHERE user_id = $1 AND date BETWEEN $2::date AND $3::date
+			        GROUP BY date_trunc($4::text, date)
+			    )
+			  ORDER BY n.date, a.name, n.account_id, n.kind, n.currency`
+	rows, err := s.DB.Query(query, userID, rrule.Date(from), rrule.Date(to), granularity)
+	if err != nil {
+		log.Printf("Error retrieving net worth snapshots: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	converter := s.Rates.NewConverter()
+	report := &models.NetWorthReport{Currency: currency, Granularity: granularity, Points: []models.NetWorthPoint{}}
+	var point *models.NetWorthPoint
+	for rows.Next() {
+		var item netWorthItem
+		var name, reportCurrency string
+		var converted money.Amount
+		err := rows.Scan(&item.date, &item.kind, &item.accountID, &name, &item.currency, &item.amount,
+			&reportCurrency, &converted)
+		if err != nil {
+			log.Printf("Error scanning net worth snapshot: %v", err)
+			return nil, err
+		}
+		if reportCurrency != currency {
+			if converted, err = converter.Convert(item.amount, item.currency, currency, item.date); err != nil {
+				return nil, err
+			}
+		}
+
+		if point == nil || !point.Date.Equal(item.date) {
+			report.Points = append(report.Points, models.NetWorthPoint{
+				Period:   netWorthPeriod(granularity, item.date),
+				Date:     item.date,
+				Accounts: []models.NetWorthBalance{},
+			})
+			point = &report.Points[len(report.Points)-1]
+		}
+		switch item.kind {
+		case models.NetWorthAccount:
+			point.Assets += converted
+			point.Accounts = append(point.Accounts, models.NetWorthBalance{
+				AccountID: item.accountID,
+				Name:      name,
+				Currency:  item.currency,
+				Balance:   item.amount,
+				Converted: converted,
+			})
+		case models.DebtLent:
+			point.Receivables += converted
+		case models.DebtBorrowed:
+			point.Liabilities += converted
+		}
+		point.NetWorth = point.Assets + point.Receivables - point.Liabilities
+	}
+	return report, rows.Err()
+}
+
+// queryNetWorthItems выполняет запрос, выбирающий строки снимков (дата, вид, счёт, валюта, сумма).
+func (s *ReportsService) queryNetWorthItems(query string, args ...interface{}) ([]netWorthItem, error) {
+	rows, err := s.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []netWorthItem
+	for rows.Next() {
+		var item netWorthItem
+		if err := rows.Scan(&item.date, &item.kind, &item.accountID, &item.currency, &item.amount); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// saveNetWorthSnapshots пересчитывает строки в предпочитаемую валюту пользователя по курсу на их дату
+// и заменяет ими снимки пользователя с from по to.
+func (s *ReportsService) saveNetWorthSnapshots(userID int, from, to time.Time, items []netWorthItem) error {
+	currency, err := s.Rates.PreferredCurrency(userID)
+	if err != nil {
+		return err
+	}
+	converter := s.Rates.NewConverter()
+	converted := make([]money.Amount, len(items))
+	for i, item := range items {
+		if converted[i], err = converter.Convert(item.amount, item.currency, currency, item.date); err != nil {
+			return err
+		}
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Блокировка пользователя не даёт задаче и восстановлению истории записать один день дважды
+	var locked int
+	err = tx.QueryRow(`SELECT id FROM users WHERE id = $1 FOR UPDATE`, userID).Scan(&locked)
+	if err == sql.ErrNoRows {
+		return ErrNotFound
+	} else if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`DELETE FROM net_worth_snapshots WHERE user_id = $1 AND date BETWEEN $2 AND $3`, userID, from, to)
+	if err != nil {
+		log.Printf("Error replacing net worth snapshots: %v", err)
+		return err
+	}
+	insert := `INSERT INTO net_worth_snapshots (user_id, date, kind, account_id, currency, amount, report_currency, converted)
+			   VALUES ($1, $2, $3, NULLIF($4, 0), $5, $6, $7, $8)`
+	for i, item := range items {
+		_, err := tx.Exec(insert, userID, item.date, item.kind, item.accountID, item.currency, item.amount, currency, converted[i])
+		if err != nil {
+			log.Printf("Error saving net worth snapshot: %v", err)
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// netWorthPeriod возвращает первый день периода granularity, содержащего date.
+func netWorthPeriod(granularity string, date time.Time) time.Time {
+	switch granularity {
+	case models.NetWorthWeekly:
+		return periodStart(models.BudgetWeekly, date)
+	case models.NetWorthMonthly:
+		return periodStart(models.BudgetMonthly, date)
+	}
+	return date
+}
@@ -3,17 +3,25 @@ package services
 import (
 	"database/sql"
 	"encoding/json"
+	"fmt"
 	"log"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
+
+	"finance_project/internal/models"
+	"finance_project/internal/money"
 )
 
 type ReportsService struct {
-	DB *sql.DB
+	DB    *sql.DB
+	Rates *CurrencyRateService
 }
 
 // NewReportsService создает новый сервис для отчетов.
-func NewReportsService(db *sql.DB) *ReportsService {
-	return &ReportsService{DB: db}
+func NewReportsService(db *sql.DB, rates *CurrencyRateService) *ReportsService {
+	return &ReportsService{DB: db, Rates: rates}
 }
 
 // GetOrCreateSummaryReport возвращает существующий отчет или создает новый.
@@ -56,30 +64,72 @@ func (s *ReportsService) GetOrCreateSummaryReport(userID int) (map[string]interf
 }
 
 // GenerateSummaryReport создает сводный отчет.
+// Суммы пересчитываются в предпочитаемую валюту пользователя: балансы по текущему курсу,
+// расходы — по курсу на дату каждой транзакции.
 func (s *ReportsService) GenerateSummaryReport(userID int) (map[string]interface{}, error) {
 	summary := make(map[string]interface{})
 
+	currency, err := s.Rates.PreferredCurrency(userID)
+	if err != nil {
+		return nil, err
+	}
+	converter := s.Rates.NewConverter()
+	summary["currency"] = currency
+
 	// Общий баланс по счетам
-	var totalBalance float64
-	err := s.DB.QueryRow(`SELECT COALESCE(SUM(balance), 0) FROM accounts WHERE user_id = $1`, userID).Scan(&totalBalance)
+	rows, err := s.DB.Query(`
+		SELECT 'total_balance', currency, CURRENT_DATE, SUM(balance)
+		FROM accounts
+		WHERE user_id = $1 AND trash_id IS NULL
+		GROUP BY currency
+	`, userID)
 	if err != nil {
 		log.Printf("Error fetching total balance: %v", err)
 		return nil, err
 	}
-	summary["total_balance"] = totalBalance
+	balances, err := converter.sumRows(rows, currency)
+	if err != nil {
+		log.Printf("Error converting total balance: %v", err)
+		return nil, err
+	}
+	summary["total_balance"] = balances["total_balance"]
 
 	// Расходы за текущий месяц
-	var totalExpenses float64
-	err = s.DB.QueryRow(`
-		SELECT COALESCE(SUM(amount), 0) 
-		FROM transactions 
-		WHERE user_id = $1 AND type = 'expense' AND EXTRACT(MONTH FROM created_at) = EXTRACT(MONTH FROM CURRENT_DATE)
-	`, userID).Scan(&totalExpenses)
+	rows, err = s.DB.Query(`
+		SELECT 'total_expenses', currency, created_at::date, SUM(amount)
+		FROM transactions
+		WHERE user_id = $1 AND type = 'expense' AND created_at >= date_trunc('month', CURRENT_DATE) AND trash_id IS NULL
+		GROUP BY currency, created_at::date
+	`, userID)
 	if err != nil {
 		log.Printf("Error fetching total expenses: %v", err)
 		return nil, err
 	}
-	summary["total_expenses"] = totalExpenses
+	expenses, err := converter.sumRows(rows, currency)
+	if err != nil {
+		log.Printf("Error converting total expenses: %v", err)
+		return nil, err
+	}
+	summary["total_expenses"] = expenses["total_expenses"]
+
+	// Непогашенные долги: lent — нам должны, borrowed — мы должны
+	rows, err = s.DB.Query(`
+		SELECT direction, currency, CURRENT_DATE, SUM(amount - repaid)
+		FROM (`+debtQuery+` WHERE d.user_id = $1 GROUP BY d.id) AS debt
+		GROUP BY direction, currency
+	`, userID)
+	if err != nil {
+		log.Printf("Error fetching debts: %v", err)
+		return nil, err
+	}
+	debts, err := converter.sumRows(rows, currency)
+	if err != nil {
+		log.Printf("Error converting debts: %v", err)
+		return nil, err
+	}
+	summary["debts_receivable"] = debts["lent"]
+	summary["debts_payable"] = debts["borrowed"]
+	summary["net_debt"] = debts["lent"] - debts["borrowed"]
 
 	// Выполненные финансовые цели
 	var completedGoals int
@@ -100,32 +150,298 @@ func (s *ReportsService) GenerateSummaryReport(userID int) (map[string]interface
 	return summary, nil
 }
 
-// GetExpensesByCategory возвращает расходы, сгруппированные по категориям.
-func (s *ReportsService) GetExpensesByCategory(userID int, startDate, endDate string) (map[string]float64, error) {
-	query := `
-		SELECT c.name AS category, COALESCE(SUM(t.amount), 0) AS total_expenses
-		FROM transactions t
-		JOIN categories c ON t.category_id = c.id
-		WHERE t.user_id = $1 AND t.created_at BETWEEN $2 AND $3 AND t.type = 'expense'
-		GROUP BY c.name
-	`
-	rows, err := s.DB.Query(query, userID, startDate, endDate)
+// GetExpensesByCategory возвращает расходы каждой категории без подкатегорий
+// в предпочитаемой валюте пользователя по курсу на дату каждой транзакции.
+// Категории различаются по id, поэтому одноимённые подкатегории разных родителей
+// не сливаются; Path содержит полный путь категории. Список упорядочен по убыванию суммы.
+func (s *ReportsService) GetExpensesByCategory(userID int, startDate, endDate string) ([]models.CategoryTotal, error) {
+	currency, err := s.Rates.PreferredCurrency(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	amounts, err := s.sumExpenses(s.Rates.NewConverter(), currency, "c.id::text",
+		"t.user_id = $1 AND t.created_at BETWEEN $2 AND $3", userID, startDate, endDate)
 	if err != nil {
 		log.Printf("Error fetching expenses by category: %v", err)
 		return nil, err
 	}
+
+	// Категории из корзины тоже нужны: расходы по ним остаются в отчёте
+	rows, err := s.DB.Query(`SELECT id, COALESCE(parent_id, 0), name FROM categories WHERE user_id = $1`, userID)
+	if err != nil {
+		log.Printf("Error retrieving categories: %v", err)
+		return nil, err
+	}
 	defer rows.Close()
 
-	expenses := make(map[string]float64)
+	names := make(map[int]string)
+	parents := make(map[int]int)
 	for rows.Next() {
-		var category string
-		var total float64
-		if err := rows.Scan(&category, &total); err != nil {
-			log.Printf("Error scanning row: %v", err)
+		var id, parentID int
+		var name string
+		if err := rows.Scan(&id, &parentID, &name); err != nil {
 			return nil, err
 		}
-		expenses[category] = total
+		names[id], parents[id] = name, parentID
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	path := func(id int) string {
+		var parts []string
+		for ; id != 0 && len(parts) <= len(names); id = parents[id] {
+			parts = append([]string{names[id]}, parts...)
+		}
+		return strings.Join(parts, " / ")
 	}
 
+	expenses := []models.CategoryTotal{}
+	for key, amount := range amounts {
+		id, err := strconv.Atoi(key)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected category key %q: %v", key, err)
+		}
+		expenses = append(expenses, models.CategoryTotal{CategoryID: id, Name: names[id], Path: path(id), Amount: amount, Total: amount})
+	}
+	sort.Slice(expenses, func(i, j int) bool {
+		if expenses[i].Total != expenses[j].Total {
+			return expenses[i].Total > expenses[j].Total
+		}
+		return expenses[i].Path < expenses[j].Path
+	})
 	return expenses, nil
 }
+
+// GetExpensesByCategoryTree возвращает расходы за период деревом категорий в предпочитаемой валюте
+// пользователя. У каждого узла Amount — расходы самой категории, Total — вместе с подкатегориями.
+// depth ограничивает глубину дерева: подкатегории глубже depth не выводятся, но входят в Total
+// своего предка; depth = 0 — дерево целиком. Ветви без расходов отбрасываются.
+func (s *ReportsService) GetExpensesByCategoryTree(userID int, startDate, endDate string, depth int) ([]models.CategoryTotal, error) {
+	currency, err := s.Rates.PreferredCurrency(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	amounts, err := s.sumExpenses(s.Rates.NewConverter(), currency, "c.id::text",
+		"t.user_id = $1 AND t.created_at BETWEEN $2 AND $3", userID, startDate, endDate)
+	if err != nil {
+		log.Printf("Error fetching expenses by category: %v", err)
+		return nil, err
+	}
+
+	rows, err := s.DB.Query(`SELECT id, COALESCE(parent_id, 0), name FROM categories WHERE user_id = $1 AND trash_id IS NULL ORDER BY name, id`, userID)
+	if err != nil {
+		log.Printf("Error retrieving categories: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	names := make(map[int]string)
+	children := make(map[int][]int)
+	for rows.Next() {
+		var id, parentID int
+		var name string
+		if err := rows.Scan(&id, &parentID, &name); err != nil {
+			return nil, err
+		}
+		names[id] = name
+		children[parentID] = append(children[parentID], id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var build func(id, level int) models.CategoryTotal
+	build = func(id, level int) models.CategoryTotal {
+		node := models.CategoryTotal{CategoryID: id, Name: names[id], Amount: amounts[strconv.Itoa(id)]}
+		node.Total = node.Amount
+		for _, childID := range children[id] {
+			child := build(childID, level+1)
+			if child.Total.IsZero() {
+				continue
+			}
+			node.Total += child.Total
+			if depth == 0 || level < depth {
+				node.Children = append(node.Children, child)
+			}
+		}
+		sortCategoryTotals(node.Children)
+		return node
+	}
+
+	tree := []models.CategoryTotal{}
+	for _, id := range children[0] {
+		if node := build(id, 1); !node.Total.IsZero() {
+			tree = append(tree, node)
+		}
+	}
+	sortCategoryTotals(tree)
+	return tree, nil
+}
+
+// sortCategoryTotals упорядочивает узлы по убыванию суммы с подкатегориями.
+func sortCategoryTotals(nodes []models.CategoryTotal) {
+	sort.SliceStable(nodes, func(i, j int) bool { return nodes[i].Total > nodes[j].Total })
+}
+
+// GetTotalsByTag возвращает доходы и расходы по меткам за период в предпочитаемой валюте
+// пользователя по курсу на дату каждой транзакции, с разбивкой по категориям и счетам.
+// Транзакция с несколькими метками учитывается в каждой из них; переводы и долги не учитываются.
+func (s *ReportsService) GetTotalsByTag(userID int, startDate, endDate string) (*models.TagReport, error) {
+	currency, err := s.Rates.PreferredCurrency(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	const lines = `
+		FROM transaction_lines t
+		JOIN transaction_tags tt ON tt.transaction_id = t.transaction_id
+		JOIN tags g ON g.id = tt.tag_id
+		WHERE t.user_id = $1 AND t.type IN ('income', 'expense') AND t.created_at BETWEEN $2 AND $3`
+	rows, err := s.DB.Query(`SELECT g.id, g.name, COUNT(DISTINCT t.transaction_id)`+lines+`
+		GROUP BY g.id, g.name
+		ORDER BY lower(g.name)`, userID, startDate, endDate)
+	if err != nil {
+		log.Printf("Error fetching tags for report: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	report := &models.TagReport{Currency: currency, Tags: []models.TagTotal{}}
+	index := make(map[int]int)
+	for rows.Next() {
+		var total models.TagTotal
+		if err := rows.Scan(&total.TagID, &total.Name, &total.Transactions); err != nil {
+			return nil, err
+		}
+		index[total.TagID] = len(report.Tags)
+		report.Tags = append(report.Tags, total)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(report.Tags) == 0 {
+		return report, nil
+	}
+
+	rows, err = s.DB.Query(`
+		SELECT concat_ws(' ', g.id, t.type, COALESCE(t.category_id, 0), t.account_id), t.currency, t.created_at::date, SUM(t.amount)`+lines+`
+		GROUP BY 1, t.currency, t.created_at::date`, userID, startDate, endDate)
+	if err != nil {
+		log.Printf("Error fetching totals by tag: %v", err)
+		return nil, err
+	}
+	sums, err := s.Rates.NewConverter().sumRows(rows, currency)
+	if err != nil {
+		log.Printf("Error converting totals by tag: %v", err)
+		return nil, err
+	}
+	categories, err := s.namesByID("categories", userID)
+	if err != nil {
+		return nil, err
+	}
+	accounts, err := s.namesByID("accounts", userID)
+	if err != nil {
+		return nil, err
+	}
+
+	byCategory := make(map[[2]int]*models.TagBreakdown)
+	byAccount := make(map[[2]int]*models.TagBreakdown)
+	breakdown := func(parts map[[2]int]*models.TagBreakdown, names map[int]string, tagID, id int) *models.TagBreakdown {
+		part := parts[[2]int{tagID, id}]
+		if part == nil {
+			part = &models.TagBreakdown{ID: id, Name: names[id]}
+			parts[[2]int{tagID, id}] = part
+		}
+		return part
+	}
+	for key, amount := range sums {
+		var tagID, categoryID, accountID int
+		var kind string
+		if _, err := fmt.Sscanf(key, "%d %s %d %d", &tagID, &kind, &categoryID, &accountID); err != nil {
+			return nil, fmt.Errorf("unexpected tag report key %q: %v", key, err)
+		}
+		total := &report.Tags[index[tagID]]
+		category := breakdown(byCategory, categories, tagID, categoryID)
+		account := breakdown(byAccount, accounts, tagID, accountID)
+		if kind == "income" {
+			total.Income += amount
+			category.Income += amount
+			account.Income += amount
+		} else {
+			total.Expenses += amount
+			category.Expenses += amount
+			account.Expenses += amount
+		}
+	}
+
+	for i := range report.Tags {
+		total := &report.Tags[i]
+		total.Categories = collectTagBreakdown(byCategory, total.TagID)
+		total.Accounts = collectTagBreakdown(byAccount, total.TagID)
+	}
+	sort.SliceStable(report.Tags, func(i, j int) bool { return report.Tags[i].Expenses > report.Tags[j].Expenses })
+	return report, nil
+}
+
+// collectTagBreakdown возвращает разбивку метки tagID по убыванию расходов, затем доходов.
+func collectTagBreakdown(parts map[[2]int]*models.TagBreakdown, tagID int) []models.TagBreakdown {
+	result := []models.TagBreakdown{}
+	for key, part := range parts {
+		if key[0] == tagID {
+			result = append(result, *part)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Expenses != result[j].Expenses {
+			return result[i].Expenses > result[j].Expenses
+		}
+		if result[i].Income != result[j].Income {
+			return result[i].Income > result[j].Income
+		}
+		return result[i].ID < result[j].ID
+	})
+	return result
+}
+
+// namesByID возвращает имена записей пользователя из таблицы table (категорий или счетов) по ID.
+func (s *ReportsService) namesByID(table string, userID int) (map[int]string, error) {
+	rows, err := s.DB.Query(`SELECT id, name FROM `+table+` WHERE user_id = $1`, userID)
+	if err != nil {
+		log.Printf("Error retrieving %s: %v", table, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	names := make(map[int]string)
+	for rows.Next() {
+		var id int
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return nil, err
+		}
+		names[id] = name
+	}
+	return names, rows.Err()
+}
+
+// sumExpenses суммирует расходы по категориям, отобранные условием where, в валюте currency
+// по курсу на дату каждой транзакции. Суммы группируются по SQL-выражению key; в выражениях
+// доступны строки транзакций t (представление transaction_lines, где разбитая транзакция
+// даёт строку на каждую категорию) и categories c.
+func (s *ReportsService) sumExpenses(converter *Converter, currency, key, where string, args ...interface{}) (map[string]money.Amount, error) {
+	query := `
+		SELECT ` + key + `, t.currency, t.created_at::date, SUM(t.amount)
+		FROM transaction_lines t
+		JOIN categories c ON t.category_id = c.id
+		WHERE t.type = 'expense' AND ` + where + `
+		GROUP BY 1, t.currency, t.created_at::date
+	`
+	rows, err := s.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return converter.sumRows(rows, currency)
+}
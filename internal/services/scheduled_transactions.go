@@ -0,0 +1,553 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"finance_project/internal/models"
+	"finance_project/internal/money"
+	"finance_project/internal/rrule"
+
+	"github.com/lib/pq"
+)
+
+const scheduleColumns = `id, user_id, account_id, amount, type, COALESCE(category_id, 0), description, schedule,
+	start_date, end_date, paused, next_run_date, last_error, created_at`
+
+// ScheduledTransactionService управляет регулярными транзакциями и создаёт их по расписанию.
+type ScheduledTransactionService struct {
+	DB           *sql.DB
+	Transactions *TransactionService
+}
+
+// NewScheduledTransactionService создает новый сервис регулярных транзакций.
+func NewScheduledTransactionService(db *sql.DB, transactions *TransactionService) *ScheduledTransactionService {
+	return &ScheduledTransactionService{DB: db, Transactions: transactions}
+}
+
+// CreateSchedule добавляет расписание. Первое выполнение — первая дата правила не раньше StartDate.
+func (s *ScheduledTransactionService) CreateSchedule(schedule *models.ScheduledTransaction) (int, error) {
+	rule, err := s.validate(schedule)
+	if err != nil {
+		return 0, err
+	}
+	next := nextRunDate(rule, *schedule, schedule.StartDate)
+	if next == nil {
+		return 0, fmt.Errorf("%w: schedule has no occurrences", ErrInvalidInput)
+	}
+
+	query := `INSERT INTO scheduled_transactions
+			  (user_id, account_id, amount, type, category_id, description, schedule, start_date, end_date, paused, next_run_date)
+			  VALUES ($1, $2, $3, $4, NULLIF($5, 0), $6, $7, $8, $9, $10, $11)
+			  RETURNING id`
+	err = s.DB.QueryRow(query, schedule.UserID, schedule.AccountID, schedule.Amount, schedule.Type, schedule.CategoryID,
+		schedule.Description, schedule.Schedule, schedule.StartDate, schedule.EndDate, schedule.Paused, next).Scan(&schedule.ID)
+	if err != nil {
+		log.Printf("Error creating scheduled transaction: %v", err)
+		return 0, err
+	}
+	return schedule.ID, nil
+}
+
+// GetSchedules возвращает все расписания пользователя.
+func (s *ScheduledTransactionService) GetSchedules(userID int) ([]models.ScheduledTransaction, error) {
+	rows, err := s.DB.Query(`SELECT `+scheduleColumns+` FROM scheduled_transactions WHERE user_id = $1 ORDER BY id`, userID)
+	if err != nil {
+		log.Printf("Error retrieving scheduled transactions: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var schedules []models.ScheduledTransaction
+	for rows.Next() {
+		schedule, err := scanSchedule(rows)
+		if err != nil {
+			log.Printf("Error scanning scheduled transaction: %v", err)
+			return nil, err
+		}
+		schedules = append(schedules, schedule)
+	}
+	return schedules, rows.Err()
+}
+
+// GetScheduleByID возвращает расписание пользователя по ID.
+func (s *ScheduledTransactionService) GetScheduleByID(id, userID int) (*models.ScheduledTransaction, error) {
+	if err := checkOwnership(s.DB, "scheduled_transactions", id, userID); err != nil {
+		return nil, err
+	}
+	schedule, err := scanSchedule(s.DB.QueryRow(`SELECT `+scheduleColumns+` FROM scheduled_transactions WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		log.Printf("Error retrieving scheduled transaction: %v", err)
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+// UpdateSchedule изменяет расписание. Новое правило действует с сегодняшнего дня;
+// уже созданные транзакции не меняются.
+func (s *ScheduledTransactionService) UpdateSchedule(schedule *models.ScheduledTransaction) error {
+	rule, err := s.validate(schedule)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := lockSchedule(tx, schedule.ID, schedule.UserID); err != nil {
+		return err
+	}
+	next := nextRunDate(rule, *schedule, maxDate(schedule.StartDate, today()))
+
+	query := `UPDATE scheduled_transactions
+			  SET account_id = $1, amount = $2, type = $3, category_id = NULLIF($4, 0), description = $5, schedule = $6,
+			      start_date = $7, end_date = $8, paused = $9, next_run_date = $10, last_error = ''
+			  WHERE id = $11`
+	_, err = tx.Exec(query, schedule.AccountID, schedule.Amount, schedule.Type, schedule.CategoryID, schedule.Description,
+		schedule.Schedule, schedule.StartDate, schedule.EndDate, schedule.Paused, next, schedule.ID)
+	if err != nil {
+		log.Printf("Error updating scheduled transaction: %v", err)
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeleteSchedule удаляет расписание. Созданные по нему транзакции остаются.
+func (s *ScheduledTransactionService) DeleteSchedule(id, userID int) error {
+	if err := checkOwnership(s.DB, "scheduled_transactions", id, userID); err != nil {
+		return err
+	}
+	_, err := s.DB.Exec(`DELETE FROM scheduled_transactions WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		log.Printf("Error deleting scheduled transaction: %v", err)
+	}
+	return err
+}
+
+// SetPaused приостанавливает или возобновляет расписание.
+// Выполнения, наступившие за время паузы, при возобновлении не создаются.
+func (s *ScheduledTransactionService) SetPaused(id, userID int, paused bool) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	schedule, err := lockSchedule(tx, id, userID)
+	if err != nil {
+		return err
+	}
+	if schedule.Paused == paused {
+		return nil
+	}
+
+	next := schedule.NextRunDate
+	if !paused && next != nil {
+		rule, err := rrule.Parse(schedule.Schedule)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidInput, err)
+		}
+		next = nextRunDate(rule, schedule, maxDate(*next, today()))
+	}
+
+	_, err = tx.Exec(`UPDATE scheduled_transactions SET paused = $1, next_run_date = $2 WHERE id = $3`, paused, next, id)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetOccurrences возвращает выполнения расписания в диапазоне дат: уже обработанные
+// (posted, skipped, held) и предстоящие (scheduled).
+func (s *ScheduledTransactionService) GetOccurrences(id, userID int, from, to time.Time) ([]models.ScheduledOccurrence, error) {
+	schedule, err := s.GetScheduleByID(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	rule, err := rrule.Parse(schedule.Schedule)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	from, to = rrule.Date(from), rrule.Date(to)
+
+	recorded := make(map[time.Time]models.ScheduledOccurrence)
+	rows, err := s.DB.Query(`SELECT occurrence_date, status, COALESCE(transaction_id, 0)
+							 FROM scheduled_occurrences
+							 WHERE schedule_id = $1 AND occurrence_date BETWEEN $2 AND $3`, id, from, to)
+	if err != nil {
+		log.Printf("Error retrieving schedule occurrences: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		occurrence := models.ScheduledOccurrence{ScheduleID: id}
+		if err := rows.Scan(&occurrence.Date, &occurrence.Status, &occurrence.TransactionID); err != nil {
+			return nil, err
+		}
+		occurrence.Date = rrule.Date(occurrence.Date)
+		recorded[occurrence.Date] = occurrence
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	occurrences := []models.ScheduledOccurrence{}
+	for _, date := range rule.Between(schedule.StartDate, from, to) {
+		if occurrence, ok := recorded[date]; ok {
+			occurrences = append(occurrences, occurrence)
+			delete(recorded, date)
+			continue
+		}
+		// Прошедшие необработанные даты не будут созданы (пауза или изменение правила)
+		if schedule.NextRunDate == nil || date.Before(*schedule.NextRunDate) || afterEnd(*schedule, date) {
+			continue
+		}
+		occurrences = append(occurrences, models.ScheduledOccurrence{ScheduleID: id, Date: date, Status: models.OccurrenceScheduled})
+	}
+	// Записи по датам старого правила тоже показываются
+	for _, occurrence := range recorded {
+		occurrences = append(occurrences, occurrence)
+	}
+	sort.Slice(occurrences, func(i, j int) bool { return occurrences[i].Date.Before(occurrences[j].Date) })
+	return occurrences, nil
+}
+
+// SkipOccurrence отменяет одно выполнение расписания: транзакция за эту дату не будет создана.
+func (s *ScheduledTransactionService) SkipOccurrence(id, userID int, date time.Time) error {
+	return s.markOccurrence(id, userID, date, models.OccurrenceSkipped)
+}
+
+// HoldOccurrence приостанавливает одно выполнение до вызова ReleaseOccurrence.
+func (s *ScheduledTransactionService) HoldOccurrence(id, userID int, date time.Time) error {
+	return s.markOccurrence(id, userID, date, models.OccurrenceHeld)
+}
+
+func (s *ScheduledTransactionService) markOccurrence(id, userID int, date time.Time, status string) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	schedule, err := lockSchedule(tx, id, userID)
+	if err != nil {
+		return err
+	}
+	date = rrule.Date(date)
+	if err := checkOccurrenceDate(schedule, date); err != nil {
+		return err
+	}
+
+	var current string
+	err = tx.QueryRow(`SELECT status FROM scheduled_occurrences WHERE schedule_id = $1 AND occurrence_date = $2`,
+		id, date).Scan(&current)
+	switch {
+	case err == sql.ErrNoRows:
+		_, err = tx.Exec(`INSERT INTO scheduled_occurrences (schedule_id, occurrence_date, status) VALUES ($1, $2, $3)`,
+			id, date, status)
+	case err != nil:
+	case current == models.OccurrencePosted:
+		return fmt.Errorf("%w: occurrence on %s is already posted", ErrInvalidInput, date.Format(rateDateLayout))
+	default:
+		_, err = tx.Exec(`UPDATE scheduled_occurrences SET status = $1 WHERE schedule_id = $2 AND occurrence_date = $3`,
+			status, id, date)
+	}
+	if err != nil {
+		log.Printf("Error updating schedule occurrence: %v", err)
+		return err
+	}
+	return tx.Commit()
+}
+
+// ReleaseOccurrence снимает пропуск или паузу с выполнения. Если дата уже наступила,
+// транзакция создаётся сразу; будущие даты будут обработаны по расписанию.
+func (s *ScheduledTransactionService) ReleaseOccurrence(id, userID int, date time.Time) (*models.ScheduledOccurrence, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	schedule, err := lockSchedule(tx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	date = rrule.Date(date)
+
+	var status string
+	err = tx.QueryRow(`SELECT status FROM scheduled_occurrences WHERE schedule_id = $1 AND occurrence_date = $2`,
+		id, date).Scan(&status)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if status == models.OccurrencePosted {
+		return nil, fmt.Errorf("%w: occurrence on %s is already posted", ErrInvalidInput, date.Format(rateDateLayout))
+	}
+
+	occurrence := &models.ScheduledOccurrence{ScheduleID: id, Date: date, Status: models.OccurrenceScheduled}
+	processed := schedule.NextRunDate == nil || date.Before(*schedule.NextRunDate)
+	if processed && !date.After(today()) {
+		// Воркер уже прошёл эту дату, поэтому транзакция создаётся здесь
+		_, err = tx.Exec(`UPDATE scheduled_occurrences SET status = $1 WHERE schedule_id = $2 AND occurrence_date = $3`,
+			models.OccurrencePosted, id, date)
+		if err == nil {
+			occurrence.TransactionID, err = postOccurrence(tx, schedule, date)
+			occurrence.Status = models.OccurrencePosted
+		}
+	} else {
+		_, err = tx.Exec(`DELETE FROM scheduled_occurrences WHERE schedule_id = $1 AND occurrence_date = $2`, id, date)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	if occurrence.TransactionID != 0 {
+		s.Transactions.invalidateCache(userID)
+	}
+	return occurrence, nil
+}
+
+// ProcessDue создает транзакции для всех наступивших к дате now выполнений и возвращает их число.
+// Каждое расписание обрабатывается в отдельной транзакции БД под блокировкой FOR UPDATE SKIP LOCKED,
+// а каждое выполнение фиксируется уникальной строкой scheduled_occurrences, поэтому при перезапусках
+// и нескольких экземплярах приложения транзакция за дату создаётся ровно один раз.
+func (s *ScheduledTransactionService) ProcessDue(now time.Time) (int, error) {
+	day := rrule.Date(now)
+	failed := []int64{}
+	total := 0
+	for {
+		posted, scheduleID, err := s.processNext(day, failed)
+		if err == sql.ErrNoRows {
+			return total, nil
+		}
+		if err != nil {
+			if scheduleID == 0 {
+				return total, err
+			}
+			// Ошибка одного расписания не должна останавливать остальные
+			log.Printf("Error processing scheduled transaction %d: %v", scheduleID, err)
+			if _, uerr := s.DB.Exec(`UPDATE scheduled_transactions SET last_error = $1 WHERE id = $2`, err.Error(), scheduleID); uerr != nil {
+				log.Printf("Error saving schedule error: %v", uerr)
+			}
+			failed = append(failed, int64(scheduleID))
+			continue
+		}
+		total += posted
+	}
+}
+
+// processNext обрабатывает одно наступившее расписание, не заблокированное другим воркером.
+// Если таких нет, возвращается sql.ErrNoRows.
+func (s *ScheduledTransactionService) processNext(day time.Time, exclude []int64) (int, int, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback()
+	if err := setAuditActor(tx, "job:scheduled-transactions"); err != nil {
+		return 0, 0, err
+	}
+
+	// Расписания счетов и категорий, лежащих в корзине, ждут их восстановления;
+	// расписания удалённых пользователей не выполняются
+	query := `SELECT ` + scheduleColumns + ` FROM scheduled_transactions
+			  WHERE NOT paused AND next_run_date <= $1 AND NOT (id = ANY($2))
+			    AND user_id IN (SELECT id FROM users WHERE deleted_at IS NULL)
+			    AND account_id IN (SELECT id FROM accounts WHERE trash_id IS NULL)
+			    AND (category_id IS NULL OR category_id IN (SELECT id FROM categories WHERE trash_id IS NULL))
+			  ORDER BY next_run_date, id
+			  LIMIT 1
+			  FOR UPDATE SKIP LOCKED`
+	schedule, err := scanSchedule(tx.QueryRow(query, day, pq.Array(exclude)))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	rule, err := rrule.Parse(schedule.Schedule)
+	if err != nil {
+		return 0, schedule.ID, err
+	}
+
+	posted := 0
+	next := nextRunDate(rule, schedule, *schedule.NextRunDate)
+	for next != nil && !next.After(day) {
+		var occurrenceID int
+		err := tx.QueryRow(`INSERT INTO scheduled_occurrences (schedule_id, occurrence_date, status)
+							VALUES ($1, $2, $3)
+							ON CONFLICT (schedule_id, occurrence_date) DO NOTHING
+							RETURNING id`, schedule.ID, *next, models.OccurrencePosted).Scan(&occurrenceID)
+		switch {
+		case err == sql.ErrNoRows:
+			// Дата уже обработана, пропущена или приостановлена
+		case err != nil:
+			return 0, schedule.ID, err
+		default:
+			if _, err := postOccurrence(tx, schedule, *next); err != nil {
+				return 0, schedule.ID, err
+			}
+			posted++
+		}
+		next = nextRunDate(rule, schedule, next.AddDate(0, 0, 1))
+	}
+
+	_, err = tx.Exec(`UPDATE scheduled_transactions SET next_run_date = $1, last_error = '' WHERE id = $2`, next, schedule.ID)
+	if err != nil {
+		return 0, schedule.ID, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, schedule.ID, err
+	}
+	if posted > 0 {
+		s.Transactions.invalidateCache(schedule.UserID)
+	}
+	return posted, schedule.ID, nil
+}
+
+// postOccurrence создает транзакцию выполнения за дату date и связывает её с записью выполнения.
+func postOccurrence(tx *sql.Tx, schedule models.ScheduledTransaction, date time.Time) (int, error) {
+	transaction := models.Transaction{
+		UserID:      schedule.UserID,
+		AccountID:   schedule.AccountID,
+		Amount:      schedule.Amount,
+		Type:        schedule.Type,
+		CategoryID:  schedule.CategoryID,
+		Description: schedule.Description,
+		CreatedAt:   date,
+	}
+	id, err := createTransactionTx(tx, &transaction)
+	if err != nil {
+		return 0, err
+	}
+	_, err = tx.Exec(`UPDATE scheduled_occurrences SET transaction_id = $1 WHERE schedule_id = $2 AND occurrence_date = $3`,
+		id, schedule.ID, date)
+	return id, err
+}
+
+// validate проверяет расписание и возвращает разобранное правило. Сумма не может быть мельче
+// минимальной единицы валюты счёта.
+func (s *ScheduledTransactionService) validate(schedule *models.ScheduledTransaction) (*rrule.Rule, error) {
+	if err := checkRegularType(models.Transaction{Type: schedule.Type}); err != nil {
+		return nil, err
+	}
+	if !schedule.Amount.IsPositive() {
+		return nil, fmt.Errorf("%w: amount must be positive", ErrInvalidInput)
+	}
+	rule, err := rrule.Parse(schedule.Schedule)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	if schedule.StartDate.IsZero() {
+		schedule.StartDate = today()
+	}
+	schedule.StartDate = rrule.Date(schedule.StartDate)
+	if schedule.EndDate != nil {
+		end := rrule.Date(*schedule.EndDate)
+		if end.Before(schedule.StartDate) {
+			return nil, fmt.Errorf("%w: end_date is before start_date", ErrInvalidInput)
+		}
+		schedule.EndDate = &end
+	}
+
+	if err := checkOwnership(s.DB, "accounts", schedule.AccountID, schedule.UserID); err != nil {
+		return nil, err
+	}
+	var currency string
+	if err := s.DB.QueryRow(`SELECT currency FROM accounts WHERE id = $1`, schedule.AccountID).Scan(&currency); err != nil {
+		return nil, err
+	}
+	if err := money.CheckPrecision(schedule.Amount, currency); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	if schedule.CategoryID != 0 {
+		if err := checkOwnership(s.DB, "categories", schedule.CategoryID, schedule.UserID); err != nil {
+			return nil, err
+		}
+	}
+	return rule, nil
+}
+
+// lockSchedule блокирует расписание пользователя до конца tx.
+func lockSchedule(tx *sql.Tx, id, userID int) (models.ScheduledTransaction, error) {
+	schedule, err := scanSchedule(tx.QueryRow(`SELECT `+scheduleColumns+` FROM scheduled_transactions WHERE id = $1 FOR UPDATE`, id))
+	if err == sql.ErrNoRows {
+		return schedule, ErrNotFound
+	}
+	if err != nil {
+		return schedule, err
+	}
+	if schedule.UserID != userID {
+		return schedule, ErrForbidden
+	}
+	return schedule, nil
+}
+
+// checkOccurrenceDate проверяет, что date — дата выполнения расписания.
+func checkOccurrenceDate(schedule models.ScheduledTransaction, date time.Time) error {
+	rule, err := rrule.Parse(schedule.Schedule)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	if next, ok := rule.Next(schedule.StartDate, date); !ok || !next.Equal(date) || afterEnd(schedule, date) {
+		return fmt.Errorf("%w: %s is not an occurrence of the schedule", ErrInvalidInput, date.Format(rateDateLayout))
+	}
+	return nil
+}
+
+// nextRunDate возвращает первую дату выполнения не раньше from или nil, если расписание завершено.
+func nextRunDate(rule *rrule.Rule, schedule models.ScheduledTransaction, from time.Time) *time.Time {
+	next, ok := rule.Next(schedule.StartDate, from)
+	if !ok || afterEnd(schedule, next) {
+		return nil
+	}
+	return &next
+}
+
+func afterEnd(schedule models.ScheduledTransaction, date time.Time) bool {
+	return schedule.EndDate != nil && date.After(rrule.Date(*schedule.EndDate))
+}
+
+func scanSchedule(row rowScanner) (models.ScheduledTransaction, error) {
+	var schedule models.ScheduledTransaction
+	var endDate, nextRunDate sql.NullTime
+	err := row.Scan(&schedule.ID, &schedule.UserID, &schedule.AccountID, &schedule.Amount, &schedule.Type,
+		&schedule.CategoryID, &schedule.Description, &schedule.Schedule, &schedule.StartDate, &endDate,
+		&schedule.Paused, &nextRunDate, &schedule.LastError, &schedule.CreatedAt)
+	if err != nil {
+		return schedule, err
+	}
+	schedule.StartDate = rrule.Date(schedule.StartDate)
+	if endDate.Valid {
+		end := rrule.Date(endDate.Time)
+		schedule.EndDate = &end
+	}
+	if nextRunDate.Valid {
+		next := rrule.Date(nextRunDate.Time)
+		schedule.NextRunDate = &next
+	}
+	return schedule, nil
+}
+
+func today() time.Time {
+	return rrule.Date(time.Now())
+}
+
+func maxDate(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,579 @@
+package services
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"finance_project/internal/models"
+	"finance_project/internal/statements"
+
+	"github.com/lib/pq"
+)
+
+const statementProfileColumns = `id, user_id, name, delimiter, skip_rows, no_header, date_column, date_format, amount_column,
+	sign_convention, debit_column, credit_column, decimal_separator, description_column, currency_column, currency, created_at`
+
+// StatementService импортирует банковские выписки и управляет профилями CSV-выписок.
+type StatementService struct {
+	DB           *sql.DB
+	Transactions *TransactionService
+}
+
+// NewStatementService создает новый сервис импорта выписок.
+func NewStatementService(db *sql.DB, transactions *TransactionService) *StatementService {
+	return &StatementService{DB: db, Transactions: transactions}
+}
+
+// CreateProfile сохраняет профиль CSV-выписки.
+func (s *StatementService) CreateProfile(profile *models.StatementProfile) (int, error) {
+	if err := validateStatementProfile(profile); err != nil {
+		return 0, err
+	}
+
+	query := `INSERT INTO statement_profiles (user_id, name, delimiter, skip_rows, no_header, date_column, date_format,
+			  amount_column, sign_convention, debit_column, credit_column, decimal_separator, description_column,
+			  currency_column, currency)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+			  RETURNING id`
+	err := s.DB.QueryRow(query, profile.UserID, profile.Name, profile.Delimiter, profile.SkipRows, profile.NoHeader,
+		profile.DateColumn, profile.DateFormat, profile.AmountColumn, profile.SignConvention, profile.DebitColumn,
+		profile.CreditColumn, profile.DecimalSeparator, profile.DescriptionColumn, profile.CurrencyColumn,
+		profile.Currency).Scan(&profile.ID)
+	if err != nil {
+		log.Printf("Error creating statement profile: %v", err)
+		return 0, err
+	}
+	return profile.ID, nil
+}
+
+// GetProfiles возвращает профили выписок пользователя.
+func (s *StatementService) GetProfiles(userID int) ([]models.StatementProfile, error) {
+	rows, err := s.DB.Query(`SELECT `+statementProfileColumns+` FROM statement_profiles WHERE user_id = $1 ORDER BY id`, userID)
+	if err != nil {
+		log.Printf("Error retrieving statement profiles: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var profiles []models.StatementProfile
+	for rows.Next() {
+		profile, err := scanStatementProfile(rows)
+		if err != nil {
+			log.Printf("Error scanning statement profile: %v", err)
+			return nil, err
+		}
+		profiles = append(profiles, profile)
+	}
+	return profiles, rows.Err()
+}
+
+// GetProfileByID возвращает профиль выписки пользователя по ID.
+func (s *StatementService) GetProfileByID(id, userID int) (*models.StatementProfile, error) {
+	if err := checkOwnership(s.DB, "statement_profiles", id, userID); err != nil {
+		return nil, err
+	}
+	profile, err := scanStatementProfile(s.DB.QueryRow(`SELECT `+statementProfileColumns+` FROM statement_profiles WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		log.Printf("Error retrieving statement profile: %v", err)
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// AccountOwner возвращает ID владельца счёта; используется CLI, где пользователь не аутентифицирован.
+func (s *StatementService) AccountOwner(accountID int) (int, error) {
+	var userID int
+	err := s.DB.QueryRow(`SELECT user_id FROM accounts WHERE id = $1 AND trash_id IS NULL`, accountID).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return 0, ErrNotFound
+	}
+	return userID, err
+}
+
+// UpdateProfile изменяет профиль выписки.
+func (s *StatementService) UpdateProfile(profile *models.StatementProfile) error {
+	if err := checkOwnership(s.DB, "statement_profiles", profile.ID, profile.UserID); err != nil {
+		return err
+	}
+	if err := validateStatementProfile(profile); err != nil {
+		return err
+	}
+
+	query := `UPDATE statement_profiles
+			  SET name = $1, delimiter = $2, skip_rows = $3, no_header = $4, date_column = $5, date_format = $6,
+			      amount_column = $7, sign_convention = $8, debit_column = $9, credit_column = $10,
+			      decimal_separator = $11, description_column = $12, currency_column = $13, currency = $14
+			  WHERE id = $15`
+	_, err := s.DB.Exec(query, profile.Name, profile.Delimiter, profile.SkipRows, profile.NoHeader, profile.DateColumn,
+		profile.DateFormat, profile.AmountColumn, profile.SignConvention, profile.DebitColumn, profile.CreditColumn,
+		profile.DecimalSeparator, profile.DescriptionColumn, profile.CurrencyColumn, profile.Currency, profile.ID)
+	if err != nil {
+		log.Printf("Error updating statement profile: %v", err)
+	}
+	return err
+}
+
+// DeleteProfile удаляет профиль выписки.
+func (s *StatementService) DeleteProfile(id, userID int) error {
+	if err := checkOwnership(s.DB, "statement_profiles", id, userID); err != nil {
+		return err
+	}
+	_, err := s.DB.Exec(`DELETE FROM statement_profiles WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		log.Printf("Error deleting statement profile: %v", err)
+	}
+	return err
+}
+
+// Import разбирает выписку и создаёт по ней транзакции на счёте request.AccountID.
+// Формат без явного указания определяется по имени файла и содержимому; CSV читается по профилю,
+// из файлов camt.053 и MT940 берутся выписки счёта request.StatementAccount.
+// Строки с ошибками попадают в отчёт и не импортируются, строки OFX/QIF, уже импортированные
+// на этот счёт, пропускаются; при DryRun транзакции не создаются.
+func (s *StatementService) Import(userID int, request models.StatementImportRequest, r io.Reader) (*models.StatementImportResult, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	format := strings.ToLower(request.Format)
+	if format == "" || format == statements.FormatAuto {
+		format = statements.Detect(request.FileName, data)
+		if request.ProfileID != 0 {
+			format = statements.FormatCSV
+		}
+	}
+
+	var statement *statements.Statement
+	switch format {
+	case statements.FormatCSV:
+		if request.ProfileID == 0 {
+			return nil, fmt.Errorf("%w: profile_id is required for CSV statements", ErrInvalidInput)
+		}
+		profile, err := s.GetProfileByID(request.ProfileID, userID)
+		if err != nil {
+			return nil, err
+		}
+		entries, err := statements.ParseCSV(bytes.NewReader(data), *profile)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+		}
+		statement = &statements.Statement{Currency: profile.Currency, Entries: entries}
+	case statements.FormatOFX, statements.FormatQFX:
+		if statement, err = statements.ParseOFX(bytes.NewReader(data)); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+		}
+	case statements.FormatQIF:
+		if request.DecimalSeparator != "" && request.DecimalSeparator != "." && request.DecimalSeparator != "," {
+			return nil, fmt.Errorf("%w: decimal_separator must be . or ,", ErrInvalidInput)
+		}
+		options := statements.QIFOptions{DateFormat: request.DateFormat, DecimalSeparator: request.DecimalSeparator}
+		if statement, err = statements.ParseQIF(bytes.NewReader(data), options); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+		}
+	case statements.FormatCAMT, statements.FormatMT940:
+		parse := statements.ParseCAMT053
+		if format == statements.FormatMT940 {
+			parse = statements.ParseMT940
+		}
+		found, err := parse(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+		}
+		currency, err := s.accountCurrency(userID, request.AccountID)
+		if err != nil {
+			return nil, err
+		}
+		if statement, err = selectStatements(found, request.StatementAccount, currency); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%w: unknown statement format %q", ErrInvalidInput, request.Format)
+	}
+
+	if statement.Currency != "" {
+		for i := range statement.Entries {
+			if statement.Entries[i].Currency == "" {
+				statement.Entries[i].Currency = statement.Currency
+			}
+		}
+	}
+	result, err := s.importEntries(userID, request, statement.Entries)
+	if err != nil {
+		return nil, err
+	}
+	result.Format = format
+	if statement.LedgerBalance != nil {
+		if result.Balance, err = s.reconcileStatement(request.AccountID, statement, result); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// importEntries проверяет строки выписки и, если это не DryRun, создаёт по ним транзакции одной транзакцией БД.
+// Идентификатор строки (ExternalID) закрепляется в statement_lines до создания транзакции, поэтому
+// одновременные импорты одной выписки не создают дублей. Категорию строки выбирают правила категоризации,
+// CategoryID запроса достаётся строкам, к которым не подошло ни одно правило.
+func (s *StatementService) importEntries(userID int, request models.StatementImportRequest, entries []statements.Entry) (*models.StatementImportResult, error) {
+	accountID, categoryID := request.AccountID, request.CategoryID
+	accountCurrency, err := s.accountCurrency(userID, accountID)
+	if err != nil {
+		return nil, err
+	}
+	if categoryID != 0 {
+		if err := checkOwnership(s.DB, "categories", categoryID, userID); err != nil {
+			return nil, err
+		}
+	}
+	imported, err := s.importedIDs(accountID, entries)
+	if err != nil {
+		return nil, err
+	}
+	var rules []categoryRule
+	if s.Transactions.Rules != nil {
+		if rules, err = s.Transactions.Rules.loadRules(userID); err != nil {
+			return nil, err
+		}
+	}
+
+	var suggester *categoryClassifier
+	result := &models.StatementImportResult{DryRun: request.DryRun, Total: len(entries), Rows: []models.StatementRow{}}
+	for _, entry := range entries {
+		row := models.StatementRow{Line: entry.Line, ExternalID: entry.ID}
+		if entry.Err != nil {
+			row.Error = entry.Err.Error()
+		} else {
+			transaction := statementTransaction(userID, accountID, categoryID, entry)
+			// правила категоризации важнее категории, выбранной для всей выписки
+			if rule := matchRule(rules, transaction); rule != nil {
+				transaction.CategoryID = rule.CategoryID
+			}
+			row.Transaction = &transaction
+			if entry.ID != "" && imported[entry.ID] {
+				row.Duplicate = true
+			} else if err := checkTransactionCurrency(&transaction, accountCurrency); err != nil {
+				row.Transaction = nil
+				row.Error = strings.TrimPrefix(err.Error(), ErrInvalidInput.Error()+": ")
+			}
+			// повтор FITID внутри одного файла тоже считается дублем
+			if entry.ID != "" {
+				imported[entry.ID] = true
+			}
+			if row.Transaction != nil && !row.Duplicate && transaction.CategoryID == 0 && s.Transactions.Suggestions != nil {
+				if suggester == nil {
+					if suggester, err = s.Transactions.Suggestions.train(userID, 0); err != nil {
+						return nil, err
+					}
+				}
+				transaction.SuggestedCategory = suggester.best(transaction)
+			}
+		}
+		switch {
+		case row.Error != "":
+			result.Failed++
+		case row.Duplicate:
+			result.Skipped++
+		}
+		result.Rows = append(result.Rows, row)
+	}
+	if request.DryRun {
+		if s.Transactions.Duplicates == nil {
+			return result, nil
+		}
+		for i := range result.Rows {
+			row := &result.Rows[i]
+			if row.Transaction == nil || row.Duplicate {
+				continue
+			}
+			if row.Transaction.PossibleDuplicates, err = s.Transactions.Duplicates.findDuplicates(s.DB, *row.Transaction, nil); err != nil {
+				return nil, err
+			}
+			if len(row.Transaction.PossibleDuplicates) > 0 {
+				result.Flagged++
+			}
+		}
+		return result, nil
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	created := []int64{}
+	for i := range result.Rows {
+		row := &result.Rows[i]
+		if row.Transaction == nil || row.Duplicate {
+			continue
+		}
+		var lineID int
+		if row.ExternalID != "" {
+			err := tx.QueryRow(`INSERT INTO statement_lines (account_id, external_id) VALUES ($1, $2)
+								ON CONFLICT (account_id, external_id) DO NOTHING
+								RETURNING id`, accountID, row.ExternalID).Scan(&lineID)
+			if err == sql.ErrNoRows {
+				// строку успел импортировать параллельный запрос
+				row.Duplicate = true
+				result.Skipped++
+				continue
+			}
+			if err != nil {
+				log.Printf("Error recording statement line %d: %v", row.Line, err)
+				return nil, err
+			}
+		}
+		if row.Transaction.ID, err = createTransactionTx(tx, row.Transaction); err != nil {
+			log.Printf("Error importing statement line %d: %v", row.Line, err)
+			return nil, err
+		}
+		if lineID != 0 {
+			if _, err := tx.Exec(`UPDATE statement_lines SET transaction_id = $1 WHERE id = $2`, row.Transaction.ID, lineID); err != nil {
+				log.Printf("Error recording statement line %d: %v", row.Line, err)
+				return nil, err
+			}
+		}
+		if s.Transactions.Duplicates != nil {
+			// строки одной выписки не сравниваются между собой: повторы внутри файла отсекает ExternalID
+			if row.Transaction.PossibleDuplicates, err = s.Transactions.Duplicates.flagDuplicatesTx(tx, *row.Transaction, created); err != nil {
+				return nil, err
+			}
+			if len(row.Transaction.PossibleDuplicates) > 0 {
+				result.Flagged++
+			}
+		}
+		created = append(created, int64(row.Transaction.ID))
+		result.Imported++
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	if result.Imported > 0 {
+		s.Transactions.invalidateCache(userID)
+	}
+	return result, nil
+}
+
+// accountCurrency проверяет, что счёт принадлежит пользователю, и возвращает его валюту.
+func (s *StatementService) accountCurrency(userID, accountID int) (string, error) {
+	if err := checkOwnership(s.DB, "accounts", accountID, userID); err != nil {
+		return "", err
+	}
+	var currency string
+	err := s.DB.QueryRow(`SELECT currency FROM accounts WHERE id = $1`, accountID).Scan(&currency)
+	return currency, err
+}
+
+// selectStatements объединяет выписки файла camt.053 или MT940, относящиеся к одному банковскому
+// счёту: указанному в bankAccount или единственному в файле. Выписки в валюте, отличной от валюты
+// счёта, пропускаются, так что файл мультивалютного счёта импортируется по частям в счета нужных валют.
+// Остатком объединённой выписки становится остаток самой поздней из них.
+func selectStatements(found []*statements.Statement, bankAccount, currency string) (*statements.Statement, error) {
+	normalize := func(s string) string { return strings.ToUpper(strings.ReplaceAll(s, " ", "")) }
+	bankAccount = normalize(bankAccount)
+
+	var selected []*statements.Statement
+	var accounts []string
+	seen := make(map[string]bool)
+	for _, statement := range found {
+		account := normalize(statement.Account)
+		if bankAccount != "" && account != bankAccount {
+			continue
+		}
+		if statement.Currency != "" && statement.Currency != currency {
+			continue
+		}
+		if !seen[account] {
+			seen[account] = true
+			accounts = append(accounts, account)
+		}
+		selected = append(selected, statement)
+	}
+	switch {
+	case len(selected) == 0 && bankAccount != "":
+		return nil, fmt.Errorf("%w: no %s statement for account %s in the file", ErrInvalidInput, currency, bankAccount)
+	case len(selected) == 0:
+		return nil, fmt.Errorf("%w: no %s statement in the file", ErrInvalidInput, currency)
+	case len(accounts) > 1:
+		return nil, fmt.Errorf("%w: file contains statements for several accounts (%s); choose one with statement_account",
+			ErrInvalidInput, strings.Join(accounts, ", "))
+	}
+
+	merged := &statements.Statement{Account: accounts[0], Currency: currency}
+	for _, statement := range selected {
+		merged.Entries = append(merged.Entries, statement.Entries...)
+		if statement.LedgerBalance != nil && (merged.LedgerBalance == nil || !statement.BalanceDate.Before(merged.BalanceDate)) {
+			merged.LedgerBalance, merged.BalanceDate = statement.LedgerBalance, statement.BalanceDate
+		}
+	}
+	return merged, nil
+}
+
+// importedIDs возвращает идентификаторы строк выписки, уже импортированных на счёт.
+func (s *StatementService) importedIDs(accountID int, entries []statements.Entry) (map[string]bool, error) {
+	imported := make(map[string]bool)
+	var ids []string
+	for _, entry := range entries {
+		if entry.ID != "" {
+			ids = append(ids, entry.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return imported, nil
+	}
+
+	rows, err := s.DB.Query(`SELECT external_id FROM statement_lines WHERE account_id = $1 AND external_id = ANY($2)`,
+		accountID, pq.Array(ids))
+	if err != nil {
+		log.Printf("Error retrieving imported statement lines: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		imported[id] = true
+	}
+	return imported, rows.Err()
+}
+
+// reconcileStatement сравнивает остаток по выписке с балансом счёта на дату остатка:
+// из текущего баланса вычитаются транзакции, проведённые после этой даты. При DryRun
+// к балансу добавляются строки, которые были бы импортированы. Если валюта выписки
+// не совпадает с валютой счёта, сверка не выполняется.
+func (s *StatementService) reconcileStatement(accountID int, statement *statements.Statement, result *models.StatementImportResult) (*models.StatementReconciliation, error) {
+	reconciliation := &models.StatementReconciliation{StatementBalance: *statement.LedgerBalance}
+	// Остаток включает операции за саму дату остатка
+	var cutoff *time.Time
+	if !statement.BalanceDate.IsZero() {
+		date, next := statement.BalanceDate, statement.BalanceDate.AddDate(0, 0, 1)
+		reconciliation.Date, cutoff = &date, &next
+	}
+
+	var currency string
+	query := `SELECT a.currency, a.balance - COALESCE((
+				  SELECT SUM(CASE WHEN t.type IN ('income', 'transfer_in', 'debt_in') THEN t.amount ELSE -t.amount END)
+				  FROM transactions t
+				  WHERE t.account_id = a.id AND t.created_at >= $2 AND t.trash_id IS NULL
+			  ), 0)
+			  FROM accounts a
+			  WHERE a.id = $1`
+	if err := s.DB.QueryRow(query, accountID, cutoff).Scan(&currency, &reconciliation.AccountBalance); err != nil {
+		log.Printf("Error reconciling statement balance: %v", err)
+		return nil, err
+	}
+	if statement.Currency != "" && statement.Currency != currency {
+		return nil, nil
+	}
+
+	if result.DryRun {
+		for _, row := range result.Rows {
+			if row.Transaction == nil || row.Duplicate {
+				continue
+			}
+			if cutoff != nil && !row.Transaction.CreatedAt.Before(*cutoff) {
+				continue
+			}
+			delta, err := signedAmount(*row.Transaction)
+			if err != nil {
+				return nil, err
+			}
+			reconciliation.AccountBalance += delta
+		}
+	}
+	reconciliation.Difference = reconciliation.StatementBalance - reconciliation.AccountBalance
+	reconciliation.Reconciled = reconciliation.Difference.IsZero()
+	return reconciliation, nil
+}
+
+// statementTransaction превращает строку выписки в доход или расход.
+func statementTransaction(userID, accountID, categoryID int, entry statements.Entry) models.Transaction {
+	transaction := models.Transaction{
+		UserID:      userID,
+		AccountID:   accountID,
+		Amount:      entry.Amount.Abs(),
+		Type:        models.TransactionTypeIncome,
+		CategoryID:  categoryID,
+		Currency:    entry.Currency,
+		Description: entry.Description,
+		CreatedAt:   entry.Date,
+
+		CounterpartyName:    entry.CounterpartyName,
+		CounterpartyAccount: entry.CounterpartyAccount,
+		CounterpartyBank:    entry.CounterpartyBank,
+	}
+	if entry.Amount.IsNegative() {
+		transaction.Type = models.TransactionTypeExpense
+	}
+	if !entry.ValueDate.IsZero() {
+		valueDate := entry.ValueDate
+		transaction.ValueDate = &valueDate
+	}
+	if entry.OriginalCurrency != "" {
+		original := entry.OriginalAmount
+		transaction.OriginalAmount, transaction.OriginalCurrency = &original, entry.OriginalCurrency
+	}
+	return transaction
+}
+
+// validateStatementProfile проверяет профиль и подставляет значения по умолчанию.
+func validateStatementProfile(profile *models.StatementProfile) error {
+	profile.Name = strings.TrimSpace(profile.Name)
+	if profile.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidInput)
+	}
+	if profile.Delimiter == "" {
+		profile.Delimiter = ","
+	}
+	if len([]rune(profile.Delimiter)) != 1 && profile.Delimiter != `\t` {
+		return fmt.Errorf("%w: delimiter must be a single character", ErrInvalidInput)
+	}
+	if profile.SkipRows < 0 {
+		return fmt.Errorf("%w: skip_rows must not be negative", ErrInvalidInput)
+	}
+	if strings.TrimSpace(profile.DateColumn) == "" {
+		return fmt.Errorf("%w: date_column is required", ErrInvalidInput)
+	}
+	if profile.AmountColumn == "" && profile.DebitColumn == "" && profile.CreditColumn == "" {
+		return fmt.Errorf("%w: amount_column or debit_column/credit_column is required", ErrInvalidInput)
+	}
+	if profile.SignConvention == "" {
+		profile.SignConvention = models.SignNegativeExpense
+	}
+	if profile.SignConvention != models.SignNegativeExpense && profile.SignConvention != models.SignPositiveExpense {
+		return fmt.Errorf("%w: sign_convention must be negative_expense or positive_expense", ErrInvalidInput)
+	}
+	if profile.DecimalSeparator == "" {
+		profile.DecimalSeparator = "."
+	}
+	if profile.DecimalSeparator != "." && profile.DecimalSeparator != "," {
+		return fmt.Errorf("%w: decimal_separator must be . or ,", ErrInvalidInput)
+	}
+	if profile.Currency != "" {
+		currency, err := normalizeCurrency(profile.Currency)
+		if err != nil {
+			return err
+		}
+		profile.Currency = currency
+	}
+	return nil
+}
+
+func scanStatementProfile(row rowScanner) (models.StatementProfile, error) {
+	var profile models.StatementProfile
+	err := row.Scan(&profile.ID, &profile.UserID, &profile.Name, &profile.Delimiter, &profile.SkipRows, &profile.NoHeader,
+		&profile.DateColumn, &profile.DateFormat, &profile.AmountColumn, &profile.SignConvention, &profile.DebitColumn,
+		&profile.CreditColumn, &profile.DecimalSeparator, &profile.DescriptionColumn, &profile.CurrencyColumn,
+		&profile.Currency, &profile.CreatedAt)
+	return profile, err
+}
@@ -0,0 +1,374 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"finance_project/internal/models"
+	"finance_project/internal/rrule"
+
+	"github.com/lib/pq"
+)
+
+// maxTagLength — наибольшая длина имени метки в символах.
+const maxTagLength = 100
+
+// TagService управляет метками транзакций.
+type TagService struct {
+	DB           *sql.DB
+	Transactions *TransactionService
+}
+
+// NewTagService создает новый сервис меток.
+func NewTagService(db *sql.DB, transactions *TransactionService) *TagService {
+	return &TagService{DB: db, Transactions: transactions}
+}
+
+// GetTags возвращает метки пользователя по алфавиту с числом помеченных транзакций.
+func (s *TagService) GetTags(userID int) ([]models.Tag, error) {
+	query := `SELECT g.id, g.user_id, g.name, COUNT(t.id), g.created_at
+			  FROM tags g
+			  LEFT JOIN transaction_tags tt ON tt.tag_id = g.id
+			  LEFT JOIN transactions t ON t.id = tt.transaction_id AND t.trash_id IS NULL
+			  WHERE g.user_id = $1
+			  GROUP BY g.id
+			  ORDER BY lower(g.name)`
+	rows, err := s.DB.Query(query, userID)
+	if err != nil {
+		log.Printf("Error retrieving tags: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []models.Tag{}
+	for rows.Next() {
+		var tag models.Tag
+		if err := rows.Scan(&tag.ID, &tag.UserID, &tag.Name, &tag.Transactions, &tag.CreatedAt); err != nil {
+			log.Printf("Error scanning tag: %v", err)
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+// GetTagByID возвращает метку пользователя по ID.
+func (s *TagService) GetTagByID(id, userID int) (*models.Tag, error) {
+	if err := checkOwnership(s.DB, "tags", id, userID); err != nil {
+		return nil, err
+	}
+	query := `SELECT g.id, g.user_id, g.name, COUNT(t.id), g.created_at
+			  FROM tags g
+			  LEFT JOIN transaction_tags tt ON tt.tag_id = g.id
+			  LEFT JOIN transactions t ON t.id = tt.transaction_id AND t.trash_id IS NULL
+			  WHERE g.id = $1
+			  GROUP BY g.id`
+	var tag models.Tag
+	err := s.DB.QueryRow(query, id).Scan(&tag.ID, &tag.UserID, &tag.Name, &tag.Transactions, &tag.CreatedAt)
+	if err != nil {
+		log.Printf("Error retrieving tag: %v", err)
+		return nil, err
+	}
+	return &tag, nil
+}
+
+// CreateTag добавляет метку. Метки также создаются автоматически при сохранении транзакции с новой меткой.
+func (s *TagService) CreateTag(tag *models.Tag) (int, error) {
+	name, err := normalizeTag(tag.Name)
+	if err != nil {
+		return 0, err
+	}
+	tag.Name = name
+
+	err = s.DB.QueryRow(`INSERT INTO tags (user_id, name) VALUES ($1, $2) RETURNING id`, tag.UserID, tag.Name).Scan(&tag.ID)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		return 0, fmt.Errorf("%w: tag %q already exists", ErrInvalidInput, tag.Name)
+	}
+	if err != nil {
+		log.Printf("Error creating tag: %v", err)
+		return 0, err
+	}
+	return tag.ID, nil
+}
+
+// UpdateTag переименовывает метку; помеченные транзакции получают новое имя.
+func (s *TagService) UpdateTag(tag *models.Tag) error {
+	if err := checkOwnership(s.DB, "tags", tag.ID, tag.UserID); err != nil {
+		return err
+	}
+	name, err := normalizeTag(tag.Name)
+	if err != nil {
+		return err
+	}
+	tag.Name = name
+
+	_, err = s.DB.Exec(`UPDATE tags SET name = $1 WHERE id = $2`, tag.Name, tag.ID)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		return fmt.Errorf("%w: tag %q already exists", ErrInvalidInput, tag.Name)
+	}
+	if err != nil {
+		log.Printf("Error updating tag: %v", err)
+		return err
+	}
+	s.Transactions.invalidateCache(tag.UserID)
+	return nil
+}
+
+// DeleteTag удаляет метку и снимает её со всех транзакций.
+func (s *TagService) DeleteTag(id, userID int) error {
+	if err := checkOwnership(s.DB, "tags", id, userID); err != nil {
+		return err
+	}
+	if _, err := s.DB.Exec(`DELETE FROM tags WHERE id = $1 AND user_id = $2`, id, userID); err != nil {
+		log.Printf("Error deleting tag: %v", err)
+		return err
+	}
+	s.Transactions.invalidateCache(userID)
+	return nil
+}
+
+// TagTransactions добавляет метки всем транзакциям пользователя, подходящим под фильтр запроса,
+// или снимает их при Remove. Недостающие метки при добавлении создаются.
+func (s *TagService) TagTransactions(userID int, request models.TransactionTagRequest) (*models.TransactionTagResult, error) {
+	names, err := normalizeTags(request.Tags)
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("%w: at least one tag is required", ErrInvalidInput)
+	}
+	if request.From.IsZero() && request.To.IsZero() && request.AccountID == 0 && request.CategoryID == 0 &&
+		request.Type == "" && request.DescriptionContains == "" && len(request.WithTags) == 0 {
+		return nil, fmt.Errorf("%w: at least one filter is required", ErrInvalidInput)
+	}
+	var from, to *time.Time
+	if !request.From.IsZero() {
+		date := rrule.Date(request.From)
+		from = &date
+	}
+	if !request.To.IsZero() {
+		date := rrule.Date(request.To).AddDate(0, 0, 1)
+		to = &date
+	}
+	if from != nil && to != nil && !to.After(*from) {
+		return nil, fmt.Errorf("%w: to must not be before from", ErrInvalidInput)
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `SELECT id FROM transactions
+			  WHERE user_id = $1 AND trash_id IS NULL
+			    AND ($2::timestamp IS NULL OR created_at >= $2)
+			    AND ($3::timestamp IS NULL OR created_at < $3)
+			    AND ($4 = 0 OR account_id = $4)
+			    AND ($5 = 0 OR category_id = $5
+			         OR id IN (SELECT transaction_id FROM transaction_splits WHERE category_id = $5))
+			    AND ($6 = '' OR type = $6)
+			    AND ($7 = '' OR strpos(lower(description), lower($7)) > 0)
+			    AND ` + tagsCondition("id", 8)
+	rows, err := tx.Query(query, userID, from, to, request.AccountID, request.CategoryID, request.Type,
+		request.DescriptionContains, pq.Array(tagKeys(request.WithTags)))
+	if err != nil {
+		log.Printf("Error retrieving transactions to tag: %v", err)
+		return nil, err
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	result := &models.TransactionTagResult{Matched: len(ids)}
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	var changed sql.Result
+	if request.Remove {
+		changed, err = tx.Exec(`DELETE FROM transaction_tags
+								WHERE transaction_id = ANY($1)
+								  AND tag_id IN (SELECT id FROM tags WHERE user_id = $2 AND lower(name) = ANY($3))`,
+			pq.Array(ids), userID, pq.Array(tagKeys(names)))
+	} else {
+		var tagIDs []int64
+		if tagIDs, err = ensureTagsTx(tx, userID, names); err != nil {
+			return nil, err
+		}
+		changed, err = tx.Exec(`INSERT INTO transaction_tags (transaction_id, tag_id)
+								SELECT t.id, g.id FROM unnest($1::int[]) AS t (id) CROSS JOIN unnest($2::int[]) AS g (id)
+								ON CONFLICT DO NOTHING`, pq.Array(ids), pq.Array(tagIDs))
+	}
+	if err != nil {
+		log.Printf("Error tagging transactions: %v", err)
+		return nil, err
+	}
+	if n, err := changed.RowsAffected(); err == nil {
+		result.Changed = int(n)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	s.Transactions.invalidateCache(userID)
+	return result, nil
+}
+
+// normalizeTag обрезает пробелы вокруг имени метки и проверяет его длину.
+func normalizeTag(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", fmt.Errorf("%w: tag name is required", ErrInvalidInput)
+	}
+	if utf8.RuneCountInString(name) > maxTagLength {
+		return "", fmt.Errorf("%w: tag %q is longer than %d characters", ErrInvalidInput, name, maxTagLength)
+	}
+	return name, nil
+}
+
+// normalizeTags нормализует имена меток и убирает повторы без учёта регистра, сохраняя порядок.
+func normalizeTags(names []string) ([]string, error) {
+	var result []string
+	seen := make(map[string]bool)
+	for _, name := range names {
+		name, err := normalizeTag(name)
+		if err != nil {
+			return nil, err
+		}
+		if key := strings.ToLower(name); !seen[key] {
+			seen[key] = true
+			result = append(result, name)
+		}
+	}
+	return result, nil
+}
+
+// tagKeys возвращает имена меток в нижнем регистре для сравнения с lower(tags.name).
+// Пустые имена пропускаются; результат не nil, чтобы в запрос уходил пустой массив, а не NULL.
+func tagKeys(names []string) []string {
+	keys := []string{}
+	seen := make(map[string]bool)
+	for _, name := range names {
+		key := strings.ToLower(strings.TrimSpace(name))
+		if key != "" && !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// tagsCondition возвращает SQL-условие «у транзакции с ID в столбце column есть все метки
+// из массива имён в нижнем регистре, переданного параметром $arg»; пустой массив условию не мешает.
+func tagsCondition(column string, arg int) string {
+	return fmt.Sprintf(`(cardinality($%[2]d::text[]) = 0 OR %[1]s IN (
+				SELECT tt.transaction_id FROM transaction_tags tt JOIN tags g ON g.id = tt.tag_id
+				WHERE lower(g.name) = ANY($%[2]d::text[])
+				GROUP BY tt.transaction_id
+				HAVING COUNT(*) = cardinality($%[2]d::text[])))`, column, arg)
+}
+
+// hasTags сообщает, есть ли у транзакции все метки keys (имена в нижнем регистре).
+func hasTags(t models.Transaction, keys []string) bool {
+	own := make(map[string]bool, len(t.Tags))
+	for _, name := range t.Tags {
+		own[strings.ToLower(name)] = true
+	}
+	for _, key := range keys {
+		if !own[key] {
+			return false
+		}
+	}
+	return true
+}
+
+// ensureTagsTx создаёт недостающие метки пользователя и возвращает ID всех меток names.
+func ensureTagsTx(tx *sql.Tx, userID int, names []string) ([]int64, error) {
+	_, err := tx.Exec(`INSERT INTO tags (user_id, name) SELECT $1, unnest($2::text[])
+					   ON CONFLICT (user_id, (lower(name))) DO NOTHING`, userID, pq.Array(names))
+	if err != nil {
+		log.Printf("Error creating tags: %v", err)
+		return nil, err
+	}
+	rows, err := tx.Query(`SELECT id FROM tags WHERE user_id = $1 AND lower(name) = ANY($2)`, userID, pq.Array(tagKeys(names)))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// insertTagsTx помечает сохранённую транзакцию t её метками, создавая недостающие.
+// Имена в t.Tags заменяются нормализованными.
+func insertTagsTx(tx *sql.Tx, t *models.Transaction) error {
+	names, err := normalizeTags(t.Tags)
+	if err != nil {
+		return err
+	}
+	t.Tags = names
+	if len(names) == 0 {
+		return nil
+	}
+	ids, err := ensureTagsTx(tx, t.UserID, names)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`INSERT INTO transaction_tags (transaction_id, tag_id) SELECT $1, unnest($2::int[])
+					  ON CONFLICT DO NOTHING`, t.ID, pq.Array(ids))
+	return err
+}
+
+// attachTags загружает имена меток транзакций.
+func attachTags(q querier, transactions []models.Transaction) error {
+	if len(transactions) == 0 {
+		return nil
+	}
+	ids := make([]int64, len(transactions))
+	index := make(map[int]int, len(transactions))
+	for i, t := range transactions {
+		ids[i] = int64(t.ID)
+		index[t.ID] = i
+	}
+
+	rows, err := q.Query(`SELECT tt.transaction_id, g.name FROM transaction_tags tt
+						  JOIN tags g ON g.id = tt.tag_id
+						  WHERE tt.transaction_id = ANY($1) ORDER BY lower(g.name)`, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var transactionID int
+		var name string
+		if err := rows.Scan(&transactionID, &name); err != nil {
+			return err
+		}
+		t := &transactions[index[transactionID]]
+		t.Tags = append(t.Tags, name)
+	}
+	return rows.Err()
+}
@@ -0,0 +1,237 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"strings"
+	"time"
+
+	"finance_project/internal/models"
+	"finance_project/internal/money"
+	"finance_project/internal/rrule"
+
+	"github.com/lib/pq"
+)
+
+const (
+	// defaultSearchLimit is the page size when the query does not set one.
+	defaultSearchLimit = 50
+	// maxSearchLimit caps the page size.
+	maxSearchLimit = 500
+	// cursorTimeLayout keeps the microsecond precision of a Postgres timestamp.
+	cursorTimeLayout = "2006-01-02 15:04:05.999999"
+)
+
+// descriptionSortLength is how many leading characters of the description are sorted on; the
+// sort expression matches the index from migration 032.
+const descriptionSortLength = 200
+
+// transactionSortKey describes a column transactions can be sorted by. column is the SQL expression
+// that is sorted, compared against the cursor and indexed; value must produce the same value in Go.
+type transactionSortKey struct {
+	column string
+	cast   string
+	value  func(t models.Transaction) string
+}
+
+var transactionSortKeys = map[string]transactionSortKey{
+	"date": {"created_at", "timestamp", func(t models.Transaction) string {
+		return t.CreatedAt.Format(cursorTimeLayout)
+	}},
+	"amount": {"amount", "numeric", func(t models.Transaction) string {
+		return t.Amount.String()
+	}},
+	"description": {"left(COALESCE(description, ''), 200)", "text", func(t models.Transaction) string {
+		// A NULL description is scanned as "", which is what COALESCE compares it as
+		if runes := []rune(t.Description); len(runes) > descriptionSortLength {
+			return string(runes[:descriptionSortLength])
+		}
+		return t.Description
+	}},
+}
+
+// searchCursor is the decoded form of an opaque page cursor: the sort key value and ID of the
+// last transaction on the previous page, plus the sort and a fingerprint of the filters the
+// page was produced with, so a cursor cannot be replayed against a different search.
+type searchCursor struct {
+	Sort        string `json:"s"`
+	Value       string `json:"v"`
+	ID          int    `json:"id"`
+	Fingerprint uint64 `json:"f"`
+}
+
+// SearchTransactions returns one page of a user's transactions matching the query, in the
+// requested order. Pages are keyset-based: the cursor points after the last row of the previous
+// page, so rows inserted meanwhile neither shift nor repeat later pages.
+func (s *TransactionService) SearchTransactions(userID int, q models.TransactionQuery) (*models.TransactionPage, error) {
+	if err := normalizeTransactionQuery(&q); err != nil {
+		return nil, err
+	}
+	descending := strings.HasPrefix(q.Sort, "-")
+	key := transactionSortKeys[strings.TrimPrefix(q.Sort, "-")]
+	fingerprint := queryFingerprint(q)
+
+	var after *string
+	var afterID int
+	if q.Cursor != "" {
+		cursor, err := decodeSearchCursor(q.Cursor)
+		if err != nil || cursor.Sort != q.Sort || cursor.Fingerprint != fingerprint {
+			return nil, fmt.Errorf("%w: cursor does not belong to this search", ErrInvalidInput)
+		}
+		after, afterID = &cursor.Value, cursor.ID
+	}
+
+	var from, to *time.Time
+	if !q.From.IsZero() {
+		date := rrule.Date(q.From)
+		from = &date
+	}
+	if !q.To.IsZero() {
+		date := rrule.Date(q.To).AddDate(0, 0, 1)
+		to = &date
+	}
+	accountIDs := make([]int64, len(q.AccountIDs))
+	for i, id := range q.AccountIDs {
+		accountIDs[i] = int64(id)
+	}
+	categoryIDs := make([]int64, len(q.CategoryIDs))
+	for i, id := range q.CategoryIDs {
+		categoryIDs[i] = int64(id)
+	}
+
+	// Unset filters are passed as NULL or empty arrays. lib/pq runs unnamed statements, which
+	// Postgres plans for the actual values, so the unused conditions fold away and the
+	// remaining ones can use the indexes from migration 028.
+	order, comparison := "ASC", ">"
+	if descending {
+		order, comparison = "DESC", "<"
+	}
+	query := fmt.Sprintf(`WITH RECURSIVE filter_categories AS (
+				  SELECT id FROM categories WHERE user_id = $1 AND id = ANY($8::int[])
+				  UNION
+				  SELECT c.id FROM categories c JOIN filter_categories f ON c.parent_id = f.id
+			  )
+			  SELECT `+transactionColumns+`
+			  FROM transactions
+			  WHERE user_id = $1 AND trash_id IS NULL
+			    AND ($2::timestamp IS NULL OR created_at >= $2)
+			    AND ($3::timestamp IS NULL OR created_at < $3)
+			    AND ($4::numeric IS NULL OR amount >= $4)
+			    AND ($5::numeric IS NULL OR amount <= $5)
+			    AND (cardinality($6::text[]) = 0 OR type = ANY($6))
+			    AND (cardinality($7::int[]) = 0 OR account_id = ANY($7))
+			    AND (cardinality($8::int[]) = 0
+			         OR category_id IN (SELECT id FROM filter_categories)
+			         OR id IN (SELECT transaction_id FROM transaction_splits
+			                   WHERE category_id IN (SELECT id FROM filter_categories)))
+			    AND (cardinality($9::text[]) = 0 OR currency = ANY($9))
+			    AND ($10 = '' OR to_tsvector('simple', description) @@ websearch_to_tsquery('simple', $10))
+			    AND %[1]s
+			    AND ($12::%[3]s IS NULL OR (%[2]s, id) %[4]s ($12, $13))
+			  ORDER BY %[2]s %[5]s, id %[5]s
+			  LIMIT $14`, tagsCondition("id", 11), key.column, key.cast, comparison, order)
+	transactions, err := s.queryTransactions(query, userID, from, to, q.MinAmount, q.MaxAmount, pq.Array(q.Types),
+		pq.Array(accountIDs), pq.Array(categoryIDs), pq.Array(q.Currencies), q.Search, pq.Array(tagKeys(q.Tags)),
+		after, afterID, q.Limit+1)
+	if err != nil {
+		log.Printf("Error searching transactions: %v", err)
+		return nil, err
+	}
+
+	page := &models.TransactionPage{Transactions: transactions}
+	if len(transactions) > q.Limit {
+		page.Transactions = transactions[:q.Limit]
+		last := page.Transactions[q.Limit-1]
+		page.NextCursor = encodeSearchCursor(searchCursor{
+			Sort:        q.Sort,
+			Value:       key.value(last),
+			ID:          last.ID,
+			Fingerprint: fingerprint,
+		})
+	}
+	if page.Transactions == nil {
+		page.Transactions = []models.Transaction{}
+	}
+	return page, nil
+}
+
+// normalizeTransactionQuery validates the query and fills in the default sort and page size.
+func normalizeTransactionQuery(q *models.TransactionQuery) error {
+	if q.Sort == "" {
+		q.Sort = "-date"
+	}
+	if _, ok := transactionSortKeys[strings.TrimPrefix(q.Sort, "-")]; !ok {
+		return fmt.Errorf("%w: sort must be date, amount or description, optionally prefixed with -", ErrInvalidInput)
+	}
+	switch {
+	case q.Limit == 0:
+		q.Limit = defaultSearchLimit
+	case q.Limit < 0 || q.Limit > maxSearchLimit:
+		return fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidInput, maxSearchLimit)
+	}
+	if !q.From.IsZero() && !q.To.IsZero() && q.To.Before(q.From) {
+		return fmt.Errorf("%w: to must not be before from", ErrInvalidInput)
+	}
+	if q.MinAmount != nil && q.MaxAmount != nil && *q.MaxAmount < *q.MinAmount {
+		return fmt.Errorf("%w: max_amount must not be below min_amount", ErrInvalidInput)
+	}
+	for _, t := range q.Types {
+		switch t {
+		case models.TransactionTypeIncome, models.TransactionTypeExpense, models.TransactionTypeTransferIn,
+			models.TransactionTypeTransferOut, models.TransactionTypeDebtIn, models.TransactionTypeDebtOut:
+		default:
+			return fmt.Errorf("%w: unknown transaction type %q", ErrInvalidInput, t)
+		}
+	}
+	for i, currency := range q.Currencies {
+		normalized, err := normalizeCurrency(currency)
+		if err != nil {
+			return err
+		}
+		q.Currencies[i] = normalized
+	}
+	// nil slices would reach Postgres as NULL instead of empty arrays
+	if q.Types == nil {
+		q.Types = []string{}
+	}
+	if q.Currencies == nil {
+		q.Currencies = []string{}
+	}
+	q.Search = strings.TrimSpace(q.Search)
+	return nil
+}
+
+// queryFingerprint hashes the filters and sort of a normalized query, leaving out the page size and cursor.
+func queryFingerprint(q models.TransactionQuery) uint64 {
+	var minAmount, maxAmount money.Amount
+	if q.MinAmount != nil {
+		minAmount = *q.MinAmount
+	}
+	if q.MaxAmount != nil {
+		maxAmount = *q.MaxAmount
+	}
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%s|%s|%t%s|%t%s|%v|%v|%v|%v|%q|%v|%s",
+		rrule.Date(q.From).Format("2006-01-02"), rrule.Date(q.To).Format("2006-01-02"),
+		q.MinAmount != nil, minAmount, q.MaxAmount != nil, maxAmount,
+		q.Types, q.AccountIDs, q.CategoryIDs, q.Currencies, q.Search, tagKeys(q.Tags), q.Sort)
+	return h.Sum64()
+}
+
+func encodeSearchCursor(c searchCursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeSearchCursor(s string) (searchCursor, error) {
+	var c searchCursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, err
+	}
+	err = json.Unmarshal(data, &c)
+	return c, err
+}
@@ -4,53 +4,145 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
-	"errors"
 	"finance_project/internal/models"
+	"finance_project/internal/money"
 	"fmt"
 	"log"
+	"strings"
+	"time"
 
 	"github.com/go-redis/redis/v8"
+	"github.com/lib/pq"
 )
 
+// transactionColumns lists the columns read by scanTransaction, in order.
+// Uncategorized transactions and transactions not linked to a transfer or debt are read as zero IDs.
+const transactionColumns = `id, user_id, account_id, amount, type, COALESCE(category_id, 0), currency, COALESCE(description, ''),
+	COALESCE(transfer_id, 0), COALESCE(debt_id, 0), created_at, value_date, counterparty_name, counterparty_account,
+	counterparty_bank, original_amount, original_currency`
+
 type TransactionService struct {
 	DB          *sql.DB
 	RedisClient *redis.Client
+	Rates       *CurrencyRateService
+	// Duplicates, when set, flags likely duplicates of newly created transactions.
+	Duplicates *DuplicateService
+	// Rules, when set, categorizes new transactions created without a category.
+	Rules *CategoryRuleService
+	// Suggestions, when set, suggests categories for transactions that are still uncategorized.
+	Suggestions *CategorySuggestionService
 }
 
-func NewTransactionService(db *sql.DB, redisClient *redis.Client) *TransactionService {
+func NewTransactionService(db *sql.DB, redisClient *redis.Client, rates *CurrencyRateService) *TransactionService {
 	return &TransactionService{
 		DB:          db,
 		RedisClient: redisClient,
+		Rates:       rates,
 	}
 }
 
-// GetAllTransactions retrieves all transactions for a specific user
-func (s *TransactionService) GetAllTransactions(userID int) ([]models.Transaction, error) {
-	var transactions []models.Transaction
+// rowScanner is implemented by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
 
-	query := `SELECT id, user_id, account_id, amount, type, category_id, currency, description, created_at 
-			FROM transactions WHERE user_id = $1`
-	rows, err := s.DB.Query(query, userID)
+// scanTransaction reads a row selected with transactionColumns
+func scanTransaction(row rowScanner) (models.Transaction, error) {
+	var t models.Transaction
+	err := row.Scan(&t.ID, &t.UserID, &t.AccountID, &t.Amount, &t.Type, &t.CategoryID, &t.Currency, &t.Description,
+		&t.TransferID, &t.DebtID, &t.CreatedAt, &t.ValueDate, &t.CounterpartyName, &t.CounterpartyAccount,
+		&t.CounterpartyBank, &t.OriginalAmount, &t.OriginalCurrency)
+	return t, err
+}
+
+// queryTransactions runs a query selecting transactionColumns and collects the rows
+func (s *TransactionService) queryTransactions(query string, args ...interface{}) ([]models.Transaction, error) {
+	rows, err := s.DB.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
+	var transactions []models.Transaction
 	for rows.Next() {
-		var t models.Transaction
-		if err := rows.Scan(&t.ID, &t.UserID, &t.AccountID, &t.Amount, &t.Type, &t.CategoryID, &t.Currency, &t.Description, &t.CreatedAt); err != nil {
+		t, err := scanTransaction(rows)
+		if err != nil {
 			return nil, err
 		}
 		transactions = append(transactions, t)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
-	return transactions, nil
+	if err := attachSplits(s.DB, transactions); err != nil {
+		return nil, err
+	}
+	return transactions, attachTags(s.DB, transactions)
 }
 
-// GetAllTransactionsWithCache retrieves all transactions for a user, with caching
-func (s *TransactionService) GetAllTransactionsWithCache(userID int) ([]models.Transaction, error) {
+// attachSplits loads the split lines of the given transactions
+func attachSplits(q querier, transactions []models.Transaction) error {
+	if len(transactions) == 0 {
+		return nil
+	}
+	ids := make([]int64, len(transactions))
+	index := make(map[int]int, len(transactions))
+	for i, t := range transactions {
+		ids[i] = int64(t.ID)
+		index[t.ID] = i
+	}
+
+	rows, err := q.Query(`SELECT transaction_id, id, category_id, amount, memo FROM transaction_splits
+						  WHERE transaction_id = ANY($1) ORDER BY id`, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var transactionID int
+		var split models.TransactionSplit
+		if err := rows.Scan(&transactionID, &split.ID, &split.CategoryID, &split.Amount, &split.Memo); err != nil {
+			return err
+		}
+		t := &transactions[index[transactionID]]
+		t.Splits = append(t.Splits, split)
+	}
+	return rows.Err()
+}
+
+// GetAllTransactions retrieves all transactions for a specific user, newest first.
+// With tags, only transactions carrying all of them are returned. SearchTransactions
+// filters and pages through the same list.
+func (s *TransactionService) GetAllTransactions(userID int, tags []string) ([]models.Transaction, error) {
+	query := `SELECT ` + transactionColumns + ` FROM transactions WHERE user_id = $1 AND trash_id IS NULL AND ` + tagsCondition("id", 2) + `
+			  ORDER BY created_at DESC, id DESC`
+	return s.queryTransactions(query, userID, pq.Array(tagKeys(tags)))
+}
+
+// GetAllTransactionsWithCache retrieves all transactions for a user, with caching.
+// The whole list is cached; the tag filter is applied to the cached list.
+func (s *TransactionService) GetAllTransactionsWithCache(userID int, tags []string) ([]models.Transaction, error) {
+	transactions, err := s.getAllTransactionsWithCache(userID)
+	if err != nil {
+		return nil, err
+	}
+	keys := tagKeys(tags)
+	if len(keys) == 0 {
+		return transactions, nil
+	}
+	var tagged []models.Transaction
+	for _, t := range transactions {
+		if hasTags(t, keys) {
+			tagged = append(tagged, t)
+		}
+	}
+	return tagged, nil
+}
+
+func (s *TransactionService) getAllTransactionsWithCache(userID int) ([]models.Transaction, error) {
 	ctx := context.Background()
-	cacheKey := fmt.Sprintf("transactions:user:%d", userID) // Формируем ключ для кэша
+	cacheKey := userTransactionsCacheKey(userID) // Формируем ключ для кэша
 
 	// Попытка получить данные из Redis
 	cachedData, err := s.RedisClient.Get(ctx, cacheKey).Result()
@@ -64,7 +156,7 @@ func (s *TransactionService) GetAllTransactionsWithCache(userID int) ([]models.T
 
 	// Если данных в кэше нет, получаем их из базы данных
 	log.Printf("Cache miss for key: %s", cacheKey) // Логируем, что кэш отсутствует
-	transactions, err := s.GetAllTransactions(userID)
+	transactions, err := s.GetAllTransactions(userID, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -79,116 +171,578 @@ func (s *TransactionService) GetAllTransactionsWithCache(userID int) ([]models.T
 	return transactions, nil
 }
 
-// CreateTransaction adds a new transaction to the database
-func (s *TransactionService) CreateTransaction(transaction models.Transaction) error {
-	query := `INSERT INTO transactions (user_id, account_id, amount, type, category_id, currency, description, created_at)
-			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
-	_, err := s.DB.Exec(query, transaction.UserID, transaction.AccountID, transaction.Amount, transaction.Type,
-		transaction.CategoryID, transaction.Currency, transaction.Description, transaction.CreatedAt)
+// CreateTransaction adds a new transaction to the database and applies it to the
+// account balance in the same SQL transaction. The account and category must belong
+// to the transaction's user; without a category the user's categorization rules pick one.
+// It returns the ID of the new transaction.
+func (s *TransactionService) CreateTransaction(transaction models.Transaction) (int, error) {
+	if err := checkRegularType(transaction); err != nil {
+		return 0, err
+	}
+	if s.Rules != nil {
+		if err := s.Rules.Categorize(&transaction); err != nil {
+			return 0, err
+		}
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
 
-	return err
+	id, err := createTransactionTx(tx, &transaction)
+	if err != nil {
+		return 0, err
+	}
+	if s.Duplicates != nil {
+		if _, err := s.Duplicates.flagDuplicatesTx(tx, transaction, nil); err != nil {
+			return 0, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	s.invalidateCache(transaction.UserID)
+	return id, nil
 }
 
-// GetTransactionByID retrieves a transaction by its ID
-func (s *TransactionService) GetTransactionByID(id int) (*models.Transaction, error) {
-	var transaction models.Transaction
+// createTransactionTx inserts a transaction and updates the balance of its account inside tx.
+// Missing currency and creation time are filled in on the passed transaction.
+func createTransactionTx(tx *sql.Tx, t *models.Transaction) (int, error) {
+	delta, err := signedAmount(*t)
+	if err != nil {
+		return 0, err
+	}
+
+	currency, err := lockAccount(tx, t.AccountID, t.UserID)
+	if err != nil {
+		return 0, err
+	}
+	if err := checkTransactionCurrency(t, currency); err != nil {
+		return 0, err
+	}
+	if err := checkCategory(tx, t); err != nil {
+		return 0, err
+	}
+	if err := checkSplits(tx, t); err != nil {
+		return 0, err
+	}
+	if t.CreatedAt.IsZero() {
+		t.CreatedAt = time.Now()
+	}
 
-	query := `SELECT id, user_id, account_id, amount, type, category_id, currency, description, created_at 
-			FROM transactions WHERE id = $1`
-	err := s.DB.QueryRow(query, id).Scan(&transaction.ID, &transaction.UserID, &transaction.AccountID, &transaction.Amount,
-		&transaction.Type, &transaction.CategoryID, &transaction.Currency, &transaction.Description, &transaction.CreatedAt)
+	query := `INSERT INTO transactions (user_id, account_id, amount, type, category_id, currency, description, transfer_id, debt_id, created_at,
+			  value_date, counterparty_name, counterparty_account, counterparty_bank, original_amount, original_currency)
+			  VALUES ($1, $2, $3, $4, NULLIF($5, 0), $6, $7, NULLIF($8, 0), NULLIF($9, 0), $10, $11, $12, $13, $14, $15, $16)
+			  RETURNING id`
+	err = tx.QueryRow(query, t.UserID, t.AccountID, t.Amount, t.Type,
+		t.CategoryID, t.Currency, t.Description, t.TransferID, t.DebtID, t.CreatedAt,
+		t.ValueDate, t.CounterpartyName, t.CounterpartyAccount, t.CounterpartyBank, t.OriginalAmount, t.OriginalCurrency).Scan(&t.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := insertSplitsTx(tx, t); err != nil {
+		return 0, err
+	}
+	if err := insertTagsTx(tx, t); err != nil {
+		return 0, err
+	}
+
+	if err := adjustBalance(tx, t.AccountID, delta); err != nil {
+		return 0, err
+	}
+	return t.ID, nil
+}
+
+// GetTransactionByID retrieves a transaction by its ID. Transactions in the trash are not found.
+func (s *TransactionService) GetTransactionByID(id int) (*models.Transaction, error) {
+	query := `SELECT ` + transactionColumns + ` FROM transactions WHERE id = $1 AND trash_id IS NULL`
+	transaction, err := scanTransaction(s.DB.QueryRow(query, id))
 	if err == sql.ErrNoRows {
-		return nil, errors.New("transaction not found")
+		return nil, ErrNotFound
 	} else if err != nil {
 		return nil, err
 	}
+	transactions := []models.Transaction{transaction}
+	if err := attachSplits(s.DB, transactions); err != nil {
+		return nil, err
+	}
+	if err := attachTags(s.DB, transactions); err != nil {
+		return nil, err
+	}
+
+	return &transactions[0], nil
+}
 
-	return &transaction, nil
+// UpdateTransaction replaces a user's transaction. The previous amount is reverted on the
+// old account and the new amount is applied to the (possibly different) new account atomically.
+func (s *TransactionService) UpdateTransaction(transaction models.Transaction) error {
+	return s.updateTransaction(transaction.ID, transaction.UserID, func(models.Transaction) models.Transaction {
+		return transaction
+	})
+}
+
+// PatchTransaction applies the fields set in the patch to a user's transaction and saves it.
+// The patch is applied to the row locked for the update, so concurrent patches of different
+// fields are not lost. Setting a category drops the splits and setting splits clears the
+// category, unless the patch sets both; moving to another account without a currency switches
+// the transaction to that account's currency.
+func (s *TransactionService) PatchTransaction(id, userID int, patch models.TransactionPatch) (*models.Transaction, error) {
+	err := s.updateTransaction(id, userID, func(t models.Transaction) models.Transaction {
+		if patch.AccountID != nil && *patch.AccountID != t.AccountID {
+			t.AccountID = *patch.AccountID
+			if patch.Currency == nil {
+				t.Currency = ""
+			}
+		}
+		if patch.Amount != nil {
+			t.Amount = *patch.Amount
+		}
+		if patch.Type != nil {
+			t.Type = *patch.Type
+		}
+		if patch.CategoryID != nil {
+			t.CategoryID = *patch.CategoryID
+			if t.CategoryID != 0 && patch.Splits == nil {
+				t.Splits = nil
+			}
+		}
+		if patch.Splits != nil {
+			t.Splits = *patch.Splits
+			if len(t.Splits) > 0 && patch.CategoryID == nil {
+				t.CategoryID = 0
+			}
+		}
+		if patch.Currency != nil {
+			t.Currency = *patch.Currency
+		}
+		if patch.Description != nil {
+			t.Description = *patch.Description
+		}
+		if patch.CreatedAt != nil {
+			t.CreatedAt = *patch.CreatedAt
+		}
+		if patch.Tags != nil {
+			t.Tags = *patch.Tags
+		}
+		return t
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetTransactionByID(id)
 }
 
-// DeleteTransaction deletes a transaction by its ID
-func (s *TransactionService) DeleteTransaction(id int) error {
-	query := `DELETE FROM transactions WHERE id = $1`
-	result, err := s.DB.Exec(query, id)
+// updateTransaction saves the transaction that change builds from the stored one, with its splits
+// and tags. change is called on the row as first read, to find the accounts to lock, and again on
+// the row re-read FOR UPDATE after the accounts are locked; the second result is saved.
+func (s *TransactionService) updateTransaction(id, userID int, change func(models.Transaction) models.Transaction) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `SELECT ` + transactionColumns + ` FROM transactions WHERE id = $1 AND trash_id IS NULL`
+	load := func(lock string) (models.Transaction, error) {
+		t, err := scanTransaction(tx.QueryRow(query+lock, id))
+		if err == sql.ErrNoRows {
+			return t, ErrNotFound
+		} else if err != nil {
+			return t, err
+		}
+		transactions := []models.Transaction{t}
+		if err := attachSplits(tx, transactions); err != nil {
+			return t, err
+		}
+		if err := attachTags(tx, transactions); err != nil {
+			return t, err
+		}
+		return transactions[0], nil
+	}
+
+	old, err := load("")
 	if err != nil {
 		return err
 	}
+	if old.UserID != userID {
+		return ErrForbidden
+	}
+	if old.TransferID != 0 {
+		return fmt.Errorf("%w: transaction %d is part of transfer %d and can only be changed through /transfers", ErrInvalidInput, old.ID, old.TransferID)
+	}
+	if old.DebtID != 0 {
+		return fmt.Errorf("%w: transaction %d belongs to debt %d and can only be deleted", ErrInvalidInput, old.ID, old.DebtID)
+	}
+
+	// Accounts are locked before the transaction row, in ID order, so concurrent writes cannot deadlock
+	first, second := old.AccountID, change(old).AccountID
+	if first > second {
+		first, second = second, first
+	}
+	if _, err := lockAccount(tx, first, userID); err != nil {
+		return err
+	}
+	if second != first {
+		if _, err := lockAccount(tx, second, userID); err != nil {
+			return err
+		}
+	}
 
-	rowsAffected, err := result.RowsAffected()
+	lockedAccountID := old.AccountID
+	old, err = load(` FOR UPDATE`)
+	if err != nil {
+		return err
+	}
+	if old.AccountID != lockedAccountID {
+		return fmt.Errorf("transaction %d was moved to another account concurrently", old.ID)
+	}
+	transaction := change(old)
+	transaction.ID, transaction.UserID = id, userID
+	if transaction.AccountID != first && transaction.AccountID != second {
+		return fmt.Errorf("transaction %d was moved to another account concurrently", old.ID)
+	}
+	if err := checkRegularType(transaction); err != nil {
+		return err
+	}
+	delta, err := signedAmount(transaction)
+	if err != nil {
+		return err
+	}
+	oldDelta, err := signedAmount(old)
 	if err != nil {
 		return err
 	}
-	if rowsAffected == 0 {
-		return errors.New("transaction not found")
+
+	var currency string
+	if err := tx.QueryRow(`SELECT currency FROM accounts WHERE id = $1`, transaction.AccountID).Scan(&currency); err != nil {
+		return err
+	}
+	if err := checkTransactionCurrency(&transaction, currency); err != nil {
+		return err
+	}
+	if err := checkCategory(tx, &transaction); err != nil {
+		return err
+	}
+	if err := checkSplits(tx, &transaction); err != nil {
+		return err
+	}
+	if transaction.CreatedAt.IsZero() {
+		transaction.CreatedAt = old.CreatedAt
 	}
 
+	update := `UPDATE transactions
+			   SET account_id = $1, amount = $2, type = $3, category_id = NULLIF($4, 0), currency = $5, description = $6, created_at = $7
+			   WHERE id = $8`
+	_, err = tx.Exec(update, transaction.AccountID, transaction.Amount, transaction.Type, transaction.CategoryID,
+		transaction.Currency, transaction.Description, transaction.CreatedAt, transaction.ID)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM transaction_splits WHERE transaction_id = $1`, transaction.ID); err != nil {
+		return err
+	}
+	if err := insertSplitsTx(tx, &transaction); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM transaction_tags WHERE transaction_id = $1`, transaction.ID); err != nil {
+		return err
+	}
+	if err := insertTagsTx(tx, &transaction); err != nil {
+		return err
+	}
+
+	if err := adjustBalance(tx, old.AccountID, -oldDelta); err != nil {
+		return err
+	}
+	if err := adjustBalance(tx, transaction.AccountID, delta); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	s.invalidateCache(userID)
 	return nil
 }
 
-// CompareIncomeAndExpenses compares income and expenses for a user
-func (s *TransactionService) CompareIncomeAndExpenses(userID int) (map[string]float64, error) {
-	query := `SELECT type, SUM(amount) FROM transactions WHERE user_id = $1 GROUP BY type`
-	rows, err := s.DB.Query(query, userID)
+// DeleteTransaction moves a user's transaction to the trash and reverts it from the account balance.
+// Deleting either side of a transfer moves the whole transfer to the trash.
+func (s *TransactionService) DeleteTransaction(id, userID int) error {
+	tx, err := s.DB.Begin()
 	if err != nil {
-		return nil, err
+		return err
+	}
+	defer tx.Rollback()
+
+	var transferID int
+	var description string
+	query := `SELECT COALESCE(transfer_id, 0), COALESCE(description, '') FROM transactions
+			  WHERE id = $1 AND user_id = $2 AND trash_id IS NULL`
+	if err := tx.QueryRow(query, id, userID).Scan(&transferID, &description); err != nil {
+		if err == sql.ErrNoRows {
+			return ErrNotFound
+		}
+		return err
+	}
+	entityType, entityID := models.TrashEntityTransaction, id
+	if transferID != 0 {
+		entityType, entityID = models.TrashEntityTransfer, transferID
+	}
+	trashID, err := createTrashEntryTx(tx, userID, entityType, entityID, description)
+	if err != nil {
+		return err
+	}
+	if err := trashTransactionsTx(tx, trashID, userID, []int64{int64(id)}); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
 	}
-	defer rows.Close()
 
-	result := map[string]float64{"income": 0, "expense": 0}
-	for rows.Next() {
-		var tType string
-		var total float64
-		if err := rows.Scan(&tType, &total); err != nil {
-			return nil, err
+	s.invalidateCache(userID)
+	return nil
+}
+
+// deleteTransactionTx permanently deletes a transaction and reverts its amount from the account balance inside tx
+func deleteTransactionTx(tx *sql.Tx, id, userID int) error {
+	var accountID, transferID int
+	query := `SELECT account_id, COALESCE(transfer_id, 0) FROM transactions WHERE id = $1 AND user_id = $2 AND trash_id IS NULL`
+	if err := tx.QueryRow(query, id, userID).Scan(&accountID, &transferID); err != nil {
+		if err == sql.ErrNoRows {
+			return ErrNotFound
 		}
-		result[tType] = total
+		return err
+	}
+	if transferID != 0 {
+		return deleteTransferTx(tx, transferID, userID)
 	}
 
-	return result, nil
+	return deleteLedgerEntryTx(tx, id, accountID, userID)
 }
 
-// GetTransactionsByCategory retrieves transactions by category ID
-func (s *TransactionService) GetTransactionsByCategory(categoryID int) ([]models.Transaction, error) {
-	var transactions []models.Transaction
+// deleteLedgerEntryTx deletes a single ledger row and reverts its amount from the account balance inside tx
+func deleteLedgerEntryTx(tx *sql.Tx, id, accountID, userID int) error {
+	// The account row is locked before the transaction row, in the same order as on create
+	if _, err := lockAccount(tx, accountID, userID); err != nil {
+		return err
+	}
+
+	deleteQuery := `DELETE FROM transactions WHERE id = $1 AND user_id = $2 AND trash_id IS NULL RETURNING ` + transactionColumns
+	t, err := scanTransaction(tx.QueryRow(deleteQuery, id, userID))
+	if err == sql.ErrNoRows {
+		return ErrNotFound
+	} else if err != nil {
+		return err
+	}
+
+	delta, err := signedAmount(t)
+	if err != nil {
+		return err
+	}
+	return adjustBalance(tx, t.AccountID, -delta)
+}
 
-	query := `SELECT id, user_id, account_id, amount, type, category_id, currency, description, created_at 
-			FROM transactions WHERE category_id = $1`
-	rows, err := s.DB.Query(query, categoryID)
+// CompareIncomeAndExpenses compares income and expenses for a user. Transfers are not counted.
+// Totals are converted to the user's preferred currency at the rate of each transaction's date.
+func (s *TransactionService) CompareIncomeAndExpenses(userID int) (map[string]money.Amount, error) {
+	currency, err := s.Rates.PreferredCurrency(userID)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 
-	for rows.Next() {
-		var t models.Transaction
-		if err := rows.Scan(&t.ID, &t.UserID, &t.AccountID, &t.Amount, &t.Type, &t.CategoryID, &t.Currency, &t.Description, &t.CreatedAt); err != nil {
-			return nil, err
-		}
-		transactions = append(transactions, t)
+	query := `SELECT type, currency, created_at::date, SUM(amount) FROM transactions
+			  WHERE user_id = $1 AND type IN ('income', 'expense') AND trash_id IS NULL
+			  GROUP BY type, currency, created_at::date`
+	rows, err := s.DB.Query(query, userID)
+	if err != nil {
+		return nil, err
 	}
 
-	return transactions, nil
+	totals, err := s.Rates.NewConverter().sumRows(rows, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[string]money.Amount{"income": 0, "expense": 0}
+	for tType, total := range totals {
+		result[tType] = total
+	}
+	return result, nil
 }
 
-// GetTransactionsByAccount retrieves transactions by account ID
-func (s *TransactionService) GetTransactionsByAccount(accountID int) ([]models.Transaction, error) {
-	var transactions []models.Transaction
+// GetTransactionsByCategory retrieves a user's transactions by category ID, including
+// split transactions with a line in that category, optionally narrowed to the given tags
+func (s *TransactionService) GetTransactionsByCategory(categoryID, userID int, tags []string) ([]models.Transaction, error) {
+	if err := checkOwnership(s.DB, "categories", categoryID, userID); err != nil {
+		return nil, err
+	}
 
-	query := `SELECT id, user_id, account_id, amount, type, category_id, currency, description, created_at 
-			FROM transactions WHERE account_id = $1`
-	rows, err := s.DB.Query(query, accountID)
-	if err != nil {
+	query := `SELECT ` + transactionColumns + ` FROM transactions
+			  WHERE user_id = $2 AND trash_id IS NULL
+			    AND (category_id = $1 OR id IN (SELECT transaction_id FROM transaction_splits WHERE category_id = $1))
+			    AND ` + tagsCondition("id", 3)
+	return s.queryTransactions(query, categoryID, userID, pq.Array(tagKeys(tags)))
+}
+
+// GetTransactionsByAccount retrieves a user's transactions by account ID, optionally narrowed to the given tags
+func (s *TransactionService) GetTransactionsByAccount(accountID, userID int, tags []string) ([]models.Transaction, error) {
+	if err := checkOwnership(s.DB, "accounts", accountID, userID); err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 
-	for rows.Next() {
-		var t models.Transaction
-		if err := rows.Scan(&t.ID, &t.UserID, &t.AccountID, &t.Amount, &t.Type, &t.CategoryID, &t.Currency, &t.Description, &t.CreatedAt); err != nil {
-			return nil, err
+	query := `SELECT ` + transactionColumns + ` FROM transactions WHERE account_id = $1 AND user_id = $2 AND trash_id IS NULL AND ` + tagsCondition("id", 3)
+	return s.queryTransactions(query, accountID, userID, pq.Array(tagKeys(tags)))
+}
+
+// invalidateCache drops the cached transaction list of a user after a write
+func (s *TransactionService) invalidateCache(userID int) {
+	if s.RedisClient == nil {
+		return
+	}
+	if err := s.RedisClient.Del(context.Background(), userTransactionsCacheKey(userID)).Err(); err != nil {
+		log.Printf("Error invalidating transactions cache for user %d: %v", userID, err)
+	}
+}
+
+func userTransactionsCacheKey(userID int) string {
+	return fmt.Sprintf("transactions:user:%d", userID)
+}
+
+// signedAmount returns the effect of a transaction on its account balance
+func signedAmount(t models.Transaction) (money.Amount, error) {
+	if !t.Amount.IsPositive() {
+		return 0, fmt.Errorf("%w: amount must be positive", ErrInvalidInput)
+	}
+	switch t.Type {
+	case models.TransactionTypeIncome, models.TransactionTypeTransferIn, models.TransactionTypeDebtIn:
+		return t.Amount, nil
+	case models.TransactionTypeExpense, models.TransactionTypeTransferOut, models.TransactionTypeDebtOut:
+		return -t.Amount, nil
+	default:
+		return 0, fmt.Errorf("%w: unknown transaction type %q", ErrInvalidInput, t.Type)
+	}
+}
+
+// checkRegularType rejects transfer legs and debt transactions, which are only created
+// by TransferService and DebtService
+func checkRegularType(t models.Transaction) error {
+	if t.Type != models.TransactionTypeIncome && t.Type != models.TransactionTypeExpense {
+		return fmt.Errorf("%w: transaction type must be income or expense", ErrInvalidInput)
+	}
+	if t.TransferID != 0 {
+		return fmt.Errorf("%w: transfers are created through /transfers", ErrInvalidInput)
+	}
+	if t.DebtID != 0 {
+		return fmt.Errorf("%w: debt transactions are created through /debts", ErrInvalidInput)
+	}
+	return nil
+}
+
+// checkCategory verifies that the category belongs to the user.
+// Transfer legs and debt transactions never have a category; other transactions may stay uncategorized.
+func checkCategory(q queryRower, t *models.Transaction) error {
+	if t.TransferID != 0 || t.DebtID != 0 {
+		t.CategoryID = 0
+		return nil
+	}
+	if t.CategoryID == 0 {
+		return nil
+	}
+	return checkOwnership(q, "categories", t.CategoryID, t.UserID)
+}
+
+// checkSplits validates the split lines of t. Only income and expenses can be split; every line
+// needs a positive amount in the transaction currency and a category of the user, and the lines
+// must add up to the transaction amount. A split transaction has no category of its own.
+func checkSplits(q queryRower, t *models.Transaction) error {
+	if len(t.Splits) == 0 {
+		return nil
+	}
+	if t.TransferID != 0 || t.DebtID != 0 {
+		return fmt.Errorf("%w: transfers and debt transactions cannot be split", ErrInvalidInput)
+	}
+	if t.CategoryID != 0 {
+		return fmt.Errorf("%w: a split transaction takes its categories from the splits, leave category empty", ErrInvalidInput)
+	}
+
+	var total money.Amount
+	for i := range t.Splits {
+		split := &t.Splits[i]
+		if !split.Amount.IsPositive() {
+			return fmt.Errorf("%w: split %d: amount must be positive", ErrInvalidInput, i+1)
 		}
-		transactions = append(transactions, t)
+		if err := money.CheckPrecision(split.Amount, t.Currency); err != nil {
+			return fmt.Errorf("%w: split %d: %v", ErrInvalidInput, i+1, err)
+		}
+		if split.CategoryID == 0 {
+			return fmt.Errorf("%w: split %d: category is required", ErrInvalidInput, i+1)
+		}
+		if err := checkOwnership(q, "categories", split.CategoryID, t.UserID); err != nil {
+			return err
+		}
+		split.Memo = strings.TrimSpace(split.Memo)
+		total += split.Amount
+	}
+	if total != t.Amount {
+		return fmt.Errorf("%w: splits add up to %s, transaction amount is %s", ErrInvalidInput, total, t.Amount)
 	}
+	return nil
+}
 
-	return transactions, nil
+// insertSplitsTx stores the split lines of t, which must already have its ID
+func insertSplitsTx(tx *sql.Tx, t *models.Transaction) error {
+	for i := range t.Splits {
+		split := &t.Splits[i]
+		err := tx.QueryRow(`INSERT INTO transaction_splits (transaction_id, category_id, amount, memo)
+							VALUES ($1, $2, $3, $4)
+							RETURNING id`, t.ID, split.CategoryID, split.Amount, split.Memo).Scan(&split.ID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkTransactionCurrency defaults the transaction currency to the account currency,
+// rejects transactions in any other currency and amounts finer than its minor unit
+func checkTransactionCurrency(t *models.Transaction, accountCurrency string) error {
+	t.Currency = strings.ToUpper(strings.TrimSpace(t.Currency))
+	if t.Currency == "" {
+		t.Currency = accountCurrency
+	}
+	if t.Currency != accountCurrency {
+		return fmt.Errorf("%w: transaction currency %s does not match account currency %s", ErrInvalidInput, t.Currency, accountCurrency)
+	}
+	if err := money.CheckPrecision(t.Amount, t.Currency); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	return nil
+}
+
+// lockAccount locks a user's account row for the rest of tx and returns its currency.
+// Accounts in the trash are reported as not found.
+func lockAccount(tx *sql.Tx, accountID, userID int) (string, error) {
+	var ownerID int
+	var currency string
+	err := tx.QueryRow(`SELECT user_id, currency FROM accounts WHERE id = $1 AND trash_id IS NULL FOR UPDATE`, accountID).Scan(&ownerID, &currency)
+	if err == sql.ErrNoRows {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	if ownerID != userID {
+		return "", ErrForbidden
+	}
+	return currency, nil
+}
+
+// adjustBalance adds delta to the balance of an account locked with lockAccount
+func adjustBalance(tx *sql.Tx, accountID int, delta money.Amount) error {
+	_, err := tx.Exec(`UPDATE accounts SET balance = balance + $1 WHERE id = $2`, delta, accountID)
+	return err
 }
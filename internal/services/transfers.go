@@ -0,0 +1,247 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"finance_project/internal/models"
+	"finance_project/internal/money"
+
+	"github.com/lib/pq"
+)
+
+// transferQuery выбирает переводы вместе с валютами счетов и ID обеих записей журнала.
+const transferQuery = `
+	SELECT tr.id, tr.user_id, tr.from_account_id, tr.to_account_id, tr.amount, tr.rate, tr.converted_amount,
+	       fa.currency, ta.currency, tr.description, COALESCE(o.id, 0), COALESCE(i.id, 0), tr.created_at
+	FROM transfers tr
+	JOIN accounts fa ON fa.id = tr.from_account_id
+	JOIN accounts ta ON ta.id = tr.to_account_id
+	LEFT JOIN transactions o ON o.transfer_id = tr.id AND o.type = 'transfer_out'
+	LEFT JOIN transactions i ON i.transfer_id = tr.id AND i.type = 'transfer_in'
+`
+
+// TransferService управляет переводами между счетами пользователя.
+type TransferService struct {
+	DB           *sql.DB
+	Transactions *TransactionService
+}
+
+// NewTransferService создает новый сервис переводов.
+func NewTransferService(db *sql.DB, transactions *TransactionService) *TransferService {
+	return &TransferService{DB: db, Transactions: transactions}
+}
+
+// CreateTransfer атомарно создает перевод и две связанные записи журнала:
+// списание со счёта-источника и зачисление на счёт-получатель.
+// Для счетов в разных валютах курс Rate обязателен, для одной валюты он равен 1.
+func (s *TransferService) CreateTransfer(transfer models.Transfer) (int, error) {
+	if transfer.FromAccountID == transfer.ToAccountID {
+		return 0, fmt.Errorf("%w: source and destination accounts must differ", ErrInvalidInput)
+	}
+	if !transfer.Amount.IsPositive() {
+		return 0, fmt.Errorf("%w: amount must be positive", ErrInvalidInput)
+	}
+	if transfer.Rate < 0 {
+		return 0, fmt.Errorf("%w: rate must be positive", ErrInvalidInput)
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// Блокируем счета в порядке ID, чтобы встречные переводы не приводили к взаимоблокировке
+	first, second := transfer.FromAccountID, transfer.ToAccountID
+	if first > second {
+		first, second = second, first
+	}
+	currencies := make(map[int]string, 2)
+	for _, accountID := range []int{first, second} {
+		currency, err := lockAccount(tx, accountID, transfer.UserID)
+		if err != nil {
+			return 0, err
+		}
+		currencies[accountID] = currency
+	}
+	transfer.FromCurrency = currencies[transfer.FromAccountID]
+	transfer.ToCurrency = currencies[transfer.ToAccountID]
+
+	switch {
+	case transfer.FromCurrency == transfer.ToCurrency && transfer.Rate.IsZero():
+		transfer.Rate = money.One
+	case transfer.FromCurrency == transfer.ToCurrency && transfer.Rate != money.One:
+		return 0, fmt.Errorf("%w: rate must be 1 for accounts in the same currency", ErrInvalidInput)
+	case transfer.FromCurrency != transfer.ToCurrency && transfer.Rate.IsZero():
+		return 0, fmt.Errorf("%w: rate is required to transfer from %s to %s", ErrInvalidInput, transfer.FromCurrency, transfer.ToCurrency)
+	}
+	transfer.ConvertedAmount = money.RoundTo(transfer.Amount.Mul(transfer.Rate), transfer.ToCurrency)
+	if !transfer.ConvertedAmount.IsPositive() {
+		return 0, fmt.Errorf("%w: converted amount rounds to zero", ErrInvalidInput)
+	}
+	if transfer.CreatedAt.IsZero() {
+		transfer.CreatedAt = time.Now()
+	}
+
+	query := `INSERT INTO transfers (user_id, from_account_id, to_account_id, amount, rate, converted_amount, description, created_at)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			  RETURNING id`
+	err = tx.QueryRow(query, transfer.UserID, transfer.FromAccountID, transfer.ToAccountID, transfer.Amount,
+		transfer.Rate, transfer.ConvertedAmount, transfer.Description, transfer.CreatedAt).Scan(&transfer.ID)
+	if err != nil {
+		log.Printf("Error creating transfer: %v", err)
+		return 0, err
+	}
+
+	legs := []models.Transaction{
+		{
+			UserID:      transfer.UserID,
+			AccountID:   transfer.FromAccountID,
+			Amount:      transfer.Amount,
+			Type:        models.TransactionTypeTransferOut,
+			Currency:    transfer.FromCurrency,
+			Description: transfer.Description,
+			TransferID:  transfer.ID,
+			CreatedAt:   transfer.CreatedAt,
+		},
+		{
+			UserID:      transfer.UserID,
+			AccountID:   transfer.ToAccountID,
+			Amount:      transfer.ConvertedAmount,
+			Type:        models.TransactionTypeTransferIn,
+			Currency:    transfer.ToCurrency,
+			Description: transfer.Description,
+			TransferID:  transfer.ID,
+			CreatedAt:   transfer.CreatedAt,
+		},
+	}
+	for i := range legs {
+		if _, err := createTransactionTx(tx, &legs[i]); err != nil {
+			log.Printf("Error creating transfer leg: %v", err)
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	s.Transactions.invalidateCache(transfer.UserID)
+	return transfer.ID, nil
+}
+
+// GetTransfers возвращает все переводы пользователя.
+func (s *TransferService) GetTransfers(userID int) ([]models.Transfer, error) {
+	rows, err := s.DB.Query(transferQuery+` WHERE tr.user_id = $1 AND tr.trash_id IS NULL ORDER BY tr.created_at DESC, tr.id DESC`, userID)
+	if err != nil {
+		log.Printf("Error retrieving transfers: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transfers []models.Transfer
+	for rows.Next() {
+		transfer, err := scanTransfer(rows)
+		if err != nil {
+			log.Printf("Error scanning transfer: %v", err)
+			return nil, err
+		}
+		transfers = append(transfers, transfer)
+	}
+	return transfers, rows.Err()
+}
+
+// GetTransferByID возвращает перевод пользователя по ID.
+func (s *TransferService) GetTransferByID(id, userID int) (*models.Transfer, error) {
+	if err := checkOwnership(s.DB, "transfers", id, userID); err != nil {
+		return nil, err
+	}
+
+	transfer, err := scanTransfer(s.DB.QueryRow(transferQuery+` WHERE tr.id = $1 AND tr.trash_id IS NULL`, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		log.Printf("Error retrieving transfer: %v", err)
+		return nil, err
+	}
+	return &transfer, nil
+}
+
+// DeleteTransfer переносит перевод вместе с обеими записями журнала в корзину и откатывает балансы счетов.
+func (s *TransferService) DeleteTransfer(id, userID int) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := checkOwnership(tx, "transfers", id, userID); err != nil {
+		return err
+	}
+	var description string
+	var legs []int64
+	err = tx.QueryRow(`SELECT COALESCE(tr.description, ''), ARRAY(SELECT t.id FROM transactions t WHERE t.transfer_id = tr.id)
+					   FROM transfers tr WHERE tr.id = $1`, id).Scan(&description, pq.Array(&legs))
+	if err != nil {
+		return err
+	}
+	trashID, err := createTrashEntryTx(tx, userID, models.TrashEntityTransfer, id, description)
+	if err != nil {
+		return err
+	}
+	if err := trashTransactionsTx(tx, trashID, userID, legs); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	s.Transactions.invalidateCache(userID)
+	return nil
+}
+
+// deleteTransferTx окончательно удаляет обе записи журнала перевода и сам перевод внутри tx.
+func deleteTransferTx(tx *sql.Tx, id, userID int) error {
+	if err := checkOwnership(tx, "transfers", id, userID); err != nil {
+		return err
+	}
+
+	rows, err := tx.Query(`SELECT id, account_id FROM transactions WHERE transfer_id = $1 ORDER BY account_id`, id)
+	if err != nil {
+		return err
+	}
+	type leg struct{ id, accountID int }
+	var legs []leg
+	for rows.Next() {
+		var l leg
+		if err := rows.Scan(&l.id, &l.accountID); err != nil {
+			rows.Close()
+			return err
+		}
+		legs = append(legs, l)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, l := range legs {
+		if err := deleteLedgerEntryTx(tx, l.id, l.accountID, userID); err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec(`DELETE FROM transfers WHERE id = $1 AND user_id = $2`, id, userID)
+	return err
+}
+
+func scanTransfer(row rowScanner) (models.Transfer, error) {
+	var t models.Transfer
+	err := row.Scan(&t.ID, &t.UserID, &t.FromAccountID, &t.ToAccountID, &t.Amount, &t.Rate, &t.ConvertedAmount,
+		&t.FromCurrency, &t.ToCurrency, &t.Description, &t.OutgoingTransactionID, &t.IncomingTransactionID, &t.CreatedAt)
+	return t, err
+}
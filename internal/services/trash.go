@@ -0,0 +1,412 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"finance_project/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// TrashService управляет корзиной пользователя.
+//
+// Удаление транзакции, счёта или категории не стирает записи, а переносит их в корзину одной записью:
+//   - транзакция — вместе с разбивкой и метками; часть перевода — вместе со второй частью и самим переводом;
+//   - счёт — вместе со всеми его транзакциями (и переводами, в которых он участвует);
+//   - категория — вместе с подкатегориями и транзакциями, у которых есть категория или часть разбивки из них.
+//
+// Транзакции в корзине откатываются из балансов счетов и не видны в списках и отчётах. Плановые транзакции,
+// вклады, бюджеты и правила удалённых счетов и категорий не работают, пока те в корзине, и удаляются
+// вместе с ними по истечении срока хранения.
+type TrashService struct {
+	DB           *sql.DB
+	Transactions *TransactionService
+	// Retention — срок хранения в корзине и срок, в течение которого удалённый пользователь может восстановить профиль.
+	Retention time.Duration
+}
+
+// NewTrashService создает новый сервис корзины.
+func NewTrashService(db *sql.DB, transactions *TransactionService, retention time.Duration) *TrashService {
+	return &TrashService{DB: db, Transactions: transactions, Retention: retention}
+}
+
+// GetTrash возвращает содержимое корзины пользователя, начиная с последних удалённых.
+func (s *TrashService) GetTrash(userID int) ([]models.TrashItem, error) {
+	query := `SELECT tr.id, tr.entity_type, tr.entity_id, tr.name, tr.deleted_at,
+			         (SELECT COUNT(*) FROM transactions t WHERE t.trash_id = tr.id)
+			  FROM trash tr
+			  WHERE tr.user_id = $1
+			  ORDER BY tr.deleted_at DESC, tr.id DESC`
+	rows, err := s.DB.Query(query, userID)
+	if err != nil {
+		log.Printf("Error retrieving trash: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []models.TrashItem{}
+	for rows.Next() {
+		var item models.TrashItem
+		if err := rows.Scan(&item.ID, &item.EntityType, &item.EntityID, &item.Name, &item.DeletedAt, &item.Transactions); err != nil {
+			log.Printf("Error scanning trash item: %v", err)
+			return nil, err
+		}
+		item.PurgeAt = item.DeletedAt.Add(s.Retention)
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// RestoreTrash восстанавливает запись корзины со всем, что было удалено вместе с ней, и возвращает
+// транзакции в балансы счетов. Если запись ссылается на счёт или категорию, удалённые отдельно
+// и всё ещё лежащие в корзине, их нужно восстановить первыми.
+func (s *TrashService) RestoreTrash(id, userID int) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var ownerID int
+	err = tx.QueryRow(`SELECT user_id FROM trash WHERE id = $1 FOR UPDATE`, id).Scan(&ownerID)
+	if err == sql.ErrNoRows {
+		return ErrNotFound
+	} else if err != nil {
+		return err
+	}
+	if ownerID != userID {
+		return ErrForbidden
+	}
+	if err := checkRestorable(tx, id); err != nil {
+		return err
+	}
+
+	// Счета восстанавливаются раньше транзакций, чтобы их можно было заблокировать и изменить баланс
+	for _, statement := range []string{
+		`UPDATE accounts SET trash_id = NULL WHERE trash_id = $1`,
+		`UPDATE categories SET trash_id = NULL WHERE trash_id = $1`,
+		`UPDATE transfers SET trash_id = NULL WHERE trash_id = $1`,
+	} {
+		if _, err := tx.Exec(statement, id); err != nil {
+			log.Printf("Error restoring trash %d: %v", id, err)
+			return err
+		}
+	}
+	if err := restoreTransactionsTx(tx, id, userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM trash WHERE id = $1`, id); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	s.Transactions.invalidateCache(userID)
+	return nil
+}
+
+// PurgeTrash окончательно удаляет записи корзины и профили пользователей, удалённые раньше now - Retention,
+// вместе со всеми данными этих пользователей, и возвращает число удалённых записей корзины и профилей.
+// Ошибка одной записи или профиля не останавливает остальные.
+func (s *TrashService) PurgeTrash(now time.Time) (int, error) {
+	cutoff := now.Add(-s.Retention)
+
+	rows, err := s.DB.Query(`SELECT id FROM trash WHERE deleted_at < $1 ORDER BY deleted_at, id`, cutoff)
+	if err != nil {
+		log.Printf("Error retrieving expired trash: %v", err)
+		return 0, err
+	}
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	var firstErr error
+	for _, id := range ids {
+		done, err := s.purgeEntry(id, cutoff)
+		if err != nil {
+			log.Printf("Error purging trash %d: %v", id, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		if done {
+			purged++
+		}
+	}
+
+	rows, err = s.DB.Query(`SELECT id FROM users WHERE deleted_at < $1 ORDER BY id`, cutoff)
+	if err != nil {
+		log.Printf("Error retrieving deleted users: %v", err)
+		if firstErr == nil {
+			firstErr = err
+		}
+		return purged, firstErr
+	}
+	var users []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return purged, err
+		}
+		users = append(users, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return purged, err
+	}
+
+	for _, id := range users {
+		done, err := s.purgeUser(id, cutoff)
+		if err != nil {
+			log.Printf("Error purging deleted user %d: %v", id, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		if done {
+			purged++
+		}
+	}
+	return purged, firstErr
+}
+
+// userPurgeStatements удаляет всё, чем владеет пользователь, дочерние таблицы раньше родительских:
+// транзакции — раньше переводов, долгов, категорий и счетов, на которые они ссылаются; записи корзины —
+// после всего, что на них ссылается. Разбивка, метки транзакций, наступления расписаний, начисления
+// по вкладам, уведомления бюджетов, категории конвертов и строки выписок удаляются каскадом.
+// Журнал изменений (audit_log) только пополняется и не чистится.
+var userPurgeStatements = []string{
+	`DELETE FROM category_feedback WHERE user_id = $1`,
+	`DELETE FROM transaction_duplicates WHERE user_id = $1`,
+	`DELETE FROM scheduled_transactions WHERE user_id = $1`,
+	`DELETE FROM deposits WHERE user_id = $1`,
+	`DELETE FROM budgets WHERE user_id = $1`,
+	`DELETE FROM envelope_assignments WHERE user_id = $1`,
+	`DELETE FROM envelopes WHERE user_id = $1`,
+	`DELETE FROM category_rules WHERE user_id = $1`,
+	`DELETE FROM statement_profiles WHERE user_id = $1`,
+	`DELETE FROM net_worth_snapshots WHERE user_id = $1`,
+	`DELETE FROM transactions WHERE user_id = $1`,
+	`DELETE FROM transfers WHERE user_id = $1`,
+	`DELETE FROM debts WHERE user_id = $1`,
+	`DELETE FROM tags WHERE user_id = $1`,
+	`DELETE FROM categories WHERE user_id = $1`,
+	`DELETE FROM accounts WHERE user_id = $1`,
+	`DELETE FROM trash WHERE user_id = $1`,
+	`DELETE FROM financial_goals WHERE user_id = $1`,
+	`DELETE FROM reports WHERE user_id = $1`,
+	`DELETE FROM users WHERE id = $1`,
+}
+
+// purgeUser окончательно удаляет профиль пользователя со всеми его данными одной транзакцией,
+// если профиль не восстановили до начала удаления.
+func (s *TrashService) purgeUser(id int, cutoff time.Time) (bool, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	if err := setAuditActor(tx, "job:trash-purge"); err != nil {
+		return false, err
+	}
+
+	var locked int
+	err = tx.QueryRow(`SELECT id FROM users WHERE id = $1 AND deleted_at < $2 FOR UPDATE`, id, cutoff).Scan(&locked)
+	if err == sql.ErrNoRows {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	for _, statement := range userPurgeStatements {
+		if _, err := tx.Exec(statement, id); err != nil {
+			return false, err
+		}
+	}
+	return true, tx.Commit()
+}
+
+// purgeEntry окончательно удаляет одну запись корзины, если её не восстановили до начала удаления.
+func (s *TrashService) purgeEntry(id int, cutoff time.Time) (bool, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	if err := setAuditActor(tx, "job:trash-purge"); err != nil {
+		return false, err
+	}
+
+	var locked int
+	err = tx.QueryRow(`SELECT id FROM trash WHERE id = $1 AND deleted_at < $2 FOR UPDATE`, id, cutoff).Scan(&locked)
+	if err == sql.ErrNoRows {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	// Транзакции уже откатаны из балансов, поэтому удаляются напрямую. Переводы и категории
+	// удаляются раньше счетов, на которые они ссылаются.
+	for _, statement := range []string{
+		`DELETE FROM transactions WHERE trash_id = $1`,
+		`DELETE FROM transfers WHERE trash_id = $1`,
+		`DELETE FROM categories WHERE trash_id = $1`,
+		`DELETE FROM accounts WHERE trash_id = $1`,
+		`DELETE FROM trash WHERE id = $1`,
+	} {
+		if _, err := tx.Exec(statement, id); err != nil {
+			return false, err
+		}
+	}
+	return true, tx.Commit()
+}
+
+// createTrashEntryTx создает запись корзины для удаляемого объекта и возвращает её ID.
+func createTrashEntryTx(tx *sql.Tx, userID int, entityType string, entityID int, name string) (int, error) {
+	var id int
+	query := `INSERT INTO trash (user_id, entity_type, entity_id, name) VALUES ($1, $2, $3, $4) RETURNING id`
+	err := tx.QueryRow(query, userID, entityType, entityID, name).Scan(&id)
+	if err != nil {
+		log.Printf("Error creating trash entry: %v", err)
+	}
+	return id, err
+}
+
+// trashTransactionsTx переносит транзакции пользователя с указанными ID в запись корзины trashID
+// и откатывает их из балансов счетов. Часть перевода переносится вместе со второй частью и переводом.
+func trashTransactionsTx(tx *sql.Tx, trashID, userID int, ids []int64) error {
+	_, err := tx.Exec(`UPDATE transfers SET trash_id = $1
+					   WHERE trash_id IS NULL AND id IN (SELECT transfer_id FROM transactions WHERE id = ANY($2) AND user_id = $3)`,
+		trashID, pq.Array(ids), userID)
+	if err != nil {
+		return err
+	}
+
+	// Счета блокируются в порядке ID, как при удалении долга
+	query := `SELECT id, account_id FROM transactions
+			  WHERE user_id = $1 AND trash_id IS NULL
+			    AND (id = ANY($2) OR transfer_id IN (SELECT id FROM transfers WHERE trash_id = $3))
+			  ORDER BY account_id, id`
+	entries, err := queryLedgerEntries(tx, query, userID, pq.Array(ids), trashID)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if _, err := lockAccount(tx, e.accountID, userID); err != nil {
+			return err
+		}
+		update := `UPDATE transactions SET trash_id = $2 WHERE id = $1 RETURNING ` + transactionColumns
+		t, err := scanTransaction(tx.QueryRow(update, e.id, trashID))
+		if err != nil {
+			return err
+		}
+		delta, err := signedAmount(t)
+		if err != nil {
+			return err
+		}
+		if err := adjustBalance(tx, t.AccountID, -delta); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restoreTransactionsTx возвращает транзакции записи корзины trashID в балансы счетов.
+// Счета записи к этому моменту уже должны быть восстановлены.
+func restoreTransactionsTx(tx *sql.Tx, trashID, userID int) error {
+	query := `SELECT id, account_id FROM transactions WHERE trash_id = $1 ORDER BY account_id, id`
+	entries, err := queryLedgerEntries(tx, query, trashID)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if _, err := lockAccount(tx, e.accountID, userID); err != nil {
+			return err
+		}
+		update := `UPDATE transactions SET trash_id = NULL WHERE id = $1 RETURNING ` + transactionColumns
+		t, err := scanTransaction(tx.QueryRow(update, e.id))
+		if err != nil {
+			return err
+		}
+		delta, err := signedAmount(t)
+		if err != nil {
+			return err
+		}
+		if err := adjustBalance(tx, t.AccountID, delta); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ledgerEntry — ID транзакции и её счёта.
+type ledgerEntry struct{ id, accountID int }
+
+// queryLedgerEntries выполняет запрос, выбирающий ID транзакций и их счетов.
+func queryLedgerEntries(tx *sql.Tx, query string, args ...interface{}) ([]ledgerEntry, error) {
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []ledgerEntry
+	for rows.Next() {
+		var e ledgerEntry
+		if err := rows.Scan(&e.id, &e.accountID); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// checkRestorable проверяет, что записи корзины trashID не ссылаются на счета и категории,
+// которые удалены отдельно и всё ещё лежат в корзине.
+func checkRestorable(tx *sql.Tx, trashID int) error {
+	query := `SELECT 'account', a.id FROM transactions t JOIN accounts a ON a.id = t.account_id
+			  WHERE t.trash_id = $1 AND a.trash_id <> $1
+			  UNION ALL
+			  SELECT 'account', a.id FROM transfers tr JOIN accounts a ON a.id IN (tr.from_account_id, tr.to_account_id)
+			  WHERE tr.trash_id = $1 AND a.trash_id <> $1
+			  UNION ALL
+			  SELECT 'category', c.id FROM transactions t JOIN categories c ON c.id = t.category_id
+			  WHERE t.trash_id = $1 AND c.trash_id <> $1
+			  UNION ALL
+			  SELECT 'category', c.id FROM transactions t
+			  JOIN transaction_splits s ON s.transaction_id = t.id
+			  JOIN categories c ON c.id = s.category_id
+			  WHERE t.trash_id = $1 AND c.trash_id <> $1
+			  UNION ALL
+			  SELECT 'category', p.id FROM categories c JOIN categories p ON p.id = c.parent_id
+			  WHERE c.trash_id = $1 AND p.trash_id <> $1
+			  LIMIT 1`
+	var kind string
+	var id int
+	err := tx.QueryRow(query, trashID).Scan(&kind, &id)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("%w: %s %d is in the trash and must be restored first", ErrInvalidInput, kind, id)
+}
@@ -1,94 +1,154 @@
 package services
 
 import (
+	"crypto/subtle"
 	"database/sql"
 	"errors"
 	"finance_project/internal/models"
+	"fmt"
 	"log"
+	"strings"
+
+	"github.com/lib/pq"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const minPasswordLength = 8
+
+var (
+	// ErrEmailTaken возвращается при регистрации с уже занятым email.
+	ErrEmailTaken = errors.New("email already registered")
+	// ErrWeakPassword возвращается, если пароль короче minPasswordLength.
+	ErrWeakPassword = errors.New("password is too short")
+	// ErrInvalidEmail возвращается для пустого или некорректного email.
+	ErrInvalidEmail = errors.New("invalid email")
 )
 
 // UserService предоставляет методы для работы с пользователями.
 type UserService struct {
 	DB *sql.DB
+	// BcryptCost — стоимость bcrypt для новых хешей. Хеши с другой стоимостью пересчитываются при входе.
+	BcryptCost int
+	// dummyHash сравнивается с паролем, если пользователь не найден, чтобы время ответа не выдавало наличие email.
+	dummyHash []byte
+}
+
+// NewUserService создает новый сервис пользователей.
+func NewUserService(db *sql.DB, bcryptCost int) *UserService {
+	if bcryptCost < bcrypt.MinCost || bcryptCost > bcrypt.MaxCost {
+		bcryptCost = bcrypt.DefaultCost
+	}
+	dummyHash, _ := bcrypt.GenerateFromPassword([]byte("dummy password"), bcryptCost)
+	return &UserService{DB: db, BcryptCost: bcryptCost, dummyHash: dummyHash}
 }
 
-// RegisterUser регистрирует нового пользователя.
-func (s *UserService) RegisterUser(user models.User) error {
+// RegisterUser регистрирует нового пользователя, сохраняя bcrypt-хеш пароля, и возвращает его ID.
+func (s *UserService) RegisterUser(user models.User, password string) (int, error) {
+	user.Email = strings.TrimSpace(user.Email)
+	if !strings.Contains(user.Email, "@") {
+		return 0, ErrInvalidEmail
+	}
+	if len(password) < minPasswordLength {
+		return 0, ErrWeakPassword
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), s.BcryptCost)
+	if err != nil {
+		return 0, err
+	}
+
+	var userID int
 	query := `
 		INSERT INTO users (name, email, password_hash, preferred_currency, created_at)
 		VALUES ($1, $2, $3, $4, NOW())
+		RETURNING id
 	`
-	_, err := s.DB.Exec(query, user.Name, user.Email, user.PasswordHash, user.PreferredCurrency)
-	return err
+	err = s.DB.QueryRow(query, user.Name, user.Email, string(hash), user.PreferredCurrency).Scan(&userID)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		return 0, ErrEmailTaken
+	}
+	if err != nil {
+		log.Printf("Error registering user: %v", err)
+		return 0, err
+	}
+	return userID, nil
 }
 
-// Authenticate аутентифицирует пользователя.
+// Authenticate аутентифицирует пользователя. Удалённые пользователи войти не могут.
+// Если хеш создан с другой стоимостью bcrypt или пароль хранится в открытом виде,
+// он прозрачно пересчитывается после успешной проверки.
 func (s *UserService) Authenticate(email, password string) (int, error) {
-	var userID int
-	var storedPasswordHash string
+	return s.authenticate(email, password, false)
+}
 
-	query := `SELECT id, password_hash FROM users WHERE email = $1`
-	err := s.DB.QueryRow(query, email).Scan(&userID, &storedPasswordHash)
+// RestoreUser восстанавливает удалённого пользователя по email и паролю, пока его профиль
+// не удалён окончательно, и возвращает его ID.
+func (s *UserService) RestoreUser(email, password string) (int, error) {
+	userID, err := s.authenticate(email, password, true)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return 0, errors.New("user not found")
-		}
 		return 0, err
 	}
-
-	if storedPasswordHash != password { // Добавьте реальное хеширование
-		return 0, errors.New("invalid credentials")
+	if _, err := s.DB.Exec(`UPDATE users SET deleted_at = NULL WHERE id = $1`, userID); err != nil {
+		log.Printf("Error restoring user: %v", err)
+		return 0, err
 	}
-
 	return userID, nil
 }
 
-// NewUserService создает новый сервис пользователей.
-func NewUserService(db *sql.DB) *UserService {
-	return &UserService{DB: db}
-}
+// authenticate проверяет пароль действующего (deleted = false) или удалённого пользователя.
+func (s *UserService) authenticate(email, password string, deleted bool) (int, error) {
+	var userID int
+	var storedPasswordHash string
 
-// CreateUser добавляет нового пользователя в базу данных.
-func (s *UserService) CreateUser(user models.User) error {
-	query := `INSERT INTO users (name, email, password_hash, preferred_currency, created_at)
-			  VALUES ($1, $2, $3, $4, NOW())`
-	_, err := s.DB.Exec(query, user.Name, user.Email, user.PasswordHash, user.PreferredCurrency)
+	query := `SELECT id, password_hash FROM users WHERE LOWER(email) = LOWER($1) AND (deleted_at IS NOT NULL) = $2`
+	err := s.DB.QueryRow(query, strings.TrimSpace(email), deleted).Scan(&userID, &storedPasswordHash)
 	if err != nil {
-		log.Printf("Error creating user: %v", err)
-		return err
+		if err == sql.ErrNoRows {
+			bcrypt.CompareHashAndPassword(s.dummyHash, []byte(password))
+			return 0, ErrInvalidCredentials
+		}
+		return 0, err
 	}
-	return nil
-}
-
-// GetAllUsers возвращает список всех пользователей.
-func (s *UserService) GetAllUsers() ([]models.User, error) {
-	query := `SELECT id, name, email, preferred_currency, created_at FROM users`
 
-	rows, err := s.DB.Query(query)
+	cost, err := bcrypt.Cost([]byte(storedPasswordHash))
 	if err != nil {
-		log.Printf("Error retrieving users: %v", err)
-		return nil, err
+		// Пароли, сохраненные до появления хеширования, хранятся в открытом виде
+		if subtle.ConstantTimeCompare([]byte(storedPasswordHash), []byte(password)) != 1 {
+			return 0, ErrInvalidCredentials
+		}
+	} else if bcrypt.CompareHashAndPassword([]byte(storedPasswordHash), []byte(password)) != nil {
+		return 0, ErrInvalidCredentials
 	}
-	defer rows.Close()
 
-	var users []models.User
-	for rows.Next() {
-		var user models.User
-		if err := rows.Scan(&user.ID, &user.Name, &user.Email, &user.PreferredCurrency, &user.CreatedAt); err != nil {
-			log.Printf("Error scanning user: %v", err)
-			return nil, err
+	if cost != s.BcryptCost {
+		if err := s.setPassword(userID, password); err != nil {
+			// Вход не должен падать из-за неудачного пересчета хеша
+			log.Printf("Error rehashing password for user %d: %v", userID, err)
 		}
-		users = append(users, user)
 	}
-	return users, nil
+
+	return userID, nil
+}
+
+// setPassword сохраняет новый хеш пароля пользователя.
+func (s *UserService) setPassword(userID int, password string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), s.BcryptCost)
+	if err != nil {
+		return err
+	}
+	_, err = s.DB.Exec(`UPDATE users SET password_hash = $1 WHERE id = $2`, string(hash), userID)
+	return err
 }
 
 // GetUserByID возвращает пользователя по ID.
 func (s *UserService) GetUserByID(id int) (*models.User, error) {
-	query := `SELECT id, name, email, preferred_currency, created_at FROM users WHERE id = $1`
+	query := `SELECT id, name, email, preferred_currency, created_at FROM users WHERE id = $1 AND deleted_at IS NULL`
 
 	var user models.User
 	err := s.DB.QueryRow(query, id).Scan(&user.ID, &user.Name, &user.Email, &user.PreferredCurrency, &user.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
 	if err != nil {
 		log.Printf("Error retrieving user by ID: %v", err)
 		return nil, err
@@ -96,10 +156,19 @@ func (s *UserService) GetUserByID(id int) (*models.User, error) {
 	return &user, nil
 }
 
-// UpdateUser обновляет информацию о пользователе.
+// UpdateUser обновляет информацию о пользователе. Некорректный или занятый другим пользователем
+// email отклоняется с ErrInvalidInput.
 func (s *UserService) UpdateUser(user models.User) error {
-	query := `UPDATE users SET name = $1, email = $2, preferred_currency = $3 WHERE id = $4`
+	user.Email = strings.TrimSpace(user.Email)
+	if !strings.Contains(user.Email, "@") {
+		return fmt.Errorf("%w: %w", ErrInvalidInput, ErrInvalidEmail)
+	}
+
+	query := `UPDATE users SET name = $1, email = $2, preferred_currency = $3 WHERE id = $4 AND deleted_at IS NULL`
 	_, err := s.DB.Exec(query, user.Name, user.Email, user.PreferredCurrency, user.ID)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		return fmt.Errorf("%w: %w", ErrInvalidInput, ErrEmailTaken)
+	}
 	if err != nil {
 		log.Printf("Error updating user: %v", err)
 		return err
@@ -107,9 +176,10 @@ func (s *UserService) UpdateUser(user models.User) error {
 	return nil
 }
 
-// DeleteUser удаляет пользователя.
+// DeleteUser помечает пользователя удалённым. До окончательного удаления по истечении срока
+// хранения корзины профиль и все данные сохраняются и восстанавливаются через RestoreUser.
 func (s *UserService) DeleteUser(id int) error {
-	query := `DELETE FROM users WHERE id = $1`
+	query := `UPDATE users SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL`
 	_, err := s.DB.Exec(query, id)
 	if err != nil {
 		log.Printf("Error deleting user: %v", err)
@@ -0,0 +1,264 @@
+package statements
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"finance_project/internal/money"
+)
+
+// The camt.053 elements below are matched by local name, so every version of the
+// message (camt.053.001.02 to .08) parses with the same structs.
+type camtDocument struct {
+	Statements []camtStatement `xml:"BkToCstmrStmt>Stmt"`
+}
+
+type camtStatement struct {
+	IBAN    string        `xml:"Acct>Id>IBAN"`
+	Other   string        `xml:"Acct>Id>Othr>Id"`
+	Ccy     string        `xml:"Acct>Ccy"`
+	Balance []camtBalance `xml:"Bal"`
+	Entries []camtEntry   `xml:"Ntry"`
+}
+
+type camtBalance struct {
+	Type   string     `xml:"Tp>CdOrPrtry>Cd"`
+	Amount camtAmount `xml:"Amt"`
+	Sign   string     `xml:"CdtDbtInd"`
+	Date   camtDate   `xml:"Dt"`
+}
+
+type camtAmount struct {
+	Value    string `xml:",chardata"`
+	Currency string `xml:"Ccy,attr"`
+}
+
+type camtDate struct {
+	Date     string `xml:"Dt"`
+	DateTime string `xml:"DtTm"`
+}
+
+type camtEntry struct {
+	Amount      camtAmount      `xml:"Amt"`
+	Sign        string          `xml:"CdtDbtInd"`
+	Status      camtStatus      `xml:"Sts"`
+	Booking     camtDate        `xml:"BookgDt"`
+	Value       camtDate        `xml:"ValDt"`
+	ServicerRef string          `xml:"AcctSvcrRef"`
+	Info        string          `xml:"AddtlNtryInf"`
+	Details     []camtTxDetails `xml:"NtryDtls>TxDtls"`
+}
+
+// camtStatus is "BOOK" as text up to version .07 and <Cd>BOOK</Cd> from .08 on.
+type camtStatus struct {
+	Text string `xml:",chardata"`
+	Code string `xml:"Cd"`
+}
+
+type camtTxDetails struct {
+	ServicerRef   string     `xml:"Refs>AcctSvcrRef"`
+	Instructed    camtAmount `xml:"AmtDtls>InstdAmt>Amt"`
+	Debtor        camtParty  `xml:"RltdPties>Dbtr"`
+	DebtorAcct    camtAcct   `xml:"RltdPties>DbtrAcct"`
+	Creditor      camtParty  `xml:"RltdPties>Cdtr"`
+	CreditorAcct  camtAcct   `xml:"RltdPties>CdtrAcct"`
+	DebtorAgent   camtAgent  `xml:"RltdAgts>DbtrAgt"`
+	CreditorAgent camtAgent  `xml:"RltdAgts>CdtrAgt"`
+	Remittance    []string   `xml:"RmtInf>Ustrd"`
+}
+
+// camtParty holds a name directly (up to .07) or inside <Pty> (.08).
+type camtParty struct {
+	Name      string `xml:"Nm"`
+	PartyName string `xml:"Pty>Nm"`
+}
+
+type camtAcct struct {
+	IBAN  string `xml:"Id>IBAN"`
+	Other string `xml:"Id>Othr>Id"`
+}
+
+// camtAgent holds a BIC (up to .03) or BICFI (from .04 on).
+type camtAgent struct {
+	BIC   string `xml:"FinInstnId>BIC"`
+	BICFI string `xml:"FinInstnId>BICFI"`
+}
+
+// ParseCAMT053 parses an ISO 20022 camt.053 bank-to-customer statement file. Every Stmt
+// element becomes a Statement; booked entries become entries and pending ones are skipped.
+// Entries are identified by the bank's AcctSvcrRef when present. The counterparty is the
+// debtor of incoming and the creditor of outgoing payments; for batch entries with several
+// transactions only the entry total is imported.
+func ParseCAMT053(r io.Reader) ([]*Statement, error) {
+	var document camtDocument
+	if err := xml.NewDecoder(r).Decode(&document); err != nil {
+		return nil, fmt.Errorf("parsing camt.053 statement: %w", err)
+	}
+	if len(document.Statements) == 0 {
+		return nil, fmt.Errorf("parsing camt.053 statement: no statements found")
+	}
+
+	seen := make(map[string]int)
+	line := 0
+	var result []*Statement
+	for i, stmt := range document.Statements {
+		statement := &Statement{Account: firstNonEmpty(stmt.IBAN, stmt.Other), Currency: strings.ToUpper(stmt.Ccy)}
+		if err := stmt.closingBalance(statement); err != nil {
+			return nil, fmt.Errorf("parsing camt.053 statement %d: %w", i+1, err)
+		}
+		for _, ntry := range stmt.Entries {
+			// entries are numbered through the file in place of line numbers
+			line++
+			status := strings.ToUpper(firstNonEmpty(ntry.Status.Code, ntry.Status.Text))
+			if status != "" && status != "BOOK" {
+				continue
+			}
+			entry := ntry.entry()
+			entry.Line = line
+			if entry.Currency == "" {
+				entry.Currency = statement.Currency
+			}
+			if statement.Currency == "" {
+				statement.Currency = entry.Currency
+			}
+			if entry.ID == "" && entry.Err == nil {
+				entry.ID = fingerprint("camt:", entry, statement.Account, seen)
+			}
+			statement.Entries = append(statement.Entries, entry)
+		}
+		result = append(result, statement)
+	}
+	return result, nil
+}
+
+// closingBalance sets the ledger balance from the closing booked (CLBD) balance.
+func (s camtStatement) closingBalance(statement *Statement) error {
+	for _, balance := range s.Balance {
+		if balance.Type != "CLBD" {
+			continue
+		}
+		amount, err := camtSigned(balance.Amount.Value, balance.Sign)
+		if err != nil {
+			return fmt.Errorf("closing balance: %w", err)
+		}
+		statement.LedgerBalance = &amount
+		if statement.Currency == "" {
+			statement.Currency = strings.ToUpper(balance.Amount.Currency)
+		}
+		if balance.Date.Date != "" || balance.Date.DateTime != "" {
+			if statement.BalanceDate, err = balance.Date.parse(); err != nil {
+				return fmt.Errorf("closing balance: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+func (n camtEntry) entry() Entry {
+	entry := Entry{Currency: strings.ToUpper(n.Amount.Currency)}
+	if ref := strings.TrimSpace(n.ServicerRef); ref != "" {
+		entry.ID = "camt:" + ref
+	}
+	var err error
+	if entry.Date, err = n.Booking.parse(); err != nil {
+		entry.Err = err
+		return entry
+	}
+	if n.Value.Date != "" || n.Value.DateTime != "" {
+		if entry.ValueDate, err = n.Value.parse(); err != nil {
+			entry.Err = err
+			return entry
+		}
+	}
+	if entry.Amount, err = camtSigned(n.Amount.Value, n.Sign); err != nil {
+		entry.Err = err
+		return entry
+	}
+	if entry.Amount.IsZero() {
+		entry.Err = fmt.Errorf("amount is missing or zero")
+		return entry
+	}
+
+	var remittance []string
+	if len(n.Details) == 1 {
+		d := n.Details[0]
+		if entry.ID == "" && strings.TrimSpace(d.ServicerRef) != "" {
+			entry.ID = "camt:" + strings.TrimSpace(d.ServicerRef)
+		}
+		party, account, agent := d.Creditor, d.CreditorAcct, d.CreditorAgent
+		if entry.Amount.IsPositive() {
+			party, account, agent = d.Debtor, d.DebtorAcct, d.DebtorAgent
+		}
+		entry.CounterpartyName = firstNonEmpty(party.Name, party.PartyName)
+		entry.CounterpartyAccount = firstNonEmpty(account.IBAN, account.Other)
+		entry.CounterpartyBank = firstNonEmpty(agent.BIC, agent.BICFI)
+		instructed := strings.ToUpper(d.Instructed.Currency)
+		if instructed != "" && instructed != entry.Currency {
+			if amount, err := money.Parse(strings.TrimSpace(d.Instructed.Value)); err == nil {
+				entry.OriginalAmount, entry.OriginalCurrency = amount.Abs(), instructed
+			}
+		}
+		remittance = d.Remittance
+	}
+
+	var parts []string
+	if entry.CounterpartyName != "" {
+		parts = append(parts, entry.CounterpartyName)
+	}
+	for _, line := range remittance {
+		if line = strings.TrimSpace(line); line != "" {
+			parts = append(parts, line)
+		}
+	}
+	if len(parts) == 0 || len(n.Details) > 1 {
+		if info := strings.TrimSpace(n.Info); info != "" {
+			parts = append(parts, info)
+		}
+	}
+	if len(n.Details) > 1 {
+		parts = append(parts, fmt.Sprintf("(batch of %d transactions)", len(n.Details)))
+	}
+	entry.Description = strings.Join(parts, " ")
+	return entry
+}
+
+// parse reads an ISO date or the date part of an ISO datetime.
+func (d camtDate) parse() (time.Time, error) {
+	value := strings.TrimSpace(firstNonEmpty(d.Date, d.DateTime))
+	if len(value) < 10 {
+		return time.Time{}, fmt.Errorf("invalid date %q", value)
+	}
+	date, err := time.Parse("2006-01-02", value[:10])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q", value)
+	}
+	return date, nil
+}
+
+// camtSigned applies a CRDT/DBIT indicator to an unsigned camt amount.
+func camtSigned(value, indicator string) (money.Amount, error) {
+	amount, err := money.Parse(strings.TrimSpace(value))
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q", value)
+	}
+	switch strings.TrimSpace(indicator) {
+	case "CRDT":
+		return amount.Abs(), nil
+	case "DBIT":
+		return -amount.Abs(), nil
+	default:
+		return 0, fmt.Errorf("invalid credit/debit indicator %q", indicator)
+	}
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
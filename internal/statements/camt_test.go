@@ -0,0 +1,139 @@
+package statements
+
+import (
+	"strings"
+	"testing"
+
+	"finance_project/internal/money"
+)
+
+const camtInput = `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.08">
+  <BkToCstmrStmt>
+    <Stmt>
+      <Acct><Id><IBAN>DE89370400440532013000</IBAN></Id><Ccy>EUR</Ccy></Acct>
+      <Bal>
+        <Tp><CdOrPrtry><Cd>OPBD</Cd></CdOrPrtry></Tp>
+        <Amt Ccy="EUR">1000.00</Amt><CdtDbtInd>CRDT</CdtDbtInd><Dt><Dt>2024-01-01</Dt></Dt>
+      </Bal>
+      <Bal>
+        <Tp><CdOrPrtry><Cd>CLBD</Cd></CdOrPrtry></Tp>
+        <Amt Ccy="EUR">50.00</Amt><CdtDbtInd>DBIT</CdtDbtInd><Dt><DtTm>2024-01-31T23:59:59</DtTm></Dt>
+      </Bal>
+      <Ntry>
+        <Amt Ccy="EUR">1100.00</Amt><CdtDbtInd>DBIT</CdtDbtInd><Sts><Cd>BOOK</Cd></Sts>
+        <BookgDt><Dt>2024-01-05</Dt></BookgDt><ValDt><Dt>2024-01-04</Dt></ValDt>
+        <AcctSvcrRef>REF-1</AcctSvcrRef>
+        <NtryDtls><TxDtls>
+          <AmtDtls><InstdAmt><Amt Ccy="USD">1200.00</Amt></InstdAmt></AmtDtls>
+          <RltdPties><Cdtr><Pty><Nm>Landlord</Nm></Pty></Cdtr><CdtrAcct><Id><IBAN>DE02100100100006820101</IBAN></Id></CdtrAcct></RltdPties>
+          <RltdAgts><CdtrAgt><FinInstnId><BICFI>PBNKDEFFXXX</BICFI></FinInstnId></CdtrAgt></RltdAgts>
+          <RmtInf><Ustrd>Rent</Ustrd><Ustrd>January</Ustrd></RmtInf>
+        </TxDtls></NtryDtls>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="EUR">20.00</Amt><CdtDbtInd>CRDT</CdtDbtInd><Sts><Cd>PDNG</Cd></Sts>
+        <BookgDt><Dt>2024-01-30</Dt></BookgDt>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="EUR">50.00</Amt><CdtDbtInd>CRDT</CdtDbtInd><Sts>BOOK</Sts>
+        <BookgDt><DtTm>2024-01-31T10:00:00</DtTm></BookgDt>
+        <AddtlNtryInf>Collective credit</AddtlNtryInf>
+        <NtryDtls><TxDtls><Refs><AcctSvcrRef>A</AcctSvcrRef></Refs></TxDtls><TxDtls><Refs><AcctSvcrRef>B</AcctSvcrRef></Refs></TxDtls></NtryDtls>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="EUR">5.00</Amt><CdtDbtInd>XXXX</CdtDbtInd>
+        <BookgDt><Dt>2024-01-31</Dt></BookgDt>
+      </Ntry>
+    </Stmt>
+    <Stmt>
+      <Acct><Id><Othr><Id>12345</Id></Othr></Id></Acct>
+      <Ntry>
+        <Amt Ccy="CHF">7.00</Amt><CdtDbtInd>CRDT</CdtDbtInd>
+        <BookgDt><Dt>2024-02-01</Dt></BookgDt>
+        <AddtlNtryInf>Interest</AddtlNtryInf>
+      </Ntry>
+    </Stmt>
+  </BkToCstmrStmt>
+</Document>
+`
+
+func TestParseCAMT053(t *testing.T) {
+	statements, err := ParseCAMT053(strings.NewReader(camtInput))
+	if err != nil {
+		t.Fatalf("ParseCAMT053: %v", err)
+	}
+	if len(statements) != 2 {
+		t.Fatalf("got %d statements, want 2", len(statements))
+	}
+
+	first := statements[0]
+	if first.Account != "DE89370400440532013000" || first.Currency != "EUR" {
+		t.Errorf("statement 0: account %q, currency %q", first.Account, first.Currency)
+	}
+	if first.LedgerBalance == nil || *first.LedgerBalance != money.MustParse("-50") {
+		t.Errorf("statement 0: ledger balance = %v, want -50", first.LedgerBalance)
+	}
+	if got := first.BalanceDate.Format("2006-01-02"); got != "2024-01-31" {
+		t.Errorf("statement 0: balance date = %s, want 2024-01-31", got)
+	}
+	if len(first.Entries) != 3 {
+		t.Fatalf("statement 0: got %d entries, want 3 (pending entries are skipped): %+v", len(first.Entries), first.Entries)
+	}
+
+	rent := first.Entries[0]
+	if rent.Err != nil {
+		t.Fatalf("entry 0: unexpected error %v", rent.Err)
+	}
+	checkEntry(t, 0, rent, "2024-01-05", "-1100", "Landlord Rent January", "EUR")
+	switch {
+	case rent.Line != 1, rent.ID != "camt:REF-1":
+		t.Errorf("entry 0: line %d, ID %q", rent.Line, rent.ID)
+	case rent.ValueDate.Format("2006-01-02") != "2024-01-04":
+		t.Errorf("entry 0: value date = %s", rent.ValueDate.Format("2006-01-02"))
+	case rent.CounterpartyName != "Landlord", rent.CounterpartyAccount != "DE02100100100006820101", rent.CounterpartyBank != "PBNKDEFFXXX":
+		t.Errorf("entry 0: counterparty %q, %q, %q", rent.CounterpartyName, rent.CounterpartyAccount, rent.CounterpartyBank)
+	case rent.OriginalAmount != money.MustParse("1200") || rent.OriginalCurrency != "USD":
+		t.Errorf("entry 0: original amount %s %s", rent.OriginalAmount, rent.OriginalCurrency)
+	}
+
+	batch := first.Entries[1]
+	if batch.Err != nil {
+		t.Fatalf("entry 1: unexpected error %v", batch.Err)
+	}
+	checkEntry(t, 1, batch, "2024-01-31", "50", "Collective credit (batch of 2 transactions)", "EUR")
+	if batch.Line != 3 || !strings.HasPrefix(batch.ID, "camt:") {
+		t.Errorf("entry 1: line %d, ID %q", batch.Line, batch.ID)
+	}
+	if first.Entries[2].Err == nil {
+		t.Errorf("entry 2: want an error for the bad credit/debit indicator")
+	}
+
+	second := statements[1]
+	if second.Account != "12345" || second.Currency != "CHF" || second.LedgerBalance != nil {
+		t.Errorf("statement 1: account %q, currency %q, balance %v", second.Account, second.Currency, second.LedgerBalance)
+	}
+	if len(second.Entries) != 1 {
+		t.Fatalf("statement 1: got %d entries, want 1", len(second.Entries))
+	}
+	checkEntry(t, 0, second.Entries[0], "2024-02-01", "7", "Interest", "CHF")
+	if second.Entries[0].Line != 5 {
+		t.Errorf("statement 1: entry line = %d, want 5", second.Entries[0].Line)
+	}
+}
+
+func TestParseCAMT053Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"not XML", "Date,Amount\n"},
+		{"no statements", "<Document><BkToCstmrStmt></BkToCstmrStmt></Document>"},
+		{"bad closing balance", `<Document><BkToCstmrStmt><Stmt><Bal><Tp><CdOrPrtry><Cd>CLBD</Cd></CdOrPrtry></Tp><Amt>x</Amt><CdtDbtInd>CRDT</CdtDbtInd></Bal></Stmt></BkToCstmrStmt></Document>`},
+	}
+	for _, tt := range tests {
+		if _, err := ParseCAMT053(strings.NewReader(tt.input)); err == nil {
+			t.Errorf("%s: ParseCAMT053 succeeded, want error", tt.name)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package statements
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"finance_project/internal/models"
+)
+
+// ParseCSV parses a CSV statement described by profile. Lines that cannot be parsed are
+// returned with Err set, so that the caller can report them; an error is returned only
+// when the file itself is unusable (bad header, unknown column).
+func ParseCSV(r io.Reader, profile models.StatementProfile) ([]Entry, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	text := strings.TrimPrefix(string(data), "\ufeff")
+
+	reader := csv.NewReader(strings.NewReader(text))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	if profile.Delimiter != "" {
+		delimiter := []rune(profile.Delimiter)
+		if profile.Delimiter == `\t` {
+			delimiter = []rune{'\t'}
+		}
+		if len(delimiter) != 1 {
+			return nil, fmt.Errorf("parsing CSV statement: delimiter must be a single character")
+		}
+		reader.Comma = delimiter[0]
+	}
+	decimalSeparator := profile.DecimalSeparator
+	if decimalSeparator == "" {
+		decimalSeparator = "."
+	}
+
+	line := 0
+	for ; line < profile.SkipRows; line++ {
+		if _, err := reader.Read(); err != nil {
+			return nil, fmt.Errorf("parsing CSV statement: skipping line %d: %w", line+1, err)
+		}
+	}
+	var header []string
+	if !profile.NoHeader {
+		if header, err = reader.Read(); err != nil {
+			return nil, fmt.Errorf("parsing CSV statement: reading header: %w", err)
+		}
+		line++
+	}
+
+	columns, err := resolveColumns(profile, header)
+	if err != nil {
+		return nil, fmt.Errorf("parsing CSV statement: %w", err)
+	}
+
+	var entries []Entry
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		if err != nil {
+			entries = append(entries, Entry{Line: line, Err: err})
+			continue
+		}
+		if blank(record) {
+			continue
+		}
+		entry := columns.entry(record, profile, decimalSeparator)
+		entry.Line = line
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// csvColumns holds zero-based column positions; -1 means the column is not mapped.
+type csvColumns struct {
+	date, amount, debit, credit, currency int
+	description                           []int
+}
+
+func resolveColumns(profile models.StatementProfile, header []string) (csvColumns, error) {
+	var columns csvColumns
+	var err error
+	if columns.date, err = findColumn(profile.DateColumn, header); err != nil {
+		return columns, err
+	}
+	if columns.date < 0 {
+		return columns, fmt.Errorf("date column is not configured")
+	}
+	if columns.amount, err = findColumn(profile.AmountColumn, header); err != nil {
+		return columns, err
+	}
+	if columns.debit, err = findColumn(profile.DebitColumn, header); err != nil {
+		return columns, err
+	}
+	if columns.credit, err = findColumn(profile.CreditColumn, header); err != nil {
+		return columns, err
+	}
+	if columns.amount < 0 && columns.debit < 0 && columns.credit < 0 {
+		return columns, fmt.Errorf("amount column or debit/credit columns are not configured")
+	}
+	if columns.currency, err = findColumn(profile.CurrencyColumn, header); err != nil {
+		return columns, err
+	}
+	if profile.DescriptionColumn != "" {
+		for _, name := range strings.Split(profile.DescriptionColumn, "+") {
+			i, err := findColumn(name, header)
+			if err != nil {
+				return columns, err
+			}
+			columns.description = append(columns.description, i)
+		}
+	}
+	return columns, nil
+}
+
+// findColumn resolves a column given by header name (case-insensitive) or 1-based number.
+func findColumn(spec string, header []string) (int, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return -1, nil
+	}
+	if n, err := strconv.Atoi(spec); err == nil && n > 0 {
+		return n - 1, nil
+	}
+	for i, name := range header {
+		if strings.EqualFold(strings.TrimSpace(name), spec) {
+			return i, nil
+		}
+	}
+	return -1, fmt.Errorf("column %q not found in header", spec)
+}
+
+func (c csvColumns) entry(record []string, profile models.StatementProfile, decimalSeparator string) Entry {
+	var entry Entry
+	field := func(i int) string {
+		if i < 0 || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	date, err := time.Parse(dateLayout(profile.DateFormat), field(c.date))
+	if err != nil {
+		entry.Err = fmt.Errorf("invalid date %q", field(c.date))
+		return entry
+	}
+	entry.Date = date
+
+	if c.amount >= 0 && field(c.amount) != "" {
+		amount, err := parseAmount(field(c.amount), decimalSeparator)
+		if err != nil {
+			entry.Err = err
+			return entry
+		}
+		if profile.SignConvention == models.SignPositiveExpense {
+			amount = -amount
+		}
+		entry.Amount = amount
+	} else {
+		// Debits are withdrawals and credits are deposits; signs in these columns are ignored
+		if value := field(c.debit); value != "" {
+			amount, err := parseAmount(value, decimalSeparator)
+			if err != nil {
+				entry.Err = err
+				return entry
+			}
+			entry.Amount -= amount.Abs()
+		}
+		if value := field(c.credit); value != "" {
+			amount, err := parseAmount(value, decimalSeparator)
+			if err != nil {
+				entry.Err = err
+				return entry
+			}
+			entry.Amount += amount.Abs()
+		}
+	}
+	if entry.Amount.IsZero() {
+		entry.Err = fmt.Errorf("amount is missing or zero")
+		return entry
+	}
+
+	var parts []string
+	for _, i := range c.description {
+		if value := field(i); value != "" {
+			parts = append(parts, value)
+		}
+	}
+	entry.Description = strings.Join(parts, " ")
+	entry.Currency = strings.ToUpper(field(c.currency))
+	return entry
+}
+
+func blank(record []string) bool {
+	for _, value := range record {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
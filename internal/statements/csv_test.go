@@ -0,0 +1,142 @@
+package statements
+
+import (
+	"strings"
+	"testing"
+
+	"finance_project/internal/models"
+	"finance_project/internal/money"
+)
+
+func TestParseCSV(t *testing.T) {
+	type line struct {
+		line        int
+		date        string
+		amount      string
+		description string
+		currency    string
+		err         bool
+	}
+	tests := []struct {
+		name    string
+		profile models.StatementProfile
+		input   string
+		want    []line
+	}{
+		{
+			name:    "signed amount with header names",
+			profile: models.StatementProfile{DateColumn: "Date", AmountColumn: "Amount", DescriptionColumn: "Payee+Memo", CurrencyColumn: "Currency"},
+			input: "\ufeffDate,Amount,Payee,Memo,Currency\n" +
+				"2024-01-31,-12.50,Coffee Shop,latte,eur\n" +
+				"2024-02-01,1000,Employer,,EUR\n",
+			want: []line{
+				{2, "2024-01-31", "-12.5", "Coffee Shop latte", "EUR", false},
+				{3, "2024-02-01", "1000", "Employer", "EUR", false},
+			},
+		},
+		{
+			name: "European format without header",
+			profile: models.StatementProfile{Delimiter: ";", NoHeader: true, DateColumn: "1", DateFormat: "DD.MM.YYYY",
+				AmountColumn: "2", DecimalSeparator: ",", DescriptionColumn: "3"},
+			input: "31.01.2024;-1 234,56;Rent\n\n01.02.2024;7,5;Refund\n",
+			want: []line{
+				{1, "2024-01-31", "-1234.56", "Rent", "", false},
+				{2, "2024-02-01", "7.5", "Refund", "", false},
+			},
+		},
+		{
+			name:    "positive expense convention",
+			profile: models.StatementProfile{DateColumn: "date", AmountColumn: "amount", SignConvention: models.SignPositiveExpense},
+			input:   "date,amount\n2024-03-01,40\n2024-03-02,-15\n",
+			want: []line{
+				{2, "2024-03-01", "-40", "", "", false},
+				{3, "2024-03-02", "15", "", "", false},
+			},
+		},
+		{
+			name:    "debit and credit columns with skipped rows",
+			profile: models.StatementProfile{Delimiter: `\t`, SkipRows: 2, DateColumn: "Date", DebitColumn: "Out", CreditColumn: "In"},
+			input:   "Bank statement\nAccount 123\nDate\tOut\tIn\n2024-04-01\t-20.00\t\n2024-04-02\t\t300\n",
+			want: []line{
+				{4, "2024-04-01", "-20", "", "", false},
+				{5, "2024-04-02", "300", "", "", false},
+			},
+		},
+		{
+			name:    "bad lines are reported and parsing goes on",
+			profile: models.StatementProfile{DateColumn: "Date", AmountColumn: "Amount"},
+			input:   "Date,Amount\n31/01/2024,5\n2024-01-31,five\n2024-01-31,0\n2024-01-31,5\n",
+			want: []line{
+				{2, "", "", "", "", true},
+				{3, "", "", "", "", true},
+				{4, "", "", "", "", true},
+				{5, "2024-01-31", "5", "", "", false},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := ParseCSV(strings.NewReader(tt.input), tt.profile)
+			if err != nil {
+				t.Fatalf("ParseCSV: %v", err)
+			}
+			if len(entries) != len(tt.want) {
+				t.Fatalf("got %d entries, want %d: %+v", len(entries), len(tt.want), entries)
+			}
+			for i, want := range tt.want {
+				got := entries[i]
+				if got.Line != want.line {
+					t.Errorf("entry %d: line = %d, want %d", i, got.Line, want.line)
+				}
+				if want.err {
+					if got.Err == nil {
+						t.Errorf("entry %d: want an error, got %+v", i, got)
+					}
+					continue
+				}
+				if got.Err != nil {
+					t.Errorf("entry %d: unexpected error %v", i, got.Err)
+					continue
+				}
+				checkEntry(t, i, got, want.date, want.amount, want.description, want.currency)
+			}
+		})
+	}
+}
+
+func TestParseCSVErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile models.StatementProfile
+		input   string
+	}{
+		{"date column missing", models.StatementProfile{AmountColumn: "Amount"}, "Date,Amount\n"},
+		{"amount columns missing", models.StatementProfile{DateColumn: "Date"}, "Date,Amount\n"},
+		{"unknown column", models.StatementProfile{DateColumn: "Date", AmountColumn: "Sum"}, "Date,Amount\n"},
+		{"long delimiter", models.StatementProfile{Delimiter: ";;", DateColumn: "1", AmountColumn: "2"}, "Date;Amount\n"},
+		{"no header", models.StatementProfile{DateColumn: "1", AmountColumn: "2"}, ""},
+		{"too many skipped rows", models.StatementProfile{SkipRows: 3, DateColumn: "1", AmountColumn: "2"}, "a\nb\n"},
+	}
+	for _, tt := range tests {
+		if _, err := ParseCSV(strings.NewReader(tt.input), tt.profile); err == nil {
+			t.Errorf("%s: ParseCSV succeeded, want error", tt.name)
+		}
+	}
+}
+
+// checkEntry compares the common fields of a parsed entry.
+func checkEntry(t *testing.T, i int, got Entry, date, amount, description, currency string) {
+	t.Helper()
+	if d := got.Date.Format("2006-01-02"); d != date {
+		t.Errorf("entry %d: date = %s, want %s", i, d, date)
+	}
+	if want := money.MustParse(amount); got.Amount != want {
+		t.Errorf("entry %d: amount = %s, want %s", i, got.Amount, want)
+	}
+	if got.Description != description {
+		t.Errorf("entry %d: description = %q, want %q", i, got.Description, description)
+	}
+	if got.Currency != currency {
+		t.Errorf("entry %d: currency = %q, want %q", i, got.Currency, currency)
+	}
+}
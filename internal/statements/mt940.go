@@ -0,0 +1,287 @@
+package statements
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"finance_project/internal/money"
+)
+
+var (
+	// mt940Tag matches the start of a field such as ":61:" or ":28C:".
+	mt940Tag = regexp.MustCompile(`^:(\d{2}[A-Z]?):`)
+	// mt940Line splits a :61: statement line: value date, optional booking date (MMDD),
+	// debit/credit mark, optional funds code, amount, transaction type, customer and bank references.
+	mt940Line = regexp.MustCompile(`^(\d{6})(\d{4})?(RC|RD|C|D)([A-Z])?(\d+,\d*)([NFS][A-Z0-9]{3})([^/]*?)(?://(.*))?$`)
+	// mt940Balance splits a :60F:/:62F: balance: mark, date, currency and amount.
+	mt940Balance = regexp.MustCompile(`^([CD])(\d{6})([A-Z]{3})(\d+,\d*)$`)
+	// mt940Structured matches the /KEY/value/ layout of SEPA :86: fields.
+	mt940Structured = regexp.MustCompile(`/(NAME|IBAN|BIC|REMI|CNTP|EREF)/`)
+)
+
+// mt940Field is one ":tag:value" field; continuation lines are joined with "\n".
+type mt940Field struct {
+	tag, value string
+	line       int
+}
+
+// ParseMT940 parses a SWIFT MT940 file. Every :20: field starts a new statement, so files with
+// several days or accounts give several statements. SWIFT block headers ({1:...}{4:) are skipped.
+// Each :61: line and its :86: information become an entry; the closing balance (:62F:) becomes
+// the ledger balance. The bank reference after "//" identifies the entry together with its
+// date and amount, since banks reuse references across days.
+func ParseMT940(r io.Reader) ([]*Statement, error) {
+	fields, err := mt940Fields(r)
+	if err != nil {
+		return nil, fmt.Errorf("parsing MT940 statement: %w", err)
+	}
+
+	seen := make(map[string]int)
+	var (
+		result    []*Statement
+		statement *Statement
+		entry     *Entry
+		bankRef   string
+	)
+	flush := func() {
+		if entry == nil {
+			return
+		}
+		if entry.Err == nil {
+			entry.ID = fingerprint("mt940:", *entry, statement.Account+"|"+bankRef, seen)
+		}
+		statement.Entries = append(statement.Entries, *entry)
+		entry = nil
+	}
+	for _, field := range fields {
+		if field.tag != "20" && statement == nil {
+			return nil, fmt.Errorf("parsing MT940 statement: line %d: field :%s: before :20:", field.line, field.tag)
+		}
+		switch field.tag {
+		case "20":
+			flush()
+			statement = &Statement{}
+			result = append(result, statement)
+		case "25":
+			statement.Account = strings.ReplaceAll(strings.TrimSpace(field.value), " ", "")
+		case "60F", "60M":
+			if _, currency, _, err := parseMT940Balance(field.value); err == nil {
+				statement.Currency = currency
+			}
+		case "61":
+			flush()
+			entry = &Entry{Line: field.line, Currency: statement.Currency}
+			bankRef = parseMT940Line(field.value, entry)
+		case "86":
+			if entry != nil {
+				parseMT940Information(field.value, entry)
+			}
+		case "62F":
+			flush()
+			amount, currency, date, err := parseMT940Balance(field.value)
+			if err != nil {
+				return nil, fmt.Errorf("parsing MT940 statement: line %d: closing balance: %w", field.line, err)
+			}
+			statement.LedgerBalance, statement.BalanceDate = &amount, date
+			if statement.Currency == "" {
+				statement.Currency = currency
+			}
+		default:
+			// :28C: statement number, :62M:, :64: and :65: balances
+			flush()
+		}
+	}
+	if statement == nil {
+		return nil, fmt.Errorf("parsing MT940 statement: no statements found")
+	}
+	flush()
+	return result, nil
+}
+
+// mt940Fields splits the file into fields, dropping SWIFT block wrappers and the "-" message trailer.
+func mt940Fields(r io.Reader) ([]mt940Field, error) {
+	var fields []mt940Field
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if line == 1 {
+			text = strings.TrimPrefix(text, "\ufeff")
+		}
+		if i := strings.Index(text, "{4:"); strings.HasPrefix(text, "{") && i >= 0 {
+			text = text[i+3:]
+		}
+		trimmed := strings.TrimSpace(text)
+		if trimmed == "" || trimmed == "-" || trimmed == "-}" || strings.HasPrefix(trimmed, "{") {
+			continue
+		}
+		if m := mt940Tag.FindStringSubmatch(text); m != nil {
+			fields = append(fields, mt940Field{tag: m[1], value: text[len(m[0]):], line: line})
+			continue
+		}
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("line %d: expected a field such as :20:", line)
+		}
+		fields[len(fields)-1].value += "\n" + text
+	}
+	return fields, scanner.Err()
+}
+
+// parseMT940Line fills the date, value date and amount of entry from a :61: field and
+// returns the bank reference.
+func parseMT940Line(value string, entry *Entry) string {
+	first, supplementary, _ := strings.Cut(value, "\n")
+	m := mt940Line.FindStringSubmatch(strings.TrimSpace(first))
+	if m == nil {
+		entry.Err = fmt.Errorf("invalid statement line %q", first)
+		return ""
+	}
+	valueDate, err := time.Parse("060102", m[1])
+	if err != nil {
+		entry.Err = fmt.Errorf("invalid date %q", m[1])
+		return ""
+	}
+	entry.ValueDate, entry.Date = valueDate, valueDate
+	if m[2] != "" {
+		month, _ := strconv.Atoi(m[2][:2])
+		day, _ := strconv.Atoi(m[2][2:])
+		year := valueDate.Year()
+		// a booking date in December for a January value date belongs to the previous year and vice versa
+		switch {
+		case month == 12 && valueDate.Month() == time.January:
+			year--
+		case month == 1 && valueDate.Month() == time.December:
+			year++
+		}
+		booking := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+		if booking.Day() != day || int(booking.Month()) != month {
+			entry.Err = fmt.Errorf("invalid booking date %q", m[2])
+			return ""
+		}
+		entry.Date = booking
+	}
+
+	amount, err := parseMT940Amount(m[5])
+	if err != nil {
+		entry.Err = fmt.Errorf("invalid amount %q", m[5])
+		return ""
+	}
+	// RC (reversal of credit) takes money out, RD (reversal of debit) puts it back
+	if m[3] == "D" || m[3] == "RC" {
+		amount = -amount
+	}
+	if amount.IsZero() {
+		entry.Err = fmt.Errorf("amount is missing or zero")
+		return ""
+	}
+	entry.Amount = amount
+
+	reference := strings.TrimSpace(m[7])
+	if reference != "" && reference != "NONREF" {
+		entry.Description = reference
+	}
+	if supplementary = strings.TrimSpace(supplementary); supplementary != "" {
+		entry.Description = strings.TrimSpace(entry.Description + " " + supplementary)
+	}
+	return strings.TrimSpace(m[8])
+}
+
+// parseMT940Information reads the :86: field. German banks structure it with "?NN" subfields
+// (20-29 and 60-63 purpose, 30 bank code or BIC, 31 account or IBAN, 32-33 name), SEPA exports
+// with /KEY/ pairs (/NAME/, /IBAN/, /BIC/, /REMI/); anything else is taken as free text.
+func parseMT940Information(value string, entry *Entry) {
+	text := strings.ReplaceAll(value, "\n", "")
+	var purpose []string
+	switch {
+	case len(text) > 3 && text[3] == '?':
+		for _, sub := range strings.Split(text[3:], "?")[1:] {
+			if len(sub) < 2 {
+				continue
+			}
+			code, content := sub[:2], sub[2:]
+			switch {
+			case code >= "20" && code <= "29", code >= "60" && code <= "63":
+				purpose = append(purpose, content)
+			case code == "30":
+				entry.CounterpartyBank = strings.TrimSpace(content)
+			case code == "31":
+				entry.CounterpartyAccount = strings.TrimSpace(content)
+			case code == "32", code == "33":
+				entry.CounterpartyName = strings.TrimSpace(entry.CounterpartyName + strings.TrimRight(content, " "))
+			}
+		}
+	case mt940Structured.MatchString(text):
+		values := mt940KeyValues(text)
+		entry.CounterpartyName = values["NAME"]
+		entry.CounterpartyAccount = values["IBAN"]
+		entry.CounterpartyBank = values["BIC"]
+		if cntp := values["CNTP"]; cntp != "" {
+			// /CNTP/account/BIC/name/city/
+			parts := strings.Split(cntp, "/")
+			for i, target := range []*string{&entry.CounterpartyAccount, &entry.CounterpartyBank, &entry.CounterpartyName} {
+				if i < len(parts) && *target == "" {
+					*target = strings.TrimSpace(parts[i])
+				}
+			}
+		}
+		purpose = append(purpose, values["REMI"])
+	default:
+		purpose = append(purpose, strings.TrimSpace(strings.ReplaceAll(value, "\n", " ")))
+	}
+
+	var parts []string
+	if entry.CounterpartyName != "" {
+		parts = append(parts, entry.CounterpartyName)
+	}
+	if text := strings.TrimSpace(strings.Join(purpose, "")); text != "" {
+		parts = append(parts, text)
+	}
+	if len(parts) > 0 {
+		entry.Description = strings.Join(parts, " ")
+	}
+}
+
+// mt940KeyValues splits "/KEY/value/KEY2/value2" on the known keys, keeping slashes inside values.
+func mt940KeyValues(text string) map[string]string {
+	values := make(map[string]string)
+	matches := mt940Structured.FindAllStringSubmatchIndex(text, -1)
+	for i, m := range matches {
+		end := len(text)
+		if i+1 < len(matches) {
+			end = matches[i+1][0]
+		}
+		values[text[m[2]:m[3]]] = strings.Trim(strings.TrimSpace(text[m[1]:end]), "/")
+	}
+	return values
+}
+
+// parseMT940Balance parses a balance field such as "C240131EUR1234,56".
+func parseMT940Balance(value string) (money.Amount, string, time.Time, error) {
+	m := mt940Balance.FindStringSubmatch(strings.TrimSpace(value))
+	if m == nil {
+		return 0, "", time.Time{}, fmt.Errorf("invalid balance %q", value)
+	}
+	date, err := time.Parse("060102", m[2])
+	if err != nil {
+		return 0, "", time.Time{}, fmt.Errorf("invalid date %q", m[2])
+	}
+	amount, err := parseMT940Amount(m[4])
+	if err != nil {
+		return 0, "", time.Time{}, fmt.Errorf("invalid amount %q", m[4])
+	}
+	if m[1] == "D" {
+		amount = -amount
+	}
+	return amount, m[3], date, nil
+}
+
+// parseMT940Amount parses an unsigned SWIFT amount such as "1234,56" or "100,".
+func parseMT940Amount(s string) (money.Amount, error) {
+	return money.Parse(strings.TrimSuffix(strings.Replace(s, ",", ".", 1), "."))
+}
@@ -0,0 +1,124 @@
+package statements
+
+import (
+	"strings"
+	"testing"
+
+	"finance_project/internal/money"
+)
+
+const mt940Input = `{1:F01BANKDEFFXXXX0000000000}{2:I940BANKDEFFXXXXN}{4:
+:20:STMT1
+:25:DE89370400440532013000
+:28C:1/1
+:60F:C231229EUR1000,00
+:61:2401020101D12,50NTRFNONREF//B1
+:86:166?00SEPA?20Coffee?21 latte?30COBADEFFXXX?31DE02120300000000202051?32Coffee Shop
+:61:240103C100,NTRFINV-7//B1
+:86:/NAME/Employer/IBAN/DE02100100100006820101/REMI/Salary/
+:61:240104C0,NTRF
+:62F:C240104EUR1087,50
+-}
+:20:STMT2
+:25:DE89370400440532013000
+:60F:C240104EUR1087,50
+:61:2412310101RD5,NTRF//B2
+:86:Refund of a card fee
+:62F:C241231EUR1092,50
+-
+`
+
+func TestParseMT940(t *testing.T) {
+	statements, err := ParseMT940(strings.NewReader(mt940Input))
+	if err != nil {
+		t.Fatalf("ParseMT940: %v", err)
+	}
+	if len(statements) != 2 {
+		t.Fatalf("got %d statements, want 2", len(statements))
+	}
+
+	type line struct {
+		line        int
+		date        string
+		valueDate   string
+		amount      string
+		description string
+		counterpart string
+		err         bool
+	}
+	tests := []struct {
+		statement   int
+		balance     string
+		balanceDate string
+		entries     []line
+	}{
+		{0, "1087.50", "2024-01-04", []line{
+			{6, "2024-01-01", "2024-01-02", "-12.50", "Coffee Shop Coffee latte", "DE02120300000000202051", false},
+			{8, "2024-01-03", "2024-01-03", "100", "Employer Salary", "DE02100100100006820101", false},
+			{10, "", "", "", "", "", true},
+		}},
+		{1, "1092.50", "2024-12-31", []line{
+			{16, "2025-01-01", "2024-12-31", "5", "Refund of a card fee", "", false},
+		}},
+	}
+	for _, tt := range tests {
+		statement := statements[tt.statement]
+		if statement.Account != "DE89370400440532013000" || statement.Currency != "EUR" {
+			t.Errorf("statement %d: account %q, currency %q", tt.statement, statement.Account, statement.Currency)
+		}
+		if statement.LedgerBalance == nil || *statement.LedgerBalance != money.MustParse(tt.balance) {
+			t.Errorf("statement %d: ledger balance = %v, want %s", tt.statement, statement.LedgerBalance, tt.balance)
+		}
+		if got := statement.BalanceDate.Format("2006-01-02"); got != tt.balanceDate {
+			t.Errorf("statement %d: balance date = %s, want %s", tt.statement, got, tt.balanceDate)
+		}
+		if len(statement.Entries) != len(tt.entries) {
+			t.Fatalf("statement %d: got %d entries, want %d: %+v", tt.statement, len(statement.Entries), len(tt.entries), statement.Entries)
+		}
+		for i, want := range tt.entries {
+			got := statement.Entries[i]
+			if got.Line != want.line {
+				t.Errorf("entry %d: line = %d, want %d", i, got.Line, want.line)
+			}
+			if want.err {
+				if got.Err == nil {
+					t.Errorf("entry %d: want an error, got %+v", i, got)
+				}
+				continue
+			}
+			if got.Err != nil {
+				t.Errorf("entry %d: unexpected error %v", i, got.Err)
+				continue
+			}
+			checkEntry(t, i, got, want.date, want.amount, want.description, "EUR")
+			if d := got.ValueDate.Format("2006-01-02"); d != want.valueDate {
+				t.Errorf("entry %d: value date = %s, want %s", i, d, want.valueDate)
+			}
+			if got.CounterpartyAccount != want.counterpart {
+				t.Errorf("entry %d: counterparty account = %q, want %q", i, got.CounterpartyAccount, want.counterpart)
+			}
+		}
+	}
+
+	// the same bank reference on different days must not collide
+	if statements[0].Entries[0].ID == statements[0].Entries[1].ID {
+		t.Errorf("entries with a shared bank reference got the same ID %q", statements[0].Entries[0].ID)
+	}
+}
+
+func TestParseMT940Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"empty file", ""},
+		{"field before :20:", ":25:DE89370400440532013000\n:20:STMT\n"},
+		{"text before the first field", "hello\n:20:STMT\n"},
+		{"bad closing balance", ":20:STMT\n:62F:X240104EUR1,00\n"},
+	}
+	for _, tt := range tests {
+		if _, err := ParseMT940(strings.NewReader(tt.input)); err == nil {
+			t.Errorf("%s: ParseMT940 succeeded, want error", tt.name)
+		}
+	}
+}
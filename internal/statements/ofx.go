@@ -0,0 +1,207 @@
+package statements
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"time"
+)
+
+// ofxTransaction collects the fields of one STMTTRN aggregate.
+type ofxTransaction struct {
+	line                              int
+	fitID, posted, amount, name, memo string
+	currency                          string
+}
+
+// ParseOFX parses an OFX 1.x (SGML) or 2.x (XML) bank or credit card statement; QFX files
+// are OFX with Quicken extensions and parse the same way. The file must hold a single
+// statement. Transactions without a FITID get a fingerprint ID.
+//
+// OFX 1.x leaves leaf elements unclosed (<TRNAMT>-12.50) while 2.x closes them; both are read
+// by one tokenizer that treats a tag followed by text as a leaf and anything else as an aggregate.
+func ParseOFX(r io.Reader) (*Statement, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	text := string(data)
+	start := strings.Index(strings.ToUpper(text), "<OFX>")
+	if start < 0 {
+		return nil, fmt.Errorf("parsing OFX statement: <OFX> element not found")
+	}
+	line := 1 + strings.Count(text[:start], "\n")
+	text = text[start:]
+
+	statement := &Statement{}
+	var (
+		path       []string // open aggregates
+		current    *ofxTransaction
+		found      []ofxTransaction
+		statements int
+		balance    string
+		balanceAt  string
+	)
+	for {
+		open := strings.IndexByte(text, '<')
+		if open < 0 {
+			break
+		}
+		line += strings.Count(text[:open], "\n")
+		end := strings.IndexByte(text[open:], '>')
+		if end < 0 {
+			return nil, fmt.Errorf("parsing OFX statement: unterminated tag on line %d", line)
+		}
+		tag := strings.ToUpper(strings.TrimSpace(text[open+1 : open+end]))
+		text = text[open+end+1:]
+		next := strings.IndexByte(text, '<')
+		if next < 0 {
+			next = len(text)
+		}
+		value := strings.TrimSpace(html.UnescapeString(text[:next]))
+
+		switch {
+		case strings.HasPrefix(tag, "?") || strings.HasPrefix(tag, "!") || strings.HasSuffix(tag, "/"):
+			// XML declaration, OFX processing instruction, comment or empty element
+		case strings.HasPrefix(tag, "/"):
+			name := tag[1:]
+			for i := len(path) - 1; i >= 0; i-- {
+				if path[i] == name {
+					path = path[:i]
+					break
+				}
+			}
+			if name == "STMTTRN" && current != nil {
+				found = append(found, *current)
+				current = nil
+			}
+		case value != "":
+			switch {
+			case current != nil:
+				current.set(tag, value, path)
+			case tag == "CURDEF":
+				statement.Currency = strings.ToUpper(value)
+			case tag == "BALAMT" && contains(path, "LEDGERBAL"):
+				balance = value
+			case tag == "DTASOF" && contains(path, "LEDGERBAL"):
+				balanceAt = value
+			}
+		default:
+			path = append(path, tag)
+			switch tag {
+			case "STMTRS", "CCSTMTRS":
+				statements++
+			case "STMTTRN":
+				current = &ofxTransaction{line: line}
+			}
+		}
+	}
+	switch {
+	case statements == 0:
+		return nil, fmt.Errorf("parsing OFX statement: no bank or credit card statement found")
+	case statements > 1:
+		return nil, fmt.Errorf("parsing OFX statement: file contains %d statements, export one account at a time", statements)
+	}
+
+	if balance != "" {
+		amount, err := parseAmount(balance, ofxDecimalSeparator(balance))
+		if err != nil {
+			return nil, fmt.Errorf("parsing OFX statement: ledger balance: %w", err)
+		}
+		statement.LedgerBalance = &amount
+		if balanceAt != "" {
+			if statement.BalanceDate, err = parseOFXDate(balanceAt); err != nil {
+				return nil, fmt.Errorf("parsing OFX statement: ledger balance date: %w", err)
+			}
+		}
+	}
+
+	seen := make(map[string]int)
+	for _, t := range found {
+		entry := t.entry()
+		if entry.Currency == "" {
+			entry.Currency = statement.Currency
+		}
+		if entry.ID == "" && entry.Err == nil {
+			entry.ID = fingerprint("ofx:", entry, "", seen)
+		}
+		statement.Entries = append(statement.Entries, entry)
+	}
+	return statement, nil
+}
+
+func (t *ofxTransaction) set(tag, value string, path []string) {
+	switch tag {
+	case "FITID":
+		t.fitID = value
+	case "DTPOSTED":
+		t.posted = value
+	case "TRNAMT":
+		t.amount = value
+	case "NAME":
+		t.name = value
+	case "MEMO":
+		t.memo = value
+	case "CURSYM":
+		// CURRENCY means the amount is in that currency; with ORIGCURRENCY it is already
+		// converted to the statement currency
+		if contains(path, "CURRENCY") {
+			t.currency = strings.ToUpper(value)
+		}
+	}
+}
+
+func (t *ofxTransaction) entry() Entry {
+	entry := Entry{Line: t.line, ID: t.fitID, Currency: t.currency}
+	date, err := parseOFXDate(t.posted)
+	if err != nil {
+		entry.Err = err
+		return entry
+	}
+	entry.Date = date
+	if entry.Amount, err = parseAmount(t.amount, ofxDecimalSeparator(t.amount)); err != nil {
+		entry.Err = err
+		return entry
+	}
+	if entry.Amount.IsZero() {
+		entry.Err = fmt.Errorf("amount is missing or zero")
+		return entry
+	}
+	entry.Description = t.name
+	if t.memo != "" && !strings.EqualFold(t.memo, t.name) {
+		entry.Description = strings.TrimSpace(t.name + " " + t.memo)
+	}
+	return entry
+}
+
+// parseOFXDate reads the date part of an OFX datetime such as 20240131, 20240131120000
+// or 20240131120000.000[-5:EST]. The time and zone are dropped: statement lines are dated.
+func parseOFXDate(s string) (time.Time, error) {
+	if len(s) < 8 {
+		return time.Time{}, fmt.Errorf("invalid date %q", s)
+	}
+	date, err := time.Parse("20060102", s[:8])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q", s)
+	}
+	return date, nil
+}
+
+// ofxDecimalSeparator returns "," for amounts such as "-12,50" that some European banks
+// export, and "." otherwise.
+func ofxDecimalSeparator(s string) string {
+	if strings.Contains(s, ",") && !strings.Contains(s, ".") {
+		return ","
+	}
+	return "."
+}
+
+func contains(path []string, name string) bool {
+	for _, p := range path {
+		if p == name {
+			return true
+		}
+	}
+	return false
+}
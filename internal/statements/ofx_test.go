@@ -0,0 +1,180 @@
+package statements
+
+import (
+	"strings"
+	"testing"
+
+	"finance_project/internal/money"
+)
+
+const ofxSGML = `OFXHEADER:100
+DATA:OFXSGML
+VERSION:102
+
+<OFX>
+<BANKMSGSRSV1><STMTTRNRS><STMTRS>
+<CURDEF>EUR
+<BANKTRANLIST>
+<STMTTRN>
+<TRNTYPE>DEBIT
+<DTPOSTED>20240131120000.000[-5:EST]
+<TRNAMT>-12.50
+<FITID>T1
+<NAME>Coffee Shop
+<MEMO>latte
+</STMTTRN>
+<STMTTRN>
+<TRNTYPE>CREDIT
+<DTPOSTED>20240201
+<TRNAMT>1000,00
+<NAME>Employer
+<MEMO>EMPLOYER
+<CURRENCY><CURRATE>1.1<CURSYM>usd</CURRENCY>
+</STMTTRN>
+<STMTTRN>
+<DTPOSTED>2024
+<TRNAMT>5
+<FITID>T3
+</STMTTRN>
+</BANKTRANLIST>
+<LEDGERBAL><BALAMT>987.50<DTASOF>20240201</LEDGERBAL>
+</STMTRS></STMTTRNRS></BANKMSGSRSV1>
+</OFX>
+`
+
+const ofxXML = `<?xml version="1.0" encoding="UTF-8"?>
+<?OFX OFXHEADER="200" VERSION="220"?>
+<OFX>
+  <CREDITCARDMSGSRSV1><CCSTMTTRNRS><CCSTMTRS>
+    <CURDEF>usd</CURDEF>
+    <BANKTRANLIST>
+      <STMTTRN>
+        <DTPOSTED>20240305</DTPOSTED>
+        <TRNAMT>-4.99</TRNAMT>
+        <FITID>X1</FITID>
+        <NAME>Books &amp; Co</NAME>
+        <ORIGCURRENCY><CURRATE>0.9</CURRATE><CURSYM>EUR</CURSYM></ORIGCURRENCY>
+      </STMTTRN>
+      <STMTTRN>
+        <DTPOSTED>20240306</DTPOSTED>
+        <TRNAMT>0</TRNAMT>
+        <FITID>X2</FITID>
+      </STMTTRN>
+    </BANKTRANLIST>
+  </CCSTMTRS></CCSTMTTRNRS></CREDITCARDMSGSRSV1>
+</OFX>
+`
+
+func TestParseOFX(t *testing.T) {
+	type line struct {
+		line        int
+		id          string
+		date        string
+		amount      string
+		description string
+		currency    string
+		err         bool
+	}
+	tests := []struct {
+		name        string
+		input       string
+		currency    string
+		balance     string
+		balanceDate string
+		want        []line
+	}{
+		{
+			name: "SGML", input: ofxSGML, currency: "EUR", balance: "987.50", balanceDate: "2024-02-01",
+			want: []line{
+				{9, "T1", "2024-01-31", "-12.50", "Coffee Shop latte", "EUR", false},
+				{17, "", "2024-02-01", "1000", "Employer", "USD", false},
+				{25, "T3", "", "", "", "", true},
+			},
+		},
+		{
+			name: "XML credit card", input: ofxXML, currency: "USD",
+			want: []line{
+				{7, "X1", "2024-03-05", "-4.99", "Books & Co", "USD", false},
+				{14, "X2", "", "", "", "", true},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statement, err := ParseOFX(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("ParseOFX: %v", err)
+			}
+			if statement.Currency != tt.currency {
+				t.Errorf("currency = %q, want %q", statement.Currency, tt.currency)
+			}
+			switch {
+			case tt.balance == "" && statement.LedgerBalance != nil:
+				t.Errorf("ledger balance = %s, want none", *statement.LedgerBalance)
+			case tt.balance != "" && (statement.LedgerBalance == nil || *statement.LedgerBalance != money.MustParse(tt.balance)):
+				t.Errorf("ledger balance = %v, want %s", statement.LedgerBalance, tt.balance)
+			}
+			if tt.balanceDate != "" && statement.BalanceDate.Format("2006-01-02") != tt.balanceDate {
+				t.Errorf("balance date = %s, want %s", statement.BalanceDate.Format("2006-01-02"), tt.balanceDate)
+			}
+			if len(statement.Entries) != len(tt.want) {
+				t.Fatalf("got %d entries, want %d: %+v", len(statement.Entries), len(tt.want), statement.Entries)
+			}
+			for i, want := range tt.want {
+				got := statement.Entries[i]
+				if got.Line != want.line {
+					t.Errorf("entry %d: line = %d, want %d", i, got.Line, want.line)
+				}
+				if want.err {
+					if got.Err == nil {
+						t.Errorf("entry %d: want an error, got %+v", i, got)
+					}
+					continue
+				}
+				if got.Err != nil {
+					t.Errorf("entry %d: unexpected error %v", i, got.Err)
+					continue
+				}
+				switch {
+				case want.id != "" && got.ID != want.id:
+					t.Errorf("entry %d: ID = %q, want %q", i, got.ID, want.id)
+				case want.id == "" && !strings.HasPrefix(got.ID, "ofx:"):
+					t.Errorf("entry %d: ID = %q, want a fingerprint", i, got.ID)
+				}
+				checkEntry(t, i, got, want.date, want.amount, want.description, want.currency)
+			}
+		})
+	}
+}
+
+func TestParseOFXFingerprintIsStable(t *testing.T) {
+	first, err := ParseOFX(strings.NewReader(ofxSGML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := ParseOFX(strings.NewReader(ofxSGML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Entries[1].ID != second.Entries[1].ID {
+		t.Errorf("fingerprint changed between imports: %q, %q", first.Entries[1].ID, second.Entries[1].ID)
+	}
+}
+
+func TestParseOFXErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"no OFX element", "OFXHEADER:100\n"},
+		{"no statement", "<OFX><SIGNONMSGSRSV1><SONRS><STATUS><CODE>0</STATUS></SONRS></SIGNONMSGSRSV1></OFX>"},
+		{"two statements", "<OFX><STMTRS><CURDEF>EUR</STMTRS><STMTRS><CURDEF>USD</STMTRS></OFX>"},
+		{"unterminated tag", "<OFX><STMTRS><CURDEF"},
+		{"bad ledger balance", "<OFX><STMTRS><LEDGERBAL><BALAMT>lots</LEDGERBAL></STMTRS></OFX>"},
+	}
+	for _, tt := range tests {
+		if _, err := ParseOFX(strings.NewReader(tt.input)); err == nil {
+			t.Errorf("%s: ParseOFX succeeded, want error", tt.name)
+		}
+	}
+}
@@ -0,0 +1,240 @@
+package statements
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"finance_project/internal/money"
+)
+
+// QIFOptions covers what QIF leaves to the exporting program.
+type QIFOptions struct {
+	// DateFormat gives the order of day, month and year, e.g. "DD/MM/YYYY";
+	// by default dates are month first as Quicken writes them.
+	DateFormat string
+	// DecimalSeparator is "." (default) or ",".
+	DecimalSeparator string
+}
+
+// qifRecord collects the fields of one QIF transaction up to its "^" terminator.
+type qifRecord struct {
+	line                              int
+	date, amount, payee, memo, number string
+}
+
+// ParseQIF parses the bank, cash and credit card sections of a QIF file. Investment, category
+// and other lists are skipped. QIF has no transaction IDs, so every line gets a fingerprint of
+// its date, amount, payee and check number; re-importing the same export yields the same IDs.
+// The statement balance of an !Account block ("$" and "/" fields), if present, becomes the
+// ledger balance.
+func ParseQIF(r io.Reader, options QIFOptions) (*Statement, error) {
+	order := qifDateOrder(options.DateFormat)
+	decimalSeparator := options.DecimalSeparator
+	if decimalSeparator == "" {
+		decimalSeparator = "."
+	}
+
+	statement := &Statement{}
+	seen := make(map[string]int)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	const (
+		sectionNone = iota
+		sectionBank
+		sectionAccount
+		sectionOther
+	)
+	var (
+		section            = sectionNone
+		banking            bool
+		record             qifRecord
+		balance, balanceAt string
+		line               int
+	)
+	for scanner.Scan() {
+		line++
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if line == 1 {
+			text = strings.TrimPrefix(text, "\ufeff")
+		}
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		if strings.HasPrefix(text, "!") {
+			header := strings.ToUpper(strings.TrimSpace(text))
+			switch {
+			case header == "!ACCOUNT":
+				section = sectionAccount
+			case header == "!TYPE:BANK", header == "!TYPE:CASH", header == "!TYPE:CCARD",
+				header == "!TYPE:OTH A", header == "!TYPE:OTH L":
+				section, banking = sectionBank, true
+			case strings.HasPrefix(header, "!OPTION") || strings.HasPrefix(header, "!CLEAR"):
+				// switches that do not change the current section
+			default:
+				section = sectionOther
+			}
+			record = qifRecord{}
+			continue
+		}
+
+		code, value := text[0], strings.TrimSpace(text[1:])
+		switch section {
+		case sectionAccount:
+			switch code {
+			case '$':
+				balance = value
+			case '/':
+				balanceAt = value
+			}
+		case sectionBank:
+			if record.line == 0 && code != '^' {
+				record.line = line
+			}
+			switch code {
+			case 'D':
+				record.date = value
+			case 'T':
+				record.amount = value
+			case 'U':
+				if record.amount == "" {
+					record.amount = value
+				}
+			case 'P':
+				record.payee = value
+			case 'M':
+				record.memo = value
+			case 'N':
+				record.number = value
+			case '^':
+				if record.line == 0 {
+					continue
+				}
+				entry := record.entry(order, decimalSeparator)
+				if entry.Err == nil {
+					entry.ID = fingerprint("qif:", entry, record.number, seen)
+				}
+				statement.Entries = append(statement.Entries, entry)
+				record = qifRecord{}
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("parsing QIF statement: %w", err)
+	}
+	if !banking {
+		return nil, fmt.Errorf("parsing QIF statement: no bank, cash or credit card transactions found")
+	}
+	if record.line != 0 {
+		// the last record may lack its terminator
+		entry := record.entry(order, decimalSeparator)
+		if entry.Err == nil {
+			entry.ID = fingerprint("qif:", entry, record.number, seen)
+		}
+		statement.Entries = append(statement.Entries, entry)
+	}
+
+	if balance != "" {
+		amount, err := parseAmount(balance, decimalSeparator)
+		if err != nil {
+			return nil, fmt.Errorf("parsing QIF statement: statement balance: %w", err)
+		}
+		statement.LedgerBalance = &amount
+		if balanceAt != "" {
+			if statement.BalanceDate, err = parseQIFDate(balanceAt, order); err != nil {
+				return nil, fmt.Errorf("parsing QIF statement: statement balance date: %w", err)
+			}
+		}
+	}
+	return statement, nil
+}
+
+func (r qifRecord) entry(order, decimalSeparator string) Entry {
+	entry := Entry{Line: r.line}
+	date, err := parseQIFDate(r.date, order)
+	if err != nil {
+		entry.Err = err
+		return entry
+	}
+	entry.Date = date
+	var amount money.Amount
+	if amount, err = parseAmount(r.amount, decimalSeparator); err != nil {
+		entry.Err = err
+		return entry
+	}
+	if amount.IsZero() {
+		entry.Err = fmt.Errorf("amount is missing or zero")
+		return entry
+	}
+	entry.Amount = amount
+	entry.Description = r.payee
+	if r.memo != "" && !strings.EqualFold(r.memo, r.payee) {
+		entry.Description = strings.TrimSpace(r.payee + " " + r.memo)
+	}
+	return entry
+}
+
+// qifDateOrder reduces a format such as "DD/MM/YYYY" to the order of its parts ("dmy").
+func qifDateOrder(format string) string {
+	var order []byte
+	for _, r := range strings.ToLower(format) {
+		if (r == 'd' || r == 'm' || r == 'y') && !strings.ContainsRune(string(order), r) {
+			order = append(order, byte(r))
+		}
+	}
+	if len(order) != 3 {
+		return "mdy"
+	}
+	return string(order)
+}
+
+// parseQIFDate parses dates such as "1/31/2024", "01/31/24", "1/31'24" or "31.01.2024".
+// Quicken marks years from 2000 with an apostrophe; other two-digit years below 50 are
+// taken as 20xx.
+func parseQIFDate(s, order string) (time.Time, error) {
+	invalid := fmt.Errorf("invalid date %q", s)
+	parts := strings.FieldsFunc(s, func(r rune) bool { return r < '0' || r > '9' })
+	if len(parts) == 1 && len(parts[0]) == 8 {
+		date, err := time.Parse("20060102", parts[0])
+		if err != nil {
+			return time.Time{}, invalid
+		}
+		return date, nil
+	}
+	if len(parts) != 3 {
+		return time.Time{}, invalid
+	}
+
+	var day, month, year int
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return time.Time{}, invalid
+		}
+		switch order[i] {
+		case 'd':
+			day = n
+		case 'm':
+			month = n
+		case 'y':
+			year = n
+			if len(part) <= 2 {
+				switch {
+				case strings.Contains(s, "'") || year < 50:
+					year += 2000
+				default:
+					year += 1900
+				}
+			}
+		}
+	}
+	date := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	if date.Day() != day || int(date.Month()) != month {
+		return time.Time{}, invalid
+	}
+	return date, nil
+}
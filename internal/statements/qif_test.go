@@ -0,0 +1,145 @@
+package statements
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"finance_project/internal/money"
+)
+
+func TestParseQIF(t *testing.T) {
+	input := "!Account\nNChecking\nTBank\n$1,500.00\n/1/31'24\n^\n" +
+		"!Type:Bank\n" +
+		"D1/30'24\nT-12.50\nPCoffee Shop\nMlatte\n^\n" +
+		"D01/31/2024\nU1,000.00\nT1,000.00\nPEmployer\nMemployer\nN101\n^\n" +
+		"D13/01/2024\nT5\n^\n" +
+		"D1/31/2024\nT0\n^\n" +
+		"!Type:Invst\nD1/31/2024\nT99\n^\n" +
+		"!Type:Cash\nD2/1/2024\nT-3\nPBus"
+
+	statement, err := ParseQIF(strings.NewReader(input), QIFOptions{})
+	if err != nil {
+		t.Fatalf("ParseQIF: %v", err)
+	}
+	if statement.LedgerBalance == nil || *statement.LedgerBalance != money.MustParse("1500") {
+		t.Errorf("ledger balance = %v, want 1500", statement.LedgerBalance)
+	}
+	if got := statement.BalanceDate.Format("2006-01-02"); got != "2024-01-31" {
+		t.Errorf("balance date = %s, want 2024-01-31", got)
+	}
+
+	want := []struct {
+		line        int
+		date        string
+		amount      string
+		description string
+		err         bool
+	}{
+		{8, "2024-01-30", "-12.5", "Coffee Shop latte", false},
+		{13, "2024-01-31", "1000", "Employer", false},
+		{20, "", "", "", true},
+		{23, "", "", "", true},
+		{31, "2024-02-01", "-3", "Bus", false},
+	}
+	if len(statement.Entries) != len(want) {
+		t.Fatalf("got %d entries, want %d: %+v", len(statement.Entries), len(want), statement.Entries)
+	}
+	for i, w := range want {
+		got := statement.Entries[i]
+		if got.Line != w.line {
+			t.Errorf("entry %d: line = %d, want %d", i, got.Line, w.line)
+		}
+		if w.err {
+			if got.Err == nil {
+				t.Errorf("entry %d: want an error, got %+v", i, got)
+			}
+			continue
+		}
+		if got.Err != nil {
+			t.Errorf("entry %d: unexpected error %v", i, got.Err)
+			continue
+		}
+		if !strings.HasPrefix(got.ID, "qif:") {
+			t.Errorf("entry %d: ID = %q, want a fingerprint", i, got.ID)
+		}
+		checkEntry(t, i, got, w.date, w.amount, w.description, "")
+	}
+}
+
+func TestParseQIFDuplicateLines(t *testing.T) {
+	input := "!Type:Bank\nD1/31/2024\nT-5\nPBus\n^\nD1/31/2024\nT-5\nPBus\n^\n"
+	statement, err := ParseQIF(strings.NewReader(input), QIFOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statement.Entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(statement.Entries))
+	}
+	if statement.Entries[0].ID == statement.Entries[1].ID {
+		t.Errorf("identical lines share the ID %q", statement.Entries[0].ID)
+	}
+}
+
+func TestParseQIFOptions(t *testing.T) {
+	input := "!Type:CCard\nD31.01.2024\nT-1.234,56\nPRent\n^\n"
+	statement, err := ParseQIF(strings.NewReader(input), QIFOptions{DateFormat: "DD.MM.YYYY", DecimalSeparator: ","})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statement.Entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(statement.Entries))
+	}
+	if err := statement.Entries[0].Err; err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	checkEntry(t, 0, statement.Entries[0], "2024-01-31", "-1234.56", "Rent", "")
+}
+
+func TestParseQIFErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"no bank section", "!Type:Invst\nD1/31/2024\nT5\n^\n"},
+		{"empty file", ""},
+		{"bad statement balance", "!Account\n$lots\n^\n!Type:Bank\nD1/31/2024\nT5\n^\n"},
+	}
+	for _, tt := range tests {
+		if _, err := ParseQIF(strings.NewReader(tt.input), QIFOptions{}); err == nil {
+			t.Errorf("%s: ParseQIF succeeded, want error", tt.name)
+		}
+	}
+}
+
+func TestParseQIFDate(t *testing.T) {
+	tests := []struct {
+		in      string
+		order   string
+		want    string
+		wantErr bool
+	}{
+		{"1/31/2024", "mdy", "2024-01-31", false},
+		{"01/31/24", "mdy", "2024-01-31", false},
+		{"1/31'99", "mdy", "2099-01-31", false},
+		{"12/31/99", "mdy", "1999-12-31", false},
+		{"31.01.2024", "dmy", "2024-01-31", false},
+		{"2024-01-31", "ymd", "2024-01-31", false},
+		{"20240131", "mdy", "2024-01-31", false},
+		{"2/30/2024", "mdy", "", true},
+		{"1/31", "mdy", "", true},
+	}
+	for _, tt := range tests {
+		got, err := parseQIFDate(tt.in, tt.order)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseQIFDate(%q, %q) = %s, want error", tt.in, tt.order, got.Format("2006-01-02"))
+			}
+			continue
+		}
+		want, _ := time.Parse("2006-01-02", tt.want)
+		if err != nil || !got.Equal(want) {
+			t.Errorf("parseQIFDate(%q, %q) = %s, %v, want %s", tt.in, tt.order, got.Format("2006-01-02"), err, tt.want)
+		}
+	}
+}
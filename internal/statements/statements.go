@@ -0,0 +1,166 @@
+// Package statements parses bank statements into entries that can be posted as transactions.
+//
+// CSV statements are described by a models.StatementProfile that maps the bank's columns,
+// date format and sign convention. OFX 1.x (SGML) and 2.x (XML) files, including Quicken's
+// QFX, QIF files, ISO 20022 camt.053 XML and SWIFT MT940 are self-describing; camt.053 and
+// MT940 files may hold several statements.
+package statements
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"finance_project/internal/money"
+)
+
+// Statement formats accepted by the importer.
+const (
+	FormatAuto  = "auto"
+	FormatCSV   = "csv"
+	FormatOFX   = "ofx"
+	FormatQFX   = "qfx"
+	FormatQIF   = "qif"
+	FormatCAMT  = "camt053"
+	FormatMT940 = "mt940"
+)
+
+// Statement is a parsed statement. LedgerBalance is the closing balance reported by the
+// bank as of BalanceDate (zero when the file gives no date); it is nil when the file has none.
+// Account is the bank's account identifier (IBAN or account number) when the format has one.
+type Statement struct {
+	Account       string
+	Currency      string
+	LedgerBalance *money.Amount
+	BalanceDate   time.Time
+	Entries       []Entry
+}
+
+// Entry is one statement line. Amount is signed: credits (income) are positive,
+// debits (expenses) are negative. ID identifies the line across re-imports of overlapping
+// statements (the OFX FITID); it is empty for CSV. Date is the booking date.
+// The value date, counterparty and original amount are filled by formats that carry them;
+// OriginalAmount is the unsigned instructed amount when it was in another currency.
+// Err is set when the line could not be parsed.
+type Entry struct {
+	Line        int
+	ID          string
+	Date        time.Time
+	Amount      money.Amount
+	Description string
+	Currency    string
+
+	ValueDate           time.Time
+	CounterpartyName    string
+	CounterpartyAccount string
+	CounterpartyBank    string
+	OriginalAmount      money.Amount
+	OriginalCurrency    string
+
+	Err error
+}
+
+// Detect guesses the statement format from the file name and the beginning of its content.
+func Detect(name string, data []byte) string {
+	switch ext := strings.ToLower(filepath.Ext(name)); ext {
+	case ".csv", ".ofx", ".qfx", ".qif", ".mt940":
+		return ext[1:]
+	case ".sta":
+		return FormatMT940
+	}
+	head := data
+	if len(head) > 1024 {
+		head = head[:1024]
+	}
+	text := strings.TrimSpace(strings.TrimPrefix(string(head), "\ufeff"))
+	upper := strings.ToUpper(text)
+	switch {
+	case strings.HasPrefix(upper, "OFXHEADER") || strings.Contains(upper, "<OFX>"):
+		return FormatOFX
+	case strings.Contains(text, "camt.053") || strings.Contains(text, "BkToCstmrStmt"):
+		return FormatCAMT
+	case strings.HasPrefix(text, "{1:") || strings.HasPrefix(text, ":20:") || strings.Contains(text, "\n:20:"):
+		return FormatMT940
+	case strings.HasPrefix(text, "!"):
+		return FormatQIF
+	default:
+		return FormatCSV
+	}
+}
+
+// fingerprint builds a stable ID for a line that has none from its date, amount and
+// description. Identical lines within one file are told apart by their occurrence number,
+// which seen tracks.
+func fingerprint(prefix string, entry Entry, extra string, seen map[string]int) string {
+	key := strings.Join([]string{entry.Date.Format("2006-01-02"), entry.Amount.String(), entry.Description, extra}, "|")
+	seen[key]++
+	sum := sha1.Sum([]byte(fmt.Sprintf("%s#%d", key, seen[key])))
+	return prefix + hex.EncodeToString(sum[:10])
+}
+
+// dateTokens translates DD.MM.YYYY-style patterns to Go layouts, longest tokens first.
+var dateTokens = strings.NewReplacer(
+	"YYYY", "2006",
+	"YY", "06",
+	"MM", "01",
+	"DD", "02",
+	"hh", "15",
+	"mm", "04",
+	"ss", "05",
+)
+
+// dateLayout returns the Go layout for a profile date format.
+func dateLayout(format string) string {
+	switch {
+	case format == "":
+		return "2006-01-02"
+	case strings.Contains(format, "2006") || strings.Contains(format, "02"):
+		return format
+	default:
+		return dateTokens.Replace(format)
+	}
+}
+
+// parseAmount parses a bank-formatted number such as "1 234,56", "-1,234.56", "(12.00)" or "12.00-".
+// Thousands separators and a currency sign are ignored.
+func parseAmount(s, decimalSeparator string) (money.Amount, error) {
+	raw := s
+	s = strings.TrimSpace(s)
+	negative := false
+	if strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
+		negative, s = true, s[1:len(s)-1]
+	}
+	if strings.HasSuffix(s, "-") {
+		negative, s = true, strings.TrimSuffix(s, "-")
+	}
+
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9', r == '-', r == '+':
+			b.WriteRune(r)
+		case string(r) == decimalSeparator:
+			b.WriteByte('.')
+		case r == '.' || r == ',' || r == ' ' || r == '\u00a0' || r == '\u202f' || r == '\'':
+			// thousands separator
+		case r > 127 || r == '$':
+			// currency sign
+		default:
+			return 0, fmt.Errorf("invalid amount %q", raw)
+		}
+	}
+	if b.Len() == 0 {
+		return 0, fmt.Errorf("invalid amount %q", raw)
+	}
+	amount, err := money.Parse(strings.TrimPrefix(b.String(), "+"))
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q", raw)
+	}
+	if negative {
+		amount = -amount
+	}
+	return amount, nil
+}
@@ -0,0 +1,86 @@
+package statements
+
+import (
+	"testing"
+
+	"finance_project/internal/money"
+)
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		in        string
+		separator string
+		want      string
+		wantErr   bool
+	}{
+		{"12.50", ".", "12.5", false},
+		{"-1,234.56", ".", "-1234.56", false},
+		{"1 234,56", ",", "1234.56", false},
+		{"1 234,56 ₽", ",", "1234.56", false},
+		{"1'234.56", ".", "1234.56", false},
+		{"(12.00)", ".", "-12", false},
+		{"12.00-", ".", "-12", false},
+		{"+7", ".", "7", false},
+		{"$5.25", ".", "5.25", false},
+		{"", ".", "", true},
+		{"abc", ".", "", true},
+		{"1,2,3", ",", "", true},
+	}
+	for _, tt := range tests {
+		got, err := parseAmount(tt.in, tt.separator)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseAmount(%q, %q) = %s, want error", tt.in, tt.separator, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseAmount(%q, %q) error: %v", tt.in, tt.separator, err)
+			continue
+		}
+		if want := money.MustParse(tt.want); got != want {
+			t.Errorf("parseAmount(%q, %q) = %s, want %s", tt.in, tt.separator, got, want)
+		}
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"export.CSV", "", FormatCSV},
+		{"export.qfx", "", FormatQFX},
+		{"export.sta", "", FormatMT940},
+		{"export.txt", "OFXHEADER:100\nDATA:OFXSGML\n", FormatOFX},
+		{"export.xml", `<?xml version="1.0"?><OFX><SIGNONMSGSRSV1>`, FormatOFX},
+		{"export.xml", `<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.02">`, FormatCAMT},
+		{"export.txt", "{1:F01BANKDEFFXXXX0000000000}{4:\n:20:STMT\n", FormatMT940},
+		{"export.txt", "\ufeff!Type:Bank\nD1/31/2024\n", FormatQIF},
+		{"export", "Date,Amount,Payee\n", FormatCSV},
+	}
+	for _, tt := range tests {
+		if got := Detect(tt.name, []byte(tt.data)); got != tt.want {
+			t.Errorf("Detect(%q, %q) = %q, want %q", tt.name, tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestDateLayout(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"", "2006-01-02"},
+		{"DD.MM.YYYY", "02.01.2006"},
+		{"MM/DD/YY", "01/02/06"},
+		{"YYYY-MM-DD hh:mm:ss", "2006-01-02 15:04:05"},
+		{"02/01/2006", "02/01/2006"},
+	}
+	for _, tt := range tests {
+		if got := dateLayout(tt.format); got != tt.want {
+			t.Errorf("dateLayout(%q) = %q, want %q", tt.format, got, tt.want)
+		}
+	}
+}
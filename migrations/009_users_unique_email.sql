@@ -0,0 +1,2 @@
+-- 009_users_unique_email.sql
+CREATE UNIQUE INDEX IF NOT EXISTS users_email_lower_key ON users (LOWER(email));
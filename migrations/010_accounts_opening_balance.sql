@@ -0,0 +1,9 @@
+-- 010_accounts_opening_balance.sql
+-- Balance is now maintained from the ledger: balance = opening_balance + income - expenses.
+-- Until now transactions never touched accounts.balance, so the stored balance is the opening one.
+ALTER TABLE accounts
+ADD COLUMN IF NOT EXISTS opening_balance NUMERIC(15,2) NOT NULL DEFAULT 0;
+
+UPDATE accounts SET opening_balance = balance;
+
+CREATE INDEX IF NOT EXISTS idx_transactions_account_id ON transactions (account_id);
@@ -0,0 +1,34 @@
+-- 011_create_transfers.sql
+CREATE TABLE IF NOT EXISTS transfers (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    from_account_id INTEGER NOT NULL REFERENCES accounts (id),
+    to_account_id INTEGER NOT NULL REFERENCES accounts (id),
+    amount NUMERIC(15,2) NOT NULL CHECK (amount > 0),
+    rate NUMERIC(20,10) NOT NULL DEFAULT 1 CHECK (rate > 0),
+    converted_amount NUMERIC(15,2) NOT NULL CHECK (converted_amount > 0),
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK (from_account_id <> to_account_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_transfers_user_id ON transfers (user_id);
+
+-- Каждый перевод отражается в журнале двумя связанными записями: transfer_out и transfer_in.
+-- Они не относятся к доходам и расходам и не имеют категории.
+ALTER TABLE transactions
+ADD COLUMN IF NOT EXISTS transfer_id INTEGER REFERENCES transfers (id) ON DELETE CASCADE;
+
+ALTER TABLE transactions ALTER COLUMN category_id DROP NOT NULL;
+ALTER TABLE transactions ALTER COLUMN type TYPE VARCHAR(20);
+
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_type_check;
+ALTER TABLE transactions
+ADD CONSTRAINT transactions_type_check
+CHECK (type IN ('income', 'expense', 'transfer_in', 'transfer_out'));
+
+ALTER TABLE transactions
+ADD CONSTRAINT transactions_transfer_link_check
+CHECK ((transfer_id IS NULL) = (type IN ('income', 'expense')));
+
+CREATE INDEX IF NOT EXISTS idx_transactions_transfer_id ON transactions (transfer_id);
@@ -0,0 +1,9 @@
+-- 012_money_columns_scale.sql
+-- Суммы хранятся с 4 знаками после запятой, чтобы вместить валюты с тремя знаками (KWD, BHD, ...).
+ALTER TABLE transactions ALTER COLUMN amount TYPE NUMERIC(19,4);
+ALTER TABLE accounts ALTER COLUMN balance TYPE NUMERIC(19,4);
+ALTER TABLE accounts ALTER COLUMN opening_balance TYPE NUMERIC(19,4);
+ALTER TABLE transfers ALTER COLUMN amount TYPE NUMERIC(19,4);
+ALTER TABLE transfers ALTER COLUMN converted_amount TYPE NUMERIC(19,4);
+ALTER TABLE financial_goals ALTER COLUMN target_amount TYPE NUMERIC(19,4);
+ALTER TABLE financial_goals ALTER COLUMN saved_amount TYPE NUMERIC(19,4);
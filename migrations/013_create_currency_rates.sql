@@ -0,0 +1,16 @@
+-- 013_create_currency_rates.sql
+-- Курс rate означает: 1 единица base_currency = rate единиц target_currency на дату rate_date.
+CREATE TABLE IF NOT EXISTS currency_rates (
+    id SERIAL PRIMARY KEY,
+    base_currency VARCHAR(3) NOT NULL,
+    target_currency VARCHAR(3) NOT NULL,
+    rate NUMERIC(20,10) NOT NULL CHECK (rate > 0),
+    rate_date DATE NOT NULL,
+    source VARCHAR(50) NOT NULL DEFAULT 'manual',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK (base_currency <> target_currency),
+    UNIQUE (base_currency, target_currency, rate_date)
+);
+
+CREATE INDEX IF NOT EXISTS idx_currency_rates_pair_date
+ON currency_rates (base_currency, target_currency, rate_date DESC);
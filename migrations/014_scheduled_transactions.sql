@@ -0,0 +1,41 @@
+-- 014_scheduled_transactions.sql
+CREATE TABLE IF NOT EXISTS scheduled_transactions (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    account_id INTEGER NOT NULL REFERENCES accounts (id) ON DELETE CASCADE,
+    amount NUMERIC(19,4) NOT NULL,
+    type VARCHAR(20) NOT NULL,
+    schedule VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE scheduled_transactions ALTER COLUMN amount TYPE NUMERIC(19,4);
+ALTER TABLE scheduled_transactions ALTER COLUMN schedule TYPE VARCHAR(255);
+
+ALTER TABLE scheduled_transactions
+ADD COLUMN IF NOT EXISTS category_id INTEGER REFERENCES categories (id) ON DELETE SET NULL,
+ADD COLUMN IF NOT EXISTS description TEXT NOT NULL DEFAULT '',
+ADD COLUMN IF NOT EXISTS start_date DATE NOT NULL DEFAULT CURRENT_DATE,
+ADD COLUMN IF NOT EXISTS end_date DATE,
+ADD COLUMN IF NOT EXISTS paused BOOLEAN NOT NULL DEFAULT FALSE,
+ADD COLUMN IF NOT EXISTS next_run_date DATE,
+ADD COLUMN IF NOT EXISTS last_error TEXT NOT NULL DEFAULT '';
+
+-- Существующие расписания никогда не выполнялись: начинаем с сегодняшнего дня, без проводок задним числом.
+UPDATE scheduled_transactions SET next_run_date = CURRENT_DATE WHERE next_run_date IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_scheduled_transactions_due
+ON scheduled_transactions (next_run_date) WHERE NOT paused;
+CREATE INDEX IF NOT EXISTS idx_scheduled_transactions_user_id ON scheduled_transactions (user_id);
+
+-- Каждое выполнение расписания фиксируется одной строкой: уникальный ключ (schedule_id, occurrence_date)
+-- гарантирует, что транзакция за дату создаётся ровно один раз даже при нескольких экземплярах приложения.
+CREATE TABLE IF NOT EXISTS scheduled_occurrences (
+    id SERIAL PRIMARY KEY,
+    schedule_id INTEGER NOT NULL REFERENCES scheduled_transactions (id) ON DELETE CASCADE,
+    occurrence_date DATE NOT NULL,
+    status VARCHAR(10) NOT NULL CHECK (status IN ('posted', 'skipped', 'held')),
+    transaction_id INTEGER REFERENCES transactions (id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (schedule_id, occurrence_date)
+);
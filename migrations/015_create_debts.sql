@@ -0,0 +1,41 @@
+-- 015_create_debts.sql
+CREATE TABLE IF NOT EXISTS debts (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    contact VARCHAR(255) NOT NULL,
+    amount NUMERIC(19,4) NOT NULL,
+    due_date DATE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE debts ALTER COLUMN amount TYPE NUMERIC(19,4);
+ALTER TABLE debts ALTER COLUMN due_date DROP NOT NULL;
+
+-- direction: lent — деньги дали в долг (нам должны), borrowed — деньги взяли в долг (мы должны)
+ALTER TABLE debts
+ADD COLUMN IF NOT EXISTS direction VARCHAR(10) NOT NULL DEFAULT 'lent' CHECK (direction IN ('lent', 'borrowed')),
+ADD COLUMN IF NOT EXISTS currency VARCHAR(3) NOT NULL DEFAULT 'KZT',
+ADD COLUMN IF NOT EXISTS description TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_debts_user_id ON debts (user_id);
+
+-- Выдача долга и его возвраты — записи журнала типов debt_out/debt_in, связанные с долгом.
+-- Как и переводы, они не относятся к доходам и расходам и не имеют категории.
+ALTER TABLE transactions
+ADD COLUMN IF NOT EXISTS debt_id INTEGER REFERENCES debts (id);
+
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_type_check;
+ALTER TABLE transactions
+ADD CONSTRAINT transactions_type_check
+CHECK (type IN ('income', 'expense', 'transfer_in', 'transfer_out', 'debt_in', 'debt_out'));
+
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_transfer_link_check;
+ALTER TABLE transactions
+ADD CONSTRAINT transactions_transfer_link_check
+CHECK ((transfer_id IS NOT NULL) = (type IN ('transfer_in', 'transfer_out')));
+
+ALTER TABLE transactions
+ADD CONSTRAINT transactions_debt_link_check
+CHECK ((debt_id IS NOT NULL) = (type IN ('debt_in', 'debt_out')));
+
+CREATE INDEX IF NOT EXISTS idx_transactions_debt_id ON transactions (debt_id);
@@ -0,0 +1,39 @@
+-- 016_deposits.sql
+CREATE TABLE IF NOT EXISTS deposits (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    account_id INTEGER NOT NULL REFERENCES accounts (id) ON DELETE CASCADE,
+    initial_amount NUMERIC(19,4) NOT NULL,
+    interest_rate NUMERIC(9,4) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    end_date DATE NOT NULL
+);
+
+ALTER TABLE deposits ALTER COLUMN initial_amount TYPE NUMERIC(19,4);
+
+-- interest_type: simple — проценты начисляются на первоначальную сумму, compound — на сумму с капитализацией.
+-- capitalization задаёт периодичность начисления: monthly, quarterly или maturity (в конце срока).
+ALTER TABLE deposits
+ADD COLUMN IF NOT EXISTS currency VARCHAR(3) NOT NULL DEFAULT 'KZT',
+ADD COLUMN IF NOT EXISTS interest_type VARCHAR(10) NOT NULL DEFAULT 'simple' CHECK (interest_type IN ('simple', 'compound')),
+ADD COLUMN IF NOT EXISTS capitalization VARCHAR(10) NOT NULL DEFAULT 'maturity'
+    CHECK (capitalization IN ('monthly', 'quarterly', 'maturity')),
+ADD COLUMN IF NOT EXISTS start_date DATE NOT NULL DEFAULT CURRENT_DATE,
+ADD COLUMN IF NOT EXISTS status VARCHAR(10) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'closed')),
+ADD COLUMN IF NOT EXISTS next_accrual_date DATE,
+ADD COLUMN IF NOT EXISTS last_error TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_deposits_due ON deposits (next_accrual_date) WHERE status = 'active';
+CREATE INDEX IF NOT EXISTS idx_deposits_user_id ON deposits (user_id);
+
+-- Каждое начисление процентов фиксируется одной строкой: уникальный ключ (deposit_id, accrual_date)
+-- гарантирует, что проценты за период зачисляются ровно один раз.
+CREATE TABLE IF NOT EXISTS deposit_accruals (
+    id SERIAL PRIMARY KEY,
+    deposit_id INTEGER NOT NULL REFERENCES deposits (id) ON DELETE CASCADE,
+    accrual_date DATE NOT NULL,
+    interest NUMERIC(19,4) NOT NULL,
+    transaction_id INTEGER REFERENCES transactions (id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (deposit_id, accrual_date)
+);
@@ -0,0 +1,31 @@
+-- 017_create_budgets.sql
+-- Лимит расходов по категории на период (неделя, месяц, квартал, год). При rollover неизрасходованный
+-- остаток переносится на следующий период, начиная с периода start_date.
+CREATE TABLE IF NOT EXISTS budgets (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    category_id INTEGER NOT NULL REFERENCES categories (id) ON DELETE CASCADE,
+    amount NUMERIC(19,4) NOT NULL CHECK (amount > 0),
+    currency VARCHAR(3) NOT NULL,
+    period VARCHAR(10) NOT NULL CHECK (period IN ('weekly', 'monthly', 'quarterly', 'yearly')),
+    rollover BOOLEAN NOT NULL DEFAULT FALSE,
+    thresholds INTEGER[] NOT NULL DEFAULT '{80,100}',
+    start_date DATE NOT NULL DEFAULT CURRENT_DATE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (user_id, category_id, period)
+);
+
+CREATE INDEX IF NOT EXISTS idx_budgets_user_id ON budgets (user_id);
+
+-- Уведомление о превышении порога создаётся один раз на бюджет, период и порог.
+CREATE TABLE IF NOT EXISTS budget_alerts (
+    id SERIAL PRIMARY KEY,
+    budget_id INTEGER NOT NULL REFERENCES budgets (id) ON DELETE CASCADE,
+    period_start DATE NOT NULL,
+    threshold INTEGER NOT NULL,
+    spent NUMERIC(19,4) NOT NULL,
+    available NUMERIC(19,4) NOT NULL,
+    read BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (budget_id, period_start, threshold)
+);
@@ -0,0 +1,29 @@
+-- 018_create_envelopes.sql
+-- Конверты (zero-based бюджет): доходы попадают в «готово к распределению», пользователь распределяет их
+-- по конвертам, расходы по категориям конверта уменьшают его остаток. Суммы — в предпочитаемой валюте пользователя.
+CREATE TABLE IF NOT EXISTS envelopes (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_envelopes_user_id ON envelopes (user_id);
+
+-- Категория расходов относится не более чем к одному конверту.
+CREATE TABLE IF NOT EXISTS envelope_categories (
+    category_id INTEGER PRIMARY KEY REFERENCES categories (id) ON DELETE CASCADE,
+    envelope_id INTEGER NOT NULL REFERENCES envelopes (id) ON DELETE CASCADE
+);
+
+-- Распределение денег по конвертам за месяц; перемещение между конвертами — две строки с противоположными знаками.
+CREATE TABLE IF NOT EXISTS envelope_assignments (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    envelope_id INTEGER NOT NULL REFERENCES envelopes (id) ON DELETE CASCADE,
+    month DATE NOT NULL CHECK (month = date_trunc('month', month)),
+    amount NUMERIC(19,4) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_envelope_assignments_user_month ON envelope_assignments (user_id, month);
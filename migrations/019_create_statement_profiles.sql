@@ -0,0 +1,24 @@
+-- 019_create_statement_profiles.sql
+-- Профили CSV-выписок: как читать выписку конкретного банка.
+CREATE TABLE IF NOT EXISTS statement_profiles (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    delimiter VARCHAR(2) NOT NULL DEFAULT ',',
+    skip_rows INTEGER NOT NULL DEFAULT 0 CHECK (skip_rows >= 0),
+    no_header BOOLEAN NOT NULL DEFAULT FALSE,
+    date_column VARCHAR(255) NOT NULL,
+    date_format VARCHAR(64) NOT NULL DEFAULT '',
+    amount_column VARCHAR(255) NOT NULL DEFAULT '',
+    sign_convention VARCHAR(20) NOT NULL DEFAULT 'negative_expense'
+        CHECK (sign_convention IN ('negative_expense', 'positive_expense')),
+    debit_column VARCHAR(255) NOT NULL DEFAULT '',
+    credit_column VARCHAR(255) NOT NULL DEFAULT '',
+    decimal_separator VARCHAR(1) NOT NULL DEFAULT '.' CHECK (decimal_separator IN ('.', ',')),
+    description_column VARCHAR(255) NOT NULL DEFAULT '',
+    currency_column VARCHAR(255) NOT NULL DEFAULT '',
+    currency VARCHAR(3) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_statement_profiles_user_id ON statement_profiles (user_id);
@@ -0,0 +1,12 @@
+-- 020_create_statement_lines.sql
+-- Идентификаторы импортированных строк выписок (FITID из OFX, отпечаток строки QIF).
+-- Уникальный ключ (account_id, external_id) делает повторный импорт той же или пересекающейся
+-- выписки идемпотентным: уже импортированные строки пропускаются.
+CREATE TABLE IF NOT EXISTS statement_lines (
+    id SERIAL PRIMARY KEY,
+    account_id INTEGER NOT NULL REFERENCES accounts (id) ON DELETE CASCADE,
+    external_id VARCHAR(255) NOT NULL,
+    transaction_id INTEGER REFERENCES transactions (id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (account_id, external_id)
+);
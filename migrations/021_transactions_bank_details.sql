@@ -0,0 +1,10 @@
+-- 021_transactions_bank_details.sql
+-- Данные банковских выписок (camt.053, MT940) на транзакции: дата валютирования (дата проводки
+-- хранится в created_at), реквизиты контрагента и исходная сумма платежа в другой валюте.
+ALTER TABLE transactions
+    ADD COLUMN IF NOT EXISTS value_date DATE,
+    ADD COLUMN IF NOT EXISTS counterparty_name VARCHAR(255) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS counterparty_account VARCHAR(64) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS counterparty_bank VARCHAR(64) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS original_amount NUMERIC(19,4),
+    ADD COLUMN IF NOT EXISTS original_currency VARCHAR(3) NOT NULL DEFAULT '';
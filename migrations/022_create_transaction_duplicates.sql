@@ -0,0 +1,18 @@
+-- 022_create_transaction_duplicates.sql
+-- Пары транзакций, похожих на дубли: transaction_id — новая транзакция, duplicate_of — уже существовавшая.
+-- Пара ждёт решения пользователя (pending): объединения, при котором одна из транзакций удаляется
+-- вместе с парой, или отклонения (dismissed).
+CREATE TABLE IF NOT EXISTS transaction_duplicates (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    transaction_id INTEGER NOT NULL REFERENCES transactions (id) ON DELETE CASCADE,
+    duplicate_of INTEGER NOT NULL REFERENCES transactions (id) ON DELETE CASCADE,
+    score NUMERIC(3,2) NOT NULL CHECK (score > 0 AND score <= 1),
+    status VARCHAR(10) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'dismissed')),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    resolved_at TIMESTAMP,
+    UNIQUE (transaction_id, duplicate_of)
+);
+
+CREATE INDEX IF NOT EXISTS idx_transaction_duplicates_user_status ON transaction_duplicates (user_id, status);
+CREATE INDEX IF NOT EXISTS idx_transaction_duplicates_duplicate_of ON transaction_duplicates (duplicate_of);
@@ -0,0 +1,20 @@
+-- 023_create_category_rules.sql
+-- Правила автоматической категоризации. Условия (подстрока или регулярное выражение описания, тип,
+-- счёт, границы суммы) объединяются по И; правила проверяются по возрастанию priority, срабатывает первое.
+CREATE TABLE IF NOT EXISTS category_rules (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    name VARCHAR(255) NOT NULL DEFAULT '',
+    category_id INTEGER NOT NULL REFERENCES categories (id) ON DELETE CASCADE,
+    priority INTEGER NOT NULL DEFAULT 0,
+    description_contains VARCHAR(255) NOT NULL DEFAULT '',
+    description_pattern VARCHAR(500) NOT NULL DEFAULT '',
+    type VARCHAR(10) NOT NULL DEFAULT '' CHECK (type IN ('', 'income', 'expense')),
+    account_id INTEGER REFERENCES accounts (id) ON DELETE CASCADE,
+    min_amount NUMERIC(19,4) CHECK (min_amount >= 0),
+    max_amount NUMERIC(19,4) CHECK (max_amount >= 0),
+    disabled BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_category_rules_user_priority ON category_rules (user_id, priority, id);
@@ -0,0 +1,15 @@
+-- 024_create_category_feedback.sql
+-- Ответы пользователя на подсказки категорий: принятая подсказка (accepted) или исправление.
+-- Транзакции с подтверждённой категорией получают больший вес при обучении классификатора.
+CREATE TABLE IF NOT EXISTS category_feedback (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    transaction_id INTEGER NOT NULL UNIQUE REFERENCES transactions (id) ON DELETE CASCADE,
+    suggested_category_id INTEGER REFERENCES categories (id) ON DELETE SET NULL,
+    suggested_confidence NUMERIC(5,4),
+    category_id INTEGER NOT NULL REFERENCES categories (id) ON DELETE CASCADE,
+    accepted BOOLEAN NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_category_feedback_user_id ON category_feedback (user_id);
@@ -0,0 +1,26 @@
+-- 025_create_transaction_splits.sql
+-- Разбивка дохода или расхода по нескольким категориям (например, чек супермаркета: продукты,
+-- хозяйственные товары, алкоголь). Сумма строк равна сумме транзакции, category_id самой транзакции пуст.
+CREATE TABLE IF NOT EXISTS transaction_splits (
+    id SERIAL PRIMARY KEY,
+    transaction_id INTEGER NOT NULL REFERENCES transactions (id) ON DELETE CASCADE,
+    category_id INTEGER NOT NULL REFERENCES categories (id),
+    amount NUMERIC(19,4) NOT NULL CHECK (amount > 0),
+    memo VARCHAR(255) NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_transaction_splits_transaction_id ON transaction_splits (transaction_id);
+CREATE INDEX IF NOT EXISTS idx_transaction_splits_category_id ON transaction_splits (category_id);
+
+-- Строки транзакций для отчётов по категориям: транзакция без разбивки даёт одну строку,
+-- транзакция с разбивкой — по строке на каждую часть со своей категорией и суммой.
+CREATE OR REPLACE VIEW transaction_lines AS
+SELECT t.id AS transaction_id, NULL::INTEGER AS split_id, t.user_id, t.account_id, t.type, t.category_id,
+       t.amount, t.currency, t.description, t.created_at
+FROM transactions t
+WHERE NOT EXISTS (SELECT 1 FROM transaction_splits s WHERE s.transaction_id = t.id)
+UNION ALL
+SELECT t.id, s.id, t.user_id, t.account_id, t.type, s.category_id,
+       s.amount, t.currency, COALESCE(NULLIF(s.memo, ''), t.description), t.created_at
+FROM transaction_splits s
+JOIN transactions t ON t.id = s.transaction_id;
@@ -0,0 +1,8 @@
+-- 026_categories_hierarchy.sql
+-- Вложенные категории (Транспорт → Топливо, Такси, Парковка). Родитель принадлежит тому же
+-- пользователю и имеет тот же тип; циклы запрещаются сервисом. При удалении родителя
+-- дочерние категории становятся верхнеуровневыми.
+ALTER TABLE categories
+ADD COLUMN IF NOT EXISTS parent_id INTEGER REFERENCES categories (id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_categories_parent_id ON categories (parent_id);
@@ -0,0 +1,10 @@
+-- 028_transaction_search_indexes.sql
+-- Индексы поиска транзакций (/transactions): сортировка и постраничная выдача по ключу
+-- (столбец сортировки, id) внутри пользователя, фильтры по счёту и категории и полнотекстовый
+-- поиск по описанию. Конфигурация 'simple' не привязана к языку: описания бывают и на русском,
+-- и на английском, и с названиями магазинов.
+CREATE INDEX IF NOT EXISTS idx_transactions_user_created_at ON transactions (user_id, created_at, id);
+CREATE INDEX IF NOT EXISTS idx_transactions_user_amount ON transactions (user_id, amount, id);
+CREATE INDEX IF NOT EXISTS idx_transactions_account_created_at ON transactions (account_id, created_at, id);
+CREATE INDEX IF NOT EXISTS idx_transactions_category_created_at ON transactions (category_id, created_at, id);
+CREATE INDEX IF NOT EXISTS idx_transactions_description_fts ON transactions USING GIN (to_tsvector('simple', description));
@@ -0,0 +1,76 @@
+-- 029_create_audit_log.sql
+-- Журнал изменений транзакций, счетов и финансовых целей. Строки пишутся триггерами при каждой
+-- вставке, изменении и удалении (включая каскадные удаления и пересчёт балансов) и не могут
+-- быть изменены или удалены. before — строка до изменения, after — после.
+-- Автор изменения берётся из параметра сеанса finance.actor (его задают фоновые задачи
+-- в своей транзакции), иначе изменение приписывается владельцу записи: через API пользователь
+-- меняет только свои записи.
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    entity_type VARCHAR(30) NOT NULL,
+    entity_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    action VARCHAR(10) NOT NULL CHECK (action IN ('insert', 'update', 'delete')),
+    actor VARCHAR(100) NOT NULL,
+    before JSONB,
+    after JSONB,
+    created_at TIMESTAMP NOT NULL DEFAULT clock_timestamp()
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_entity ON audit_log (entity_type, entity_id, id);
+
+CREATE OR REPLACE FUNCTION audit_row() RETURNS TRIGGER AS $$
+DECLARE
+    old_row JSONB;
+    new_row JSONB;
+    owner_id INTEGER;
+    entity INTEGER;
+BEGIN
+    IF TG_OP <> 'INSERT' THEN
+        old_row := to_jsonb(OLD);
+        owner_id := OLD.user_id;
+        entity := OLD.id;
+    END IF;
+    IF TG_OP <> 'DELETE' THEN
+        new_row := to_jsonb(NEW);
+        owner_id := NEW.user_id;
+        entity := NEW.id;
+    END IF;
+    -- UPDATE без фактических изменений в журнал не попадает
+    IF TG_OP = 'UPDATE' AND old_row = new_row THEN
+        RETURN NULL;
+    END IF;
+
+    INSERT INTO audit_log (entity_type, entity_id, user_id, action, actor, before, after)
+    VALUES (TG_ARGV[0], entity, owner_id, lower(TG_OP),
+            COALESCE(NULLIF(current_setting('finance.actor', true), ''), 'user:' || owner_id),
+            old_row, new_row);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_log_append_only ON audit_log;
+CREATE TRIGGER audit_log_append_only BEFORE UPDATE OR DELETE ON audit_log
+    FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
+
+DROP TRIGGER IF EXISTS audit_log_no_truncate ON audit_log;
+CREATE TRIGGER audit_log_no_truncate BEFORE TRUNCATE ON audit_log
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only();
+
+DROP TRIGGER IF EXISTS transactions_audit ON transactions;
+CREATE TRIGGER transactions_audit AFTER INSERT OR UPDATE OR DELETE ON transactions
+    FOR EACH ROW EXECUTE FUNCTION audit_row('transaction');
+
+DROP TRIGGER IF EXISTS accounts_audit ON accounts;
+CREATE TRIGGER accounts_audit AFTER INSERT OR UPDATE OR DELETE ON accounts
+    FOR EACH ROW EXECUTE FUNCTION audit_row('account');
+
+DROP TRIGGER IF EXISTS financial_goals_audit ON financial_goals;
+CREATE TRIGGER financial_goals_audit AFTER INSERT OR UPDATE OR DELETE ON financial_goals
+    FOR EACH ROW EXECUTE FUNCTION audit_row('financial_goal');
@@ -0,0 +1,43 @@
+-- 030_soft_delete.sql
+-- Корзина. Удалённые пользователем транзакции, переводы, счета и категории не стираются, а получают
+-- trash_id — запись корзины. Одна запись объединяет удалённый объект и всё, что удалено вместе с ним
+-- (счёт и его транзакции, категория с подкатегориями и их транзакциями), поэтому и восстанавливаются
+-- они вместе. Записи старше срока хранения удаляются окончательно фоновой задачей.
+CREATE TABLE IF NOT EXISTS trash (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    entity_type VARCHAR(30) NOT NULL CHECK (entity_type IN ('transaction', 'transfer', 'account', 'category')),
+    entity_id INTEGER NOT NULL,
+    name TEXT NOT NULL DEFAULT '',
+    deleted_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_trash_user_id ON trash (user_id, deleted_at);
+CREATE INDEX IF NOT EXISTS idx_trash_deleted_at ON trash (deleted_at);
+
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS trash_id INTEGER REFERENCES trash (id);
+ALTER TABLE transfers ADD COLUMN IF NOT EXISTS trash_id INTEGER REFERENCES trash (id);
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS trash_id INTEGER REFERENCES trash (id);
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS trash_id INTEGER REFERENCES trash (id);
+
+CREATE INDEX IF NOT EXISTS idx_transactions_trash_id ON transactions (trash_id) WHERE trash_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_transfers_trash_id ON transfers (trash_id) WHERE trash_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_accounts_trash_id ON accounts (trash_id) WHERE trash_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_categories_trash_id ON categories (trash_id) WHERE trash_id IS NOT NULL;
+
+-- Удалённый пользователь не может войти, но до окончательного удаления может восстановить профиль
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+
+-- Транзакции в корзине не попадают в отчёты
+CREATE OR REPLACE VIEW transaction_lines AS
+SELECT t.id AS transaction_id, NULL::INTEGER AS split_id, t.user_id, t.account_id, t.type, t.category_id,
+       t.amount, t.currency, t.description, t.created_at
+FROM transactions t
+WHERE t.trash_id IS NULL
+  AND NOT EXISTS (SELECT 1 FROM transaction_splits s WHERE s.transaction_id = t.id)
+UNION ALL
+SELECT t.id, s.id, t.user_id, t.account_id, t.type, s.category_id,
+       s.amount, t.currency, COALESCE(NULLIF(s.memo, ''), t.description), t.created_at
+FROM transaction_splits s
+JOIN transactions t ON t.id = s.transaction_id
+WHERE t.trash_id IS NULL;
@@ -0,0 +1,20 @@
+-- 031_create_net_worth_snapshots.sql
+-- Ежедневные снимки собственного капитала. Снимок пользователя за день — строки по каждому счёту
+-- и по непогашенным долгам в каждой валюте (lent — нам должны, borrowed — мы должны). Сумма хранится
+-- в исходной валюте и пересчитанной в валюту отчётов пользователя на дату снимка; если пользователь
+-- сменит валюту, история пересчитывается из исходных сумм.
+CREATE TABLE IF NOT EXISTS net_worth_snapshots (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    date DATE NOT NULL,
+    kind VARCHAR(10) NOT NULL CHECK (kind IN ('account', 'lent', 'borrowed')),
+    account_id INTEGER REFERENCES accounts (id) ON DELETE CASCADE,
+    currency VARCHAR(3) NOT NULL,
+    amount NUMERIC(19,4) NOT NULL,
+    report_currency VARCHAR(3) NOT NULL,
+    converted NUMERIC(19,4) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK ((account_id IS NOT NULL) = (kind = 'account'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_net_worth_snapshots_user_date ON net_worth_snapshots (user_id, date);
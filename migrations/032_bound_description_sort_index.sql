@@ -0,0 +1,7 @@
+-- 032_bound_description_sort_index.sql
+-- Индекс сортировки по описанию из 028 строился по самому столбцу TEXT: описание длиннее ~2.7 КБ
+-- не помещалось в строку B-дерева, и вставка такой транзакции падала. Поиск сортирует по первым
+-- 200 символам описания, а NULL считает пустой строкой, чтобы постраничная выдача не теряла такие строки.
+DROP INDEX IF EXISTS idx_transactions_user_description;
+CREATE INDEX IF NOT EXISTS idx_transactions_user_description_prefix
+    ON transactions (user_id, (left(COALESCE(description, ''), 200)), id);
@@ -0,0 +1,6 @@
+-- 033_uppercase_account_currency.sql
+-- Счета создавались с кодом валюты в том виде, в каком его прислал клиент ("usd"), и такой счёт
+-- отклонял транзакции в "USD". Теперь код приводится к верхнему регистру при создании счёта;
+-- приводим к нему и уже сохранённые коды счетов и их транзакций.
+UPDATE accounts SET currency = upper(trim(currency)) WHERE currency <> upper(trim(currency));
+UPDATE transactions SET currency = upper(trim(currency)) WHERE currency <> upper(trim(currency));